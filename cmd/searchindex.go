@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/spf13/cobra"
+)
+
+// 离线重建消息的搜索索引（全文索引和发送者+消息时间索引），需要先停止服务
+type rebuildSearchIndexCMD struct {
+	ctx *WuKongIMContext
+}
+
+func newRebuildSearchIndexCMD(ctx *WuKongIMContext) *rebuildSearchIndexCMD {
+	return &rebuildSearchIndexCMD{
+		ctx: ctx,
+	}
+}
+
+func (r *rebuildSearchIndexCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild-search-index",
+		Short: "rebuild the message search indexes (the server must be stopped)",
+		RunE:  r.run,
+	}
+	return cmd
+}
+
+func (r *rebuildSearchIndexCMD) run(cmd *cobra.Command, args []string) error {
+	db := wkdb.NewWukongDB(wkdb.NewOptions(
+		wkdb.WithDir(path.Join(serverOpts.DataDir, "db")),
+		wkdb.WithShardNum(serverOpts.Db.ShardNum),
+		wkdb.WithNodeId(serverOpts.Cluster.NodeId),
+		wkdb.WithSlotCount(serverOpts.Cluster.SlotCount),
+		wkdb.WithFullTextIndex(true),
+		wkdb.WithIsCmdChannel(serverOpts.IsCmdChannel),
+	))
+	if err := db.Open(); err != nil {
+		fmt.Println("Error: ", err)
+		return err
+	}
+	defer db.Close()
+
+	if err := db.RebuildMessageSearchIndex(); err != nil {
+		fmt.Println("Error: ", err)
+		return err
+	}
+	fmt.Println("message search index rebuilt")
+	return nil
+}
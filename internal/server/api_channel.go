@@ -5,6 +5,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -41,6 +42,10 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	//################### 订阅者 ###################// 删除频道
 	r.POST("/channel/subscriber_add", ch.addSubscriber)       // 添加订阅者
 	r.POST("/channel/subscriber_remove", ch.removeSubscriber) // 移除订阅者
+	r.POST("/channel/subscriber_role", ch.setSubscriberRole)  // 设置订阅者角色
+	r.POST("/channel/subscriber_update", ch.updateSubscriber) // 更新订阅者的频道内昵称和扩展数据
+	r.GET("/channel/subscribers", ch.subscribersGet)          // 获取订阅者列表（包含角色），支持cursor和limit分页
+	r.GET("/channel/subscribers/count", ch.subscriberCount)   // 获取订阅者数量
 
 	//################### 黑明单 ###################// 删除频道
 	r.POST("/channel/blacklist_add", ch.blacklistAdd)       // 添加黑明单
@@ -52,6 +57,11 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/channel/whitelist_set", ch.whitelistSet) // 设置白明单（覆盖
 	r.POST("/channel/whitelist_remove", ch.whitelistRemove)
 	r.GET("/channel/whitelist", ch.whitelistGet) // 获取白名单
+
+	//################### 禁言 ###################
+	r.POST("/channel/mute", ch.mute)     // 全员禁言或成员禁言（可设置时长）
+	r.POST("/channel/unmute", ch.unmute) // 解除全员禁言或成员禁言
+	r.GET("/channel/mutes", ch.muteList) // 获取频道的禁言列表
 	//################### 频道消息 ###################
 	// 同步频道消息
 	r.POST("/channel/messagesync", ch.syncMessages)
@@ -101,6 +111,14 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 		ch.Error("创建频道失败！", zap.Error(err))
 		return
 	}
+
+	// 保留原订阅者的加入点等属性，新订阅者记录加入点
+	existSubscribers, err := ch.s.store.GetSubscribersDetail(req.ChannelID, req.ChannelType)
+	if err != nil {
+		ch.Error("获取所有订阅者失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取所有订阅者失败！"))
+		return
+	}
 	err = ch.s.store.RemoveAllSubscriber(req.ChannelID, req.ChannelType)
 	if err != nil {
 		ch.Error("移除所有订阅者失败！", zap.Error(err))
@@ -108,7 +126,13 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 		return
 	}
 	if len(req.Subscribers) > 0 {
-		err = ch.s.store.AddSubscribers(req.ChannelID, req.ChannelType, req.Subscribers)
+		subscribers, err := ch.newJoinSubscribers(req.ChannelID, req.ChannelType, req.Subscribers, existSubscribers)
+		if err != nil {
+			ch.Error("获取频道最新消息序号失败！", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		err = ch.s.store.AddOrUpdateSubscribers(req.ChannelID, req.ChannelType, subscribers)
 		if err != nil {
 			ch.Error("添加订阅者失败！", zap.Error(err))
 			c.ResponseError(err)
@@ -125,6 +149,35 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// newJoinSubscribers 生成订阅者，已存在的订阅者保留原属性，新订阅者记录加入时间和加入点
+func (ch *ChannelAPI) newJoinSubscribers(channelId string, channelType uint8, uids []string, existSubscribers []wkdb.Subscriber) ([]wkdb.Subscriber, error) {
+	existMap := make(map[string]wkdb.Subscriber, len(existSubscribers))
+	for _, subscriber := range existSubscribers {
+		existMap[subscriber.Uid] = subscriber
+	}
+	joinSeq, err := ch.s.channelLastMsgSeq(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	joinedAt := time.Now().Unix()
+	subscribers := make([]wkdb.Subscriber, 0, len(uids))
+	for _, uid := range uids {
+		if strings.TrimSpace(uid) == "" {
+			continue
+		}
+		subscriber, ok := existMap[uid]
+		if !ok {
+			subscriber = wkdb.Subscriber{
+				Uid:      uid,
+				JoinedAt: joinedAt,
+				JoinSeq:  joinSeq,
+			}
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers, nil
+}
+
 // 更新或添加频道信息
 func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 	var req ChannelInfoReq
@@ -134,6 +187,10 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 		c.ResponseError(errors.New("数据格式有误！"))
 		return
 	}
+	if err := checkHistoryVisibility(req.HistoryVisibility); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	if ch.s.opts.ClusterOn() {
 		leaderInfo, err := ch.s.cluster.SlotLeaderOfChannel(req.ChannelID, req.ChannelType) // 获取频道的领导节点
@@ -245,17 +302,44 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			return err
 		}
 	}
-	newSubscribers := make([]string, 0, len(req.Subscribers))
+	existMap := make(map[string]struct{}, len(existSubscribers))
+	for _, uid := range existSubscribers {
+		existMap[uid] = struct{}{}
+	}
+
+	// 记录加入时间和加入时频道的最新消息序号
+	joinSeq, err := ch.s.channelLastMsgSeq(req.ChannelID, req.ChannelType)
+	if err != nil {
+		ch.Error("获取频道最新消息序号失败！", zap.Error(err))
+		return err
+	}
+	joinedAt := time.Now().Unix()
+	members := make([]subscriberMemberReq, 0, len(req.Subscribers)+len(req.Members))
 	for _, subscriber := range req.Subscribers {
-		if strings.TrimSpace(subscriber) == "" {
+		members = append(members, subscriberMemberReq{UID: subscriber})
+	}
+	members = append(members, req.Members...)
+
+	newSubscribers := make([]wkdb.Subscriber, 0, len(members))
+	for _, member := range members {
+		if strings.TrimSpace(member.UID) == "" {
 			continue
 		}
-		if !wkutil.ArrayContains(existSubscribers, subscriber) {
-			newSubscribers = append(newSubscribers, subscriber)
+		if _, ok := existMap[member.UID]; ok {
+			continue
 		}
+		existMap[member.UID] = struct{}{}
+		newSubscribers = append(newSubscribers, wkdb.Subscriber{
+			Uid:        member.UID,
+			JoinedAt:   joinedAt,
+			JoinSeq:    joinSeq,
+			InviterUid: req.InviterUID,
+			Nickname:   member.Nickname,
+			Extra:      member.Extra,
+		})
 	}
 	if len(newSubscribers) > 0 {
-		err = ch.s.store.AddSubscribers(req.ChannelID, req.ChannelType, newSubscribers)
+		err = ch.s.store.AddOrUpdateSubscribers(req.ChannelID, req.ChannelType, newSubscribers)
 		if err != nil {
 			ch.Error("添加订阅者失败！", zap.Error(err))
 			return err
@@ -326,6 +410,143 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 	c.ResponseOK()
 }
 
+// 设置订阅者角色
+func (ch *ChannelAPI) setSubscriberRole(c *wkhttp.Context) {
+	var req subscriberRoleReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	err = ch.s.store.SetSubscriberRole(req.ChannelID, req.ChannelType, req.Subscribers, req.Role)
+	if err != nil {
+		ch.Error("设置订阅者角色失败！", zap.Error(err))
+		c.ResponseError(errors.New("设置订阅者角色失败！"))
+		return
+	}
+
+	c.ResponseOK()
+}
+
+// 更新订阅者的频道内昵称和扩展数据，不是订阅者的忽略
+func (ch *ChannelAPI) updateSubscriber(c *wkhttp.Context) {
+	var req subscriberUpdateReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	subscribers := make([]wkdb.Subscriber, 0, len(req.Members))
+	for _, member := range req.Members {
+		subscriber, err := ch.s.store.GetSubscriber(req.ChannelID, req.ChannelType, member.UID)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			ch.Error("获取订阅者失败！", zap.Error(err))
+			c.ResponseError(errors.New("获取订阅者失败！"))
+			return
+		}
+		// 加入时间、加入序号和邀请人保持不变
+		subscriber.Nickname = member.Nickname
+		subscriber.Extra = member.Extra
+		subscribers = append(subscribers, subscriber)
+	}
+	if len(subscribers) > 0 {
+		err = ch.s.store.UpdateSubscribers(req.ChannelID, req.ChannelType, subscribers)
+		if err != nil {
+			ch.Error("更新订阅者失败！", zap.Error(err))
+			c.ResponseError(errors.New("更新订阅者失败！"))
+			return
+		}
+	}
+
+	c.ResponseOK()
+}
+
+// 获取订阅者列表（包含角色）
+func (ch *ChannelAPI) subscribersGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, channelId, channelType, nil) {
+		return
+	}
+
+	var (
+		subscribers []wkdb.Subscriber
+		err         error
+	)
+	cursor := c.Query("cursor") // 上一页最后一个订阅者的uid
+	limit := wkutil.ParseInt(c.Query("limit"))
+	if cursor != "" || limit > 0 {
+		if limit <= 0 || limit > maxSubscriberPageLimit {
+			limit = maxSubscriberPageLimit
+		}
+		subscribers, err = ch.s.store.GetSubscribersPage(channelId, channelType, cursor, limit)
+	} else {
+		subscribers, err = ch.s.store.GetSubscribersDetail(channelId, channelType)
+	}
+	if err != nil {
+		ch.Error("获取订阅者失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取订阅者失败！"))
+		return
+	}
+	if subscribers == nil {
+		subscribers = make([]wkdb.Subscriber, 0)
+	}
+
+	c.JSON(http.StatusOK, subscribers)
+}
+
+// 获取订阅者数量
+func (ch *ChannelAPI) subscriberCount(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, channelId, channelType, nil) {
+		return
+	}
+
+	count, err := ch.s.store.GetSubscriberCount(channelId, channelType)
+	if err != nil {
+		ch.Error("获取订阅者数量失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取订阅者数量失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"count": count,
+	})
+}
+
 func (ch *ChannelAPI) blacklistAdd(c *wkhttp.Context) {
 	var req blacklistReq
 	bodyBytes, err := BindJSON(&req, c)
@@ -643,6 +864,99 @@ func (ch *ChannelAPI) whitelistGet(c *wkhttp.Context) {
 	c.JSON(http.StatusOK, whitelist)
 }
 
+// 全员禁言或成员禁言
+func (ch *ChannelAPI) mute(c *wkhttp.Context) {
+	var req channelMuteReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	var expireAt int64
+	if req.Duration > 0 {
+		expireAt = time.Now().Unix() + req.Duration
+	}
+	uids := req.muteUids()
+	mutes := make([]wkdb.ChannelMute, 0, len(uids))
+	for _, uid := range uids {
+		mutes = append(mutes, wkdb.ChannelMute{
+			Uid:      uid,
+			ExpireAt: expireAt,
+		})
+	}
+	err = ch.s.store.AddOrUpdateChannelMutes(req.ChannelID, req.ChannelType, mutes)
+	if err != nil {
+		ch.Error("禁言失败！", zap.Error(err))
+		c.ResponseError(errors.New("禁言失败！"))
+		return
+	}
+
+	c.ResponseOK()
+}
+
+// 解除全员禁言或成员禁言
+func (ch *ChannelAPI) unmute(c *wkhttp.Context) {
+	var req channelMuteReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		ch.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	err = ch.s.store.RemoveChannelMutes(req.ChannelID, req.ChannelType, req.muteUids())
+	if err != nil {
+		ch.Error("解除禁言失败！", zap.Error(err))
+		c.ResponseError(errors.New("解除禁言失败！"))
+		return
+	}
+
+	c.ResponseOK()
+}
+
+// 获取频道的禁言列表（uid为空表示全员禁言）
+func (ch *ChannelAPI) muteList(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.ParseUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+
+	if ch.s.forwardToSlotLeaderIfNeed(c, channelId, channelType, nil) {
+		return
+	}
+
+	mutes, err := ch.s.store.GetChannelMutes(channelId, channelType)
+	if err != nil {
+		ch.Error("获取禁言列表失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取禁言列表失败！"))
+		return
+	}
+
+	c.JSON(http.StatusOK, mutes)
+}
+
+const maxSubscriberPageLimit = 1000 // 分页获取订阅者的最大数量
+
 type PullMode int // 拉取模式
 
 const (
@@ -724,10 +1038,26 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 			return
 		}
 	}
+
+	// 历史消息可见策略，成员只能同步可见起始序号之后的消息
+	visibleStartSeq, err := ch.s.historyVisibleStartSeq(req.ChannelID, req.ChannelType, req.LoginUID)
+	if err != nil {
+		ch.Error("获取历史消息可见范围失败！", zap.Error(err), zap.Any("req", req))
+		c.ResponseError(errors.New("获取历史消息可见范围失败！"))
+		return
+	}
+	if visibleStartSeq == historyInvisible {
+		c.JSON(http.StatusOK, emptySyncMessageResp)
+		return
+	}
+
 	if req.StartMessageSeq == 0 && req.EndMessageSeq == 0 {
 		messages, err = ch.s.store.LoadLastMsgs(fakeChannelID, req.ChannelType, limit)
 	} else if req.PullMode == PullModeUp { // 向上拉取
-		messages, err = ch.s.store.LoadNextRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
+		startMessageSeq := max(req.StartMessageSeq, visibleStartSeq)
+		if req.EndMessageSeq == 0 || startMessageSeq < req.EndMessageSeq {
+			messages, err = ch.s.store.LoadNextRangeMsgs(fakeChannelID, req.ChannelType, startMessageSeq, req.EndMessageSeq, limit)
+		}
 	} else {
 		messages, err = ch.s.store.LoadPrevRangeMsgs(fakeChannelID, req.ChannelType, req.StartMessageSeq, req.EndMessageSeq, limit)
 	}
@@ -736,16 +1066,32 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
+	firstMessageSeq, err := ch.s.store.GetChannelFirstMessageSeq(fakeChannelID, req.ChannelType)
+	if err != nil {
+		ch.Error("获取频道第一条消息序号失败！", zap.Error(err), zap.Any("req", req))
+		c.ResponseError(err)
+		return
+	}
+	loadCount := len(messages)
+	messages, hidden := filterVisibleMessages(messages, visibleStartSeq)
+	if visibleStartSeq > firstMessageSeq {
+		firstMessageSeq = visibleStartSeq
+	}
 	messageResps := make([]*MessageResp, 0, len(messages))
 	if len(messages) > 0 {
+		now := time.Now()
 		for _, message := range messages {
+			if message.IsExpired(now) { // 过期消息不返回
+				continue
+			}
 			messageResp := &MessageResp{}
 			messageResp.from(message)
+			messageResp.fillStreams(fakeChannelID, ch.s)
 			messageResps = append(messageResps, messageResp)
 		}
 	}
 	var more bool = true // 是否有更多数据
-	if len(messageResps) < limit {
+	if loadCount < limit || hidden {
 		more = false
 	}
 	if len(messageResps) > 0 {
@@ -770,6 +1116,7 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 		StartMessageSeq: req.StartMessageSeq,
 		EndMessageSeq:   req.EndMessageSeq,
 		More:            wkutil.BoolToInt(more),
+		FirstMessageSeq: firstMessageSeq,
 		Messages:        messageResps,
 	})
 }
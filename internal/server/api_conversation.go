@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
@@ -40,6 +41,9 @@ func (s *ConversationAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/conversations/delete", s.deleteConversation)           // 删除会话
 	r.POST("/conversation/sync", s.syncUserConversation)            // 同步会话
 	r.POST("/conversation/syncMessages", s.syncRecentMessages)      // 同步会话最近消息
+	r.POST("/conversation/mute", s.muteConversation)                // 设置会话免打扰（不推送离线消息）
+	r.POST("/conversation/unmute", s.unmuteConversation)            // 取消会话免打扰
+	r.GET("/conversation/mutes", s.conversationMutes)               // 获取用户免打扰的会话
 }
 
 // // Get a list of recent conversations
@@ -596,6 +600,7 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 		var (
 			recentMessages []wkdb.Message
 			err            error
+			now            = time.Now()
 		)
 		for _, channel := range channels {
 			fakeChannelID := channel.ChannelId
@@ -604,6 +609,22 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 			}
 			msgSeq := channel.LastMsgSeq
 			messageResps := MessageRespSlice{}
+
+			// 历史消息可见策略
+			var visibleStartSeq uint64
+			visibleStartSeq, err = s.historyVisibleStartSeq(channel.ChannelId, channel.ChannelType, uid)
+			if err != nil {
+				s.Error("获取历史消息可见范围失败！", zap.Error(err), zap.String("uid", uid), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+				return nil, err
+			}
+			if visibleStartSeq == historyInvisible {
+				channelRecentMessages = append(channelRecentMessages, &channelRecentMessage{
+					ChannelId:   channel.ChannelId,
+					ChannelType: channel.ChannelType,
+					Messages:    messageResps,
+				})
+				continue
+			}
 			if orderByLast {
 
 				if msgSeq > 0 {
@@ -615,35 +636,141 @@ func (s *Server) getRecentMessages(uid string, msgCount int, channels []*channel
 					s.Error("查询最近消息失败！", zap.Error(err), zap.String("uid", uid), zap.String("fakeChannelID", fakeChannelID), zap.Uint8("channelType", channel.ChannelType), zap.Uint64("LastMsgSeq", channel.LastMsgSeq))
 					return nil, err
 				}
+				recentMessages, _ = filterVisibleMessages(recentMessages, visibleStartSeq)
 				if len(recentMessages) > 0 {
 					for _, recentMessage := range recentMessages {
+						if recentMessage.IsExpired(now) { // 过期消息不返回
+							continue
+						}
 						messageResp := &MessageResp{}
 						messageResp.from(recentMessage)
+						messageResp.fillStreams(fakeChannelID, s)
 						messageResps = append(messageResps, messageResp)
 					}
 				}
 				sort.Sort(sort.Reverse(messageResps))
 			} else {
-				recentMessages, err = s.store.LoadNextRangeMsgs(fakeChannelID, channel.ChannelType, msgSeq, 0, msgCount)
+				recentMessages, err = s.store.LoadNextRangeMsgs(fakeChannelID, channel.ChannelType, max(msgSeq, visibleStartSeq), 0, msgCount)
 				if err != nil {
 					s.Error("查询最近消息失败！", zap.Error(err), zap.String("uid", uid), zap.String("fakeChannelID", fakeChannelID), zap.Uint8("channelType", channel.ChannelType), zap.Uint64("LastMsgSeq", channel.LastMsgSeq))
 					return nil, err
 				}
 				if len(recentMessages) > 0 {
 					for _, recentMessage := range recentMessages {
+						if recentMessage.IsExpired(now) { // 过期消息不返回
+							continue
+						}
 						messageResp := &MessageResp{}
 						messageResp.from(recentMessage)
+						messageResp.fillStreams(fakeChannelID, s)
 						messageResps = append(messageResps, messageResp)
 					}
 				}
 			}
 
+			firstMessageSeq, err := s.store.GetChannelFirstMessageSeq(fakeChannelID, channel.ChannelType)
+			if err != nil {
+				s.Error("查询频道第一条消息序号失败！", zap.Error(err), zap.String("fakeChannelID", fakeChannelID), zap.Uint8("channelType", channel.ChannelType))
+				return nil, err
+			}
+			if visibleStartSeq > firstMessageSeq {
+				firstMessageSeq = visibleStartSeq
+			}
+
 			channelRecentMessages = append(channelRecentMessages, &channelRecentMessage{
-				ChannelId:   channel.ChannelId,
-				ChannelType: channel.ChannelType,
-				Messages:    messageResps,
+				ChannelId:       channel.ChannelId,
+				ChannelType:     channel.ChannelType,
+				FirstMessageSeq: firstMessageSeq,
+				Messages:        messageResps,
 			})
 		}
 	}
 	return channelRecentMessages, nil
 }
+
+// 设置会话免打扰，个人频道的channel_id为对方的uid
+func (s *ConversationAPI) muteConversation(c *wkhttp.Context) {
+	var req conversationMuteReq
+	if err := c.BindJSON(&req); err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	var expireAt int64
+	if req.Duration > 0 {
+		expireAt = time.Now().Unix() + req.Duration
+	}
+	err := s.s.store.AddOrUpdateConversationMutes(req.UID, []wkdb.ConversationMute{
+		{
+			ChannelId:   req.ChannelID,
+			ChannelType: req.ChannelType,
+			ExpireAt:    expireAt,
+		},
+	})
+	if err != nil {
+		s.Error("设置会话免打扰失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 取消会话免打扰
+func (s *ConversationAPI) unmuteConversation(c *wkhttp.Context) {
+	var req conversationMuteReq
+	if err := c.BindJSON(&req); err != nil {
+		s.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	err := s.s.store.RemoveConversationMutes(req.UID, []wkdb.Channel{{ChannelId: req.ChannelID, ChannelType: req.ChannelType}})
+	if err != nil {
+		s.Error("取消会话免打扰失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("channelId", req.ChannelID))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 获取用户未到期的免打扰会话
+func (s *ConversationAPI) conversationMutes(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if s.s.opts.ClusterOn() {
+		leaderInfo, err := s.s.cluster.SlotLeaderOfChannel(uid, wkproto.ChannelTypePerson) // 获取频道的领导节点
+		if err != nil {
+			s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", uid), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			c.ResponseError(errors.New("获取频道所在节点失败！"))
+			return
+		}
+		if leaderInfo.Id != s.s.opts.Cluster.NodeId {
+			c.Forward(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path))
+			return
+		}
+	}
+	allMutes, err := s.s.store.GetConversationMutes(uid)
+	if err != nil {
+		s.Error("获取会话免打扰失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(err)
+		return
+	}
+	now := time.Now().Unix()
+	mutes := make([]wkdb.ConversationMute, 0, len(allMutes))
+	for _, mute := range allMutes {
+		if !mute.Expired(now) {
+			mutes = append(mutes, mute)
+		}
+	}
+	c.JSON(http.StatusOK, mutes)
+}
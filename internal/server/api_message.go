@@ -1,11 +1,15 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -22,34 +26,1043 @@ type MessageAPI struct {
 
 	syncRecordMap  map[string][]*syncRecord // 记录最后一次同步命令的记录（TODO：这个是临时方案，为了兼容老版本）
 	syncRecordLock sync.RWMutex
+
+	streamSeqMap       map[string]*streamSeqEntry // 消息流当前的流序号（只在槽领导节点上分配）
+	streamSeqLock      sync.Mutex
+	streamSeqCleanedAt time.Time // 上次清理过期流序号的时间
+}
+
+// streamSeqEntry 槽领导节点上缓存的流序号
+type streamSeqEntry struct {
+	seq       uint32
+	term      uint32    // 分配流序号时槽领导的任期，任期变化后需要从存储中恢复
+	updatedAt time.Time // 最后一次分配的时间
+}
+
+// streamSeqTTL 流序号缓存的过期时间，长时间没有追加内容的流（比如没有调用结束接口）从缓存中移除
+const streamSeqTTL = time.Minute * 10
+
+// NewMessageAPI NewMessageAPI
+func NewMessageAPI(s *Server) *MessageAPI {
+	return &MessageAPI{
+		s:             s,
+		Log:           wklog.NewWKLog("MessageApi"),
+		syncRecordMap: map[string][]*syncRecord{},
+		streamSeqMap:  map[string]*streamSeqEntry{},
+	}
+}
+
+// Route route
+func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/message/send", m.send)           // 发送消息
+	r.POST("/message/revoke", m.revoke)       // 撤回消息
+	r.POST("/message/edit", m.edit)           // 编辑消息
+	r.POST("/message/edits", m.editHistory)   // 消息编辑历史
+	r.POST("/message/sendbatch", m.sendBatch) // 批量发送消息
+	r.POST("/message/sync", m.sync)           // 消息同步(写模式)
+	r.POST("/message/syncack", m.syncack)     // 消息同步回执(写模式)
+
+	r.POST("/streammessage/start", m.streamMessageStart) // 流消息开始
+	r.POST("/streammessage/end", m.streamMessageEnd)     // 流消息结束
+
+	r.POST("/message/scheduled", m.scheduledMessages)             // 频道的定时消息列表
+	r.POST("/message/scheduled/cancel", m.cancelScheduledMessage) // 取消定时消息
+
+	r.POST("/message/reaction/add", m.addReaction)       // 添加消息回应
+	r.POST("/message/reaction/remove", m.removeReaction) // 取消消息回应
+	r.POST("/message/reaction/sync", m.syncReactions)    // 同步消息回应
+
+	r.POST("/message/readed", m.readed)             // 上报消息已读回执
+	r.POST("/message/readers", m.readers)           // 消息的已读用户
+	r.POST("/message/readedcounts", m.readedCounts) // 消息的已读数量
+
+	r.POST("/message/broadcast", m.broadcast)                  // 系统广播
+	r.POST("/message/broadcast/progress", m.broadcastProgress) // 系统广播进度
+
+	r.POST("/messages", m.searchMessages) // 查询消息
+
+}
+
+func (m *MessageAPI) send(c *wkhttp.Context) {
+	var req MessageSendReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+
+	if strings.TrimSpace(req.StreamNo) != "" { // 流消息的内容
+		m.sendStreamItem(c, req, bodyBytes)
+		return
+	}
+
+	if req.SendAt > time.Now().Unix() { // 定时消息
+		m.scheduleMessage(c, req)
+		return
+	}
+
+	channelId := req.ChannelID
+	channelType := req.ChannelType
+	// if strings.TrimSpace(channelId) == "" && len(req.Subscribers) > 0 { //如果没频道ID 但是有订阅者，则创建一个临时频道
+	// 	// channelId = fmt.Sprintf("%s%s", wkutil.GenUUID(), m.s.opts.TmpChannel.Suffix)
+	// 	// channelType = wkproto.ChannelTypeGroup
+	// 	// m.s.channelManager.CreateTmpChannel(channelId, channelType, req.Subscribers)
+	// }
+	m.Debug("发送消息内容：", zap.String("msg", wkutil.ToJSON(req)))
+	if strings.TrimSpace(channelId) == "" && len(req.Subscribers) == 0 { //指定了频道 才能正常发送
+		m.Error("无法处理发送消息请求！", zap.Any("req", req))
+		c.ResponseError(errors.New("无法处理发送消息请求！"))
+		return
+	}
+
+	if strings.TrimSpace(channelId) == "" && len(req.Subscribers) > 0 {
+		if req.Header.SyncOnce != 1 {
+			m.Error("subscribers有值的情况下，消息必须是syncOnce消息", zap.Any("req", req))
+			c.ResponseError(errors.New("无法处理发送消息请求！"))
+			return
+		}
+
+		for _, subscriber := range req.Subscribers {
+			clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+			// 发送消息
+			_, err := m.sendMessageToChannel(req, subscriber, wkproto.ChannelTypePerson, clientMsgNo, wkproto.StreamFlagIng, 0)
+			if err != nil {
+				c.ResponseError(err)
+				return
+			}
+		}
+
+		c.ResponseOK()
+
+		return
+	}
+
+	clientMsgNo := req.ClientMsgNo
+	if strings.TrimSpace(clientMsgNo) == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+
+	// 发送消息
+	messageId, err := m.sendMessageToChannel(req, channelId, channelType, clientMsgNo, wkproto.StreamFlagIng, 0)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":    messageId,
+		"client_msg_no": clientMsgNo,
+	})
+}
+
+func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string, channelType uint8, clientMsgNo string, streamFlag wkproto.StreamFlag, streamSeq uint32) (int64, error) {
+	messageId := m.s.channelReactor.messageIDGen.Generate().Int64()
+	return m.sendMessageToChannelWithMessageId(messageId, req, channelId, channelType, clientMsgNo, streamFlag, streamSeq)
+}
+
+// sendMessageToChannelWithMessageId 使用预先分配的消息ID发送消息
+func (m *MessageAPI) sendMessageToChannelWithMessageId(messageId int64, req MessageSendReq, channelId string, channelType uint8, clientMsgNo string, streamFlag wkproto.StreamFlag, streamSeq uint32) (int64, error) {
+
+	// m.s.monitor.SendPacketInc(req.Header.NoPersist != 1)
+	// m.s.monitor.SendSystemMsgInc()
+
+	// var messageID = m.s.dispatch.processor.genMessageID()
+
+	fakeChannelId := channelId
+	fakeChannelType := req.ChannelType
+	if channelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, channelId)
+	}
+
+	if req.Header.SyncOnce == 1 { // 命令消息，将原频道转换为cmd频道
+		fakeChannelId = m.s.opts.OrginalConvertCmdChannel(fakeChannelId)
+	}
+
+	channel := m.s.channelReactor.loadOrCreateChannel(fakeChannelId, fakeChannelType)
+
+	if channel == nil {
+		return 0, errors.New("频道信息不存在！")
+	}
+
+	var setting wkproto.Setting
+	if len(strings.TrimSpace(req.StreamNo)) > 0 {
+		setting = setting.Set(wkproto.SettingStream)
+	}
+
+	// 将消息提交到频道
+	systemDeviceId := req.FromUID
+	messageId, err := channel.proposeStreamSendWithMessageId(messageId, req.FromUID, systemDeviceId, 0, m.s.opts.Cluster.NodeId, false, streamFlag, streamSeq, &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			RedDot:    wkutil.IntToBool(req.Header.RedDot),
+			SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
+			NoPersist: wkutil.IntToBool(req.Header.NoPersist),
+		},
+		Setting:     setting,
+		Expire:      req.Expire,
+		StreamNo:    req.StreamNo,
+		ClientMsgNo: clientMsgNo,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload:     req.Payload,
+	})
+	if err != nil {
+		return messageId, err
+	}
+
+	return messageId, nil
+}
+
+const (
+	messageSendBatchConcurrency = 100              // 批量发送消息的并发数
+	messageSendBatchWaitTimeout = time.Second * 10 // 等待发送回执的超时时间
+)
+
+// 批量发送消息，每条消息可以发往不同的频道，返回每条消息的发送结果
+func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
+	var req MessageSendBatchReq
+	if err := c.BindJSON(&req); err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	var (
+		results = make([]*MessageSendBatchResult, len(req.Messages))
+		wg      sync.WaitGroup
+		limitC  = make(chan struct{}, messageSendBatchConcurrency)
+	)
+	for i, msgReq := range req.Messages {
+		wg.Add(1)
+		limitC <- struct{}{}
+		go func(index int, msgReq MessageSendReq) {
+			defer func() {
+				<-limitC
+				wg.Done()
+			}()
+			results[index] = m.sendBatchMessage(index, msgReq)
+		}(i, msgReq)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, results)
+}
+
+// 发送批量消息中的一条，并等待频道leader的发送回执
+func (m *MessageAPI) sendBatchMessage(index int, req MessageSendReq) *MessageSendBatchResult {
+	result := &MessageSendBatchResult{
+		Index:       index,
+		ClientMsgNo: req.ClientMsgNo,
+		ReasonCode:  uint8(wkproto.ReasonSystemError),
+	}
+	if err := req.Check(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if strings.TrimSpace(req.ChannelID) == "" {
+		result.ReasonCode = uint8(wkproto.ReasonChannelIDError)
+		result.Error = "频道ID不能为空！"
+		return result
+	}
+	if strings.TrimSpace(req.StreamNo) != "" || req.SendAt > time.Now().Unix() || len(req.Subscribers) > 0 {
+		result.Error = "批量发送不支持流消息、定时消息和指定订阅者！"
+		return result
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+	if strings.TrimSpace(result.ClientMsgNo) == "" {
+		result.ClientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+
+	waitC, ok := m.s.sendackWaiter.add(req.FromUID, result.ClientMsgNo)
+	if !ok {
+		result.Error = "client_msg_no重复！"
+		return result
+	}
+	defer m.s.sendackWaiter.remove(req.FromUID, result.ClientMsgNo)
+
+	messageId, err := m.sendMessageToChannel(req, req.ChannelID, req.ChannelType, result.ClientMsgNo, wkproto.StreamFlagIng, 0)
+	if err != nil {
+		m.Error("批量发送消息失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		result.Error = err.Error()
+		return result
+	}
+	result.MessageId = messageId
+
+	timeoutCtx, cancel := context.WithTimeout(m.s.ctx, messageSendBatchWaitTimeout)
+	defer cancel()
+	select {
+	case sendack := <-waitC:
+		result.MessageSeq = sendack.MessageSeq
+		result.ReasonCode = uint8(sendack.ReasonCode)
+	case <-timeoutCtx.Done():
+		result.Error = "等待发送回执超时！"
+	}
+	return result
+}
+
+// 系统广播，广播请求发给所有节点，每个节点负责本节点的在线连接或本节点是槽领导的用户，并按速率发送
+func (m *MessageAPI) broadcast(c *wkhttp.Context) {
+	var req MessageBroadcastReq
+	if err := c.BindJSON(&req); err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+
+	bReq := &broadcastReq{
+		id:       wkutil.GenUUID(),
+		target:   req.Target,
+		fromUid:  req.FromUID,
+		redDot:   wkutil.IntToBool(req.Header.RedDot),
+		syncOnce: wkutil.IntToBool(req.Header.SyncOnce),
+		payload:  req.Payload,
+		rate:     req.Rate,
+	}
+	data := bReq.Marshal()
+	failedNodes := make([]uint64, 0)
+	for _, nodeId := range m.s.clusterNodeIds() {
+		if nodeId == m.s.opts.Cluster.NodeId {
+			m.s.broadcastManager.start(bReq)
+			continue
+		}
+		if _, err := m.s.requestNode(nodeId, "/wk/broadcast", data); err != nil {
+			m.Error("请求节点开始广播失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"broadcast_id": bReq.id,
+		"failed_nodes": failedNodes,
+	})
+}
+
+// 系统广播进度，汇总所有节点的进度
+func (m *MessageAPI) broadcastProgress(c *wkhttp.Context) {
+	var req messageBroadcastProgressReq
+	if err := c.BindJSON(&req); err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.BroadcastId) == "" {
+		c.ResponseError(errors.New("broadcast_id不能为空！"))
+		return
+	}
+
+	resp := &messageBroadcastProgressResp{
+		BroadcastId: req.BroadcastId,
+		Finished:    true,
+		Nodes:       make([]*broadcastProgress, 0),
+	}
+	for _, nodeId := range m.s.clusterNodeIds() {
+		var progress *broadcastProgress
+		if nodeId == m.s.opts.Cluster.NodeId {
+			progress = m.s.broadcastManager.progress(req.BroadcastId)
+		} else {
+			data, err := m.s.requestNode(nodeId, "/wk/broadcastProgress", []byte(req.BroadcastId))
+			if err == nil {
+				progress = &broadcastProgress{}
+				if err = progress.Unmarshal(data); err != nil {
+					progress = nil
+				}
+			}
+			if err != nil {
+				m.Warn("获取节点广播进度失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			}
+		}
+		if progress == nil {
+			resp.UnknownNodes = append(resp.UnknownNodes, nodeId)
+			continue
+		}
+		resp.Nodes = append(resp.Nodes, progress)
+		resp.Total += progress.Total
+		resp.Sent += progress.Sent
+		resp.Failed += progress.Failed
+		if progress.FinishAt == 0 {
+			resp.Finished = false
+		}
+	}
+	if len(resp.Nodes) == 0 {
+		c.ResponseError(errors.New("广播不存在！"))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 参与广播的节点
+// 流消息开始，发送一条存储的锚点消息，后续的流内容通过/message/send携带stream_no追加
+func (m *MessageAPI) streamMessageStart(c *wkhttp.Context) {
+	var req MessageStreamStartReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	// 消息流通过槽的raft存储，流的读写和流序号的分配都在槽领导节点上进行
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	clientMsgNo := req.ClientMsgNo
+	if strings.TrimSpace(clientMsgNo) == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+	streamNo := wkutil.GenUUID()
+
+	// 先保存元数据再发送锚点消息，避免锚点消息已发出但流不存在
+	messageId := m.s.channelReactor.messageIDGen.Generate().Int64()
+	err = m.s.store.SaveStreamMeta(wkdb.StreamMeta{
+		StreamNo:    streamNo,
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		MessageId:   messageId,
+		FromUid:     req.FromUID,
+		StreamFlag:  wkproto.StreamFlagStart,
+		CreatedAt:   time.Now().Unix(),
+	})
+	if err != nil {
+		m.Error("保存消息流元数据失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+
+	header := req.Header
+	header.NoPersist = 0 // 锚点消息必须存储，同步消息时才能拼装出流内容
+	_, err = m.sendMessageToChannelWithMessageId(messageId, MessageSendReq{
+		Header:      header,
+		ClientMsgNo: clientMsgNo,
+		StreamNo:    streamNo,
+		FromUID:     req.FromUID,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Payload:     req.Payload,
+	}, req.ChannelID, req.ChannelType, clientMsgNo, wkproto.StreamFlagStart, 0)
+	if err != nil {
+		m.Error("发送流消息失败！", zap.Error(err))
+		// 锚点消息未发出，结束这个流，避免继续往里追加内容
+		if endErr := m.s.store.StreamEnd(fakeChannelId, req.ChannelType, streamNo); endErr != nil {
+			m.Error("结束消息流失败！", zap.Error(endErr), zap.String("streamNo", streamNo))
+		}
+		c.ResponseError(err)
+		return
+	}
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"stream_no":     streamNo,
+		"message_id":    messageId,
+		"client_msg_no": clientMsgNo,
+	})
+}
+
+// 流消息结束
+func (m *MessageAPI) streamMessageEnd(c *wkhttp.Context) {
+	var req MessageStreamEndReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.FromUID) == "" {
+		req.FromUID = m.s.opts.SystemUID
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	// 消息流通过槽的raft存储，流的读写和流序号的分配都在槽领导节点上进行
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	streamMeta, err := m.getStreamMeta(fakeChannelId, req.ChannelType, req.StreamNo)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if streamMeta.StreamFlag == wkproto.StreamFlagEnd { // 已结束
+		c.ResponseOK()
+		return
+	}
+
+	err = m.s.store.StreamEnd(fakeChannelId, req.ChannelType, req.StreamNo)
+	if err != nil {
+		m.Error("结束消息流失败！", zap.Error(err), zap.String("streamNo", req.StreamNo))
+		c.ResponseError(err)
+		return
+	}
+
+	lastSeq, err := m.s.store.GetStreamLastSeq(fakeChannelId, req.ChannelType, req.StreamNo)
+	if err != nil {
+		m.Error("获取消息流最后序号失败！", zap.Error(err), zap.String("streamNo", req.StreamNo))
+		c.ResponseError(err)
+		return
+	}
+	m.removeStreamSeq(fakeChannelId, req.ChannelType, req.StreamNo)
+
+	// 通知在线的订阅者消息流已结束
+	_, err = m.sendMessageToChannel(MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+		},
+		StreamNo:    req.StreamNo,
+		FromUID:     req.FromUID,
+		ChannelID:   req.ChannelID,
+		ChannelType: req.ChannelType,
+	}, req.ChannelID, req.ChannelType, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagEnd, lastSeq)
+	if err != nil {
+		m.Error("发送流结束消息失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 追加流消息的内容，内容单独存储，只投递给在线的订阅者
+func (m *MessageAPI) sendStreamItem(c *wkhttp.Context, req MessageSendReq, bodyBytes []byte) {
+	if strings.TrimSpace(req.ChannelID) == "" {
+		c.ResponseError(errors.New("频道ID不能为空！"))
+		return
+	}
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	// 消息流通过槽的raft存储，流的读写和流序号的分配都在槽领导节点上进行
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	streamMeta, err := m.getStreamMeta(fakeChannelId, req.ChannelType, req.StreamNo)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if streamMeta.StreamFlag == wkproto.StreamFlagEnd {
+		c.ResponseError(errors.New("消息流已结束！"))
+		return
+	}
+
+	clientMsgNo := req.ClientMsgNo
+	if strings.TrimSpace(clientMsgNo) == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+
+	streamSeq, err := m.nextStreamSeq(fakeChannelId, req.ChannelType, req.StreamNo)
+	if err != nil {
+		m.Error("分配流序号失败！", zap.Error(err), zap.String("streamNo", req.StreamNo))
+		c.ResponseError(err)
+		return
+	}
+
+	err = m.s.store.AppendStreamItem(fakeChannelId, req.ChannelType, req.StreamNo, wkdb.StreamItem{
+		StreamSeq:   streamSeq,
+		ClientMsgNo: clientMsgNo,
+		Payload:     req.Payload,
+	})
+	if err != nil {
+		m.Error("追加消息流失败！", zap.Error(err), zap.String("streamNo", req.StreamNo))
+		c.ResponseError(err)
+		return
+	}
+
+	req.Header.NoPersist = 1 // 流内容已单独存储
+	messageId, err := m.sendMessageToChannel(req, req.ChannelID, req.ChannelType, clientMsgNo, wkproto.StreamFlagIng, streamSeq)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":    messageId,
+		"client_msg_no": clientMsgNo,
+		"stream_seq":    streamSeq,
+	})
+}
+
+// 获取消息流元数据，不存在时返回错误
+func (m *MessageAPI) getStreamMeta(fakeChannelId string, channelType uint8, streamNo string) (wkdb.StreamMeta, error) {
+	streamMeta, err := m.s.store.GetStreamMeta(fakeChannelId, channelType, streamNo)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return wkdb.StreamMeta{}, errors.New("消息流不存在！")
+		}
+		m.Error("获取消息流元数据失败！", zap.Error(err), zap.String("streamNo", streamNo))
+		return wkdb.StreamMeta{}, err
+	}
+	return streamMeta, nil
+}
+
+// 分配下一个流序号，首次分配或槽领导任期变化后从存储中恢复
+func (m *MessageAPI) nextStreamSeq(fakeChannelId string, channelType uint8, streamNo string) (uint32, error) {
+	term, err := m.s.cluster.SlotLeaderTermOfChannel(fakeChannelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+
+	m.streamSeqLock.Lock()
+	defer m.streamSeqLock.Unlock()
+
+	now := time.Now()
+	m.cleanExpiredStreamSeqs(now)
+
+	key := streamSeqKey(fakeChannelId, channelType, streamNo)
+	entry, ok := m.streamSeqMap[key]
+	if !ok || entry.term != term {
+		lastSeq, err := m.s.store.GetStreamLastSeq(fakeChannelId, channelType, streamNo)
+		if err != nil {
+			return 0, err
+		}
+		entry = &streamSeqEntry{
+			seq:  lastSeq,
+			term: term,
+		}
+		m.streamSeqMap[key] = entry
+	}
+	entry.seq++
+	entry.updatedAt = now
+	return entry.seq, nil
+}
+
+// cleanExpiredStreamSeqs 清理过期的流序号缓存（调用方需持有streamSeqLock）
+func (m *MessageAPI) cleanExpiredStreamSeqs(now time.Time) {
+	if now.Sub(m.streamSeqCleanedAt) < streamSeqTTL {
+		return
+	}
+	m.streamSeqCleanedAt = now
+	for key, entry := range m.streamSeqMap {
+		if now.Sub(entry.updatedAt) >= streamSeqTTL {
+			delete(m.streamSeqMap, key)
+		}
+	}
+}
+
+func (m *MessageAPI) removeStreamSeq(fakeChannelId string, channelType uint8, streamNo string) {
+	m.streamSeqLock.Lock()
+	defer m.streamSeqLock.Unlock()
+	delete(m.streamSeqMap, streamSeqKey(fakeChannelId, channelType, streamNo))
+}
+
+func streamSeqKey(fakeChannelId string, channelType uint8, streamNo string) string {
+	return fmt.Sprintf("%s-%d-%s", fakeChannelId, channelType, streamNo)
+}
+
+// 保存定时消息，到期后由scheduledManager发送
+func (m *MessageAPI) scheduleMessage(c *wkhttp.Context, req MessageSendReq) {
+	if strings.TrimSpace(req.ChannelID) == "" || len(req.Subscribers) > 0 {
+		c.ResponseError(errors.New("定时消息必须指定频道！"))
+		return
+	}
+	if req.Header.SyncOnce == 1 {
+		c.ResponseError(errors.New("定时消息不支持syncOnce消息！"))
+		return
+	}
+	now := time.Now()
+	if m.s.opts.Scheduled.MaxDelay > 0 && req.SendAt > now.Add(m.s.opts.Scheduled.MaxDelay).Unix() {
+		c.ResponseError(fmt.Errorf("定时时长不能超过%s！", m.s.opts.Scheduled.MaxDelay))
+		return
+	}
+
+	// 到期发送时使用同一个客户端消息编号，重复发送时会被去重
+	clientMsgNo := req.ClientMsgNo
+	if strings.TrimSpace(clientMsgNo) == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	}
+
+	// 个人频道的定时消息存储在双方共同的频道下
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	scheduledMessage := wkdb.ScheduledMessage{
+		Id:          uint64(m.s.channelReactor.messageIDGen.Generate().Int64()),
+		SendAt:      req.SendAt,
+		FromUid:     req.FromUID,
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		ClientMsgNo: clientMsgNo,
+		RedDot:      wkutil.IntToBool(req.Header.RedDot),
+		NoPersist:   wkutil.IntToBool(req.Header.NoPersist),
+		Expire:      req.Expire,
+		Payload:     req.Payload,
+		CreatedAt:   now.Unix(),
+	}
+	err := m.s.store.AddScheduledMessage(scheduledMessage)
+	if err != nil {
+		m.Error("保存定时消息失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"id":            scheduledMessage.Id,
+		"id_str":        strconv.FormatUint(scheduledMessage.Id, 10),
+		"client_msg_no": clientMsgNo,
+		"send_at":       scheduledMessage.SendAt,
+	})
+}
+
+// 频道的定时消息列表
+func (m *MessageAPI) scheduledMessages(c *wkhttp.Context) {
+	var req scheduledMessageReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	scheduledMessages, err := m.s.store.GetScheduledMessages(fakeChannelId, req.ChannelType)
+	if err != nil {
+		m.Error("获取定时消息失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*scheduledMessageResp, 0, len(scheduledMessages))
+	for _, scheduledMessage := range scheduledMessages {
+		resps = append(resps, newScheduledMessageResp(scheduledMessage))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 取消定时消息
+func (m *MessageAPI) cancelScheduledMessage(c *wkhttp.Context) {
+	var req scheduledMessageReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Id == 0 {
+		c.ResponseError(errors.New("定时消息ID不能为空！"))
+		return
+	}
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	_, err = m.s.store.GetScheduledMessage(fakeChannelId, req.ChannelType, req.Id)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("定时消息不存在或已发送！"))
+			return
+		}
+		m.Error("获取定时消息失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	err = m.s.store.DeleteScheduledMessage(fakeChannelId, req.ChannelType, req.Id)
+	if err != nil {
+		m.Error("取消定时消息失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// isSystemOperator fromUid为空表示由系统（业务后端）操作，只允许在非个人频道使用，个人频道需要fromUid确定是哪个会话
+func isSystemOperator(fromUid string, channelType uint8) bool {
+	return fromUid == "" && channelType != wkproto.ChannelTypePerson
+}
+
+// allowRevoke 是否允许撤回消息，只能撤回自己发送的消息，频道的管理员可以撤回频道内的任意消息
+func (m *MessageAPI) allowRevoke(fromUid string, channelId string, channelType uint8, message wkdb.Message) (bool, error) {
+	if isSystemOperator(fromUid, channelType) {
+		return true, nil
+	}
+	if fromUid == "" {
+		return false, nil
+	}
+	if fromUid == message.FromUID {
+		return true, nil
+	}
+	if channelType == wkproto.ChannelTypePerson {
+		return false, nil
+	}
+	subscriber, err := m.s.store.GetSubscriber(channelId, channelType, fromUid)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return subscriber.IsAdmin(), nil
+}
+
+// 撤回消息
+func (m *MessageAPI) revoke(c *wkhttp.Context) {
+	var req messageRevokeReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	req.FromUID = strings.TrimSpace(req.FromUID)
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	if m.forwardToChannelLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	message, err := m.getChannelMessage(fakeChannelId, req.ChannelType, req.MessageID, req.ClientMsgNo)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("查询消息失败！", zap.Error(err), zap.Int64("messageId", req.MessageID), zap.String("clientMsgNo", req.ClientMsgNo))
+		c.ResponseError(errors.New("查询消息失败！"))
+		return
+	}
+
+	allow, err := m.allowRevoke(req.FromUID, fakeChannelId, req.ChannelType, message)
+	if err != nil {
+		m.Error("查询订阅者失败！", zap.Error(err), zap.String("fromUid", req.FromUID))
+		c.ResponseError(errors.New("查询订阅者失败！"))
+		return
+	}
+	if !allow {
+		c.ResponseError(errors.New("只能撤回自己发送的消息！"))
+		return
+	}
+
+	if !message.Revoke {
+		err = m.s.store.RevokeMessage(fakeChannelId, req.ChannelType, uint64(message.MessageSeq))
+		if err != nil {
+			m.Error("撤回消息失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+			c.ResponseError(errors.New("撤回消息失败！"))
+			return
+		}
+	}
+
+	// 通知在线的订阅者
+	err = m.sendCMDNotice(req.FromUID, req.ChannelID, req.ChannelType, CMDMessageRevoke, map[string]interface{}{
+		"channel_id":    req.ChannelID,
+		"channel_type":  req.ChannelType,
+		"message_id":    strconv.FormatInt(message.MessageID, 10),
+		"message_seq":   message.MessageSeq,
+		"client_msg_no": message.ClientMsgNo,
+	})
+	if err != nil {
+		m.Error("发送撤回通知失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+		c.ResponseError(errors.New("发送撤回通知失败！"))
+		return
+	}
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":    message.MessageID,
+		"message_seq":   message.MessageSeq,
+		"client_msg_no": message.ClientMsgNo,
+	})
+}
+
+// allowEdit 是否允许编辑消息，只能编辑自己发送的消息
+func allowEdit(fromUid string, channelType uint8, message wkdb.Message) bool {
+	if isSystemOperator(fromUid, channelType) {
+		return true
+	}
+	return fromUid != "" && fromUid == message.FromUID
+}
+
+// 编辑消息
+func (m *MessageAPI) edit(c *wkhttp.Context) {
+	var req messageEditReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	req.FromUID = strings.TrimSpace(req.FromUID)
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.FromUID, req.ChannelID)
+	}
+
+	if m.forwardToChannelLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	message, err := m.getChannelMessage(fakeChannelId, req.ChannelType, req.MessageID, req.ClientMsgNo)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
+			return
+		}
+		m.Error("查询消息失败！", zap.Error(err), zap.Int64("messageId", req.MessageID), zap.String("clientMsgNo", req.ClientMsgNo))
+		c.ResponseError(errors.New("查询消息失败！"))
+		return
+	}
+	if message.Revoke {
+		c.ResponseError(errors.New("消息已撤回，不能编辑！"))
+		return
+	}
+	if !allowEdit(req.FromUID, req.ChannelType, message) {
+		c.ResponseError(errors.New("只能编辑自己发送的消息！"))
+		return
+	}
+
+	editReq := wkdb.MessageEditReq{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		MessageId:   message.MessageID,
+		MessageSeq:  uint64(message.MessageSeq),
+		Payload:     req.Payload,
+		EditedAt:    int32(time.Now().Unix()),
+	}
+	// 版本号在编辑命令应用时分配，应用后才知道本次编辑的版本
+	messageEdit, err := m.s.store.EditMessage(editReq)
+	if err != nil {
+		m.Error("编辑消息失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+		c.ResponseError(errors.New("编辑消息失败！"))
+		return
+	}
+
+	// 通知在线的订阅者
+	err = m.sendCMDNotice(req.FromUID, req.ChannelID, req.ChannelType, CMDMessageEdit, map[string]interface{}{
+		"channel_id":    req.ChannelID,
+		"channel_type":  req.ChannelType,
+		"message_id":    strconv.FormatInt(message.MessageID, 10),
+		"message_seq":   message.MessageSeq,
+		"client_msg_no": message.ClientMsgNo,
+		"version":       messageEdit.Version,
+		"edited_at":     messageEdit.EditedAt,
+		"payload":       messageEdit.Payload,
+	})
+	if err != nil {
+		m.Error("发送编辑通知失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+		c.ResponseError(errors.New("发送编辑通知失败！"))
+		return
+	}
+
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":  message.MessageID,
+		"message_seq": message.MessageSeq,
+		"version":     messageEdit.Version,
+		"edited_at":   messageEdit.EditedAt,
+	})
 }
 
-// NewMessageAPI NewMessageAPI
-func NewMessageAPI(s *Server) *MessageAPI {
-	return &MessageAPI{
-		s:             s,
-		Log:           wklog.NewWKLog("MessageApi"),
-		syncRecordMap: map[string][]*syncRecord{},
+// 获取消息的编辑历史
+func (m *MessageAPI) editHistory(c *wkhttp.Context) {
+	var req messageEditHistoryReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
 	}
-}
 
-// Route route
-func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
-	r.POST("/message/send", m.send) // 发送消息
-	// r.POST("/message/sendbatch", m.sendBatch) // 批量发送消息
-	r.POST("/message/sync", m.sync)       // 消息同步(写模式)
-	r.POST("/message/syncack", m.syncack) // 消息同步回执(写模式)
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
+	}
 
-	// // r.POST("/streammessage/start", m.streamMessageStart) // 流消息开始
-	// // r.POST("/streammessage/end", m.streamMessageEnd)     // 流消息结束
+	if m.forwardToChannelLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
 
-	r.POST("/messages", m.searchMessages) // 查询消息
+	edits, err := m.s.store.GetMessageEdits(fakeChannelId, req.ChannelType, req.MessageID)
+	if err != nil {
+		m.Error("获取消息编辑历史失败！", zap.Error(err), zap.Int64("messageId", req.MessageID))
+		c.ResponseError(errors.New("获取消息编辑历史失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, edits)
+}
 
+// 添加消息回应
+func (m *MessageAPI) addReaction(c *wkhttp.Context) {
+	m.updateReaction(c, false)
 }
 
-func (m *MessageAPI) send(c *wkhttp.Context) {
-	var req MessageSendReq
-	if err := c.BindJSON(&req); err != nil {
+// 取消消息回应
+func (m *MessageAPI) removeReaction(c *wkhttp.Context) {
+	m.updateReaction(c, true)
+}
+
+func (m *MessageAPI) updateReaction(c *wkhttp.Context, isDeleted bool) {
+	var req messageReactionReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
 		m.Error("数据格式有误！", zap.Error(err))
 		c.ResponseError(err)
 		return
@@ -59,112 +1072,390 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 		return
 	}
 
-	if strings.TrimSpace(req.FromUID) == "" {
-		req.FromUID = m.s.opts.SystemUID
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelID)
 	}
 
-	channelId := req.ChannelID
-	channelType := req.ChannelType
-	// if strings.TrimSpace(channelId) == "" && len(req.Subscribers) > 0 { //如果没频道ID 但是有订阅者，则创建一个临时频道
-	// 	// channelId = fmt.Sprintf("%s%s", wkutil.GenUUID(), m.s.opts.TmpChannel.Suffix)
-	// 	// channelType = wkproto.ChannelTypeGroup
-	// 	// m.s.channelManager.CreateTmpChannel(channelId, channelType, req.Subscribers)
-	// }
-	m.Debug("发送消息内容：", zap.String("msg", wkutil.ToJSON(req)))
-	if strings.TrimSpace(channelId) == "" && len(req.Subscribers) == 0 { //指定了频道 才能正常发送
-		m.Error("无法处理发送消息请求！", zap.Any("req", req))
-		c.ResponseError(errors.New("无法处理发送消息请求！"))
+	if m.forwardToChannelLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
 		return
 	}
 
-	if strings.TrimSpace(channelId) == "" && len(req.Subscribers) > 0 {
-		if req.Header.SyncOnce != 1 {
-			m.Error("subscribers有值的情况下，消息必须是syncOnce消息", zap.Any("req", req))
-			c.ResponseError(errors.New("无法处理发送消息请求！"))
+	// 只有频道的成员才能回应
+	isMember, err := m.isChannelMember(fakeChannelId, req.ChannelType, req.UID)
+	if err != nil {
+		m.Error("查询订阅者失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("查询订阅者失败！"))
+		return
+	}
+	if !isMember {
+		c.ResponseError(errors.New("用户不是频道的成员！"))
+		return
+	}
+
+	// 消息必须属于这个频道（getChannelMessage会校验消息所在的频道）
+	message, err := m.getChannelMessage(fakeChannelId, req.ChannelType, req.MessageID, "")
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			c.ResponseError(errors.New("消息不存在！"))
 			return
 		}
+		m.Error("查询消息失败！", zap.Error(err), zap.Int64("messageId", req.MessageID))
+		c.ResponseError(errors.New("查询消息失败！"))
+		return
+	}
+	if message.Revoke {
+		c.ResponseError(errors.New("消息已撤回！"))
+		return
+	}
 
-		for _, subscriber := range req.Subscribers {
-			clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
-			// 发送消息
-			_, err := m.sendMessageToChannel(req, subscriber, wkproto.ChannelTypePerson, clientMsgNo, wkproto.StreamFlagIng)
-			if err != nil {
-				c.ResponseError(err)
-				return
-			}
-		}
+	reaction := wkdb.Reaction{
+		ChannelId:   fakeChannelId,
+		ChannelType: req.ChannelType,
+		MessageId:   message.MessageID,
+		MessageSeq:  uint64(message.MessageSeq),
+		Uid:         req.UID,
+		Emoji:       req.Emoji,
+		IsDeleted:   isDeleted,
+		CreatedAt:   time.Now().Unix(),
+	}
+	err = m.s.store.AddOrUpdateReaction(reaction)
+	if err != nil {
+		m.Error("保存消息回应失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+		c.ResponseError(errors.New("保存消息回应失败！"))
+		return
+	}
 
-		c.ResponseOK()
+	// 通知在线的订阅者
+	err = m.sendCMDNotice(req.UID, req.ChannelID, req.ChannelType, CMDMessageReaction, map[string]interface{}{
+		"channel_id":   req.ChannelID,
+		"channel_type": req.ChannelType,
+		"message_id":   strconv.FormatInt(message.MessageID, 10),
+		"message_seq":  message.MessageSeq,
+		"uid":          req.UID,
+		"emoji":        req.Emoji,
+		"is_deleted":   wkutil.BoolToInt(isDeleted),
+	})
+	if err != nil {
+		m.Error("发送回应通知失败！", zap.Error(err), zap.Int64("messageId", message.MessageID))
+		c.ResponseError(errors.New("发送回应通知失败！"))
+		return
+	}
+	c.ResponseOK()
+}
 
+// 同步频道的消息回应（按版本号增量同步）
+func (m *MessageAPI) syncReactions(c *wkhttp.Context) {
+	var req messageReactionSyncReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
 		return
 	}
 
-	clientMsgNo := req.ClientMsgNo
-	if strings.TrimSpace(clientMsgNo) == "" {
-		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	fakeChannelId := req.ChannelID
+	if req.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
 	}
 
-	// 发送消息
-	messageId, err := m.sendMessageToChannel(req, channelId, channelType, clientMsgNo, wkproto.StreamFlagIng)
+	// 回应通过槽的raft存储，槽领导节点上一定是最新的
+	if m.s.forwardToSlotLeaderIfNeed(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+	reactions, err := m.s.store.GetReactionsAfterVersion(fakeChannelId, req.ChannelType, req.Version, limit)
 	if err != nil {
-		c.ResponseError(err)
+		m.Error("同步消息回应失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("同步消息回应失败！"))
 		return
 	}
-	c.ResponseOKWithData(map[string]interface{}{
-		"message_id":    messageId,
-		"client_msg_no": clientMsgNo,
-	})
+	resps := make([]*messageReactionResp, 0, len(reactions))
+	for _, reaction := range reactions {
+		resps = append(resps, newMessageReactionResp(req.ChannelID, reaction))
+	}
+	c.JSON(http.StatusOK, resps)
 }
 
-func (m *MessageAPI) sendMessageToChannel(req MessageSendReq, channelId string, channelType uint8, clientMsgNo string, streamFlag wkproto.StreamFlag) (int64, error) {
+// 上报消息已读回执
+func (m *MessageAPI) readed(c *wkhttp.Context) {
+	var req messageReadedReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
-	// m.s.monitor.SendPacketInc(req.Header.NoPersist != 1)
-	// m.s.monitor.SendSystemMsgInc()
+	// 需要在频道领导节点上查询消息，已读数量从槽领导节点获取
+	if m.forwardToChannelLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
 
-	// var messageID = m.s.dispatch.processor.genMessageID()
+	// 只有频道的订阅者才能上报已读
+	isSubscriber, err := m.s.store.ExistSubscriber(req.ChannelID, req.ChannelType, req.UID)
+	if err != nil {
+		m.Error("查询订阅者失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("查询订阅者失败！"))
+		return
+	}
+	if !isSubscriber {
+		c.ResponseError(errors.New("用户不是频道的订阅者！"))
+		return
+	}
 
-	fakeChannelId := channelId
-	fakeChannelType := req.ChannelType
-	if channelType == wkproto.ChannelTypePerson {
-		fakeChannelId = GetFakeChannelIDWith(req.FromUID, channelId)
+	channelInfo, err := m.s.store.GetChannel(req.ChannelID, req.ChannelType)
+	if err != nil {
+		m.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道信息失败！"))
+		return
 	}
 
-	if req.Header.SyncOnce == 1 { // 命令消息，将原频道转换为cmd频道
-		fakeChannelId = m.s.opts.OrginalConvertCmdChannel(fakeChannelId)
+	// 只有别人发送的有效消息才需要回执
+	messages := make([]wkdb.Message, 0, len(req.MessageSeqs))
+	messageSeqs := make([]uint64, 0, len(req.MessageSeqs))
+	for _, messageSeq := range req.MessageSeqs {
+		message, err := m.s.store.LoadMsg(req.ChannelID, req.ChannelType, messageSeq)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			m.Error("查询消息失败！", zap.Error(err), zap.Uint64("messageSeq", messageSeq))
+			c.ResponseError(errors.New("查询消息失败！"))
+			return
+		}
+		if message.Revoke || message.FromUID == req.UID {
+			continue
+		}
+		messages = append(messages, message)
+		messageSeqs = append(messageSeqs, messageSeq)
+	}
+	if len(messages) == 0 {
+		c.ResponseOK()
+		return
 	}
 
-	channel := m.s.channelReactor.loadOrCreateChannel(fakeChannelId, fakeChannelType)
+	oldCounts, err := m.s.getMessageReadCounts(req.ChannelID, req.ChannelType, messageSeqs)
+	if err != nil {
+		m.Error("获取消息已读数量失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取消息已读数量失败！"))
+		return
+	}
 
-	if channel == nil {
-		return 0, errors.New("频道信息不存在！")
+	// 超大群只记录已读数量
+	err = m.s.store.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   req.ChannelID,
+		ChannelType: req.ChannelType,
+		Uid:         req.UID,
+		MessageSeqs: messageSeqs,
+		ReadedAt:    time.Now().Unix(),
+	}, channelInfo.Large)
+	if err != nil {
+		m.Error("保存消息已读回执失败！", zap.Error(err))
+		c.ResponseError(errors.New("保存消息已读回执失败！"))
+		return
 	}
 
-	var setting wkproto.Setting
-	if len(strings.TrimSpace(req.StreamNo)) > 0 {
-		setting = setting.Set(wkproto.SettingStream)
+	newCounts, err := m.s.getMessageReadCounts(req.ChannelID, req.ChannelType, messageSeqs)
+	if err != nil {
+		m.Error("获取消息已读数量失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取消息已读数量失败！"))
+		return
 	}
 
-	// 将消息提交到频道
-	systemDeviceId := req.FromUID
-	messageId, err := channel.proposeSend(req.FromUID, systemDeviceId, 0, m.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
-		Framer: wkproto.Framer{
-			RedDot:    wkutil.IntToBool(req.Header.RedDot),
-			SyncOnce:  wkutil.IntToBool(req.Header.SyncOnce),
-			NoPersist: wkutil.IntToBool(req.Header.NoPersist),
-		},
-		Setting:     setting,
-		Expire:      req.Expire,
-		StreamNo:    req.StreamNo,
-		ClientMsgNo: clientMsgNo,
-		ChannelID:   req.ChannelID,
-		ChannelType: req.ChannelType,
-		Payload:     req.Payload,
+	// 已读数量有变化的消息按发送者通知
+	changedOfSender := make(map[string][]map[string]interface{})
+	for i, message := range messages {
+		if newCounts[i].Count == oldCounts[i].Count {
+			continue
+		}
+		changedOfSender[message.FromUID] = append(changedOfSender[message.FromUID], map[string]interface{}{
+			"message_id":   strconv.FormatInt(message.MessageID, 10),
+			"message_seq":  message.MessageSeq,
+			"readed_count": newCounts[i].Count,
+		})
+	}
+	for fromUid, changed := range changedOfSender {
+		err = m.sendCMDNotice("", fromUid, wkproto.ChannelTypePerson, CMDMessageReadedCount, map[string]interface{}{
+			"channel_id":   req.ChannelID,
+			"channel_type": req.ChannelType,
+			"messages":     changed,
+		})
+		if err != nil {
+			m.Warn("发送已读数量通知失败！", zap.Error(err), zap.String("fromUid", fromUid))
+		}
+	}
+	c.ResponseOK()
+}
+
+// 获取消息的已读用户
+func (m *MessageAPI) readers(c *wkhttp.Context) {
+	var req messageReadersReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	// 已读回执通过槽的raft存储，槽领导节点上一定是最新的
+	if m.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	counts, err := m.s.store.GetMessageReadCounts(req.ChannelID, req.ChannelType, []uint64{req.MessageSeq})
+	if err != nil {
+		m.Error("获取消息已读数量失败！", zap.Error(err), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息已读数量失败！"))
+		return
+	}
+	readers, err := m.s.store.GetMessageReaders(req.ChannelID, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		m.Error("获取消息已读用户失败！", zap.Error(err), zap.Uint64("messageSeq", req.MessageSeq))
+		c.ResponseError(errors.New("获取消息已读用户失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, messageReadersResp{
+		MessageSeq:  req.MessageSeq,
+		ReadedCount: counts[0].Count,
+		Readers:     readers,
 	})
+}
+
+// 批量获取消息的已读数量
+func (m *MessageAPI) readedCounts(c *wkhttp.Context) {
+	var req messageReadedCountsReq
+	bodyBytes, err := BindJSON(&req, c)
 	if err != nil {
-		return messageId, err
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
 	}
 
-	return messageId, nil
+	// 已读回执通过槽的raft存储，槽领导节点上一定是最新的
+	if m.s.forwardToSlotLeaderIfNeed(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	counts, err := m.s.store.GetMessageReadCounts(req.ChannelID, req.ChannelType, req.MessageSeqs)
+	if err != nil {
+		m.Error("获取消息已读数量失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取消息已读数量失败！"))
+		return
+	}
+	resps := make([]*messageReadedCountResp, 0, len(counts))
+	for _, count := range counts {
+		resps = append(resps, &messageReadedCountResp{
+			MessageSeq:  count.MessageSeq,
+			ReadedCount: count.Count,
+		})
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 如果当前节点不是频道的领导节点则将请求转发给领导节点，返回true表示已转发
+func (m *MessageAPI) forwardToChannelLeaderIfNeed(c *wkhttp.Context, fakeChannelId string, channelType uint8, bodyBytes []byte) bool {
+	if !m.s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := m.s.cluster.LeaderOfChannelForRead(fakeChannelId, channelType) // 消息在频道领导节点上一定存在
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		c.ResponseError(errors.New("消息不存在！"))
+		return true
+	}
+	if err != nil {
+		m.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", fakeChannelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id != m.s.opts.Cluster.NodeId {
+		m.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
+
+// 通过消息id或客户端消息编号获取频道内的消息，不存在返回wkdb.ErrNotFound
+func (m *MessageAPI) getChannelMessage(fakeChannelId string, channelType uint8, messageId int64, clientMsgNo string) (wkdb.Message, error) {
+	var (
+		message wkdb.Message
+		err     error
+	)
+	if messageId > 0 {
+		message, err = m.s.store.GetMessage(uint64(messageId))
+		if err != nil {
+			return wkdb.EmptyMessage, err
+		}
+	} else {
+		messages, err := m.s.store.SearchMessages(wkdb.MessageSearchReq{
+			ChannelId:   fakeChannelId,
+			ChannelType: channelType,
+			ClientMsgNo: clientMsgNo,
+			Limit:       1,
+		})
+		if err != nil {
+			return wkdb.EmptyMessage, err
+		}
+		if len(messages) > 0 {
+			message = messages[0]
+		}
+	}
+	if wkdb.IsEmptyMessage(message) || message.ChannelID != fakeChannelId || message.ChannelType != channelType {
+		return wkdb.EmptyMessage, wkdb.ErrNotFound
+	}
+	return message, nil
+}
+
+// isChannelMember 用户是否是频道的成员，个人频道为通讯的双方，其他频道为订阅者
+func (m *MessageAPI) isChannelMember(fakeChannelId string, channelType uint8, uid string) (bool, error) {
+	if channelType == wkproto.ChannelTypePerson {
+		from, to := GetFromUIDAndToUIDWith(fakeChannelId)
+		return uid == from || uid == to, nil
+	}
+	return m.s.store.ExistSubscriber(fakeChannelId, channelType, uid)
+}
+
+// 通过不存储的命令消息将事件投递给频道内在线的订阅者
+func (m *MessageAPI) sendCMDNotice(fromUid string, channelId string, channelType uint8, cmd string, param map[string]interface{}) error {
+	if strings.TrimSpace(fromUid) == "" {
+		fromUid = m.s.opts.SystemUID
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"type":  ContentTypeCMD,
+		"cmd":   cmd,
+		"param": param,
+	}))
+	_, err := m.sendMessageToChannel(MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: 1,
+		},
+		FromUID:     fromUid,
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Payload:     payload,
+	}, channelId, channelType, fmt.Sprintf("%s0", wkutil.GenUUID()), wkproto.StreamFlagIng, 0)
+	return err
 }
 
 // 消息同步
@@ -435,8 +1726,22 @@ func (m *MessageAPI) searchMessages(c *wkhttp.Context) {
 	if req.ChannelType == wkproto.ChannelTypePerson {
 		fakeChannelid = GetFakeChannelIDWith(req.LoginUid, req.ChannelID)
 	}
+	// 历史消息可见策略，指定了登录用户时只返回该用户可见的消息
+	var visibleStartSeq uint64
+	if strings.TrimSpace(req.LoginUid) != "" {
+		var err error
+		visibleStartSeq, err = m.s.historyVisibleStartSeq(req.ChannelID, req.ChannelType, req.LoginUid)
+		if err != nil {
+			m.Error("获取历史消息可见范围失败！", zap.Error(err))
+			c.ResponseError(errors.New("获取历史消息可见范围失败！"))
+			return
+		}
+	}
 	var messages []wkdb.Message
 	for _, seq := range req.MessageSeqs {
+		if visibleStartSeq > 0 && uint64(seq) < visibleStartSeq {
+			continue
+		}
 		msg, err := m.s.store.LoadMsg(fakeChannelid, req.ChannelType, uint64(seq))
 		if err != nil && err != wkdb.ErrNotFound {
 			m.Error("查询消息失败！", zap.Error(err))
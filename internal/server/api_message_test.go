@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 个人频道撤回和编辑消息必须指定from_uid
+func TestMessageRevokeAndEditRequireFromUidForPerson(t *testing.T) {
+	s := NewTestServer(t)
+	r := wkhttp.New()
+	NewMessageAPI(s).Route(r)
+
+	for _, path := range []string{"/message/revoke", "/message/edit"} {
+		for _, fromUid := range []string{"", "  "} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", path, bytes.NewReader([]byte(wkutil.ToJson(map[string]interface{}{
+				"channel_id":   "u2",
+				"channel_type": wkproto.ChannelTypePerson,
+				"from_uid":     fromUid,
+				"message_id":   1,
+				"payload":      []byte("hello"),
+			}))))
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, path)
+			assert.Contains(t, w.Body.String(), "from_uid", path)
+		}
+	}
+}
+
+func TestMessageAllowRevoke(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.Open()
+	assert.Nil(t, err)
+	defer s.store.Close()
+
+	channelId := "g1"
+	channelType := wkproto.ChannelTypeGroup
+	_, err = s.store.DB().AddOrUpdateChannel(wkdb.NewChannelInfo(channelId, channelType))
+	assert.Nil(t, err)
+	err = s.store.DB().AddSubscribers(channelId, channelType, []string{"u1", "u2", "admin"})
+	assert.Nil(t, err)
+	err = s.store.DB().SetSubscriberRole(channelId, channelType, []string{"admin"}, wkdb.SubscriberRoleAdmin)
+	assert.Nil(t, err)
+
+	m := NewMessageAPI(s)
+	message := wkdb.Message{RecvPacket: wkproto.RecvPacket{FromUID: "u1"}}
+
+	tests := []struct {
+		name        string
+		fromUid     string
+		channelType uint8
+		allow       bool
+	}{
+		{"group system operator", "", channelType, true},
+		{"group sender", "u1", channelType, true},
+		{"group other member", "u2", channelType, false},
+		{"group admin", "admin", channelType, true},
+		{"person system operator", "", wkproto.ChannelTypePerson, false},
+		{"person sender", "u1", wkproto.ChannelTypePerson, true},
+		{"person other", "u2", wkproto.ChannelTypePerson, false},
+	}
+	for _, tt := range tests {
+		allow, err := m.allowRevoke(tt.fromUid, channelId, tt.channelType, message)
+		assert.Nil(t, err, tt.name)
+		assert.Equal(t, tt.allow, allow, tt.name)
+	}
+}
+
+func TestMessageAllowEdit(t *testing.T) {
+	message := wkdb.Message{RecvPacket: wkproto.RecvPacket{FromUID: "u1"}}
+
+	tests := []struct {
+		name        string
+		fromUid     string
+		channelType uint8
+		allow       bool
+	}{
+		{"group system operator", "", wkproto.ChannelTypeGroup, true},
+		{"group sender", "u1", wkproto.ChannelTypeGroup, true},
+		{"group other", "admin", wkproto.ChannelTypeGroup, false},
+		{"person system operator", "", wkproto.ChannelTypePerson, false},
+		{"person sender", "u1", wkproto.ChannelTypePerson, true},
+		{"person other", "u2", wkproto.ChannelTypePerson, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allow, allowEdit(tt.fromUid, tt.channelType, message), tt.name)
+	}
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// RateLimitAPI 发送频率限制相关API
+type RateLimitAPI struct {
+	wklog.Log
+	s *Server
+}
+
+// NewRateLimitAPI NewRateLimitAPI
+func NewRateLimitAPI(s *Server) *RateLimitAPI {
+	return &RateLimitAPI{
+		Log: wklog.NewWKLog("RateLimitAPI"),
+		s:   s,
+	}
+}
+
+// Route 发送频率限制相关路由配置
+func (a *RateLimitAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/ratelimit", a.list)           // 获取用户和频道的频率限制规则
+	r.POST("/ratelimit/set", a.set)       // 设置用户或频道的频率限制规则（覆盖全局配置）
+	r.POST("/ratelimit/remove", a.remove) // 移除用户或频道的频率限制规则（恢复全局配置）
+}
+
+// rateLimitReqItem 速率和突发数量不传或为-1表示使用全局配置，速率为0表示不限制，突发数量为0时等于速率
+type rateLimitReqItem struct {
+	TargetType  uint8    `json:"target_type"`  // 规则类型 1.用户 2.频道
+	UID         string   `json:"uid"`          // 用户uid（用户规则）
+	ChannelID   string   `json:"channel_id"`   // 频道id（频道规则）
+	ChannelType uint8    `json:"channel_type"` // 频道类型（频道规则）
+	Rate        *float64 `json:"rate"`         // 每秒允许发送的消息数量
+	Burst       *int     `json:"burst"`        // 允许的突发数量
+	MemberRate  *float64 `json:"member_rate"`  // 频道内每个用户每秒允许发送的消息数量（频道规则）
+	MemberBurst *int     `json:"member_burst"` // 频道内每个用户允许的突发数量（频道规则）
+}
+
+func (r rateLimitReqItem) toRateLimit() wkdb.RateLimit {
+	limit := wkdb.RateLimit{
+		TargetType:  r.TargetType,
+		Target:      r.UID,
+		Rate:        rateLimitRateOrInherit(r.Rate),
+		Burst:       rateLimitBurstOrInherit(r.Burst),
+		MemberRate:  rateLimitRateOrInherit(r.MemberRate),
+		MemberBurst: rateLimitBurstOrInherit(r.MemberBurst),
+	}
+	if r.TargetType == rateLimitTargetChannel {
+		limit.Target = r.ChannelID
+		limit.ChannelType = r.ChannelType
+	}
+	return limit
+}
+
+func rateLimitRateOrInherit(rate *float64) float64 {
+	if rate == nil {
+		return rateLimitInherit
+	}
+	return *rate
+}
+
+func rateLimitBurstOrInherit(burst *int) int {
+	if burst == nil {
+		return rateLimitInherit
+	}
+	return *burst
+}
+
+// 获取频率限制规则（从系统数据所在槽的领导节点获取）
+func (a *RateLimitAPI) list(c *wkhttp.Context) {
+	limits, err := a.s.rateLimitManager.load()
+	if err != nil {
+		a.Error("获取频率限制规则失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取频率限制规则失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, limits)
+}
+
+// 设置频率限制规则，规则通过系统数据所在的槽复制，并通知所有节点重新加载
+func (a *RateLimitAPI) set(c *wkhttp.Context) {
+	limits, ok := a.bindLimits(c)
+	if !ok {
+		return
+	}
+	if err := a.s.store.AddOrUpdateRateLimits(limits); err != nil {
+		a.Error("设置频率限制规则失败！", zap.Error(err))
+		c.ResponseError(errors.New("设置频率限制规则失败！"))
+		return
+	}
+	a.reload(c)
+}
+
+// 移除频率限制规则，并通知所有节点重新加载
+func (a *RateLimitAPI) remove(c *wkhttp.Context) {
+	limits, ok := a.bindLimits(c)
+	if !ok {
+		return
+	}
+	if err := a.s.store.RemoveRateLimits(limits); err != nil {
+		a.Error("移除频率限制规则失败！", zap.Error(err))
+		c.ResponseError(errors.New("移除频率限制规则失败！"))
+		return
+	}
+	a.reload(c)
+}
+
+func (a *RateLimitAPI) bindLimits(c *wkhttp.Context) ([]wkdb.RateLimit, bool) {
+	var req struct {
+		Limits []rateLimitReqItem `json:"limits"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return nil, false
+	}
+	if len(req.Limits) == 0 {
+		c.ResponseError(errors.New("limits不能为空！"))
+		return nil, false
+	}
+	limits := make([]wkdb.RateLimit, 0, len(req.Limits))
+	for _, item := range req.Limits {
+		limit := item.toRateLimit()
+		if err := checkRateLimit(limit); err != nil {
+			c.ResponseError(err)
+			return nil, false
+		}
+		limits = append(limits, limit)
+	}
+	return limits, true
+}
+
+// 通知所有节点重新加载频率限制规则，可重复执行，有节点失败时返回失败的节点，可以重试
+func (a *RateLimitAPI) reload(c *wkhttp.Context) {
+	failedNodes := make([]uint64, 0)
+	for _, nodeId := range a.s.clusterNodeIds() {
+		if nodeId == a.s.opts.Cluster.NodeId {
+			if err := a.s.rateLimitManager.reload(); err != nil {
+				a.Error("重新加载频率限制规则失败！", zap.Error(err))
+				failedNodes = append(failedNodes, nodeId)
+			}
+			continue
+		}
+		if _, err := a.s.requestNode(nodeId, "/wk/rateLimits", nil); err != nil {
+			a.Error("请求节点重新加载频率限制规则失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+	if len(failedNodes) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"msg":          "部分节点重新加载频率限制规则失败，请重试！",
+			"status":       http.StatusBadRequest,
+			"failed_nodes": failedNodes,
+		})
+		return
+	}
+	c.ResponseOK()
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SensitiveWordAPI 敏感词相关API
+type SensitiveWordAPI struct {
+	wklog.Log
+	s *Server
+}
+
+// NewSensitiveWordAPI NewSensitiveWordAPI
+func NewSensitiveWordAPI(s *Server) *SensitiveWordAPI {
+	return &SensitiveWordAPI{
+		Log: wklog.NewWKLog("SensitiveWordAPI"),
+		s:   s,
+	}
+}
+
+// Route 敏感词相关路由配置
+func (a *SensitiveWordAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/sensitive/words", a.list)           // 获取敏感词规则
+	r.POST("/sensitive/words/add", a.add)       // 添加或更新敏感词规则
+	r.POST("/sensitive/words/remove", a.remove) // 移除敏感词规则
+}
+
+// 获取本节点的敏感词规则
+func (a *SensitiveWordAPI) list(c *wkhttp.Context) {
+	words, err := a.s.store.DB().GetSensitiveWords()
+	if err != nil {
+		a.Error("获取敏感词失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取敏感词失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, words)
+}
+
+// 添加或更新敏感词规则，同步给所有节点
+func (a *SensitiveWordAPI) add(c *wkhttp.Context) {
+	var req struct {
+		Words []struct {
+			Word   string `json:"word"`   // 敏感词或正则表达式
+			Regex  int    `json:"regex"`  // 是否为正则表达式 1.是
+			Action uint8  `json:"action"` // 命中后的处理方式 1.拒绝发送 2.替换为* 3.通知（webhook的msg.sensitive事件）
+		} `json:"words"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if len(req.Words) == 0 {
+		c.ResponseError(errors.New("words不能为空！"))
+		return
+	}
+	createdAt := time.Now().Unix()
+	words := make([]wkdb.SensitiveWord, 0, len(req.Words))
+	for _, w := range req.Words {
+		word := wkdb.SensitiveWord{
+			Word:      w.Word,
+			Regex:     w.Regex == 1,
+			Action:    w.Action,
+			CreatedAt: createdAt,
+		}
+		if err := checkSensitiveWord(word); err != nil {
+			c.ResponseError(err)
+			return
+		}
+		words = append(words, word)
+	}
+	a.update(c, &sensitiveWordReq{addWords: words})
+}
+
+// 移除敏感词规则，同步给所有节点
+func (a *SensitiveWordAPI) remove(c *wkhttp.Context) {
+	var req struct {
+		Words []string `json:"words"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if len(req.Words) == 0 {
+		c.ResponseError(errors.New("words不能为空！"))
+		return
+	}
+	a.update(c, &sensitiveWordReq{removeWords: req.Words})
+}
+
+// 更新所有节点的敏感词，更新可重复执行，有节点失败时可以重试
+func (a *SensitiveWordAPI) update(c *wkhttp.Context, req *sensitiveWordReq) {
+	data := req.Marshal()
+	failedNodes := make([]uint64, 0)
+	for _, nodeId := range a.s.clusterNodeIds() {
+		if nodeId == a.s.opts.Cluster.NodeId {
+			if err := a.s.sensitiveWordManager.update(req.addWords, req.removeWords); err != nil {
+				a.Error("更新敏感词失败！", zap.Error(err))
+				failedNodes = append(failedNodes, nodeId)
+			}
+			continue
+		}
+		if _, err := a.s.requestNode(nodeId, "/wk/sensitiveWords", data); err != nil {
+			a.Error("请求节点更新敏感词失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+	if len(failedNodes) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"msg":          "部分节点更新敏感词失败，请重试！",
+			"status":       http.StatusBadRequest,
+			"failed_nodes": failedNodes,
+		})
+		return
+	}
+	c.ResponseOK()
+}
@@ -38,6 +38,10 @@ func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
 	r.POST("/user/systemuids_add", u.systemUIDsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUIDsRemove) // 移除系统uid
+	r.POST("/user/ban", u.ban)                            // 封禁用户（可设置时长，封禁后立即断开用户的所有连接）
+	r.POST("/user/unban", u.unban)                        // 解除用户封禁
+	r.GET("/user/bans", u.banList)                        // 获取封禁中的用户
+	r.POST("/user/device_push", u.devicePush)             // 注册或注销设备的离线推送令牌
 
 }
 
@@ -93,6 +97,22 @@ func (u *UserAPI) quitUserDevice(uid string, deviceFlag wkproto.DeviceFlag) erro
 		u.Error("清空用户token失败！", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
 		return err
 	}
+	// 设备退出后不再推送离线消息
+	devicePushes, err := u.s.store.GetDevicePushes(uid)
+	if err != nil {
+		u.Error("获取设备推送令牌失败！", zap.Error(err), zap.String("uid", uid))
+		return err
+	}
+	for _, devicePush := range devicePushes {
+		if devicePush.DeviceFlag == uint64(deviceFlag) {
+			if err = u.s.store.RemoveDevicePush(uid, devicePush.DeviceFlag); err != nil {
+				u.Error("移除设备推送令牌失败！", zap.Error(err), zap.String("uid", uid), zap.Uint8("deviceFlag", deviceFlag.ToUint8()))
+				return err
+			}
+			break
+		}
+	}
+
 	oldConns := u.s.userReactor.getConnContextByDeviceFlag(uid, deviceFlag)
 	if len(oldConns) > 0 {
 		for _, oldConn := range oldConns {
@@ -261,7 +281,7 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 
 	u.Debug("req", zap.Any("req", req))
 
-	ban := false // 是否被封禁
+	ban := u.s.userBanManager.isBanned(req.UID) // 是否被封禁
 
 	channelInfo, err := u.s.store.GetChannel(req.UID, wkproto.ChannelTypePerson)
 	if err != nil {
@@ -269,7 +289,7 @@ func (u *UserAPI) updateToken(c *wkhttp.Context) {
 		c.ResponseError(err)
 		return
 	}
-	if !wkdb.IsEmptyChannelInfo(channelInfo) {
+	if !ban && !wkdb.IsEmptyChannelInfo(channelInfo) {
 		ban = channelInfo.Ban
 	}
 	if ban {
@@ -400,3 +420,173 @@ type OnlinestatusResp struct {
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
 	Online     int    `json:"online"`      // 是否在线
 }
+
+// 封禁用户，封禁通过用户所在的槽复制，并通知所有节点刷新
+func (u *UserAPI) ban(c *wkhttp.Context) {
+	var req struct {
+		UIDs     []string `json:"uids"`     // 封禁的用户
+		Duration int64    `json:"duration"` // 封禁时长（秒），0表示永久
+		Reason   string   `json:"reason"`   // 封禁原因（断开连接时下发给客户端）
+	}
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := checkUserBanUids(req.UIDs); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.Duration < 0 {
+		c.ResponseError(errors.New("duration不能为负数！"))
+		return
+	}
+	now := time.Now().Unix()
+	var expireAt int64
+	if req.Duration > 0 {
+		expireAt = now + req.Duration
+	}
+	bans := make([]wkdb.UserBan, 0, len(req.UIDs))
+	for _, uid := range req.UIDs {
+		ban := wkdb.UserBan{
+			Uid:       uid,
+			Reason:    req.Reason,
+			ExpireAt:  expireAt,
+			CreatedAt: now,
+		}
+		if err := u.s.store.AddOrUpdateUserBan(ban); err != nil {
+			u.Error("封禁用户失败！", zap.Error(err), zap.String("uid", uid))
+			c.ResponseError(errors.New("封禁用户失败！"))
+			return
+		}
+		bans = append(bans, ban)
+	}
+	if !u.refreshBans(c, req.UIDs) {
+		return
+	}
+	for _, ban := range bans {
+		u.s.webhook.notifyUserBan(ban)
+	}
+	c.ResponseOK()
+}
+
+// 解除用户封禁，并通知所有节点刷新
+func (u *UserAPI) unban(c *wkhttp.Context) {
+	var req struct {
+		UIDs []string `json:"uids"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := checkUserBanUids(req.UIDs); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	for _, uid := range req.UIDs {
+		if err := u.s.store.RemoveUserBan(uid, 0); err != nil {
+			u.Error("解除用户封禁失败！", zap.Error(err), zap.String("uid", uid))
+			c.ResponseError(errors.New("解除用户封禁失败！"))
+			return
+		}
+	}
+	if !u.refreshBans(c, req.UIDs) {
+		return
+	}
+	for _, uid := range req.UIDs {
+		u.s.webhook.notifyUserUnban(uid, false)
+	}
+	c.ResponseOK()
+}
+
+// 获取封禁中的用户（汇总每个节点为槽领导的封禁）
+func (u *UserAPI) banList(c *wkhttp.Context) {
+	bans := make([]wkdb.UserBan, 0)
+	for _, nodeId := range u.s.clusterNodeIds() {
+		if nodeId == u.s.opts.Cluster.NodeId {
+			localBans, err := u.s.userBanManager.activeBansOfLocal()
+			if err != nil {
+				u.Error("获取封禁用户失败！", zap.Error(err))
+				c.ResponseError(errors.New("获取封禁用户失败！"))
+				return
+			}
+			bans = append(bans, localBans...)
+			continue
+		}
+		data, err := u.s.requestNode(nodeId, "/wk/userBanList", nil)
+		if err != nil {
+			u.Error("请求节点获取封禁用户失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			c.ResponseError(errors.New("获取封禁用户失败！"))
+			return
+		}
+		resp := &userBanResp{}
+		if err = resp.Unmarshal(data); err != nil {
+			u.Error("解析封禁用户失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			c.ResponseError(errors.New("获取封禁用户失败！"))
+			return
+		}
+		bans = append(bans, resp.bans...)
+	}
+	c.JSON(http.StatusOK, bans)
+}
+
+// 通知所有节点刷新用户封禁（断开被封禁用户的连接），可重复执行，有节点失败时返回失败的节点，可以重试
+func (u *UserAPI) refreshBans(c *wkhttp.Context, uids []string) bool {
+	data := (&userBanReq{uids: uids}).Marshal()
+	failedNodes := make([]uint64, 0)
+	for _, nodeId := range u.s.clusterNodeIds() {
+		if nodeId == u.s.opts.Cluster.NodeId {
+			if err := u.s.userBanManager.refresh(uids); err != nil {
+				u.Error("刷新用户封禁失败！", zap.Error(err))
+				failedNodes = append(failedNodes, nodeId)
+			}
+			continue
+		}
+		if _, err := u.s.requestNode(nodeId, "/wk/userBans", data); err != nil {
+			u.Error("请求节点刷新用户封禁失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+	if len(failedNodes) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"msg":          "部分节点刷新用户封禁失败，请重试！",
+			"status":       http.StatusBadRequest,
+			"failed_nodes": failedNodes,
+		})
+		return false
+	}
+	return true
+}
+
+// 注册或注销设备的离线推送令牌，推送令牌为空表示注销
+func (u *UserAPI) devicePush(c *wkhttp.Context) {
+	var req devicePushReq
+	if err := c.BindJSON(&req); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	var err error
+	if req.PushToken == "" {
+		err = u.s.store.RemoveDevicePush(req.UID, uint64(req.DeviceFlag))
+	} else {
+		err = u.s.store.AddOrUpdateDevicePush(wkdb.DevicePush{
+			Uid:        req.UID,
+			DeviceFlag: uint64(req.DeviceFlag),
+			PushType:   req.PushType,
+			PushToken:  req.PushToken,
+			UpdatedAt:  time.Now().Unix(),
+		})
+	}
+	if err != nil {
+		u.Error("更新设备推送令牌失败！", zap.Error(err), zap.String("uid", req.UID), zap.Uint8("deviceFlag", req.DeviceFlag))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
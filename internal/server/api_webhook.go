@@ -0,0 +1,290 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// WebhookAPI webhook端点、发件箱和死信相关API（发件箱为节点本地数据，通过node_id指定节点）
+type WebhookAPI struct {
+	wklog.Log
+	s *Server
+}
+
+// NewWebhookAPI NewWebhookAPI
+func NewWebhookAPI(s *Server) *WebhookAPI {
+	return &WebhookAPI{
+		Log: wklog.NewWKLog("WebhookAPI"),
+		s:   s,
+	}
+}
+
+// Route webhook相关路由配置
+func (w *WebhookAPI) Route(r *wkhttp.WKHttp) {
+	r.GET("/webhook/endpoints", w.endpoints)               // 获取webhook端点
+	r.POST("/webhook/endpoints/add", w.addEndpoints)       // 添加或更新webhook端点
+	r.POST("/webhook/endpoints/remove", w.removeEndpoints) // 移除webhook端点（同时删除端点的发件箱和死信）
+	r.GET("/webhook/outbox", w.outbox)                     // 获取发件箱内待推送的事件
+	r.GET("/webhook/deadletters", w.deadLetters)           // 获取死信事件
+	r.POST("/webhook/deadletters/replay", w.replay)        // 重新推送死信事件
+	r.POST("/webhook/deadletters/remove", w.removeLetters) // 移除死信事件
+}
+
+// 获取本节点的webhook端点
+func (w *WebhookAPI) endpoints(c *wkhttp.Context) {
+	if w.forwardIfNeed(c) {
+		return
+	}
+	endpoints := w.s.webhook.endpointList()
+	resps := make([]*webhookEndpointResp, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resps = append(resps, newWebhookEndpointResp(endpoint))
+	}
+	sort.Slice(resps, func(i, j int) bool {
+		return resps[i].Name < resps[j].Name
+	})
+	c.JSON(http.StatusOK, resps)
+}
+
+// 添加或更新webhook端点，同步给所有节点
+func (w *WebhookAPI) addEndpoints(c *wkhttp.Context) {
+	var req struct {
+		Endpoints []struct {
+			Name        string   `json:"name"`        // 端点名称（唯一）
+			Addr        string   `json:"addr"`        // 端点地址，http(s)://开头为http，否则为grpc地址（ip:port）
+			Events      []string `json:"events"`      // 订阅的事件，为空表示订阅所有事件
+			Secret      string   `json:"secret"`      // 签名密钥，为空则使用全局密钥
+			Concurrency int      `json:"concurrency"` // 推送并发数 默认10
+		} `json:"endpoints"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if len(req.Endpoints) == 0 {
+		c.ResponseError(errors.New("endpoints不能为空！"))
+		return
+	}
+	createdAt := time.Now().Unix()
+	endpoints := make([]wkdb.WebhookEndpoint, 0, len(req.Endpoints))
+	for _, e := range req.Endpoints {
+		endpoint := wkdb.WebhookEndpoint{
+			Name:        e.Name,
+			Addr:        e.Addr,
+			Events:      e.Events,
+			Secret:      e.Secret,
+			Concurrency: e.Concurrency,
+			CreatedAt:   createdAt,
+		}
+		if err := checkWebhookEndpoint(endpoint); err != nil {
+			c.ResponseError(err)
+			return
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	w.updateEndpoints(c, &webhookEndpointReq{addEndpoints: endpoints})
+}
+
+// 移除webhook端点，同步给所有节点
+func (w *WebhookAPI) removeEndpoints(c *wkhttp.Context) {
+	var req struct {
+		Names []string `json:"names"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if len(req.Names) == 0 {
+		c.ResponseError(errors.New("names不能为空！"))
+		return
+	}
+	w.updateEndpoints(c, &webhookEndpointReq{removeNames: req.Names})
+}
+
+// 更新所有节点的webhook端点，更新可重复执行，有节点失败时可以重试
+func (w *WebhookAPI) updateEndpoints(c *wkhttp.Context, req *webhookEndpointReq) {
+	if err := w.s.webhook.checkUpdateEndpoints(req.addEndpoints, req.removeNames); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	data := req.Marshal()
+	failedNodes := make([]uint64, 0)
+	for _, nodeId := range w.s.clusterNodeIds() {
+		if nodeId == w.s.opts.Cluster.NodeId {
+			if err := w.s.webhook.updateEndpoints(req.addEndpoints, req.removeNames); err != nil {
+				w.Error("更新webhook端点失败！", zap.Error(err))
+				failedNodes = append(failedNodes, nodeId)
+			}
+			continue
+		}
+		if _, err := w.s.requestNode(nodeId, "/wk/webhookEndpoints", data); err != nil {
+			w.Error("请求节点更新webhook端点失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+			failedNodes = append(failedNodes, nodeId)
+		}
+	}
+	if len(failedNodes) > 0 {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"msg":          "部分节点更新webhook端点失败，请重试！",
+			"status":       http.StatusBadRequest,
+			"failed_nodes": failedNodes,
+		})
+		return
+	}
+	c.ResponseOK()
+}
+
+func (w *WebhookAPI) outbox(c *wkhttp.Context) {
+	w.listEvents(c, wkdb.WebhookQueueOutbox)
+}
+
+func (w *WebhookAPI) deadLetters(c *wkhttp.Context) {
+	w.listEvents(c, wkdb.WebhookQueueDeadLetter)
+}
+
+func (w *WebhookAPI) listEvents(c *wkhttp.Context, queue uint8) {
+	if w.forwardIfNeed(c) {
+		return
+	}
+	startId, _ := strconv.ParseUint(c.Query("start_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 100
+	}
+	events, err := w.s.store.DB().GetWebhookEvents(queue, endpointName(c.Query("endpoint")), startId, limit)
+	if err != nil {
+		w.Error("获取webhook事件失败！", zap.Error(err), zap.Uint8("queue", queue))
+		c.ResponseError(errors.New("获取webhook事件失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// 将死信事件重新放入发件箱推送
+func (w *WebhookAPI) replay(c *wkhttp.Context) {
+	if w.forwardIfNeed(c) {
+		return
+	}
+	endpoint, ids, ok := w.bindIds(c)
+	if !ok {
+		return
+	}
+	webhookEndpoint := w.s.webhook.getEndpoint(endpoint)
+	if webhookEndpoint == nil {
+		c.ResponseError(fmt.Errorf("webhook端点[%s]不存在！", endpoint))
+		return
+	}
+	replayed, err := webhookEndpoint.replayDeadLetters(ids)
+	if err != nil {
+		w.Error("重新推送死信事件失败！", zap.Error(err))
+		c.ResponseError(errors.New("重新推送死信事件失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"ids": replayed,
+	})
+}
+
+func (w *WebhookAPI) removeLetters(c *wkhttp.Context) {
+	if w.forwardIfNeed(c) {
+		return
+	}
+	endpoint, ids, ok := w.bindIds(c)
+	if !ok {
+		return
+	}
+	if err := w.s.store.DB().RemoveWebhookEvents(wkdb.WebhookQueueDeadLetter, endpoint, ids); err != nil {
+		w.Error("移除死信事件失败！", zap.Error(err))
+		c.ResponseError(errors.New("移除死信事件失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (w *WebhookAPI) bindIds(c *wkhttp.Context) (string, []uint64, bool) {
+	var req struct {
+		Endpoint string   `json:"endpoint"` // 端点名称，为空表示default端点
+		Ids      []uint64 `json:"ids"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		w.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return "", nil, false
+	}
+	if len(req.Ids) == 0 {
+		c.ResponseError(errors.New("ids不能为空！"))
+		return "", nil, false
+	}
+	return endpointName(req.Endpoint), req.Ids, true
+}
+
+// endpointName 端点名称，为空表示default端点
+func endpointName(name string) string {
+	if strings.TrimSpace(name) == "" {
+		return defaultWebhookEndpoint
+	}
+	return name
+}
+
+type webhookEndpointResp struct {
+	Name        string   `json:"name"`
+	Addr        string   `json:"addr"`
+	Events      []string `json:"events"`      // 订阅的事件，为空表示订阅所有事件
+	Concurrency int      `json:"concurrency"` // 推送并发数
+	Signed      int      `json:"signed"`      // 是否签名 1.是
+	Static      int      `json:"static"`      // 是否为配置文件内的端点 1.是（不能通过api修改）
+	CreatedAt   int64    `json:"created_at"`
+}
+
+func newWebhookEndpointResp(endpoint *webhookEndpoint) *webhookEndpointResp {
+	events := endpoint.cfg.Events
+	if events == nil {
+		events = make([]string, 0)
+	}
+	return &webhookEndpointResp{
+		Name:        endpoint.cfg.Name,
+		Addr:        endpoint.cfg.Addr,
+		Events:      events,
+		Concurrency: endpoint.cfg.Concurrency,
+		Signed:      wkutil.BoolToInt(endpoint.secret() != ""),
+		Static:      wkutil.BoolToInt(endpoint.static),
+		CreatedAt:   endpoint.cfg.CreatedAt,
+	}
+}
+
+// forwardIfNeed 指定了其他节点则转发给该节点处理
+func (w *WebhookAPI) forwardIfNeed(c *wkhttp.Context) bool {
+	nodeIdStr := c.Query("node_id")
+	if strings.TrimSpace(nodeIdStr) == "" {
+		return false
+	}
+	nodeId, _ := strconv.ParseUint(nodeIdStr, 10, 64)
+	if nodeId == 0 || nodeId == w.s.opts.Cluster.NodeId {
+		return false
+	}
+	nodeInfo, err := w.s.cluster.NodeInfoById(nodeId)
+	if err != nil {
+		w.Error("获取节点信息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		c.ResponseError(err)
+		return true
+	}
+	if nodeInfo == nil {
+		w.Error("节点不存在！", zap.Uint64("nodeId", nodeId))
+		c.ResponseError(fmt.Errorf("节点不存在！"))
+		return true
+	}
+	c.Forward(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path))
+	return true
+}
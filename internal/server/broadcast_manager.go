@@ -0,0 +1,352 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	broadcastTargetOnline = "online" // 所有在线连接（消息不存储）
+	broadcastTargetAll    = "all"    // 所有用户（消息存储到发送者与用户的单聊频道，离线用户上线后可同步）
+
+	broadcastDefaultRate  = 1000      // 每个节点每秒默认发送数量
+	broadcastUserPageSize = 1000      // 每次从数据库加载的用户数量
+	broadcastTaskKeep     = time.Hour // 结束的广播任务保留多久（用于查询进度）
+)
+
+// broadcastManager 系统广播管理
+// 广播请求会发给所有节点，每个节点只负责本节点的真实连接（online）或本节点是槽领导的用户（all）
+type broadcastManager struct {
+	s     *Server
+	mu    sync.RWMutex
+	tasks map[string]*broadcastTask
+	wklog.Log
+}
+
+func newBroadcastManager(s *Server) *broadcastManager {
+	return &broadcastManager{
+		s:     s,
+		tasks: make(map[string]*broadcastTask),
+		Log:   wklog.NewWKLog("broadcastManager"),
+	}
+}
+
+type broadcastTask struct {
+	req      *broadcastReq
+	startAt  int64
+	total    atomic.Int64 // 需要发送的数量
+	sent     atomic.Int64 // 已发送数量
+	failed   atomic.Int64 // 发送失败数量
+	finishAt atomic.Int64 // 结束时间，为0表示未结束
+}
+
+// start 开始本节点的广播
+func (bm *broadcastManager) start(req *broadcastReq) {
+	task := &broadcastTask{
+		req:     req,
+		startAt: time.Now().Unix(),
+	}
+	bm.mu.Lock()
+	bm.removeExpiredTasks()
+	if _, ok := bm.tasks[req.id]; ok { // 重复的广播请求
+		bm.mu.Unlock()
+		return
+	}
+	bm.tasks[req.id] = task
+	bm.mu.Unlock()
+
+	go bm.run(task)
+}
+
+func (bm *broadcastManager) run(task *broadcastTask) {
+	defer task.finishAt.Store(time.Now().Unix())
+
+	bm.Info("broadcast start", zap.String("id", task.req.id), zap.String("target", task.req.target))
+	if task.req.target == broadcastTargetOnline {
+		bm.broadcastOnline(task)
+	} else {
+		bm.broadcastAllUsers(task)
+	}
+	bm.Info("broadcast finished", zap.String("id", task.req.id), zap.Int64("total", task.total.Load()), zap.Int64("sent", task.sent.Load()), zap.Int64("failed", task.failed.Load()))
+}
+
+// 发给本节点的所有真实连接
+func (bm *broadcastManager) broadcastOnline(task *broadcastTask) {
+	conns := make([]*connContext, 0)
+	for _, sub := range bm.s.userReactor.subs {
+		sub.users.iter(func(u *userHandler) bool {
+			for _, conn := range u.getConns() {
+				if conn.isRealConn {
+					conns = append(conns, conn)
+				}
+			}
+			return true
+		})
+	}
+	task.total.Store(int64(len(conns)))
+
+	messageId := bm.s.channelReactor.messageIDGen.Generate().Int64()
+	limiter := newBroadcastLimiter(task.req.rate)
+	for _, conn := range conns {
+		if bm.s.ctx.Err() != nil { // 服务已停止
+			return
+		}
+		limiter.wait()
+		if err := bm.writeToConn(task, messageId, conn); err != nil {
+			bm.Debug("broadcast write to conn failed", zap.Error(err), zap.String("uid", conn.uid), zap.Int64("connId", conn.connId))
+			task.failed.Inc()
+			continue
+		}
+		task.sent.Inc()
+	}
+}
+
+func (bm *broadcastManager) writeToConn(task *broadcastTask, messageId int64, conn *connContext) error {
+	recvPacket := &wkproto.RecvPacket{
+		Framer: wkproto.Framer{
+			RedDot:    task.req.redDot,
+			SyncOnce:  task.req.syncOnce,
+			NoPersist: true,
+		},
+		MessageID:   messageId,
+		ClientMsgNo: task.req.id,
+		FromUID:     task.req.fromUid,
+		ChannelID:   task.req.fromUid,
+		ChannelType: wkproto.ChannelTypePerson,
+		Timestamp:   int32(time.Now().Unix()),
+	}
+	payloadEnc, err := encryptMessagePayload(task.req.payload, conn)
+	if err != nil {
+		return err
+	}
+	recvPacket.Payload = payloadEnc
+
+	msgKey, err := makeMsgKey(recvPacket.VerityString(), conn)
+	if err != nil {
+		return err
+	}
+	recvPacket.MsgKey = msgKey
+
+	data, err := bm.s.opts.Proto.EncodeFrame(recvPacket, conn.protoVersion)
+	if err != nil {
+		return err
+	}
+	return conn.write(data, wkproto.RECV)
+}
+
+// 发给本节点是槽领导的所有用户
+func (bm *broadcastManager) broadcastAllUsers(task *broadcastTask) {
+	limiter := newBroadcastLimiter(task.req.rate)
+	var offsetId uint64
+	for {
+		users, err := bm.s.store.DB().SearchUser(wkdb.UserSearchReq{
+			Limit:    broadcastUserPageSize,
+			OffsetId: offsetId,
+		})
+		if err != nil {
+			bm.Error("broadcast search user failed", zap.Error(err), zap.String("id", task.req.id))
+			return
+		}
+		for _, user := range users {
+			if bm.s.ctx.Err() != nil { // 服务已停止
+				return
+			}
+			if user.Uid == task.req.fromUid {
+				continue
+			}
+			if bm.s.opts.ClusterOn() {
+				leaderId, err := bm.s.cluster.SlotLeaderIdOfChannel(user.Uid, wkproto.ChannelTypePerson)
+				if err != nil {
+					bm.Error("broadcast get slot leader failed", zap.Error(err), zap.String("uid", user.Uid))
+					continue
+				}
+				if leaderId != bm.s.opts.Cluster.NodeId {
+					continue
+				}
+			}
+			task.total.Inc()
+			limiter.wait()
+			err = bm.s.channelReactor.proposeSend(task.req.fromUid, task.req.fromUid, 0, bm.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+				Framer: wkproto.Framer{
+					RedDot:   task.req.redDot,
+					SyncOnce: task.req.syncOnce,
+				},
+				ClientMsgNo: task.req.id,
+				ChannelID:   user.Uid,
+				ChannelType: wkproto.ChannelTypePerson,
+				Payload:     task.req.payload,
+			})
+			if err != nil {
+				task.failed.Inc()
+				continue
+			}
+			task.sent.Inc()
+		}
+		if len(users) < broadcastUserPageSize {
+			return
+		}
+		offsetId = users[len(users)-1].Id // 用户按id倒序
+	}
+}
+
+// progress 获取本节点的广播进度
+func (bm *broadcastManager) progress(id string) *broadcastProgress {
+	bm.mu.RLock()
+	task := bm.tasks[id]
+	bm.mu.RUnlock()
+	if task == nil {
+		return nil
+	}
+	return &broadcastProgress{
+		NodeId:   bm.s.opts.Cluster.NodeId,
+		Total:    task.total.Load(),
+		Sent:     task.sent.Load(),
+		Failed:   task.failed.Load(),
+		StartAt:  task.startAt,
+		FinishAt: task.finishAt.Load(),
+	}
+}
+
+func (bm *broadcastManager) removeExpiredTasks() {
+	expireAt := time.Now().Add(-broadcastTaskKeep).Unix()
+	for id, task := range bm.tasks {
+		finishAt := task.finishAt.Load()
+		if finishAt > 0 && finishAt < expireAt {
+			delete(bm.tasks, id)
+		}
+	}
+}
+
+// broadcastLimiter 按每秒数量控制发送速度
+type broadcastLimiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newBroadcastLimiter(rate int) *broadcastLimiter {
+	if rate <= 0 {
+		rate = broadcastDefaultRate
+	}
+	return &broadcastLimiter{
+		interval: time.Second / time.Duration(rate),
+	}
+}
+
+func (l *broadcastLimiter) wait() {
+	now := time.Now()
+	if l.next.After(now) {
+		time.Sleep(l.next.Sub(now))
+	} else {
+		l.next = now
+	}
+	l.next = l.next.Add(l.interval)
+}
+
+// broadcastReq 节点间的广播请求
+type broadcastReq struct {
+	id       string
+	target   string
+	fromUid  string
+	redDot   bool
+	syncOnce bool
+	payload  []byte
+	rate     int // 每个节点每秒发送数量
+}
+
+func (b *broadcastReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(b.id)
+	enc.WriteString(b.target)
+	enc.WriteString(b.fromUid)
+	enc.WriteUint8(wkutil.BoolToUint8(b.redDot))
+	enc.WriteUint8(wkutil.BoolToUint8(b.syncOnce))
+	enc.WriteBinary(b.payload)
+	enc.WriteUint32(uint32(b.rate))
+	return enc.Bytes()
+}
+
+func (b *broadcastReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if b.id, err = dec.String(); err != nil {
+		return err
+	}
+	if b.target, err = dec.String(); err != nil {
+		return err
+	}
+	if b.fromUid, err = dec.String(); err != nil {
+		return err
+	}
+	var redDot, syncOnce uint8
+	if redDot, err = dec.Uint8(); err != nil {
+		return err
+	}
+	b.redDot = redDot == 1
+	if syncOnce, err = dec.Uint8(); err != nil {
+		return err
+	}
+	b.syncOnce = syncOnce == 1
+	if b.payload, err = dec.Binary(); err != nil {
+		return err
+	}
+	var rate uint32
+	if rate, err = dec.Uint32(); err != nil {
+		return err
+	}
+	b.rate = int(rate)
+	return nil
+}
+
+// broadcastProgress 节点的广播进度
+type broadcastProgress struct {
+	NodeId   uint64 `json:"node_id"`
+	Total    int64  `json:"total"`     // 需要发送的数量
+	Sent     int64  `json:"sent"`      // 已发送数量
+	Failed   int64  `json:"failed"`    // 发送失败数量
+	StartAt  int64  `json:"start_at"`  // 开始时间
+	FinishAt int64  `json:"finish_at"` // 结束时间，为0表示未结束
+}
+
+func (b *broadcastProgress) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(b.NodeId)
+	enc.WriteInt64(b.Total)
+	enc.WriteInt64(b.Sent)
+	enc.WriteInt64(b.Failed)
+	enc.WriteInt64(b.StartAt)
+	enc.WriteInt64(b.FinishAt)
+	return enc.Bytes()
+}
+
+func (b *broadcastProgress) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if b.NodeId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if b.Total, err = dec.Int64(); err != nil {
+		return err
+	}
+	if b.Sent, err = dec.Int64(); err != nil {
+		return err
+	}
+	if b.Failed, err = dec.Int64(); err != nil {
+		return err
+	}
+	if b.StartAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	if b.FinishAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -340,10 +340,20 @@ func (c *channel) tickProxy() {
 }
 
 func (c *channel) proposeSend(fromUid string, fromDeviceId string, fromConnId int64, fromNodeId uint64, isEncrypt bool, sendPacket *wkproto.SendPacket) (int64, error) {
+	return c.proposeStreamSend(fromUid, fromDeviceId, fromConnId, fromNodeId, isEncrypt, wkproto.StreamFlagIng, 0, sendPacket)
+}
+
+// proposeStreamSend 提案流消息，streamFlag和streamSeq会投递给接收者
+func (c *channel) proposeStreamSend(fromUid string, fromDeviceId string, fromConnId int64, fromNodeId uint64, isEncrypt bool, streamFlag wkproto.StreamFlag, streamSeq uint32, sendPacket *wkproto.SendPacket) (int64, error) {
+	messageId := c.r.messageIDGen.Generate().Int64() // 生成唯一消息ID
+	return c.proposeStreamSendWithMessageId(messageId, fromUid, fromDeviceId, fromConnId, fromNodeId, isEncrypt, streamFlag, streamSeq, sendPacket)
+}
+
+// proposeStreamSendWithMessageId 使用预先分配的消息ID提案流消息
+func (c *channel) proposeStreamSendWithMessageId(messageId int64, fromUid string, fromDeviceId string, fromConnId int64, fromNodeId uint64, isEncrypt bool, streamFlag wkproto.StreamFlag, streamSeq uint32, sendPacket *wkproto.SendPacket) (int64, error) {
 
 	c.sendTick = 0
 
-	messageId := c.r.messageIDGen.Generate().Int64() // 生成唯一消息ID
 	message := ReactorChannelMessage{
 		FromConnId:   fromConnId,
 		FromUid:      fromUid,
@@ -352,6 +362,8 @@ func (c *channel) proposeSend(fromUid string, fromDeviceId string, fromConnId in
 		SendPacket:   sendPacket,
 		MessageId:    messageId,
 		IsEncrypt:    isEncrypt,
+		StreamFlag:   streamFlag,
+		StreamSeq:    streamSeq,
 	}
 
 	c.sub.step(c, &ChannelAction{
@@ -435,11 +447,29 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 
 	c.Debug("makeReceiverTag", zap.String("channelId", c.channelId), zap.Uint8("channelType", c.channelType))
 
-	var subscribers []string
-	var err error
+	var nodeUserList = make([]*nodeUsers, 0, 20)
+	addSubscriber := func(subscriber string) error {
+		leaderInfo, err := c.r.s.cluster.SlotLeaderOfChannel(subscriber, wkproto.ChannelTypePerson) // 获取频道的槽领导节点
+		if err != nil {
+			c.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", subscriber), zap.Uint8("channelType", wkproto.ChannelTypePerson))
+			return err
+		}
+		for _, nodeUser := range nodeUserList {
+			if nodeUser.nodeId == leaderInfo.Id {
+				nodeUser.uids = append(nodeUser.uids, subscriber)
+				return nil
+			}
+		}
+		nodeUserList = append(nodeUserList, &nodeUsers{
+			nodeId: leaderInfo.Id,
+			uids:   []string{subscriber},
+		})
+		return nil
+	}
+
 	if c.channelType == wkproto.ChannelTypePerson {
 		if c.r.s.opts.IsFakeChannel(c.channelId) { // fake个人频道
-
+			var subscribers []string
 			if c.r.s.opts.IsCmdChannel(c.channelId) {
 				orginChannelId := c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
 				personSubscribers := strings.Split(orginChannelId, "@")
@@ -452,41 +482,31 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 			} else {
 				subscribers = strings.Split(c.channelId, "@")
 			}
-
+			for _, subscriber := range subscribers {
+				if err := addSubscriber(subscriber); err != nil {
+					return nil, err
+				}
+			}
 		}
 	} else {
 		realChannelId := c.channelId
 		if c.r.s.opts.IsCmdChannel(c.channelId) {
 			realChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
 		}
-		subscribers, err = c.r.s.store.GetSubscribers(realChannelId, c.channelType)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	var nodeUserList = make([]*nodeUsers, 0, 20)
-	for _, subscriber := range subscribers {
-		leaderInfo, err := c.r.s.cluster.SlotLeaderOfChannel(subscriber, wkproto.ChannelTypePerson) // 获取频道的槽领导节点
+		// 流式遍历订阅者，大频道不需要一次性加载所有订阅者
+		var addErr error
+		err := c.r.s.store.IterateSubscribers(realChannelId, c.channelType, func(subscriber wkdb.Subscriber) bool {
+			addErr = addSubscriber(subscriber.Uid)
+			return addErr == nil
+		})
 		if err != nil {
-			c.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", subscriber), zap.Uint8("channelType", wkproto.ChannelTypePerson))
 			return nil, err
 		}
-		exist := false
-		for _, nodeUser := range nodeUserList {
-			if nodeUser.nodeId == leaderInfo.Id {
-				nodeUser.uids = append(nodeUser.uids, subscriber)
-				exist = true
-				break
-			}
-		}
-		if !exist {
-			nodeUserList = append(nodeUserList, &nodeUsers{
-				nodeId: leaderInfo.Id,
-				uids:   []string{subscriber},
-			})
+		if addErr != nil {
+			return nil, addErr
 		}
 	}
+
 	if c.receiverTagKey.Load() != "" {
 		// 释放掉之前的tag
 		c.r.s.tagManager.releaseReceiverTag(c.receiverTagKey.Load())
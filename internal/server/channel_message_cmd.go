@@ -0,0 +1,276 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	channelMessageCmdPoolSize     = 100         // 同步频道消息命令的并发数
+	channelMessageCmdNotifyRetry  = 3           // 通知频道副本同步消息命令的重试次数
+	channelMessageCmdPullInterval = time.Minute // 频道日志应用时从槽领导拉取消息命令的最小间隔（补偿错过的同步通知）
+)
+
+// channelMessageCmdManager 频道消息命令（撤回、编辑、清理等）的同步
+// 命令保存在频道所属槽的raft存储里，不占用频道的消息序号，频道的每个副本从槽领导拉取后按序号顺序应用
+type channelMessageCmdManager struct {
+	s        *Server
+	pool     *ants.Pool
+	syncing  *lru.Cache[string, struct{}]  // 已提交同步任务还未执行的频道
+	pulledAt *lru.Cache[string, time.Time] // 频道最近一次从槽领导拉取命令的时间
+	wklog.Log
+}
+
+func newChannelMessageCmdManager(s *Server) *channelMessageCmdManager {
+	m := &channelMessageCmdManager{
+		s:   s,
+		Log: wklog.NewWKLog("channelMessageCmdManager"),
+	}
+	pool, err := ants.NewPool(channelMessageCmdPoolSize, ants.WithNonblocking(true), ants.WithPanicHandler(func(err interface{}) {
+		m.Error("channel message cmd sync panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		m.Panic("new channel message cmd pool failed", zap.Error(err))
+	}
+	m.pool = pool
+	m.syncing, _ = lru.New[string, struct{}](10000)
+	m.pulledAt, _ = lru.New[string, time.Time](10000)
+	return m
+}
+
+func (m *channelMessageCmdManager) stop() {
+	m.pool.Release()
+}
+
+// pull 从频道所属槽的领导拉取序号大于index的频道消息命令，本节点是槽领导时不需要拉取
+func (m *channelMessageCmdManager) pull(channelId string, channelType uint8, index uint64, limit int) ([]wkdb.ChannelMessageCmd, error) {
+	m.pulledAt.Add(wkutil.ChannelToKey(channelId, channelType), time.Now())
+	if !m.s.opts.ClusterOn() {
+		return nil, nil
+	}
+	leaderId, err := m.s.cluster.SlotLeaderIdOfChannel(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	if leaderId == m.s.opts.Cluster.NodeId {
+		return nil, nil
+	}
+	req := &channelMessageCmdsReq{
+		channelId:   channelId,
+		channelType: channelType,
+		index:       index,
+		limit:       uint32(limit),
+	}
+	data, err := m.s.requestNode(leaderId, "/wk/channelMessageCmds", req.Marshal())
+	if err != nil {
+		return nil, err
+	}
+	resp := &channelMessageCmdsResp{
+		channelId:   channelId,
+		channelType: channelType,
+	}
+	if err = resp.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	return resp.cmds, nil
+}
+
+// notify 异步通知频道的其他副本同步消息命令
+func (m *channelMessageCmdManager) notify(channelId string, channelType uint8) {
+	if !m.s.opts.ClusterOn() {
+		return
+	}
+	req := &channelMessageCmdSyncReq{
+		channelId:   channelId,
+		channelType: channelType,
+	}
+	data := req.Marshal()
+	for _, nodeId := range m.s.cluster.ChannelReplicaIds(channelId, channelType) {
+		if nodeId == m.s.opts.Cluster.NodeId {
+			continue
+		}
+		toNodeId := nodeId
+		err := m.pool.Submit(func() {
+			var err error
+			for i := 0; i < channelMessageCmdNotifyRetry; i++ {
+				if _, err = m.s.requestNode(toNodeId, "/wk/channelMessageCmdSync", data); err == nil {
+					return
+				}
+				time.Sleep(time.Second * time.Duration(i+1))
+			}
+			// 副本在之后应用频道日志时会从槽领导拉取
+			m.Warn("notify channel message cmd sync failed", zap.Error(err), zap.Uint64("nodeId", toNodeId), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		})
+		if err != nil {
+			m.Warn("submit channel message cmd notify failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		}
+	}
+}
+
+// onChannelApply 频道日志应用后异步应用等待这些消息的命令，距离上次拉取超过间隔时先从槽领导拉取
+func (m *channelMessageCmdManager) onChannelApply(channelId string, channelType uint8) {
+	channelKey := wkutil.ChannelToKey(channelId, channelType)
+	if ok, _ := m.syncing.ContainsOrAdd(channelKey, struct{}{}); ok { // 已有未执行的同步任务
+		return
+	}
+	err := m.pool.Submit(func() {
+		m.syncing.Remove(channelKey)
+		var err error
+		if pulledAt, ok := m.pulledAt.Get(channelKey); ok && time.Since(pulledAt) < channelMessageCmdPullInterval {
+			err = m.s.store.ApplyChannelMessageCmds(channelId, channelType)
+		} else {
+			err = m.s.store.SyncChannelMessageCmds(channelId, channelType)
+		}
+		if err != nil {
+			m.Warn("sync channel message cmds failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		}
+	})
+	if err != nil {
+		m.syncing.Remove(channelKey)
+		m.Debug("submit channel message cmd sync failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+	}
+}
+
+// handleChannelMessageCmds 获取频道的消息命令（槽领导节点）
+func (s *Server) handleChannelMessageCmds(c *wkserver.Context) {
+	req := &channelMessageCmdsReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleChannelMessageCmds Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	cmds, err := s.store.GetChannelMessageCmds(req.channelId, req.channelType, req.index, int(req.limit))
+	if err != nil {
+		s.Error("get channel message cmds failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	resp := &channelMessageCmdsResp{
+		cmds: cmds,
+	}
+	c.Write(resp.Marshal())
+}
+
+// handleChannelMessageCmdSync 从槽领导同步频道的消息命令并应用（频道副本节点）
+func (s *Server) handleChannelMessageCmdSync(c *wkserver.Context) {
+	req := &channelMessageCmdSyncReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleChannelMessageCmdSync Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if err := s.store.SyncChannelMessageCmds(req.channelId, req.channelType); err != nil {
+		s.Error("sync channel message cmds failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+type channelMessageCmdsReq struct {
+	channelId   string
+	channelType uint8
+	index       uint64 // 获取序号大于index的命令
+	limit       uint32
+}
+
+func (r *channelMessageCmdsReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.channelId)
+	enc.WriteUint8(r.channelType)
+	enc.WriteUint64(r.index)
+	enc.WriteUint32(r.limit)
+	return enc.Bytes()
+}
+
+func (r *channelMessageCmdsReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.index, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if r.limit, err = dec.Uint32(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type channelMessageCmdsResp struct {
+	channelId   string // 解码时填充到命令上
+	channelType uint8
+	cmds        []wkdb.ChannelMessageCmd
+}
+
+func (r *channelMessageCmdsResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.cmds)))
+	for _, cmd := range r.cmds {
+		enc.WriteUint64(cmd.Index)
+		enc.WriteBinary(cmd.Data)
+	}
+	return enc.Bytes()
+}
+
+func (r *channelMessageCmdsResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	r.cmds = make([]wkdb.ChannelMessageCmd, 0, count)
+	for i := 0; i < int(count); i++ {
+		cmd := wkdb.ChannelMessageCmd{
+			ChannelId:   r.channelId,
+			ChannelType: r.channelType,
+		}
+		if cmd.Index, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if cmd.Data, err = dec.Binary(); err != nil {
+			return err
+		}
+		r.cmds = append(r.cmds, cmd)
+	}
+	return nil
+}
+
+type channelMessageCmdSyncReq struct {
+	channelId   string
+	channelType uint8
+}
+
+func (r *channelMessageCmdSyncReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.channelId)
+	enc.WriteUint8(r.channelType)
+	return enc.Bytes()
+}
+
+func (r *channelMessageCmdSyncReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	return nil
+}
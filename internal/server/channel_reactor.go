@@ -9,6 +9,7 @@ import (
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/bwmarrin/snowflake"
 	"github.com/lni/goutils/syncutil"
+	"github.com/panjf2000/ants/v2"
 	"github.com/sasha-s/go-deadlock"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -26,6 +27,8 @@ type channelReactor struct {
 	processCloseC          chan *closeReq          // 关闭请求
 	processCheckTagC       chan *checkTagReq       // 检查tag请求
 
+	moderationPool *ants.Pool // 内容审核池，审核请求不占用权限检查的协程
+
 	stopper *syncutil.Stopper
 	opts    *Options
 	s       *Server
@@ -58,6 +61,14 @@ func newChannelReactor(s *Server, opts *Options) *channelReactor {
 		Log:                    wklog.NewWKLog(fmt.Sprintf("ChannelReactor[%d]", opts.Cluster.NodeId)),
 		s:                      s,
 	}
+	pool, err := ants.NewPool(opts.Webhook.ModerationConcurrency, ants.WithNonblocking(true), ants.WithPanicHandler(func(err interface{}) {
+		r.Error("moderation panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		r.Panic("new moderation pool failed", zap.Error(err))
+	}
+	r.moderationPool = pool
+
 	r.subs = make([]*channelReactorSub, r.opts.Reactor.ChannelSubCount)
 	for i := 0; i < r.opts.Reactor.ChannelSubCount; i++ {
 		sub := newChannelReactorSub(i, r)
@@ -101,6 +112,7 @@ func (r *channelReactor) stop() {
 	r.stopped.Store(true)
 
 	r.stopper.Stop()
+	r.moderationPool.Release()
 
 	for _, sub := range r.subs {
 		sub.stop()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -338,19 +339,171 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 	if reasonCode != wkproto.ReasonSuccess {
 		reason = ReasonError
 	}
-	// 返回成功
+
+	// 发送频率限制和敏感词过滤，返回被拒绝或被修改的消息
+	var (
+		checkedMsgs  []ReactorChannelMessage
+		moderateMsgs []ReactorChannelMessage
+	)
+	if reasonCode == wkproto.ReasonSuccess {
+		limitedMsgs, passedMsgs := r.s.rateLimitManager.limitMessages(req.ch, req.messages)
+		contentMsgs, needModerateMsgs := r.checkMessageContents(passedMsgs)
+		checkedMsgs = append(limitedMsgs, contentMsgs...)
+		moderateMsgs = needModerateMsgs
+	}
+
 	lastMsg := req.messages[len(req.messages)-1]
-	sub.step(req.ch, &ChannelAction{
+	action := &ChannelAction{
 		UniqueNo:   req.ch.uniqueNo,
 		ActionType: ChannelActionPermissionCheckResp,
 		Index:      lastMsg.Index,
 		Reason:     reason,
 		ReasonCode: reasonCode,
+		Messages:   checkedMsgs,
+	}
+	if len(moderateMsgs) > 0 {
+		r.moderateAsync(sub, req.ch, action, moderateMsgs)
+		return
+	}
+	// 返回成功
+	sub.step(req.ch, action)
+}
+
+// checkMessageContents 检查消息内容（敏感词过滤），返回被拒绝或被修改的消息和需要请求webhook审核的消息
+func (r *channelReactor) checkMessageContents(reqMessages []ReactorChannelMessage) ([]ReactorChannelMessage, []ReactorChannelMessage) {
+	messages := make([]ReactorChannelMessage, 0, len(reqMessages))
+	for _, msg := range reqMessages {
+		if msg.IsEncrypt || msg.FromUid == r.opts.SystemUID || r.s.systemUIDManager.SystemUID(msg.FromUid) { // 系统账号的消息和无法解密的消息不检查
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	checkedMsgs := r.s.sensitiveWordManager.filterMessages(messages)
+	if !r.opts.Webhook.ModerationOn || !r.opts.WebhookOn() {
+		return checkedMsgs, nil
+	}
+
+	// 被拒绝的消息不再审核，被替换内容的消息审核替换后的内容
+	checkedIndexes := make(map[int64]int, len(checkedMsgs))
+	for i, msg := range checkedMsgs {
+		checkedIndexes[msg.MessageId] = i
+	}
+	moderateMsgs := make([]ReactorChannelMessage, 0, len(messages))
+	for _, msg := range messages {
+		if i, ok := checkedIndexes[msg.MessageId]; ok {
+			if checkedMsgs[i].ReasonCode != wkproto.ReasonSuccess {
+				continue
+			}
+			msg = checkedMsgs[i]
+		}
+		moderateMsgs = append(moderateMsgs, msg)
+	}
+	return checkedMsgs, moderateMsgs
+}
+
+// moderateAsync 在审核池中请求webhook审核消息内容，审核完成后再返回权限检查结果
+// 审核返回前频道不会发起下一次权限检查，频道内消息的顺序不变，慢的webhook也不会阻塞其他频道的权限检查
+func (r *channelReactor) moderateAsync(sub *channelReactorSub, ch *channel, action *ChannelAction, messages []ReactorChannelMessage) {
+	err := r.moderationPool.Submit(func() {
+		action.Messages = mergeCheckedMessages(action.Messages, r.moderateMessages(ch, messages))
+		sub.step(ch, action)
 	})
+	if err != nil { // 审核池已满，按审核失败处理
+		r.Warn("submit moderation failed", zap.Error(err), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType))
+		moderatedMsgs := make([]ReactorChannelMessage, 0, len(messages))
+		for _, msg := range messages {
+			if msg, ok := r.moderationFailed(msg); ok {
+				moderatedMsgs = append(moderatedMsgs, msg)
+			}
+		}
+		action.Messages = mergeCheckedMessages(action.Messages, moderatedMsgs)
+		sub.step(ch, action)
+	}
+}
+
+// mergeCheckedMessages 用审核结果替换之前检查过的同一条消息，没有检查过的追加
+func mergeCheckedMessages(checkedMsgs []ReactorChannelMessage, moderatedMsgs []ReactorChannelMessage) []ReactorChannelMessage {
+	for _, msg := range moderatedMsgs {
+		replaced := false
+		for i, checkedMsg := range checkedMsgs {
+			if checkedMsg.MessageId == msg.MessageId {
+				checkedMsgs[i] = msg
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			checkedMsgs = append(checkedMsgs, msg)
+		}
+	}
+	return checkedMsgs
+}
+
+// moderationFailed 审核请求失败时的处理，开启了放行则不修改消息，否则拒绝发送
+func (r *channelReactor) moderationFailed(msg ReactorChannelMessage) (ReactorChannelMessage, bool) {
+	if r.opts.Webhook.ModerationFailOpen {
+		return msg, false
+	}
+	msg.ReasonCode = wkproto.ReasonSystemError
+	return msg, true
+}
+
+// moderateMessages 请求webhook审核消息内容，返回被拒绝（设置了ReasonCode）或被修改（替换了SendPacket）的消息
+func (r *channelReactor) moderateMessages(ch *channel, messages []ReactorChannelMessage) []ReactorChannelMessage {
+	var (
+		moderatedMsgs []ReactorChannelMessage
+		mu            sync.Mutex
+		wg            sync.WaitGroup
+	)
+	for _, msg := range messages {
+		wg.Add(1)
+		go func(msg ReactorChannelMessage) {
+			defer wg.Done()
+			result, err := r.s.webhook.moderate(msg)
+			if err != nil {
+				r.Warn("moderate message failed", zap.Error(err), zap.Int64("messageId", msg.MessageId), zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType))
+				if msg, ok := r.moderationFailed(msg); ok {
+					mu.Lock()
+					moderatedMsgs = append(moderatedMsgs, msg)
+					mu.Unlock()
+				}
+				return
+			}
+			switch result.Action {
+			case moderationActionReject:
+				msg.ReasonCode = wkproto.ReasonCode(result.ReasonCode)
+				if msg.ReasonCode == wkproto.ReasonSuccess || msg.ReasonCode == wkproto.ReasonUnknown {
+					msg.ReasonCode = wkproto.ReasonNotAllowSend
+				}
+			case moderationActionModify:
+				if len(result.Payload) == 0 {
+					return
+				}
+				packet := *msg.SendPacket
+				packet.Payload = result.Payload
+				msg.SendPacket = &packet
+			default:
+				return
+			}
+			mu.Lock()
+			moderatedMsgs = append(moderatedMsgs, msg)
+			mu.Unlock()
+		}(msg)
+	}
+	wg.Wait()
+	return moderatedMsgs
 }
 
 func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, ch *channel) (wkproto.ReasonCode, error) {
 
+	if r.s.userBanManager.isBanned(fromUid) { // 发送者被封禁
+		return wkproto.ReasonBan, nil
+	}
+
 	if channelType == wkproto.ChannelTypeInfo { // 资讯频道是公开的，直接通过
 		return wkproto.ReasonSuccess, nil
 	}
@@ -396,13 +549,19 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 	}
 
 	// 判断是否是订阅者
-	isSubscriber, err := r.s.store.ExistSubscriber(channelId, channelType, fromUid)
+	subscriber, err := r.s.store.GetSubscriber(channelId, channelType, fromUid)
 	if err != nil {
-		r.Error("ExistSubscriber error", zap.Error(err))
+		if err == wkdb.ErrNotFound {
+			return wkproto.ReasonSubscriberNotExist, nil
+		}
+		r.Error("GetSubscriber error", zap.Error(err))
 		return wkproto.ReasonSystemError, err
 	}
-	if !isSubscriber {
-		return wkproto.ReasonSubscriberNotExist, nil
+	isAdmin := subscriber.IsAdmin()
+
+	// 仅管理员可发言（公告频道）
+	if channelInfo.OnlyAdminSend && !isAdmin {
+		return wkproto.ReasonNotAllowSend, nil
 	}
 
 	// 判断是否在白名单内
@@ -425,6 +584,20 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		}
 	}
 
+	// 判断是否被禁言（先判断全员禁言，再判断成员禁言，到期的禁言自动失效），群主和管理员不受禁言限制，被禁言返回不允许发送
+	if !isAdmin {
+		for _, uid := range [...]string{"", fromUid} {
+			_, err = r.s.store.GetChannelMute(channelId, channelType, uid)
+			if err == nil {
+				return wkproto.ReasonNotAllowSend, nil
+			}
+			if err != wkdb.ErrNotFound {
+				r.Error("GetChannelMute error", zap.Error(err))
+				return wkproto.ReasonSystemError, err
+			}
+		}
+	}
+
 	return wkproto.ReasonSuccess, nil
 }
 
@@ -548,6 +721,10 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 
 	for _, req := range reqs {
 		dbMsgs := make([]wkdb.Message, 0, len(req.messages))
+		encrypts := make([]bool, 0, len(req.messages))
+
+		// 客户端重发的消息不再存储
+		r.dedupMessages(req)
 
 		// 将reactorChannelMessage转换为wkdb.Message
 		for _, reactorMsg := range req.messages {
@@ -557,6 +734,10 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 				continue
 
 			}
+			if reactorMsg.DedupMessageId != 0 {
+				r.Debug("msg is duplicate, no storage", zap.Uint64("messageId", uint64(reactorMsg.MessageId)), zap.Int64("dedupMessageId", reactorMsg.DedupMessageId), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+				continue
+			}
 
 			msg := wkdb.Message{
 				RecvPacket: wkproto.RecvPacket{
@@ -565,9 +746,12 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 						SyncOnce:  reactorMsg.SendPacket.Framer.SyncOnce,
 						NoPersist: reactorMsg.SendPacket.Framer.NoPersist,
 					},
+					Setting:     reactorMsg.SendPacket.Setting,
 					MessageID:   reactorMsg.MessageId,
 					ClientMsgNo: reactorMsg.SendPacket.ClientMsgNo,
 					ClientSeq:   reactorMsg.SendPacket.ClientSeq,
+					StreamNo:    reactorMsg.SendPacket.StreamNo,
+					StreamFlag:  reactorMsg.StreamFlag,
 					FromUID:     reactorMsg.FromUid,
 					ChannelID:   req.ch.channelId,
 					ChannelType: reactorMsg.SendPacket.ChannelType,
@@ -577,12 +761,12 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 				},
 			}
 			dbMsgs = append(dbMsgs, msg)
+			encrypts = append(encrypts, reactorMsg.IsEncrypt)
 		}
 
 		sotreMessages := make([]wkdb.Message, 0, 1024)
 		for i, dbMsg := range dbMsgs {
-			reactorMsg := req.messages[i]
-			if reactorMsg.IsEncrypt {
+			if encrypts[i] {
 				r.Warn("msg is encrypt, no storage", zap.Uint64("messageId", uint64(dbMsg.MessageID)), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
 				continue
 			}
@@ -592,7 +776,7 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 			sotreMessages = append(sotreMessages, dbMsg)
 		}
 
-		if r.opts.WebhookOn() {
+		if r.s.webhook.subscribed(EventMsgNotify) {
 			// 将消息存储到webhook的推送队列内
 			err := r.s.store.AppendMessageOfNotifyQueue(dbMsgs)
 			if err != nil {
@@ -623,6 +807,18 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 				}
 			}
 		}
+		// 同一批次内重复的消息，使用原消息的序号
+		for i, msg := range req.messages {
+			if msg.DedupMessageId == 0 || msg.MessageSeq != 0 {
+				continue
+			}
+			for _, originMsg := range req.messages {
+				if originMsg.MessageId == msg.DedupMessageId {
+					req.messages[i].MessageSeq = originMsg.MessageSeq
+					break
+				}
+			}
+		}
 		var reason Reason
 		if err != nil {
 			reason = ReasonError
@@ -636,6 +832,34 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 
 }
 
+// dedupMessages 标记客户端重发的重复消息（去重窗口内发送者相同的client_msg_no），重复消息回执原消息的ID和序号
+func (r *channelReactor) dedupMessages(req *storageReq) {
+	if r.opts.Db.MessageDedupWindow <= 0 {
+		return
+	}
+	batchMsgs := make(map[string]int64) // 本批次内的消息，key为发送者+客户端消息编号，value为消息ID
+	for i, msg := range req.messages {
+		if msg.ReasonCode != wkproto.ReasonSuccess || msg.DedupMessageId != 0 || msg.SendPacket.NoPersist || msg.SendPacket.ClientMsgNo == "" {
+			continue
+		}
+		batchKey := msg.FromUid + "@" + msg.SendPacket.ClientMsgNo
+		if messageId, ok := batchMsgs[batchKey]; ok {
+			req.messages[i].DedupMessageId = messageId
+			continue
+		}
+		dedup, err := r.s.store.GetMessageDedup(req.ch.channelId, req.ch.channelType, msg.FromUid, msg.SendPacket.ClientMsgNo)
+		if err != nil {
+			if err != wkdb.ErrNotFound {
+				r.Warn("GetMessageDedup error", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+			}
+			batchMsgs[batchKey] = msg.MessageId
+			continue
+		}
+		req.messages[i].DedupMessageId = dedup.MessageId
+		req.messages[i].MessageSeq = uint32(dedup.MessageSeq)
+	}
+}
+
 func (r *channelReactor) respStoreResult(req *storageReq, reason Reason) {
 	sub := r.reactorSub(req.ch.key)
 	lastIndex := req.messages[len(req.messages)-1].Index
@@ -695,18 +919,28 @@ func (r *channelReactor) processSendack(reqs []*sendackReq) {
 	for _, req := range reqs {
 		for _, msg := range req.messages {
 
-			if msg.FromUid == r.opts.SystemUID { // 如果是系统消息，不需要发送ack
-				continue
+			messageId := msg.MessageId
+			if msg.DedupMessageId != 0 { // 重复消息回执原消息的ID
+				messageId = msg.DedupMessageId
 			}
-
 			sendack := &wkproto.SendackPacket{
 				Framer:      msg.SendPacket.Framer,
-				MessageID:   msg.MessageId,
+				MessageID:   messageId,
 				MessageSeq:  msg.MessageSeq,
 				ClientSeq:   msg.SendPacket.ClientSeq,
 				ClientMsgNo: msg.SendPacket.ClientMsgNo,
 				ReasonCode:  msg.ReasonCode,
 			}
+
+			if msg.FromConnId == 0 { // api发送的消息，没有连接，回执给等待的请求
+				if msg.FromNodeId == r.opts.Cluster.NodeId {
+					r.s.sendackWaiter.notify(msg.FromUid, sendack)
+					continue
+				}
+			} else if msg.FromUid == r.opts.SystemUID { // 如果是系统消息，不需要发送ack
+				continue
+			}
+
 			if msg.FromNodeId == r.opts.Cluster.NodeId { // 连接在本节点
 				err = r.s.userReactor.writePacketByConnId(msg.FromUid, msg.FromConnId, sendack)
 				if err != nil {
@@ -819,10 +1053,21 @@ func (r *channelReactor) processDeliverLoop() {
 func (r *channelReactor) processDeliver(reqs []*deliverReq) {
 
 	for _, req := range reqs {
-		r.handleDeliver(req)
+		lastIndex := req.messages[len(req.messages)-1].Index
+
+		// 未通过校验的消息不投递，重复消息已投递过，不再投递
+		messages := make([]ReactorChannelMessage, 0, len(req.messages))
+		for _, msg := range req.messages {
+			if msg.ReasonCode == wkproto.ReasonSuccess && msg.DedupMessageId == 0 {
+				messages = append(messages, msg)
+			}
+		}
+		if len(messages) > 0 {
+			req.messages = messages
+			r.handleDeliver(req)
+		}
 		sub := r.reactorSub(req.ch.key)
 		reason := ReasonSuccess
-		lastIndex := req.messages[len(req.messages)-1].Index
 		sub.step(req.ch, &ChannelAction{
 			UniqueNo:   req.ch.uniqueNo,
 			ActionType: ChannelActionDeliverResp,
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 慢的内容审核webhook不阻塞其他频道的权限检查
+func TestModerationSlowWebhookNotBlockOtherChannels(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var msg MessageResp
+		_ = json.NewDecoder(req.Body).Decode(&msg)
+		if msg.ChannelID == "slow" {
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"action":"reject"}`))
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	s := NewTestServer(t, WithWebhookHTTPAddr(ts.URL), WithWebhookModerationOn(true), WithWebhookModerationTimeout(time.Second*10))
+	r := s.channelReactor
+	defer r.moderationPool.Release()
+
+	newReq := func(channelId string) *permissionReq {
+		ch := newChannel(r.reactorSub(channelId), channelId, wkproto.ChannelTypeInfo)
+		return &permissionReq{
+			ch:      ch,
+			fromUid: "u1",
+			messages: []ReactorChannelMessage{{
+				MessageId: 1,
+				FromUid:   "u1",
+				Index:     1,
+				SendPacket: &wkproto.SendPacket{
+					ChannelID:   channelId,
+					ChannelType: wkproto.ChannelTypeInfo,
+					Payload:     []byte("hello"),
+				},
+			}},
+		}
+	}
+	waitAction := func(req *permissionReq) *ChannelAction {
+		sub := r.reactorSub(req.ch.key)
+		timeout := time.After(time.Second * 5)
+		for {
+			select {
+			case step := <-sub.stepChannelC:
+				if step.ch == req.ch {
+					return step.action
+				}
+				sub.stepChannelC <- step // 其他频道的结果放回去
+			case <-timeout:
+				return nil
+			}
+		}
+	}
+
+	slowReq := newReq("slow")
+	done := make(chan struct{})
+	go func() {
+		r.processPermission(slowReq)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second * 2):
+		t.Fatal("processPermission blocked by the slow webhook")
+	}
+
+	fastReq := newReq("fast")
+	r.processPermission(fastReq)
+	action := waitAction(fastReq)
+	assert.NotNil(t, action)
+	assert.Equal(t, 1, len(action.Messages))
+	assert.Equal(t, wkproto.ReasonNotAllowSend, action.Messages[0].ReasonCode)
+
+	release <- struct{}{}
+	action = waitAction(slowReq)
+	assert.NotNil(t, action)
+	assert.Equal(t, 1, len(action.Messages))
+	assert.Equal(t, wkproto.ReasonNotAllowSend, action.Messages[0].ReasonCode)
+}
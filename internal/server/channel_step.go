@@ -113,6 +113,21 @@ func (c *channel) stepLeader(a *ChannelAction) error {
 			}
 		}
 
+		// 内容审核的结果，被拒绝的消息设置ReasonCode，被修改的消息替换内容
+		if len(a.Messages) > 0 {
+			startIndex := c.msgQueue.getArrayIndex(c.msgQueue.permissionCheckingIndex)
+			endIndex := min(c.msgQueue.getArrayIndex(a.Index), len(c.msgQueue.messages))
+			for _, moderatedMsg := range a.Messages {
+				for i := max(startIndex, 0); i < endIndex; i++ {
+					if c.msgQueue.messages[i].MessageId == moderatedMsg.MessageId {
+						c.msgQueue.messages[i].ReasonCode = moderatedMsg.ReasonCode
+						c.msgQueue.messages[i].SendPacket = moderatedMsg.SendPacket
+						break
+					}
+				}
+			}
+		}
+
 		if a.Index > c.msgQueue.permissionCheckingIndex {
 			c.msgQueue.permissionCheckingIndex = a.Index
 		}
@@ -140,6 +155,7 @@ func (c *channel) stepLeader(a *ChannelAction) error {
 				storedMsg := a.Messages[j]
 				if msg.MessageId == storedMsg.MessageId {
 					msg.MessageSeq = storedMsg.MessageSeq
+					msg.DedupMessageId = storedMsg.DedupMessageId
 					c.msgQueue.messages[i] = msg
 					break
 				}
@@ -242,6 +242,17 @@ const (
 	ReasonTimeout
 )
 
+// ContentTypeCMD 命令类消息的正文类型
+const ContentTypeCMD = 99
+
+// 命令类消息的命令
+const (
+	CMDMessageRevoke      = "messageRevoke"      // 消息撤回
+	CMDMessageEdit        = "messageEdit"        // 消息编辑
+	CMDMessageReaction    = "messageReaction"    // 消息回应
+	CMDMessageReadedCount = "messageReadedCount" // 消息已读数量变化
+)
+
 func parseAddr(addr string) (string, int64) {
 	addrPairs := strings.Split(addr, ":")
 	if len(addrPairs) < 2 {
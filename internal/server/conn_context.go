@@ -172,6 +172,18 @@ func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 		return
 	}
 
+	// 用户维度的发送频率在连接所在节点判断，用户往多个频道发送共用一个令牌桶
+	if !c.subReactor.r.s.rateLimitManager.allowUserSend(c.uid) {
+		sendack := &wkproto.SendackPacket{
+			Framer:      packet.Framer,
+			ClientSeq:   packet.ClientSeq,
+			ClientMsgNo: packet.ClientMsgNo,
+			ReasonCode:  wkproto.ReasonRateLimit,
+		}
+		_ = c.writeDirectlyPacket(sendack)
+		return
+	}
+
 	// 提案发送至频道
 	_ = c.subReactor.proposeSend(c, packet)
 }
@@ -255,7 +255,8 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 					MessageSeq:  message.MessageSeq,
 					ClientMsgNo: sendPacket.ClientMsgNo,
 					StreamNo:    sendPacket.StreamNo,
-					StreamFlag:  wkproto.StreamFlagIng,
+					StreamSeq:   message.StreamSeq,
+					StreamFlag:  message.StreamFlag,
 					FromUID:     message.FromUid,
 					Expire:      sendPacket.Expire,
 					ChannelID:   sendPacket.ChannelID,
@@ -322,9 +323,10 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 
 	}
 
-	if len(offlineUids) > 0 { // 有离线用户，发送webhook
+	if len(offlineUids) > 0 { // 有离线用户，发送webhook和离线推送
 		for _, message := range req.messages {
 			d.dm.s.webhook.notifyOfflineMsg(message, offlineUids)
+			d.dm.s.pushManager.push(message, offlineUids)
 		}
 	}
 }
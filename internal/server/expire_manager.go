@@ -0,0 +1,102 @@
+package server
+
+import (
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const expireSweepLimit = 1000 // 每次清理的过期消息数量
+
+// expireManager 过期消息清理，频道领导节点把本节点已过期的消息作为频道消息命令提交，由频道的每个副本清理
+type expireManager struct {
+	s          *Server
+	sweepTimer *timingwheel.Timer
+	sweeping   atomic.Bool // 是否正在清理
+	wklog.Log
+}
+
+func newExpireManager(s *Server) *expireManager {
+	return &expireManager{
+		s:   s,
+		Log: wklog.NewWKLog("expireManager"),
+	}
+}
+
+func (e *expireManager) start() error {
+	if e.s.opts.Db.ExpireSweepInterval <= 0 {
+		return nil
+	}
+	e.sweepTimer = e.s.Schedule(e.s.opts.Db.ExpireSweepInterval, e.sweep)
+	return nil
+}
+
+func (e *expireManager) stop() {
+	if e.sweepTimer != nil {
+		e.sweepTimer.Stop()
+	}
+}
+
+type expiredChannelMessages struct {
+	channelId   string
+	channelType uint8
+	messageSeqs []uint64
+}
+
+func (e *expireManager) sweep() {
+	if !e.sweeping.CompareAndSwap(false, true) { // 上一次清理还未结束
+		return
+	}
+	defer e.sweeping.Store(false)
+
+	messages, err := e.s.store.GetExpiredMessages(time.Now(), expireSweepLimit)
+	if err != nil {
+		e.Error("get expired messages failed", zap.Error(err))
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+	channels := make([]*expiredChannelMessages, 0)
+	channelMap := make(map[string]*expiredChannelMessages)
+	for _, m := range messages {
+		channelKey := wkutil.ChannelToKey(m.ChannelID, m.ChannelType)
+		channel := channelMap[channelKey]
+		if channel == nil {
+			channel = &expiredChannelMessages{
+				channelId:   m.ChannelID,
+				channelType: m.ChannelType,
+			}
+			channelMap[channelKey] = channel
+			channels = append(channels, channel)
+		}
+		channel.messageSeqs = append(channel.messageSeqs, uint64(m.MessageSeq))
+	}
+	for _, channel := range channels {
+		e.sweepChannel(channel)
+	}
+}
+
+// sweepChannel 只由频道领导节点提交清理命令，其他副本的过期消息在应用命令时清理
+func (e *expireManager) sweepChannel(channel *expiredChannelMessages) {
+	if e.s.opts.ClusterOn() {
+		leader, err := e.s.cluster.LeaderOfChannelForRead(channel.channelId, channel.channelType)
+		if err != nil {
+			e.Warn("get channel leader failed", zap.Error(err), zap.String("channelId", channel.channelId), zap.Uint8("channelType", channel.channelType))
+			return
+		}
+		if leader == nil || leader.Id != e.s.opts.Cluster.NodeId {
+			return
+		}
+	}
+	err := e.s.store.DeleteExpiredMessages(channel.channelId, channel.channelType, channel.messageSeqs)
+	if err != nil {
+		e.Error("delete expired messages failed", zap.Error(err), zap.String("channelId", channel.channelId), zap.Uint8("channelType", channel.channelType))
+		return
+	}
+	e.Debug("delete expired messages", zap.String("channelId", channel.channelId), zap.Uint8("channelType", channel.channelType), zap.Int("count", len(channel.messageSeqs)))
+}
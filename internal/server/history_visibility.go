@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+const (
+	historyVisibilityAll       uint8 = 0 // 可见全部历史消息
+	historyVisibilitySinceJoin uint8 = 1 // 只可见加入后的消息
+	historyVisibilityLastN     uint8 = 2 // 可见加入前最近N条及加入后的消息
+)
+
+// historyInvisible 用户在频道内没有可见的消息
+const historyInvisible uint64 = math.MaxUint64
+
+// historyVisibleStartSeq 获取用户在频道内可见的起始消息序号，返回0表示不限制，返回historyInvisible表示没有可见的消息
+// 订阅者数据和频道信息读取本节点的数据，需要在频道领导节点调用
+func (s *Server) historyVisibleStartSeq(channelId string, channelType uint8, uid string) (uint64, error) {
+	if channelType == wkproto.ChannelTypePerson {
+		return 0, nil
+	}
+	if uid == s.opts.SystemUID || s.systemUIDManager.SystemUID(uid) { // 系统账号不限制
+		return 0, nil
+	}
+	if s.opts.IsCmdChannel(channelId) {
+		channelId = s.opts.CmdChannelConvertOrginalChannel(channelId)
+	}
+	channelInfo, err := s.store.GetChannel(channelId, channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		return 0, err
+	}
+	if channelInfo.HistoryVisibility == historyVisibilityAll {
+		return 0, nil
+	}
+
+	subscriber, err := s.store.GetSubscriber(channelId, channelType, uid)
+	if err != nil {
+		if err == wkdb.ErrNotFound { // 不是订阅者，没有加入点
+			return historyInvisible, nil
+		}
+		return 0, err
+	}
+	if subscriber.JoinedAt == 0 { // 旧数据没有记录加入点，不限制
+		return 0, nil
+	}
+
+	switch channelInfo.HistoryVisibility {
+	case historyVisibilitySinceJoin:
+		return subscriber.JoinSeq + 1, nil
+	case historyVisibilityLastN:
+		// 加入点及之前的最近N条消息可见（不是按序号计算，已过期清理的消息不计数）
+		return s.store.GetMessageSeqBefore(channelId, channelType, subscriber.JoinSeq, uint64(channelInfo.HistoryVisibleCount))
+	}
+	return 0, nil
+}
+
+// filterVisibleMessages 过滤掉不可见的消息，返回可见的消息和是否有消息被过滤
+func filterVisibleMessages(messages []wkdb.Message, visibleStartSeq uint64) ([]wkdb.Message, bool) {
+	if visibleStartSeq == 0 || len(messages) == 0 {
+		return messages, false
+	}
+	visibleMessages := make([]wkdb.Message, 0, len(messages))
+	for _, message := range messages {
+		if uint64(message.MessageSeq) < visibleStartSeq {
+			continue
+		}
+		visibleMessages = append(visibleMessages, message)
+	}
+	return visibleMessages, len(visibleMessages) != len(messages)
+}
+
+// checkHistoryVisibility 校验历史消息可见策略
+func checkHistoryVisibility(historyVisibility uint8) error {
+	if historyVisibility != historyVisibilityAll && historyVisibility != historyVisibilitySinceJoin && historyVisibility != historyVisibilityLastN {
+		return errors.New("history_visibility不支持！")
+	}
+	return nil
+}
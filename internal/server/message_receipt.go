@@ -0,0 +1,132 @@
+package server
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// getMessageReadCounts 获取消息的已读数量
+// 已读回执通过频道所属槽的raft存储，槽的追随者可能还未应用，所以从槽领导节点读取
+func (s *Server) getMessageReadCounts(channelId string, channelType uint8, messageSeqs []uint64) ([]wkdb.MessageReadCount, error) {
+	if s.opts.ClusterOn() {
+		leaderId, err := s.cluster.SlotLeaderIdOfChannel(channelId, channelType)
+		if err != nil {
+			return nil, err
+		}
+		if leaderId != s.opts.Cluster.NodeId {
+			req := &messageReadCountsReq{
+				channelId:   channelId,
+				channelType: channelType,
+				messageSeqs: messageSeqs,
+			}
+			data, err := s.requestNode(leaderId, "/wk/messageReadCounts", req.Marshal())
+			if err != nil {
+				return nil, err
+			}
+			resp := &messageReadCountsResp{}
+			if err = resp.Unmarshal(data); err != nil {
+				return nil, err
+			}
+			return resp.counts, nil
+		}
+	}
+	return s.store.GetMessageReadCounts(channelId, channelType, messageSeqs)
+}
+
+func (s *Server) handleMessageReadCounts(c *wkserver.Context) {
+	req := &messageReadCountsReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleMessageReadCounts Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	counts, err := s.store.GetMessageReadCounts(req.channelId, req.channelType, req.messageSeqs)
+	if err != nil {
+		s.Error("get message read counts failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	resp := &messageReadCountsResp{
+		counts: counts,
+	}
+	c.Write(resp.Marshal())
+}
+
+type messageReadCountsReq struct {
+	channelId   string
+	channelType uint8
+	messageSeqs []uint64
+}
+
+func (r *messageReadCountsReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.channelId)
+	enc.WriteUint8(r.channelType)
+	enc.WriteUint32(uint32(len(r.messageSeqs)))
+	for _, messageSeq := range r.messageSeqs {
+		enc.WriteUint64(messageSeq)
+	}
+	return enc.Bytes()
+}
+
+func (r *messageReadCountsReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	r.messageSeqs = make([]uint64, 0, count)
+	for i := 0; i < int(count); i++ {
+		messageSeq, err := dec.Uint64()
+		if err != nil {
+			return err
+		}
+		r.messageSeqs = append(r.messageSeqs, messageSeq)
+	}
+	return nil
+}
+
+type messageReadCountsResp struct {
+	counts []wkdb.MessageReadCount
+}
+
+func (r *messageReadCountsResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.counts)))
+	for _, count := range r.counts {
+		enc.WriteUint64(count.MessageSeq)
+		enc.WriteUint32(count.Count)
+	}
+	return enc.Bytes()
+}
+
+func (r *messageReadCountsResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	r.counts = make([]wkdb.MessageReadCount, 0, count)
+	for i := 0; i < int(count); i++ {
+		var readCount wkdb.MessageReadCount
+		if readCount.MessageSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+		if readCount.Count, err = dec.Uint32(); err != nil {
+			return err
+		}
+		r.counts = append(r.counts, readCount)
+	}
+	return nil
+}
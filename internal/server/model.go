@@ -1,14 +1,17 @@
 package server
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
 )
 
 var defaultProtoVersion uint8 = 4
@@ -27,6 +30,10 @@ type ReactorChannelMessage struct {
 	IsEncrypt    bool // SendPacket的payload是否加密
 	ReasonCode   wkproto.ReasonCode
 	Index        uint64
+	StreamFlag   wkproto.StreamFlag // 流标记
+	StreamSeq    uint32             // 流序号
+
+	DedupMessageId int64 // 不为0表示是客户端重发的重复消息，值为原消息ID，重复消息不存储也不投递（只在领导节点上设置）
 }
 
 func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
@@ -47,6 +54,8 @@ func (r *ReactorChannelMessage) Marshal() ([]byte, error) {
 		}
 	}
 	enc.WriteBinary(packetData)
+	enc.WriteUint8(uint8(r.StreamFlag))
+	enc.WriteUint32(r.StreamSeq)
 
 	return enc.Bytes(), nil
 }
@@ -85,6 +94,17 @@ func (r *ReactorChannelMessage) Unmarshal(data []byte) error {
 		r.SendPacket = packet.(*wkproto.SendPacket)
 	}
 
+	if dec.Len() > 0 { // 兼容老版本
+		var streamFlag uint8
+		if streamFlag, err = dec.Uint8(); err != nil {
+			return err
+		}
+		r.StreamFlag = wkproto.StreamFlag(streamFlag)
+		if r.StreamSeq, err = dec.Uint32(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -103,6 +123,8 @@ func (m *ReactorChannelMessage) Size() uint64 {
 	} else {
 		size += 2
 	}
+	size += 1 // streamFlag
+	size += 4 // streamSeq
 	return size
 }
 
@@ -135,6 +157,11 @@ func (r ChannelFowardReq) Marshal() ([]byte, error) {
 		}
 		enc.WriteBinary(packetData)
 	}
+	// 流信息放在最后，兼容老版本
+	for _, m := range r.Messages {
+		enc.WriteUint8(uint8(m.StreamFlag))
+		enc.WriteUint32(m.StreamSeq)
+	}
 	return enc.Bytes(), nil
 }
 
@@ -182,6 +209,11 @@ func (r *ChannelFowardReq) Unmarshal(data []byte) error {
 		m.SendPacket = packet.(*wkproto.SendPacket)
 		r.Messages = append(r.Messages, m)
 	}
+	if dec.Len() > 0 { // 兼容老版本
+		if err = decodeMessageStreams(dec, r.Messages); err != nil {
+			return err
+		}
+	}
 	return nil
 
 }
@@ -212,6 +244,11 @@ func (rs ReactorChannelMessageSet) Marshal() ([]byte, error) {
 		}
 		enc.WriteBinary(packetData)
 	}
+	// 流信息放在最后，兼容老版本
+	for _, r := range rs {
+		enc.WriteUint8(uint8(r.StreamFlag))
+		enc.WriteUint32(r.StreamSeq)
+	}
 
 	return enc.Bytes(), nil
 }
@@ -257,6 +294,26 @@ func (rs *ReactorChannelMessageSet) Unmarshal(data []byte) error {
 		r.SendPacket = packet.(*wkproto.SendPacket)
 		*rs = append(*rs, r)
 	}
+	if dec.Len() > 0 { // 兼容老版本
+		if err = decodeMessageStreams(dec, *rs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeMessageStreams 解码消息的流信息
+func decodeMessageStreams(dec *wkproto.Decoder, messages []ReactorChannelMessage) error {
+	for i := range messages {
+		streamFlag, err := dec.Uint8()
+		if err != nil {
+			return err
+		}
+		messages[i].StreamFlag = wkproto.StreamFlag(streamFlag)
+		if messages[i].StreamSeq, err = dec.Uint32(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -383,7 +440,10 @@ type MessageResp struct {
 	Expire       uint32             `json:"expire"`                // 消息过期时间
 	Timestamp    int32              `json:"timestamp"`             // 服务器消息时间戳(10位，到秒)
 	Payload      []byte             `json:"payload"`               // 消息内容
-	// Streams      []*StreamItemResp  `json:"streams,omitempty"`     // 消息流内容
+	Revoke       int                `json:"revoke"`                // 是否已撤回 0.否 1.是
+	Version      uint32             `json:"version"`               // 消息编辑版本号（0表示未编辑）
+	EditedAt     int32              `json:"edited_at"`             // 最后一次编辑时间(10位，到秒)
+	Streams      []*StreamItemResp  `json:"streams,omitempty"`     // 消息流内容
 }
 
 func (m *MessageResp) from(messageD wkdb.Message) {
@@ -417,36 +477,49 @@ func (m *MessageResp) from(messageD wkdb.Message) {
 	m.ChannelType = messageD.ChannelType
 	m.Topic = messageD.Topic
 	m.Payload = messageD.Payload
+	m.Revoke = wkutil.BoolToInt(messageD.Revoke)
+	m.Version = messageD.Version
+	m.EditedAt = messageD.EditedAt
+
+}
+
+// fillStreams 填充流消息的流内容和流状态
+func (m *MessageResp) fillStreams(fakeChannelId string, s *Server) {
+	if strings.TrimSpace(m.StreamNo) == "" {
+		return
+	}
+	streamMeta, streamItems, err := s.getStream(fakeChannelId, m.ChannelType, m.StreamNo)
+	if err != nil {
+		if err != wkdb.ErrNotFound {
+			wklog.Error("获取消息流失败！", zap.Error(err), zap.String("streamNo", m.StreamNo))
+		}
+		return
+	}
+	m.StreamFlag = streamMeta.StreamFlag
+
+	if len(streamItems) > 0 {
+		streamItemResps := make([]*StreamItemResp, 0, len(streamItems))
+		for _, streamItem := range streamItems {
+			streamItemResps = append(streamItemResps, newStreamItemResp(streamItem))
+		}
+		m.Streams = streamItemResps
+	}
+}
 
-	// if strings.TrimSpace(messageD.StreamNo) != "" && store != nil {
-	// 	streamItems, err := store.GetStreamItems(GetFakeChannelIDWith(messageD.FromUID, messageD.ChannelID), messageD.ChannelType, messageD.StreamNo)
-	// 	if err != nil {
-	// 		wklog.Error("获取streamItems失败！", zap.Error(err))
-	// 	}
-	// 	if len(streamItems) > 0 {
-	// 		streamItemResps := make([]*StreamItemResp, 0, len(streamItems))
-	// 		for _, streamItem := range streamItems {
-	// 			streamItemResps = append(streamItemResps, newStreamItemResp(streamItem))
-	// 		}
-	// 		m.Streams = streamItemResps
-	// 	}
-	// }
-}
-
-// type StreamItemResp struct {
-// 	StreamSeq   uint32 `json:"stream_seq"`    // 流序号
-// 	ClientMsgNo string `json:"client_msg_no"` // 客户端消息唯一编号
-// 	Blob        []byte `json:"blob"`          // 消息内容
-// }
-
-// func newStreamItemResp(m *wkstore.StreamItem) *StreamItemResp {
-
-// 	return &StreamItemResp{
-// 		StreamSeq:   m.StreamSeq,
-// 		ClientMsgNo: m.ClientMsgNo,
-// 		Blob:        m.Blob,
-// 	}
-// }
+type StreamItemResp struct {
+	StreamSeq   uint32 `json:"stream_seq"`    // 流序号
+	ClientMsgNo string `json:"client_msg_no"` // 客户端消息唯一编号
+	Blob        []byte `json:"blob"`          // 消息内容
+}
+
+func newStreamItemResp(m wkdb.StreamItem) *StreamItemResp {
+
+	return &StreamItemResp{
+		StreamSeq:   m.StreamSeq,
+		ClientMsgNo: m.ClientMsgNo,
+		Blob:        m.Payload,
+	}
+}
 
 type MessageOfflineNotify struct {
 	MessageResp
@@ -456,6 +529,18 @@ type MessageOfflineNotify struct {
 	SourceID        int64    `json:"source_id,omitempty"`        // 来源节点ID
 }
 
+// MessageSensitiveNotify 消息命中敏感词的通知
+type MessageSensitiveNotify struct {
+	MessageResp
+	Words []string `json:"words"` // 命中的敏感词
+}
+
+// UserUnbanNotify 用户解除封禁的通知
+type UserUnbanNotify struct {
+	UID     string `json:"uid"`
+	Expired int    `json:"expired"` // 是否是到期自动解除 1.是
+}
+
 // MessageHeader Message header
 type MessageHeader struct {
 	NoPersist int `json:"no_persist"` // Is it not persistent
@@ -622,6 +707,43 @@ func (req deleteChannelReq) Check() error {
 	return nil
 }
 
+type conversationMuteReq struct {
+	UID         string `json:"uid"`
+	ChannelID   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	Duration    int64  `json:"duration"` // 免打扰时长（秒），0表示永久
+}
+
+func (req conversationMuteReq) Check() error {
+	if len(req.UID) <= 0 {
+		return errors.New("Uid cannot be empty")
+	}
+	if req.ChannelID == "" || req.ChannelType == 0 {
+		return errors.New("channel_id or channel_type cannot be empty")
+	}
+	if req.Duration < 0 {
+		return errors.New("duration不能为负数！")
+	}
+	return nil
+}
+
+type devicePushReq struct {
+	UID        string `json:"uid"`
+	DeviceFlag uint8  `json:"device_flag"` // 设备标识
+	PushType   uint8  `json:"push_type"`   // 推送类型 1.APNs 2.FCM
+	PushToken  string `json:"push_token"`  // 推送令牌，为空表示注销推送
+}
+
+func (req devicePushReq) Check() error {
+	if len(req.UID) <= 0 {
+		return errors.New("Uid cannot be empty")
+	}
+	if req.PushToken != "" && req.PushType != wkdb.PushTypeAPNs && req.PushType != wkdb.PushTypeFCM {
+		return errors.New("push_type不支持！")
+	}
+	return nil
+}
+
 type syncUserConversationResp struct {
 	ChannelId       string         `json:"channel_id"`         // 频道ID
 	ChannelType     uint8          `json:"channel_type"`       // 频道类型
@@ -660,9 +782,10 @@ type channelRecentMessageReq struct {
 }
 
 type channelRecentMessage struct {
-	ChannelId   string         `json:"channel_id"`
-	ChannelType uint8          `json:"channel_type"`
-	Messages    []*MessageResp `json:"messages"`
+	ChannelId       string         `json:"channel_id"`
+	ChannelType     uint8          `json:"channel_type"`
+	FirstMessageSeq uint64         `json:"first_message_seq"` // 第一条可用的消息序号（之前的消息已被清理），0表示未清理过
+	Messages        []*MessageResp `json:"messages"`
 }
 
 type MessageRespSlice []*MessageResp
@@ -690,15 +813,27 @@ func (r ChannelCreateReq) Check() error {
 	if IsSpecialChar(r.ChannelID) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
+	if err := checkHistoryVisibility(r.HistoryVisibility); err != nil {
+		return err
+	}
 	return nil
 }
 
 type subscriberAddReq struct {
-	ChannelID      string   `json:"channel_id"`      // 频道ID
-	ChannelType    uint8    `json:"channel_type"`    // 频道类型
-	Reset          int      `json:"reset"`           // 是否重置订阅者 （0.不重置 1.重置），选择重置，将删除原来的所有成员
-	TempSubscriber int      `json:"temp_subscriber"` //  是否是临时订阅者 (1. 是 0. 否)
-	Subscribers    []string `json:"subscribers"`     // 订阅者
+	ChannelID      string                `json:"channel_id"`      // 频道ID
+	ChannelType    uint8                 `json:"channel_type"`    // 频道类型
+	Reset          int                   `json:"reset"`           // 是否重置订阅者 （0.不重置 1.重置），选择重置，将删除原来的所有成员
+	TempSubscriber int                   `json:"temp_subscriber"` //  是否是临时订阅者 (1. 是 0. 否)
+	Subscribers    []string              `json:"subscribers"`     // 订阅者
+	Members        []subscriberMemberReq `json:"members"`         // 订阅者（可设置频道内昵称和扩展数据）
+	InviterUID     string                `json:"inviter_uid"`     // 邀请人
+}
+
+// subscriberMemberReq 订阅者的属性
+type subscriberMemberReq struct {
+	UID      string          `json:"uid"`      // 订阅者
+	Nickname string          `json:"nickname"` // 频道内昵称
+	Extra    json.RawMessage `json:"extra"`    // 自定义扩展数据（json）
 }
 
 func (s subscriberAddReq) Check() error {
@@ -708,9 +843,67 @@ func (s subscriberAddReq) Check() error {
 	if IsSpecialChar(s.ChannelID) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
+	if stringArrayIsEmpty(s.Subscribers) && len(s.Members) == 0 {
+		return errors.New("订阅者不能为空！")
+	}
+	for _, member := range s.Members {
+		if strings.TrimSpace(member.UID) == "" {
+			return errors.New("订阅者不能为空！")
+		}
+	}
+	return nil
+}
+
+type subscriberUpdateReq struct {
+	ChannelID   string                `json:"channel_id"`   // 频道ID
+	ChannelType uint8                 `json:"channel_type"` // 频道类型
+	Members     []subscriberMemberReq `json:"members"`      // 需要更新的订阅者属性
+}
+
+func (s subscriberUpdateReq) Check() error {
+	if strings.TrimSpace(s.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if IsSpecialChar(s.ChannelID) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	if s.ChannelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持设置订阅者属性！")
+	}
+	if len(s.Members) == 0 {
+		return errors.New("订阅者不能为空！")
+	}
+	for _, member := range s.Members {
+		if strings.TrimSpace(member.UID) == "" {
+			return errors.New("订阅者不能为空！")
+		}
+	}
+	return nil
+}
+
+type subscriberRoleReq struct {
+	ChannelID   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	Subscribers []string `json:"subscribers"`  // 订阅者
+	Role        uint8    `json:"role"`         // 角色 0.普通成员 1.群主 2.管理员 100及以上为自定义角色
+}
+
+func (s subscriberRoleReq) Check() error {
+	if strings.TrimSpace(s.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if IsSpecialChar(s.ChannelID) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	if s.ChannelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持设置角色！")
+	}
 	if stringArrayIsEmpty(s.Subscribers) {
 		return errors.New("订阅者不能为空！")
 	}
+	if s.Role != wkdb.SubscriberRoleMember && s.Role != wkdb.SubscriberRoleOwner && s.Role != wkdb.SubscriberRoleAdmin && s.Role < wkdb.SubscriberRoleCustom {
+		return errors.New("角色不支持！")
+	}
 	return nil
 }
 
@@ -794,6 +987,50 @@ func (r whitelistReq) Check() error {
 	return nil
 }
 
+type channelMuteReq struct {
+	ChannelID   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	UIDs        []string `json:"uids"`         // 禁言或解除禁言的成员
+	MuteAll     int      `json:"mute_all"`     // 是否全员禁言或解除全员禁言 1.是
+	Duration    int64    `json:"duration"`     // 禁言时长（秒），0表示永久，解除禁言时忽略
+}
+
+func (r channelMuteReq) Check() error {
+	if r.ChannelID == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if IsSpecialChar(r.ChannelID) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("频道类型不能为0！")
+	}
+	if r.ChannelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持禁言！")
+	}
+	if r.MuteAll != 1 && stringArrayIsEmpty(r.UIDs) {
+		return errors.New("uids不能为空！")
+	}
+	if r.Duration < 0 {
+		return errors.New("duration不能为负数！")
+	}
+	return nil
+}
+
+// muteUids 需要操作的禁言uid（空uid表示全员禁言）
+func (r channelMuteReq) muteUids() []string {
+	uids := make([]string, 0, len(r.UIDs)+1)
+	if r.MuteAll == 1 {
+		uids = append(uids, "")
+	}
+	for _, uid := range r.UIDs {
+		if strings.TrimSpace(uid) != "" {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
 type syncReq struct {
 	UID        string `json:"uid"`         // 用户uid
 	MessageSeq uint64 `json:"message_seq"` // 客户端最大消息序列号
@@ -816,6 +1053,7 @@ type syncMessageResp struct {
 	StartMessageSeq uint64         `json:"start_message_seq"` // 开始序列号
 	EndMessageSeq   uint64         `json:"end_message_seq"`   // 结束序列号
 	More            int            `json:"more"`              // 是否还有更多 1.是 0.否
+	FirstMessageSeq uint64         `json:"first_message_seq"` // 第一条可用的消息序号（之前的消息已被清理），0表示未清理过
 	Messages        []*MessageResp `json:"messages"`          // 消息数据
 }
 
@@ -843,15 +1081,28 @@ type ChannelInfoReq struct {
 	Large       int    `json:"large"`        // 是否是超大群
 	Ban         int    `json:"ban"`          // 是否封禁频道（封禁后此频道所有人都将不能发消息，除了系统账号）
 	Disband     int    `json:"disband"`      // 是否解散频道
+	// 消息保留策略，0表示不限制（未设置时使用频道类型的保留策略）
+	RetentionMaxAge   uint32 `json:"retention_max_age"`   // 消息最长保留时间（单位秒）
+	RetentionMaxCount uint32 `json:"retention_max_count"` // 消息最多保留数量
+	OnlyAdminSend     int    `json:"only_admin_send"`     // 是否仅群主和管理员可发言（公告频道） 1.是
+	// 历史消息可见策略
+	HistoryVisibility   uint8  `json:"history_visibility"`    // 成员可见的历史消息 0.全部 1.加入后的消息 2.加入前最近N条及加入后的消息
+	HistoryVisibleCount uint32 `json:"history_visible_count"` // 加入前可见的消息数量N（history_visibility为2时有效）
 }
 
 func (c ChannelInfoReq) ToChannelInfo() wkdb.ChannelInfo {
 	return wkdb.ChannelInfo{
-		ChannelId:   c.ChannelID,
-		ChannelType: c.ChannelType,
-		Large:       c.Large == 1,
-		Ban:         c.Ban == 1,
-		Disband:     c.Disband == 1,
+		ChannelId:         c.ChannelID,
+		ChannelType:       c.ChannelType,
+		Large:             c.Large == 1,
+		Ban:               c.Ban == 1,
+		Disband:           c.Disband == 1,
+		RetentionMaxAge:   c.RetentionMaxAge,
+		RetentionMaxCount: c.RetentionMaxCount,
+		OnlyAdminSend:     c.OnlyAdminSend == 1,
+
+		HistoryVisibility:   c.HistoryVisibility,
+		HistoryVisibleCount: c.HistoryVisibleCount,
 	}
 }
 
@@ -866,6 +1117,7 @@ type MessageSendReq struct {
 	Expire      uint32        `json:"expire"`        // 消息过期时间
 	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
 	Payload     []byte        `json:"payload"`       // 消息内容
+	SendAt      int64         `json:"send_at"`       // 定时发送时间(10位，到秒)，大于当前时间时消息将在此时间发送
 }
 
 // Check 检查输入
@@ -876,6 +1128,405 @@ func (m MessageSendReq) Check() error {
 	return nil
 }
 
+const messageSendBatchMaxCount = 1000 // 批量发送消息的最大数量
+
+// MessageSendBatchReq 批量发送消息请求，每条消息可以发往不同的频道
+type MessageSendBatchReq struct {
+	Messages []MessageSendReq `json:"messages"`
+}
+
+func (m MessageSendBatchReq) Check() error {
+	if len(m.Messages) == 0 {
+		return errors.New("messages不能为空！")
+	}
+	if len(m.Messages) > messageSendBatchMaxCount {
+		return errors.New("messages数量超过限制！")
+	}
+	return nil
+}
+
+// MessageSendBatchResult 批量发送中每条消息的发送结果
+type MessageSendBatchResult struct {
+	Index       int    `json:"index"`           // 消息在请求中的下标
+	MessageId   int64  `json:"message_id"`      // 消息ID
+	MessageSeq  uint32 `json:"message_seq"`     // 消息序号（不存储的消息为0）
+	ClientMsgNo string `json:"client_msg_no"`   // 客户端消息编号
+	ReasonCode  uint8  `json:"reason_code"`     // 发送结果，1为成功
+	Error       string `json:"error,omitempty"` // 失败原因
+}
+
+// MessageBroadcastReq 系统广播请求
+type MessageBroadcastReq struct {
+	Header  MessageHeader `json:"header"`   // 消息头（支持red_dot和sync_once）
+	FromUID string        `json:"from_uid"` // 发送者UID，为空时为系统账号
+	Target  string        `json:"target"`   // 广播目标 online: 所有在线连接（消息不存储） all: 所有用户（消息存储到与发送者的单聊频道）
+	Rate    int           `json:"rate"`     // 每个节点每秒发送数量，为0时使用默认值
+	Payload []byte        `json:"payload"`  // 消息内容
+}
+
+func (m MessageBroadcastReq) Check() error {
+	if len(m.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	if m.Target != broadcastTargetOnline && m.Target != broadcastTargetAll {
+		return errors.New("target只能是online或all！")
+	}
+	if m.Rate < 0 {
+		return errors.New("rate不能小于0！")
+	}
+	return nil
+}
+
+type messageBroadcastProgressReq struct {
+	BroadcastId string `json:"broadcast_id"`
+}
+
+type messageBroadcastProgressResp struct {
+	BroadcastId  string               `json:"broadcast_id"`
+	Total        int64                `json:"total"`                   // 需要发送的数量（发送中会增长）
+	Sent         int64                `json:"sent"`                    // 已发送数量
+	Failed       int64                `json:"failed"`                  // 发送失败数量
+	Finished     bool                 `json:"finished"`                // 所有节点是否都已结束
+	Nodes        []*broadcastProgress `json:"nodes"`                   // 每个节点的进度
+	UnknownNodes []uint64             `json:"unknown_nodes,omitempty"` // 未查询到进度的节点
+}
+
+// MessageStreamStartReq 流消息开始请求
+type MessageStreamStartReq struct {
+	Header      MessageHeader `json:"header"`        // 消息头
+	ClientMsgNo string        `json:"client_msg_no"` // 客户端消息编号
+	FromUID     string        `json:"from_uid"`      // 发送者UID
+	ChannelID   string        `json:"channel_id"`    // 频道ID
+	ChannelType uint8         `json:"channel_type"`  // 频道类型
+	Payload     []byte        `json:"payload"`       // 消息内容
+}
+
+func (m MessageStreamStartReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if len(m.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	return nil
+}
+
+// MessageStreamEndReq 流消息结束请求
+type MessageStreamEndReq struct {
+	StreamNo    string `json:"stream_no"`    // 消息流编号
+	FromUID     string `json:"from_uid"`     // 发送者UID
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (m MessageStreamEndReq) Check() error {
+	if strings.TrimSpace(m.StreamNo) == "" {
+		return errors.New("消息流编号不能为空！")
+	}
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	return nil
+}
+
+// scheduledMessageReq 定时消息查询或取消请求
+type scheduledMessageReq struct {
+	Id          uint64 `json:"id"`           // 定时消息ID（取消时必填）
+	FromUID     string `json:"from_uid"`     // 当前用户UID（个人频道必填）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+}
+
+func (r scheduledMessageReq) Check() error {
+	if strings.TrimSpace(r.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if r.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(r.FromUID) == "" {
+		return errors.New("个人频道from_uid不能为空！")
+	}
+	return nil
+}
+
+// scheduledMessageResp 定时消息
+type scheduledMessageResp struct {
+	Id          uint64        `json:"id"`            // 定时消息ID
+	IdStr       string        `json:"id_str"`        // 定时消息ID
+	SendAt      int64         `json:"send_at"`       // 计划发送时间(10位，到秒)
+	Header      MessageHeader `json:"header"`        // 消息头
+	ClientMsgNo string        `json:"client_msg_no"` // 客户端消息编号
+	FromUID     string        `json:"from_uid"`      // 发送者UID
+	ChannelID   string        `json:"channel_id"`    // 频道ID
+	ChannelType uint8         `json:"channel_type"`  // 频道类型
+	Expire      uint32        `json:"expire"`        // 消息过期时间
+	Payload     []byte        `json:"payload"`       // 消息内容
+	CreatedAt   int64         `json:"created_at"`    // 创建时间(10位，到秒)
+}
+
+func newScheduledMessageResp(m wkdb.ScheduledMessage) *scheduledMessageResp {
+	return &scheduledMessageResp{
+		Id:     m.Id,
+		IdStr:  strconv.FormatUint(m.Id, 10),
+		SendAt: m.SendAt,
+		Header: MessageHeader{
+			RedDot:    wkutil.BoolToInt(m.RedDot),
+			SyncOnce:  wkutil.BoolToInt(m.SyncOnce),
+			NoPersist: wkutil.BoolToInt(m.NoPersist),
+		},
+		ClientMsgNo: m.ClientMsgNo,
+		FromUID:     m.FromUid,
+		ChannelID:   scheduledMessageChannelId(m),
+		ChannelType: m.ChannelType,
+		Expire:      m.Expire,
+		Payload:     m.Payload,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// messageRevokeReq 消息撤回请求
+type messageRevokeReq struct {
+	FromUID     string `json:"from_uid"`      // 撤回操作者UID（个人频道必填，非个人频道为空表示系统撤回）
+	ChannelID   string `json:"channel_id"`    // 频道ID
+	ChannelType uint8  `json:"channel_type"`  // 频道类型
+	MessageID   int64  `json:"message_id"`    // 需要撤回的消息ID（与client_msg_no二选一）
+	ClientMsgNo string `json:"client_msg_no"` // 需要撤回的消息客户端编号（与message_id二选一）
+}
+
+func (m messageRevokeReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("频道类型错误！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.FromUID) == "" {
+		return errors.New("个人频道from_uid不能为空！")
+	}
+	if m.MessageID == 0 && strings.TrimSpace(m.ClientMsgNo) == "" {
+		return errors.New("message_id和client_msg_no不能同时为空！")
+	}
+	return nil
+}
+
+// messageEditReq 消息编辑请求
+type messageEditReq struct {
+	FromUID     string `json:"from_uid"`      // 编辑者UID（个人频道必填，非个人频道为空表示系统编辑，不为空时只能编辑自己发送的消息）
+	ChannelID   string `json:"channel_id"`    // 频道ID
+	ChannelType uint8  `json:"channel_type"`  // 频道类型
+	MessageID   int64  `json:"message_id"`    // 需要编辑的消息ID（与client_msg_no二选一）
+	ClientMsgNo string `json:"client_msg_no"` // 需要编辑的消息客户端编号（与message_id二选一）
+	Payload     []byte `json:"payload"`       // 新的消息内容
+}
+
+func (m messageEditReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("频道类型错误！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.FromUID) == "" {
+		return errors.New("个人频道from_uid不能为空！")
+	}
+	if m.MessageID == 0 && strings.TrimSpace(m.ClientMsgNo) == "" {
+		return errors.New("message_id和client_msg_no不能同时为空！")
+	}
+	if len(m.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	return nil
+}
+
+// messageEditHistoryReq 消息编辑历史请求
+type messageEditHistoryReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户的uid（个人频道必填）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageID   int64  `json:"message_id"`   // 消息ID
+}
+
+func (m messageEditHistoryReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	if m.MessageID == 0 {
+		return errors.New("message_id不能为空！")
+	}
+	return nil
+}
+
+// messageReactionReq 消息回应请求
+type messageReactionReq struct {
+	UID         string `json:"uid"`          // 回应者UID
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageID   int64  `json:"message_id"`   // 消息ID
+	Emoji       string `json:"emoji"`        // 回应的表情
+}
+
+func (m messageReactionReq) Check() error {
+	if strings.TrimSpace(m.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if m.ChannelType == 0 {
+		return errors.New("频道类型错误！")
+	}
+	if m.MessageID == 0 {
+		return errors.New("message_id不能为空！")
+	}
+	if strings.TrimSpace(m.Emoji) == "" {
+		return errors.New("emoji不能为空！")
+	}
+	if len(m.Emoji) > 64 {
+		return errors.New("emoji过长！")
+	}
+	return nil
+}
+
+// messageReactionSyncReq 消息回应同步请求
+type messageReactionSyncReq struct {
+	LoginUID    string `json:"login_uid"`    // 当前登录用户的uid（个人频道必填）
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	Version     uint64 `json:"version"`      // 客户端已同步的最大回应版本号
+	Limit       int    `json:"limit"`        // 每次同步数量
+}
+
+func (m messageReactionSyncReq) Check() error {
+	if strings.TrimSpace(m.ChannelID) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if m.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(m.LoginUID) == "" {
+		return errors.New("个人频道login_uid不能为空！")
+	}
+	return nil
+}
+
+// messageReactionResp 消息回应
+type messageReactionResp struct {
+	ChannelID    string `json:"channel_id"`    // 频道ID
+	ChannelType  uint8  `json:"channel_type"`  // 频道类型
+	MessageID    int64  `json:"message_id"`    // 消息ID
+	MessageIDStr string `json:"message_idstr"` // 消息ID
+	MessageSeq   uint64 `json:"message_seq"`   // 消息序号
+	UID          string `json:"uid"`           // 回应者UID
+	Emoji        string `json:"emoji"`         // 回应的表情
+	IsDeleted    int    `json:"is_deleted"`    // 是否已取消
+	Version      uint64 `json:"version"`       // 回应版本号
+	CreatedAt    int64  `json:"created_at"`    // 回应时间(10位，到秒)
+}
+
+func newMessageReactionResp(channelId string, r wkdb.Reaction) *messageReactionResp {
+	return &messageReactionResp{
+		ChannelID:    channelId,
+		ChannelType:  r.ChannelType,
+		MessageID:    r.MessageId,
+		MessageIDStr: strconv.FormatInt(r.MessageId, 10),
+		MessageSeq:   r.MessageSeq,
+		UID:          r.Uid,
+		Emoji:        r.Emoji,
+		IsDeleted:    wkutil.BoolToInt(r.IsDeleted),
+		Version:      r.Version,
+		CreatedAt:    r.CreatedAt,
+	}
+}
+
+// 每次最多回执或查询的消息数量
+const messageReceiptMaxCount = 100
+
+// messageReadedReq 消息已读回执请求
+type messageReadedReq struct {
+	UID         string   `json:"uid"`          // 已读用户UID
+	ChannelID   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	MessageSeqs []uint64 `json:"message_seqs"` // 已读的消息序号
+}
+
+func (m messageReadedReq) Check() error {
+	if strings.TrimSpace(m.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if err := checkMessageReceiptChannel(m.ChannelID, m.ChannelType); err != nil {
+		return err
+	}
+	if len(m.MessageSeqs) == 0 {
+		return errors.New("message_seqs不能为空！")
+	}
+	if len(m.MessageSeqs) > messageReceiptMaxCount {
+		return errors.New("message_seqs数量超过限制！")
+	}
+	return nil
+}
+
+// messageReadersReq 消息已读用户请求
+type messageReadersReq struct {
+	ChannelID   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+}
+
+func (m messageReadersReq) Check() error {
+	if err := checkMessageReceiptChannel(m.ChannelID, m.ChannelType); err != nil {
+		return err
+	}
+	if m.MessageSeq == 0 {
+		return errors.New("message_seq不能为空！")
+	}
+	return nil
+}
+
+// messageReadersResp 消息的已读用户（超大群只有已读数量）
+type messageReadersResp struct {
+	MessageSeq  uint64               `json:"message_seq"`  // 消息序号
+	ReadedCount uint32               `json:"readed_count"` // 已读数量
+	Readers     []wkdb.MessageReader `json:"readers"`      // 已读用户
+}
+
+// messageReadedCountsReq 消息已读数量请求
+type messageReadedCountsReq struct {
+	ChannelID   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	MessageSeqs []uint64 `json:"message_seqs"` // 消息序号
+}
+
+func (m messageReadedCountsReq) Check() error {
+	if err := checkMessageReceiptChannel(m.ChannelID, m.ChannelType); err != nil {
+		return err
+	}
+	if len(m.MessageSeqs) == 0 {
+		return errors.New("message_seqs不能为空！")
+	}
+	if len(m.MessageSeqs) > messageReceiptMaxCount {
+		return errors.New("message_seqs数量超过限制！")
+	}
+	return nil
+}
+
+// messageReadedCountResp 消息已读数量
+type messageReadedCountResp struct {
+	MessageSeq  uint64 `json:"message_seq"`  // 消息序号
+	ReadedCount uint32 `json:"readed_count"` // 已读数量
+}
+
+// 消息回执只支持群类频道，个人频道使用会话的已读位置
+func checkMessageReceiptChannel(channelId string, channelType uint8) error {
+	if strings.TrimSpace(channelId) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if channelType == 0 {
+		return errors.New("频道类型错误！")
+	}
+	if channelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持消息回执！")
+	}
+	return nil
+}
+
 type allowSendReq struct {
 	From string `json:"from"` // 发送者
 	To   string `json:"to"`   // 接收者
@@ -13,6 +13,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/crypto/tls"
 	"github.com/pkg/errors"
@@ -98,11 +99,19 @@ type Options struct {
 		CacheCount int    // 临时频道缓存数量
 	}
 	Webhook struct { // 两者配其一即可
-		HTTPAddr                    string        // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
-		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
-		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
-		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+		HTTPAddr                    string                 // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
+		GRPCAddr                    string                 //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
+		MsgNotifyEventPushInterval  time.Duration          // 消息通知事件推送间隔，默认500毫秒发起一次推送
+		MsgNotifyEventCountPerPush  int                    // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
+		MsgNotifyEventRetryMaxCount int                    // 事件推送失败最大重试次数 默认为5次，超过将移入死信队列
+		ModerationOn                bool                   // 是否开启发送前的内容审核（消息存储前同步请求webhook的msg.moderate事件）
+		ModerationTimeout           time.Duration          // 内容审核请求超时时间 默认2秒
+		ModerationFailOpen          bool                   // 内容审核请求失败或超时时是否放行，为false则拒绝发送 默认放行
+		ModerationConcurrency       int                    // 同时进行的内容审核请求数量，超过后按审核失败处理 默认1000
+		Secret                      string                 // 签名密钥，设置后请求会带上X-WK-Timestamp和X-WK-Signature头
+		RetryInterval               time.Duration          // 事件推送失败重试间隔，每次失败翻倍 默认1秒
+		RetryMaxInterval            time.Duration          // 事件推送失败最大重试间隔 默认5分钟
+		Endpoints                   []wkdb.WebhookEndpoint // 额外的webhook端点，每个端点订阅部分事件，独立队列和并发
 	}
 	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
 		Addr          string // 数据源地址
@@ -212,8 +221,54 @@ type Options struct {
 	}
 
 	Db struct {
-		ShardNum     int // 频道db分片数量
-		SlotShardNum int // 槽db分片数量
+		ShardNum            int           // 频道db分片数量
+		SlotShardNum        int           // 槽db分片数量
+		ExpireSweepInterval time.Duration // 过期消息清理间隔，为0表示不清理
+		FullTextIndex       bool          // 是否开启消息全文索引，关闭后再开启需要通过命令重建索引
+		MessageDedupWindow  time.Duration // 消息去重窗口，窗口内发送者重发相同client_msg_no的消息不再存储，为0表示不去重
+	}
+
+	// 消息保留策略（频道自身设置了保留策略时以频道的为准）
+	Retention struct {
+		CheckInterval time.Duration             // 检查间隔
+		ChannelTypes  map[uint8]RetentionPolicy // 频道类型对应的保留策略
+	}
+
+	// 定时消息
+	Scheduled struct {
+		CheckInterval time.Duration // 检查到期定时消息的间隔
+		MaxDelay      time.Duration // 最大允许的定时时长，为0表示不限制
+	}
+
+	// 消息发送频率限制（令牌桶，用户维度在用户连接所在节点计算，频道维度在频道领导节点计算），速率为0表示不限制，突发数量为0时等于速率
+	// 可以通过api对指定用户或频道覆盖全局配置
+	RateLimit struct {
+		UserRate         float64 // 每个用户每秒允许发送的消息数量（限制客户端连接发送的消息，用户的连接在N个节点上时总速率最多为N倍）
+		UserBurst        int     // 每个用户允许的突发数量
+		ChannelRate      float64 // 每个频道每秒允许发送的消息数量
+		ChannelBurst     int     // 每个频道允许的突发数量
+		UserChannelRate  float64 // 每个用户在每个频道每秒允许发送的消息数量
+		UserChannelBurst int     // 每个用户在每个频道允许的突发数量
+	}
+
+	// 离线推送（接收者没有主设备在线时通过APNs或FCM推送到设备），推送地址可配置方便对接代理或测试
+	Push struct {
+		On                    bool          // 是否开启离线推送
+		WorkerCount           int           // 推送协程数量
+		QueueSize             int           // 推送队列大小，队列满时丢弃
+		MaxRetry              int           // 推送失败最大重试次数
+		RetryInterval         time.Duration // 重试间隔，每次重试翻倍
+		Timeout               time.Duration // 单次推送请求超时时间
+		Title                 string        // 推送标题
+		DefaultBody           string        // 非文本消息的推送内容
+		APNsEndpoint          string        // APNs推送地址，为空使用生产环境地址
+		APNsKeyId             string        // APNs p8密钥的key id
+		APNsTeamId            string        // APNs开发者团队id
+		APNsTopic             string        // APNs应用的bundle id
+		APNsKeyFile           string        // APNs p8密钥文件路径，为空表示不开启APNs
+		FCMEndpoint           string        // FCM推送地址，为空使用默认地址
+		FCMTokenURL           string        // FCM获取访问令牌的地址，为空使用服务账号中的地址
+		FCMServiceAccountFile string        // FCM服务账号json文件路径，为空表示不开启FCM
 	}
 
 	Auth auth.AuthConfig // 认证配置
@@ -327,10 +382,23 @@ func NewOptions(op ...Option) *Options {
 			MsgNotifyEventPushInterval  time.Duration
 			MsgNotifyEventCountPerPush  int
 			MsgNotifyEventRetryMaxCount int
+			ModerationOn                bool
+			ModerationTimeout           time.Duration
+			ModerationFailOpen          bool
+			ModerationConcurrency       int
+			Secret                      string
+			RetryInterval               time.Duration
+			RetryMaxInterval            time.Duration
+			Endpoints                   []wkdb.WebhookEndpoint
 		}{
 			MsgNotifyEventPushInterval:  time.Millisecond * 500,
 			MsgNotifyEventCountPerPush:  100,
 			MsgNotifyEventRetryMaxCount: 5,
+			ModerationTimeout:           time.Second * 2,
+			ModerationFailOpen:          true,
+			ModerationConcurrency:       1000,
+			RetryInterval:               time.Second,
+			RetryMaxInterval:            time.Minute * 5,
 		},
 		Manager: struct {
 			On   bool
@@ -429,11 +497,57 @@ func NewOptions(op ...Option) *Options {
 			// DeliverWorkerCountPerNode: 10,
 		},
 		Db: struct {
-			ShardNum     int
-			SlotShardNum int
+			ShardNum            int
+			SlotShardNum        int
+			ExpireSweepInterval time.Duration
+			FullTextIndex       bool
+			MessageDedupWindow  time.Duration
 		}{
-			ShardNum:     16,
-			SlotShardNum: 16,
+			ShardNum:            16,
+			SlotShardNum:        16,
+			ExpireSweepInterval: time.Minute,
+			FullTextIndex:       true,
+			MessageDedupWindow:  time.Minute * 10,
+		},
+		Retention: struct {
+			CheckInterval time.Duration
+			ChannelTypes  map[uint8]RetentionPolicy
+		}{
+			CheckInterval: time.Minute * 10,
+			ChannelTypes:  map[uint8]RetentionPolicy{},
+		},
+		Scheduled: struct {
+			CheckInterval time.Duration
+			MaxDelay      time.Duration
+		}{
+			CheckInterval: time.Second,
+			MaxDelay:      time.Hour * 24 * 30,
+		},
+
+		Push: struct {
+			On                    bool
+			WorkerCount           int
+			QueueSize             int
+			MaxRetry              int
+			RetryInterval         time.Duration
+			Timeout               time.Duration
+			Title                 string
+			DefaultBody           string
+			APNsEndpoint          string
+			APNsKeyId             string
+			APNsTeamId            string
+			APNsTopic             string
+			APNsKeyFile           string
+			FCMEndpoint           string
+			FCMTokenURL           string
+			FCMServiceAccountFile string
+		}{
+			WorkerCount:   16,
+			QueueSize:     10240,
+			MaxRetry:      3,
+			RetryInterval: time.Second,
+			Timeout:       time.Second * 10,
+			DefaultBody:   "你收到一条新消息",
 		},
 
 		Jwt: struct {
@@ -531,6 +645,35 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Webhook.MsgNotifyEventRetryMaxCount = o.getInt("webhook.msgNotifyEventRetryMaxCount", o.Webhook.MsgNotifyEventRetryMaxCount)
 	o.Webhook.MsgNotifyEventCountPerPush = o.getInt("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush)
 	o.Webhook.MsgNotifyEventPushInterval = o.getDuration("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval)
+	o.Webhook.ModerationOn = o.getBool("webhook.moderationOn", o.Webhook.ModerationOn)
+	o.Webhook.ModerationTimeout = o.getDuration("webhook.moderationTimeout", o.Webhook.ModerationTimeout)
+	o.Webhook.ModerationFailOpen = o.getBool("webhook.moderationFailOpen", o.Webhook.ModerationFailOpen)
+	o.Webhook.ModerationConcurrency = o.getInt("webhook.moderationConcurrency", o.Webhook.ModerationConcurrency)
+	o.Webhook.Secret = o.getString("webhook.secret", o.Webhook.Secret)
+	o.Webhook.RetryInterval = o.getDuration("webhook.retryInterval", o.Webhook.RetryInterval)
+	o.Webhook.RetryMaxInterval = o.getDuration("webhook.retryMaxInterval", o.Webhook.RetryMaxInterval)
+	webhookEndpoints := o.getStringSlice("webhook.endpoints") // 格式为： name@addr@events@concurrency 例如 analytics@http://127.0.0.1:8080/webhook@msg.notify,user.onlinestatus@4
+	for _, endpointStr := range webhookEndpoints {
+		endpointStrs := strings.Split(endpointStr, "@")
+		if len(endpointStrs) < 4 {
+			wklog.Panic("webhook endpoints format error", zap.String("endpoint", endpointStr))
+		}
+		endpoint := wkdb.WebhookEndpoint{
+			Name: endpointStrs[0],
+			Addr: strings.Join(endpointStrs[1:len(endpointStrs)-2], "@"), // 地址内可能包含@
+		}
+		if eventsStr := strings.TrimSpace(endpointStrs[len(endpointStrs)-2]); eventsStr != "" && eventsStr != "*" {
+			endpoint.Events = strings.Split(eventsStr, ",")
+		}
+		if concurrencyStr := strings.TrimSpace(endpointStrs[len(endpointStrs)-1]); concurrencyStr != "" {
+			concurrency, err := strconv.Atoi(concurrencyStr)
+			if err != nil {
+				wklog.Panic("webhook endpoint concurrency format error", zap.String("endpoint", endpointStr), zap.Error(err))
+			}
+			endpoint.Concurrency = concurrency
+		}
+		o.Webhook.Endpoints = append(o.Webhook.Endpoints, endpoint)
+	}
 
 	o.EventPoolSize = o.getInt("eventPoolSize", o.EventPoolSize)
 	o.DeliveryMsgPoolSize = o.getInt("deliveryMsgPoolSize", o.DeliveryMsgPoolSize)
@@ -698,6 +841,67 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	// =================== db ===================
 	o.Db.ShardNum = o.getInt("db.shardNum", o.Db.ShardNum)
 	o.Db.SlotShardNum = o.getInt("db.slotShardNum", o.Db.SlotShardNum)
+	o.Db.ExpireSweepInterval = o.getDuration("db.expireSweepInterval", o.Db.ExpireSweepInterval)
+	o.Db.FullTextIndex = o.getBool("db.fullTextIndex", o.Db.FullTextIndex)
+	o.Db.MessageDedupWindow = o.getDuration("db.messageDedupWindow", o.Db.MessageDedupWindow)
+
+	// =================== retention ===================
+	o.Retention.CheckInterval = o.getDuration("retention.checkInterval", o.Retention.CheckInterval)
+	retentionChannelTypes := o.getStringSlice("retention.channelTypes") // 格式为： channelType@maxAge@maxCount 例如 2@720h@100000
+	for _, policyStr := range retentionChannelTypes {
+		policyStrs := strings.Split(policyStr, "@")
+		if len(policyStrs) != 3 {
+			wklog.Panic("retention channelTypes format error", zap.String("policy", policyStr))
+		}
+		channelType, err := strconv.ParseUint(policyStrs[0], 10, 8)
+		if err != nil {
+			wklog.Panic("retention channelType format error", zap.String("policy", policyStr), zap.Error(err))
+		}
+		var policy RetentionPolicy
+		if strings.TrimSpace(policyStrs[1]) != "" {
+			policy.MaxAge, err = time.ParseDuration(policyStrs[1])
+			if err != nil {
+				wklog.Panic("retention maxAge format error", zap.String("policy", policyStr), zap.Error(err))
+			}
+		}
+		if strings.TrimSpace(policyStrs[2]) != "" {
+			policy.MaxCount, err = strconv.ParseUint(policyStrs[2], 10, 32)
+			if err != nil {
+				wklog.Panic("retention maxCount format error", zap.String("policy", policyStr), zap.Error(err))
+			}
+		}
+		o.Retention.ChannelTypes[uint8(channelType)] = policy
+	}
+
+	// =================== scheduled ===================
+	o.Scheduled.CheckInterval = o.getDuration("scheduled.checkInterval", o.Scheduled.CheckInterval)
+	o.Scheduled.MaxDelay = o.getDuration("scheduled.maxDelay", o.Scheduled.MaxDelay)
+
+	// =================== rateLimit ===================
+	o.RateLimit.UserRate = o.getFloat64("rateLimit.userRate", o.RateLimit.UserRate)
+	o.RateLimit.UserBurst = o.getInt("rateLimit.userBurst", o.RateLimit.UserBurst)
+	o.RateLimit.ChannelRate = o.getFloat64("rateLimit.channelRate", o.RateLimit.ChannelRate)
+	o.RateLimit.ChannelBurst = o.getInt("rateLimit.channelBurst", o.RateLimit.ChannelBurst)
+	o.RateLimit.UserChannelRate = o.getFloat64("rateLimit.userChannelRate", o.RateLimit.UserChannelRate)
+	o.RateLimit.UserChannelBurst = o.getInt("rateLimit.userChannelBurst", o.RateLimit.UserChannelBurst)
+
+	// =================== push ===================
+	o.Push.On = o.getBool("push.on", o.Push.On)
+	o.Push.WorkerCount = o.getInt("push.workerCount", o.Push.WorkerCount)
+	o.Push.QueueSize = o.getInt("push.queueSize", o.Push.QueueSize)
+	o.Push.MaxRetry = o.getInt("push.maxRetry", o.Push.MaxRetry)
+	o.Push.RetryInterval = o.getDuration("push.retryInterval", o.Push.RetryInterval)
+	o.Push.Timeout = o.getDuration("push.timeout", o.Push.Timeout)
+	o.Push.Title = o.getString("push.title", o.Push.Title)
+	o.Push.DefaultBody = o.getString("push.defaultBody", o.Push.DefaultBody)
+	o.Push.APNsEndpoint = o.getString("push.apnsEndpoint", o.Push.APNsEndpoint)
+	o.Push.APNsKeyId = o.getString("push.apnsKeyId", o.Push.APNsKeyId)
+	o.Push.APNsTeamId = o.getString("push.apnsTeamId", o.Push.APNsTeamId)
+	o.Push.APNsTopic = o.getString("push.apnsTopic", o.Push.APNsTopic)
+	o.Push.APNsKeyFile = o.getString("push.apnsKeyFile", o.Push.APNsKeyFile)
+	o.Push.FCMEndpoint = o.getString("push.fcmEndpoint", o.Push.FCMEndpoint)
+	o.Push.FCMTokenURL = o.getString("push.fcmTokenURL", o.Push.FCMTokenURL)
+	o.Push.FCMServiceAccountFile = o.getString("push.fcmServiceAccountFile", o.Push.FCMServiceAccountFile)
 
 	// =================== auth ===================
 	o.configureAuth()
@@ -943,6 +1147,14 @@ func (o *Options) getInt64(key string, defaultValue int64) int64 {
 	return v
 }
 
+func (o *Options) getFloat64(key string, defaultValue float64) float64 {
+	v := o.vp.GetFloat64(key)
+	if v == 0 {
+		return defaultValue
+	}
+	return v
+}
+
 func (o *Options) getDuration(key string, defaultValue time.Duration) time.Duration {
 	v := o.vp.GetDuration(key)
 	if v == 0 {
@@ -1044,6 +1256,17 @@ type Node struct {
 	ServerAddr string
 }
 
+// RetentionPolicy 消息保留策略，0表示不限制
+type RetentionPolicy struct {
+	MaxAge   time.Duration // 消息最长保留时间
+	MaxCount uint64        // 消息最多保留数量
+}
+
+// IsEmpty 是否没有任何限制
+func (r RetentionPolicy) IsEmpty() bool {
+	return r.MaxAge <= 0 && r.MaxCount == 0
+}
+
 type Option func(opts *Options)
 
 func WithMode(mode Mode) Option {
@@ -1377,6 +1600,49 @@ func WithWebhookMsgNotifyEventRetryMaxCount(retryMaxCount int) Option {
 	}
 }
 
+func WithWebhookModerationOn(moderationOn bool) Option {
+	return func(opts *Options) {
+		opts.Webhook.ModerationOn = moderationOn
+	}
+}
+
+func WithWebhookModerationTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.Webhook.ModerationTimeout = timeout
+	}
+}
+
+func WithWebhookModerationFailOpen(failOpen bool) Option {
+	return func(opts *Options) {
+		opts.Webhook.ModerationFailOpen = failOpen
+	}
+}
+
+func WithWebhookModerationConcurrency(concurrency int) Option {
+	return func(opts *Options) {
+		opts.Webhook.ModerationConcurrency = concurrency
+	}
+}
+
+func WithWebhookSecret(secret string) Option {
+	return func(opts *Options) {
+		opts.Webhook.Secret = secret
+	}
+}
+
+func WithWebhookEndpoints(endpoints ...wkdb.WebhookEndpoint) Option {
+	return func(opts *Options) {
+		opts.Webhook.Endpoints = endpoints
+	}
+}
+
+func WithWebhookRetryInterval(retryInterval, retryMaxInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Webhook.RetryInterval = retryInterval
+		opts.Webhook.RetryMaxInterval = retryMaxInterval
+	}
+}
+
 func WithClusterNodeId(nodeId uint64) Option {
 	return func(opts *Options) {
 		opts.Cluster.NodeId = nodeId
@@ -1558,6 +1824,100 @@ func WithDbSlotShardNum(slotShardNum int) Option {
 	}
 }
 
+func WithDbExpireSweepInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Db.ExpireSweepInterval = interval
+	}
+}
+
+func WithDbFullTextIndex(fullTextIndex bool) Option {
+	return func(opts *Options) {
+		opts.Db.FullTextIndex = fullTextIndex
+	}
+}
+
+func WithDbMessageDedupWindow(window time.Duration) Option {
+	return func(opts *Options) {
+		opts.Db.MessageDedupWindow = window
+	}
+}
+
+func WithRetentionCheckInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Retention.CheckInterval = interval
+	}
+}
+
+func WithRetentionChannelType(channelType uint8, policy RetentionPolicy) Option {
+	return func(opts *Options) {
+		opts.Retention.ChannelTypes[channelType] = policy
+	}
+}
+
+func WithScheduledCheckInterval(interval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Scheduled.CheckInterval = interval
+	}
+}
+
+func WithScheduledMaxDelay(maxDelay time.Duration) Option {
+	return func(opts *Options) {
+		opts.Scheduled.MaxDelay = maxDelay
+	}
+}
+
+func WithRateLimitUser(rate float64, burst int) Option {
+	return func(opts *Options) {
+		opts.RateLimit.UserRate = rate
+		opts.RateLimit.UserBurst = burst
+	}
+}
+
+func WithRateLimitChannel(rate float64, burst int) Option {
+	return func(opts *Options) {
+		opts.RateLimit.ChannelRate = rate
+		opts.RateLimit.ChannelBurst = burst
+	}
+}
+
+func WithRateLimitUserChannel(rate float64, burst int) Option {
+	return func(opts *Options) {
+		opts.RateLimit.UserChannelRate = rate
+		opts.RateLimit.UserChannelBurst = burst
+	}
+}
+
+func WithPushOn(on bool) Option {
+	return func(opts *Options) {
+		opts.Push.On = on
+	}
+}
+
+func WithPushRetry(maxRetry int, retryInterval time.Duration) Option {
+	return func(opts *Options) {
+		opts.Push.MaxRetry = maxRetry
+		opts.Push.RetryInterval = retryInterval
+	}
+}
+
+func WithPushAPNs(endpoint, keyId, teamId, topic, keyFile string) Option {
+	return func(opts *Options) {
+		opts.Push.APNsEndpoint = endpoint
+		opts.Push.APNsKeyId = keyId
+		opts.Push.APNsTeamId = teamId
+		opts.Push.APNsTopic = topic
+		opts.Push.APNsKeyFile = keyFile
+	}
+}
+
+func WithPushFCM(endpoint, tokenURL, serviceAccountFile string) Option {
+	return func(opts *Options) {
+		opts.Push.FCMEndpoint = endpoint
+		opts.Push.FCMTokenURL = tokenURL
+		opts.Push.FCMServiceAccountFile = serviceAccountFile
+	}
+}
+
 func WithOpts(opt ...Option) Option {
 	return func(opts *Options) {
 		for _, o := range opt {
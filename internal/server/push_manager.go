@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkpush"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+)
+
+// pushReq 推送请求（一条消息推送给一个离线用户）
+type pushReq struct {
+	uid     string
+	message ReactorChannelMessage
+}
+
+// pushManager 离线推送管理
+// 投递时没有主设备在线的用户会在本节点（用户的槽领导节点）推送到用户注册了推送令牌的设备，
+// 会话开启了免打扰的不推送，推送失败按间隔翻倍重试，令牌无效时删除令牌
+type pushManager struct {
+	s         *Server
+	providers map[uint8]wkpush.Provider // key为推送类型
+	reqC      chan *pushReq
+	stopper   *syncutil.Stopper
+	wklog.Log
+}
+
+func newPushManager(s *Server) *pushManager {
+	return &pushManager{
+		s:         s,
+		providers: make(map[uint8]wkpush.Provider),
+		stopper:   syncutil.NewStopper(),
+		Log:       wklog.NewWKLog("pushManager"),
+	}
+}
+
+func (p *pushManager) start() error {
+	if !p.s.opts.Push.On {
+		return nil
+	}
+	opts := p.s.opts.Push
+	if opts.APNsKeyFile != "" {
+		privateKey, err := os.ReadFile(opts.APNsKeyFile)
+		if err != nil {
+			return err
+		}
+		provider, err := wkpush.NewAPNsProvider(wkpush.APNsOptions{
+			Endpoint:   opts.APNsEndpoint,
+			KeyId:      opts.APNsKeyId,
+			TeamId:     opts.APNsTeamId,
+			Topic:      opts.APNsTopic,
+			PrivateKey: privateKey,
+		})
+		if err != nil {
+			return err
+		}
+		p.providers[wkdb.PushTypeAPNs] = provider
+	}
+	if opts.FCMServiceAccountFile != "" {
+		fcmOpts, err := wkpush.LoadFCMServiceAccount(opts.FCMServiceAccountFile)
+		if err != nil {
+			return err
+		}
+		fcmOpts.Endpoint = opts.FCMEndpoint
+		if opts.FCMTokenURL != "" {
+			fcmOpts.TokenURL = opts.FCMTokenURL
+		}
+		provider, err := wkpush.NewFCMProvider(fcmOpts)
+		if err != nil {
+			return err
+		}
+		p.providers[wkdb.PushTypeFCM] = provider
+	}
+	if len(p.providers) == 0 {
+		p.Warn("push is on, but no provider is configured")
+	}
+
+	p.reqC = make(chan *pushReq, opts.QueueSize)
+	for i := 0; i < opts.WorkerCount; i++ {
+		p.stopper.RunWorker(p.loop)
+	}
+	return nil
+}
+
+func (p *pushManager) stop() {
+	p.stopper.Stop()
+}
+
+// push 推送消息给离线用户，队列满时丢弃
+func (p *pushManager) push(message ReactorChannelMessage, uids []string) {
+	if !p.s.opts.Push.On || len(p.providers) == 0 {
+		return
+	}
+	if message.SendPacket.NoPersist || message.SendPacket.SyncOnce { // 不存储的消息和命令消息不推送
+		return
+	}
+	for _, uid := range uids {
+		if uid == message.FromUid || uid == p.s.opts.SystemUID {
+			continue
+		}
+		select {
+		case p.reqC <- &pushReq{uid: uid, message: message}:
+		default:
+			p.Warn("push queue is full, discard", zap.String("uid", uid), zap.Int64("messageId", message.MessageId))
+			trace.GlobalTrace.Metrics.App().PushFailedCountAdd(1)
+		}
+	}
+}
+
+func (p *pushManager) loop() {
+	for {
+		select {
+		case req := <-p.reqC:
+			p.handleReq(req)
+		case <-p.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (p *pushManager) handleReq(req *pushReq) {
+	sendPacket := req.message.SendPacket
+
+	// 接收者看到的频道，个人频道为发送者
+	channelId := sendPacket.ChannelID
+	if sendPacket.ChannelType == wkproto.ChannelTypePerson && channelId == req.uid {
+		channelId = req.message.FromUid
+	}
+
+	_, err := p.s.store.GetConversationMute(req.uid, channelId, sendPacket.ChannelType)
+	if err == nil { // 会话免打扰
+		trace.GlobalTrace.Metrics.App().PushMutedCountAdd(1)
+		return
+	}
+	if err != wkdb.ErrNotFound {
+		p.Error("get conversation mute failed", zap.Error(err), zap.String("uid", req.uid), zap.String("channelId", channelId))
+		return
+	}
+
+	devicePushes, err := p.s.store.GetDevicePushes(req.uid)
+	if err != nil {
+		p.Error("get device pushes failed", zap.Error(err), zap.String("uid", req.uid))
+		return
+	}
+	if len(devicePushes) == 0 {
+		return
+	}
+
+	data := map[string]string{
+		"channel_id":   channelId,
+		"channel_type": strconv.Itoa(int(sendPacket.ChannelType)),
+		"message_id":   strconv.FormatInt(req.message.MessageId, 10),
+		"message_seq":  strconv.FormatUint(uint64(req.message.MessageSeq), 10),
+		"from_uid":     req.message.FromUid,
+	}
+	body := p.notificationBody(sendPacket.Payload)
+	for _, devicePush := range devicePushes {
+		provider := p.providers[devicePush.PushType]
+		if provider == nil {
+			continue
+		}
+		p.pushWithRetry(provider, devicePush, &wkpush.Notification{
+			Token: devicePush.PushToken,
+			Title: p.s.opts.Push.Title,
+			Body:  body,
+			Sound: "default",
+			Data:  data,
+		})
+	}
+}
+
+func (p *pushManager) pushWithRetry(provider wkpush.Provider, devicePush wkdb.DevicePush, notification *wkpush.Notification) {
+	retryInterval := p.s.opts.Push.RetryInterval
+	for i := 0; ; i++ {
+		ctx, cancel := context.WithTimeout(p.s.ctx, p.s.opts.Push.Timeout)
+		err := provider.Push(ctx, notification)
+		cancel()
+		if err == nil {
+			trace.GlobalTrace.Metrics.App().PushSuccessCountAdd(1)
+			return
+		}
+		if errors.Is(err, wkpush.ErrInvalidToken) {
+			p.Info("push token is invalid, remove it", zap.String("provider", provider.Name()), zap.String("uid", devicePush.Uid), zap.Uint64("deviceFlag", devicePush.DeviceFlag))
+			trace.GlobalTrace.Metrics.App().PushFailedCountAdd(1)
+			p.removeInvalidToken(devicePush)
+			return
+		}
+		if i >= p.s.opts.Push.MaxRetry || !wkpush.IsRetryable(err) {
+			p.Warn("push failed", zap.Error(err), zap.String("provider", provider.Name()), zap.String("uid", devicePush.Uid), zap.Uint64("deviceFlag", devicePush.DeviceFlag), zap.Int("retry", i))
+			trace.GlobalTrace.Metrics.App().PushFailedCountAdd(1)
+			return
+		}
+		trace.GlobalTrace.Metrics.App().PushRetryCountAdd(1)
+		select {
+		case <-time.After(retryInterval):
+		case <-p.stopper.ShouldStop():
+			return
+		}
+		retryInterval *= 2
+	}
+}
+
+// removeInvalidToken 删除无效的推送令牌，推送期间令牌已更新的不删除
+func (p *pushManager) removeInvalidToken(devicePush wkdb.DevicePush) {
+	devicePushes, err := p.s.store.GetDevicePushes(devicePush.Uid)
+	if err != nil {
+		p.Error("get device pushes failed", zap.Error(err), zap.String("uid", devicePush.Uid))
+		return
+	}
+	for _, current := range devicePushes {
+		if current.DeviceFlag == devicePush.DeviceFlag && current.PushToken == devicePush.PushToken {
+			if err = p.s.store.RemoveDevicePush(devicePush.Uid, devicePush.DeviceFlag); err != nil {
+				p.Error("remove device push failed", zap.Error(err), zap.String("uid", devicePush.Uid))
+			}
+			return
+		}
+	}
+}
+
+// notificationBody 文本消息推送消息内容，其他消息推送默认内容
+func (p *pushManager) notificationBody(payload []byte) string {
+	var content struct {
+		Type    int    `json:"type"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(payload, &content); err == nil && content.Type == 1 && content.Content != "" {
+		return content.Content
+	}
+	return p.s.opts.Push.DefaultBody
+}
@@ -0,0 +1,390 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	rateLimitTargetUser    uint8 = 1 // 用户规则
+	rateLimitTargetChannel uint8 = 2 // 频道规则
+
+	rateLimitInherit = -1 // 规则中的速率或突发数量为-1时使用全局配置
+
+	rateLimitCleanInterval  = time.Minute // 清理已恢复满的令牌桶的间隔
+	rateLimitReloadInterval = time.Minute // 重新加载规则的间隔（没有收到刷新通知的节点最多延迟这么久生效）
+)
+
+// rateLimitManager 消息发送频率限制
+// 用户维度的令牌桶在用户连接所在的节点上发送前计算（用户往多个频道发送共用一个令牌桶），
+// 频道和频道内用户维度的令牌桶在频道领导节点的权限检查阶段计算，
+// 规则覆盖通过系统数据所在槽的raft存储，节点从槽领导节点加载，通过api修改后会通知所有节点重新加载
+type rateLimitManager struct {
+	s           *Server
+	mu          sync.Mutex // 规则加载锁
+	rules       atomic.Pointer[rateLimitRules]
+	bucketsMu   sync.Mutex
+	buckets     map[string]*tokenBucket
+	cleanTimer  *timingwheel.Timer
+	reloadTimer *timingwheel.Timer
+	wklog.Log
+}
+
+func newRateLimitManager(s *Server) *rateLimitManager {
+	rm := &rateLimitManager{
+		s:       s,
+		buckets: make(map[string]*tokenBucket),
+		Log:     wklog.NewWKLog("rateLimitManager"),
+	}
+	rm.rules.Store(&rateLimitRules{
+		users:    make(map[string]wkdb.RateLimit),
+		channels: make(map[string]wkdb.RateLimit),
+	})
+	return rm
+}
+
+func (rm *rateLimitManager) start() error {
+	// 启动时槽领导可能还没有选出，加载失败时由定时任务重试
+	if err := rm.reload(); err != nil {
+		rm.Warn("load rate limits failed, retry later", zap.Error(err))
+	}
+	rm.cleanTimer = rm.s.Schedule(rateLimitCleanInterval, rm.cleanBuckets)
+	rm.reloadTimer = rm.s.Schedule(rateLimitReloadInterval, func() {
+		if err := rm.reload(); err != nil {
+			rm.Warn("reload rate limits failed", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+func (rm *rateLimitManager) stop() {
+	if rm.cleanTimer != nil {
+		rm.cleanTimer.Stop()
+	}
+	if rm.reloadTimer != nil {
+		rm.reloadTimer.Stop()
+	}
+}
+
+// reload 从系统数据所在槽的领导节点重新加载规则
+func (rm *rateLimitManager) reload() error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	limits, err := rm.load()
+	if err != nil {
+		return err
+	}
+	rules := &rateLimitRules{
+		users:    make(map[string]wkdb.RateLimit),
+		channels: make(map[string]wkdb.RateLimit),
+	}
+	for _, limit := range limits {
+		switch limit.TargetType {
+		case rateLimitTargetUser:
+			rules.users[limit.Target] = limit
+		case rateLimitTargetChannel:
+			rules.channels[wkutil.ChannelToKey(limit.Target, limit.ChannelType)] = limit
+		}
+	}
+	rm.rules.Store(rules)
+	return nil
+}
+
+// load 从系统数据所在槽的领导节点获取规则
+func (rm *rateLimitManager) load() ([]wkdb.RateLimit, error) {
+	if rm.s.opts.ClusterOn() {
+		leaderId, err := rm.s.cluster.SlotLeaderIdOfChannel(clusterstore.SystemDataKey, wkproto.ChannelTypePerson)
+		if err != nil {
+			return nil, err
+		}
+		if leaderId != rm.s.opts.Cluster.NodeId {
+			data, err := rm.s.requestNode(leaderId, "/wk/rateLimitList", nil)
+			if err != nil {
+				return nil, err
+			}
+			resp := &rateLimitResp{}
+			if err = resp.Unmarshal(data); err != nil {
+				return nil, err
+			}
+			return resp.limits, nil
+		}
+	}
+	return rm.s.store.GetRateLimits()
+}
+
+// allowUserSend 用户连接所在节点发送消息前判断用户维度的发送频率
+func (rm *rateLimitManager) allowUserSend(uid string) bool {
+	opts := rm.s.opts
+	rules := rm.rules.Load()
+	if len(rules.users) == 0 && opts.RateLimit.UserRate <= 0 {
+		return true
+	}
+	if uid == opts.SystemUID || rm.s.systemUIDManager.SystemUID(uid) { // 系统账号不限制
+		return true
+	}
+	userLimit := newRateLimit(opts.RateLimit.UserRate, opts.RateLimit.UserBurst)
+	if limit, ok := rules.users[uid]; ok {
+		userLimit = overrideRateLimit(opts.RateLimit.UserRate, opts.RateLimit.UserBurst, limit.Rate, limit.Burst)
+	}
+
+	rm.bucketsMu.Lock()
+	b := rm.bucket("u:"+uid, userLimit, time.Now())
+	allow := b == nil || b.tokens >= 1
+	if allow && b != nil {
+		b.tokens--
+	}
+	rm.bucketsMu.Unlock()
+
+	if !allow {
+		trace.GlobalTrace.Metrics.App().MessageRateLimitedCountAdd(1)
+		rm.Debug("user send rate limited", zap.String("uid", uid))
+	}
+	return allow
+}
+
+// limitMessages 频道领导节点按频道和频道内用户的发送频率过滤消息，返回超出频率的消息（设置了ReasonCode）和允许发送的消息
+func (rm *rateLimitManager) limitMessages(ch *channel, messages []ReactorChannelMessage) ([]ReactorChannelMessage, []ReactorChannelMessage) {
+	opts := rm.s.opts
+	rules := rm.rules.Load()
+
+	channelKey := wkutil.ChannelToKey(ch.channelId, ch.channelType)
+	channelLimit := newRateLimit(opts.RateLimit.ChannelRate, opts.RateLimit.ChannelBurst)
+	memberLimit := newRateLimit(opts.RateLimit.UserChannelRate, opts.RateLimit.UserChannelBurst)
+	if limit, ok := rules.channels[channelKey]; ok {
+		channelLimit = overrideRateLimit(opts.RateLimit.ChannelRate, opts.RateLimit.ChannelBurst, limit.Rate, limit.Burst)
+		memberLimit = overrideRateLimit(opts.RateLimit.UserChannelRate, opts.RateLimit.UserChannelBurst, limit.MemberRate, limit.MemberBurst)
+	}
+	if channelLimit.unlimited() && memberLimit.unlimited() {
+		return nil, messages
+	}
+
+	var (
+		limitedMsgs []ReactorChannelMessage
+		passedMsgs  = make([]ReactorChannelMessage, 0, len(messages))
+		now         = time.Now()
+	)
+	rm.bucketsMu.Lock()
+	for _, msg := range messages {
+		if msg.FromUid == opts.SystemUID || rm.s.systemUIDManager.SystemUID(msg.FromUid) { // 系统账号不限制
+			passedMsgs = append(passedMsgs, msg)
+			continue
+		}
+
+		// 所有维度都有令牌才消耗，避免被拒绝的消息消耗其他维度的令牌
+		buckets := [2]*tokenBucket{
+			rm.bucket("c:"+channelKey, channelLimit, now),
+			rm.bucket("m:"+channelKey+"|"+msg.FromUid, memberLimit, now),
+		}
+		allow := true
+		for _, b := range buckets {
+			if b != nil && b.tokens < 1 {
+				allow = false
+				break
+			}
+		}
+		if !allow {
+			msg.ReasonCode = wkproto.ReasonRateLimit
+			limitedMsgs = append(limitedMsgs, msg)
+			continue
+		}
+		for _, b := range buckets {
+			if b != nil {
+				b.tokens--
+			}
+		}
+		passedMsgs = append(passedMsgs, msg)
+	}
+	rm.bucketsMu.Unlock()
+
+	if len(limitedMsgs) > 0 {
+		trace.GlobalTrace.Metrics.App().MessageRateLimitedCountAdd(int64(len(limitedMsgs)))
+		rm.Debug("messages rate limited", zap.String("channelId", ch.channelId), zap.Uint8("channelType", ch.channelType), zap.Int("count", len(limitedMsgs)))
+	}
+	return limitedMsgs, passedMsgs
+}
+
+// bucket 获取令牌桶并补充令牌，不限制时返回nil
+func (rm *rateLimitManager) bucket(key string, limit rateLimit, now time.Time) *tokenBucket {
+	if limit.unlimited() {
+		return nil
+	}
+	b := rm.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: limit.burst, last: now}
+		rm.buckets[key] = b
+	}
+	b.rate = limit.rate
+	b.burst = limit.burst
+	b.refill(now)
+	return b
+}
+
+// cleanBuckets 清理已恢复满的令牌桶（满的令牌桶和新建的等价）
+func (rm *rateLimitManager) cleanBuckets() {
+	now := time.Now()
+	rm.bucketsMu.Lock()
+	defer rm.bucketsMu.Unlock()
+	for key, b := range rm.buckets {
+		b.refill(now)
+		if b.tokens >= b.burst {
+			delete(rm.buckets, key)
+		}
+	}
+}
+
+type rateLimitRules struct {
+	users    map[string]wkdb.RateLimit // key为用户uid
+	channels map[string]wkdb.RateLimit // key为频道key
+}
+
+type rateLimit struct {
+	rate  float64 // 每秒令牌数
+	burst float64 // 令牌桶容量
+}
+
+func newRateLimit(rate float64, burst int) rateLimit {
+	l := rateLimit{rate: rate, burst: float64(burst)}
+	if l.burst <= 0 {
+		l.burst = math.Max(1, math.Ceil(rate))
+	}
+	return l
+}
+
+// overrideRateLimit 规则覆盖全局配置，规则中为rateLimitInherit的字段使用全局配置，速率为0表示不限制
+func overrideRateLimit(rate float64, burst int, overrideRate float64, overrideBurst int) rateLimit {
+	if overrideRate != rateLimitInherit {
+		rate = overrideRate
+	}
+	if overrideBurst != rateLimitInherit {
+		burst = overrideBurst
+	}
+	return newRateLimit(rate, burst)
+}
+
+func (l rateLimit) unlimited() bool {
+	return l.rate <= 0
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+	if b.tokens > b.burst { // 规则修改后容量可能变小
+		b.tokens = b.burst
+	}
+}
+
+// rateLimitResp 槽领导节点返回的频率限制规则
+type rateLimitResp struct {
+	limits []wkdb.RateLimit
+}
+
+func (r *rateLimitResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.limits)))
+	for _, limit := range r.limits {
+		encodeRateLimit(enc, limit)
+	}
+	return enc.Bytes()
+}
+
+func (r *rateLimitResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		limit, err := decodeRateLimit(dec)
+		if err != nil {
+			return err
+		}
+		r.limits = append(r.limits, limit)
+	}
+	return nil
+}
+
+func encodeRateLimit(enc *wkproto.Encoder, limit wkdb.RateLimit) {
+	enc.WriteUint8(limit.TargetType)
+	enc.WriteString(limit.Target)
+	enc.WriteUint8(limit.ChannelType)
+	enc.WriteUint64(math.Float64bits(limit.Rate))
+	enc.WriteUint32(uint32(limit.Burst))
+	enc.WriteUint64(math.Float64bits(limit.MemberRate))
+	enc.WriteUint32(uint32(limit.MemberBurst))
+}
+
+func decodeRateLimit(dec *wkproto.Decoder) (wkdb.RateLimit, error) {
+	var (
+		limit wkdb.RateLimit
+		err   error
+		v64   uint64
+		v32   uint32
+	)
+	if limit.TargetType, err = dec.Uint8(); err != nil {
+		return limit, err
+	}
+	if limit.Target, err = dec.String(); err != nil {
+		return limit, err
+	}
+	if limit.ChannelType, err = dec.Uint8(); err != nil {
+		return limit, err
+	}
+	if v64, err = dec.Uint64(); err != nil {
+		return limit, err
+	}
+	limit.Rate = math.Float64frombits(v64)
+	if v32, err = dec.Uint32(); err != nil {
+		return limit, err
+	}
+	limit.Burst = int(int32(v32))
+	if v64, err = dec.Uint64(); err != nil {
+		return limit, err
+	}
+	limit.MemberRate = math.Float64frombits(v64)
+	if v32, err = dec.Uint32(); err != nil {
+		return limit, err
+	}
+	limit.MemberBurst = int(int32(v32))
+	return limit, nil
+}
+
+// checkRateLimit 校验频率限制规则
+func checkRateLimit(limit wkdb.RateLimit) error {
+	if limit.TargetType != rateLimitTargetUser && limit.TargetType != rateLimitTargetChannel {
+		return errors.New("target_type不支持！")
+	}
+	if strings.TrimSpace(limit.Target) == "" {
+		return errors.New("uid或channel_id不能为空！")
+	}
+	if (limit.Rate < 0 && limit.Rate != rateLimitInherit) || (limit.MemberRate < 0 && limit.MemberRate != rateLimitInherit) ||
+		(limit.Burst < 0 && limit.Burst != rateLimitInherit) || (limit.MemberBurst < 0 && limit.MemberBurst != rateLimitInherit) {
+		return errors.New("速率和突发数量不能为负数（-1表示使用全局配置）！")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+// 用户往多个频道发送共用一个令牌桶
+func TestRateLimitUserSendAcrossChannels(t *testing.T) {
+	s := NewTestServer(t, WithRateLimitUser(1, 2))
+	rm := s.rateLimitManager
+
+	assert.True(t, rm.allowUserSend("u1"))
+	assert.True(t, rm.allowUserSend("u1"))
+	assert.False(t, rm.allowUserSend("u1"))
+	assert.True(t, rm.allowUserSend("u2"))
+
+	// 频道领导节点不再计算用户维度
+	ch := newChannel(s.channelReactor.reactorSub("g1"), "g1", wkproto.ChannelTypeGroup)
+	limitedMsgs, passedMsgs := rm.limitMessages(ch, []ReactorChannelMessage{{MessageId: 1, FromUid: "u1"}})
+	assert.Equal(t, 0, len(limitedMsgs))
+	assert.Equal(t, 1, len(passedMsgs))
+}
+
+func TestRateLimitOverrideInherit(t *testing.T) {
+	s := NewTestServer(t, WithRateLimitUser(1, 1))
+	rm := s.rateLimitManager
+	rm.rules.Store(&rateLimitRules{
+		users: map[string]wkdb.RateLimit{
+			"inherit":   {TargetType: rateLimitTargetUser, Target: "inherit", Rate: rateLimitInherit, Burst: 2},
+			"unlimited": {TargetType: rateLimitTargetUser, Target: "unlimited", Rate: 0, Burst: rateLimitInherit},
+		},
+		channels: map[string]wkdb.RateLimit{},
+	})
+
+	// 速率使用全局配置，突发数量使用规则
+	assert.True(t, rm.allowUserSend("inherit"))
+	assert.True(t, rm.allowUserSend("inherit"))
+	assert.False(t, rm.allowUserSend("inherit"))
+
+	// 速率为0表示不限制
+	for i := 0; i < 10; i++ {
+		assert.True(t, rm.allowUserSend("unlimited"))
+	}
+}
+
+func TestRateLimitRespInherit(t *testing.T) {
+	limit := wkdb.RateLimit{TargetType: rateLimitTargetChannel, Target: "g1", ChannelType: wkproto.ChannelTypeGroup, Rate: rateLimitInherit, Burst: rateLimitInherit, MemberRate: 1, MemberBurst: rateLimitInherit}
+	resp := &rateLimitResp{limits: []wkdb.RateLimit{limit}}
+
+	newResp := &rateLimitResp{}
+	err := newResp.Unmarshal(resp.Marshal())
+	assert.Nil(t, err)
+	assert.Equal(t, []wkdb.RateLimit{limit}, newResp.limits)
+
+	assert.Nil(t, checkRateLimit(limit))
+	limit.Burst = -2
+	assert.NotNil(t, checkRateLimit(limit))
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	retentionLoadLimit       = 1000 // 按时间查找清理位置时每次加载的消息数量
+	retentionConfigLoadLimit = 1000 // 遍历频道分布式配置时每次加载的数量
+)
+
+// retentionManager 消息保留策略管理，槽领导节点定时检查频道的保留策略，由频道领导节点清理超出保留策略的消息
+type retentionManager struct {
+	s          *Server
+	checkTimer *timingwheel.Timer
+	checking   atomic.Bool // 是否正在检查
+	wklog.Log
+}
+
+func newRetentionManager(s *Server) *retentionManager {
+	return &retentionManager{
+		s:   s,
+		Log: wklog.NewWKLog("retentionManager"),
+	}
+}
+
+func (r *retentionManager) start() error {
+	if r.s.opts.Retention.CheckInterval <= 0 {
+		return nil
+	}
+	r.checkTimer = r.s.Schedule(r.s.opts.Retention.CheckInterval, r.check)
+	return nil
+}
+
+func (r *retentionManager) stop() {
+	if r.checkTimer != nil {
+		r.checkTimer.Stop()
+	}
+}
+
+func (r *retentionManager) check() {
+	if !r.checking.CompareAndSwap(false, true) { // 上一次检查还未结束
+		return
+	}
+	defer r.checking.Store(false)
+
+	// 通过本节点存储的频道分布式配置遍历频道，每个频道由其所属槽的领导节点负责检查
+	var offsetId uint64
+	for {
+		cfgs, err := r.s.store.DB().GetChannelClusterConfigs(offsetId, retentionConfigLoadLimit)
+		if err != nil {
+			r.Error("get channel cluster configs failed", zap.Error(err))
+			return
+		}
+		for _, cfg := range cfgs {
+			r.checkChannel(cfg)
+		}
+		if len(cfgs) < retentionConfigLoadLimit {
+			return
+		}
+		offsetId = cfgs[len(cfgs)-1].Id
+	}
+}
+
+// checkChannel 检查频道的保留策略，频道的消息在频道领导节点上清理
+func (r *retentionManager) checkChannel(cfg wkdb.ChannelClusterConfig) {
+	if cfg.LeaderId == 0 {
+		return
+	}
+	isSlotLeader, err := r.s.cluster.IsSlotLeaderOfChannel(cfg.ChannelId, cfg.ChannelType)
+	if err != nil {
+		r.Warn("check slot leader failed", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType))
+		return
+	}
+	if !isSlotLeader {
+		return
+	}
+	info, err := r.s.store.GetChannel(cfg.ChannelId, cfg.ChannelType)
+	if err != nil {
+		r.Error("get channel failed", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType))
+		return
+	}
+	policy := r.policyOf(cfg.ChannelType, info)
+	if policy.IsEmpty() {
+		return
+	}
+	if cfg.LeaderId == r.s.opts.Cluster.NodeId {
+		err = r.applyPolicy(cfg.ChannelId, cfg.ChannelType, policy)
+	} else {
+		req := &retentionReq{
+			channelId:   cfg.ChannelId,
+			channelType: cfg.ChannelType,
+			policy:      policy,
+		}
+		_, err = r.s.requestNode(cfg.LeaderId, "/wk/retention", req.Marshal())
+	}
+	if err != nil {
+		r.Error("apply retention policy failed", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType), zap.Uint64("leaderId", cfg.LeaderId))
+	}
+}
+
+// policyOf 获取频道的保留策略，频道自身没有设置时使用频道类型的保留策略
+func (r *retentionManager) policyOf(channelType uint8, info wkdb.ChannelInfo) RetentionPolicy {
+	if info.RetentionMaxAge > 0 || info.RetentionMaxCount > 0 {
+		return RetentionPolicy{
+			MaxAge:   time.Duration(info.RetentionMaxAge) * time.Second,
+			MaxCount: uint64(info.RetentionMaxCount),
+		}
+	}
+	return r.s.opts.Retention.ChannelTypes[channelType]
+}
+
+// applyPolicy 按保留策略清理频道消息
+func (r *retentionManager) applyPolicy(channelId string, channelType uint8, policy RetentionPolicy) error {
+	lastSeq, err := r.s.store.GetLastMsgSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if lastSeq == 0 {
+		return nil
+	}
+	firstSeq, err := r.s.store.GetChannelFirstMessageSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+
+	var deleteBeforeSeq uint64 // 删除此序号之前的消息
+	if policy.MaxCount > 0 && lastSeq > policy.MaxCount {
+		deleteBeforeSeq = lastSeq - policy.MaxCount + 1
+	}
+	if policy.MaxAge > 0 {
+		seq, err := r.firstSeqAfter(channelId, channelType, firstSeq, time.Now().Add(-policy.MaxAge))
+		if err != nil {
+			return err
+		}
+		if seq > deleteBeforeSeq {
+			deleteBeforeSeq = seq
+		}
+	}
+	// 只清理所有副本都已存储的消息，落后的副本还需要从领导同步这些日志
+	syncedIndex := r.s.cluster.ChannelSyncedIndex(channelId, channelType)
+	if deleteBeforeSeq > syncedIndex+1 {
+		deleteBeforeSeq = syncedIndex + 1
+	}
+	if deleteBeforeSeq <= 1 || deleteBeforeSeq <= firstSeq {
+		return nil
+	}
+
+	r.Info("delete messages by retention policy", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("deleteBeforeSeq", deleteBeforeSeq))
+	return r.s.store.DeleteMessagesBefore(channelId, channelType, deleteBeforeSeq)
+}
+
+// firstSeqAfter 查找第一条发送时间不早于t的消息序号，所有消息都早于t时返回最后一条消息的序号
+func (r *retentionManager) firstSeqAfter(channelId string, channelType uint8, firstSeq uint64, t time.Time) (uint64, error) {
+	startSeq := firstSeq
+	if startSeq == 0 {
+		startSeq = 1
+	}
+	var lastSeq uint64
+	for {
+		messages, err := r.s.store.LoadNextRangeMsgs(channelId, channelType, startSeq, 0, retentionLoadLimit)
+		if err != nil {
+			return 0, err
+		}
+		for _, m := range messages {
+			if int64(m.Timestamp) >= t.Unix() {
+				return uint64(m.MessageSeq), nil
+			}
+			lastSeq = uint64(m.MessageSeq)
+		}
+		if len(messages) < retentionLoadLimit {
+			break
+		}
+		startSeq = lastSeq + 1
+	}
+	return lastSeq, nil
+}
+
+func (s *Server) handleRetention(c *wkserver.Context) {
+	req := &retentionReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleRetention Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if err := s.retentionManager.applyPolicy(req.channelId, req.channelType, req.policy); err != nil {
+		s.Error("apply retention policy failed", zap.Error(err), zap.String("channelId", req.channelId), zap.Uint8("channelType", req.channelType))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+type retentionReq struct {
+	channelId   string
+	channelType uint8
+	policy      RetentionPolicy
+}
+
+func (r *retentionReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.channelId)
+	enc.WriteUint8(r.channelType)
+	enc.WriteInt64(int64(r.policy.MaxAge))
+	enc.WriteUint64(r.policy.MaxCount)
+	return enc.Bytes()
+}
+
+func (r *retentionReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	maxAge, err := dec.Int64()
+	if err != nil {
+		return err
+	}
+	r.policy.MaxAge = time.Duration(maxAge)
+	if r.policy.MaxCount, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
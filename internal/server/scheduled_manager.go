@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	scheduledLoadLimit      = 1000             // 每次检查最多处理的到期定时消息数量
+	scheduledSendackTimeout = time.Second * 10 // 等待定时消息发送回执的超时时间
+)
+
+// scheduledManager 定时消息管理，由频道所属槽的领导节点发送到期的定时消息
+type scheduledManager struct {
+	s          *Server
+	checkTimer *timingwheel.Timer
+	checking   atomic.Bool // 是否正在检查
+	wklog.Log
+}
+
+func newScheduledManager(s *Server) *scheduledManager {
+	return &scheduledManager{
+		s:   s,
+		Log: wklog.NewWKLog("scheduledManager"),
+	}
+}
+
+func (sm *scheduledManager) start() error {
+	if sm.s.opts.Scheduled.CheckInterval <= 0 {
+		return nil
+	}
+	sm.checkTimer = sm.s.Schedule(sm.s.opts.Scheduled.CheckInterval, sm.check)
+	return nil
+}
+
+func (sm *scheduledManager) stop() {
+	if sm.checkTimer != nil {
+		sm.checkTimer.Stop()
+	}
+}
+
+func (sm *scheduledManager) check() {
+	if !sm.checking.CompareAndSwap(false, true) { // 上一次检查还未结束
+		return
+	}
+	defer sm.checking.Store(false)
+
+	messages, err := sm.s.store.GetDueScheduledMessages(time.Now().Unix(), scheduledLoadLimit)
+	if err != nil {
+		sm.Error("get due scheduled messages failed", zap.Error(err))
+		return
+	}
+	var pendings []*scheduledPending
+	for _, m := range messages {
+		if sm.s.opts.ClusterOn() {
+			// 每个副本上都有定时消息，只由槽领导节点发送
+			isLeader, err := sm.s.cluster.IsSlotLeaderOfChannel(m.ChannelId, m.ChannelType)
+			if err != nil {
+				sm.Error("get slot leader failed", zap.Error(err), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType))
+				continue
+			}
+			if !isLeader {
+				continue
+			}
+		}
+		// 收到发送回执后才删除，没收到回执的下次检查时重新发送（客户端消息编号不变，重复的消息会被去重）
+		waitC, ok := sm.s.sendackWaiter.add(m.FromUid, m.ClientMsgNo)
+		if !ok { // 上一次发送还在等待回执
+			continue
+		}
+		err = sm.send(m)
+		if err != nil {
+			sm.s.sendackWaiter.remove(m.FromUid, m.ClientMsgNo)
+			sm.Error("send scheduled message failed", zap.Error(err), zap.Uint64("id", m.Id), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType))
+			continue
+		}
+		pendings = append(pendings, &scheduledPending{message: m, waitC: waitC})
+	}
+	if len(pendings) == 0 {
+		return
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(sm.s.ctx, scheduledSendackTimeout)
+	defer cancel()
+	for _, pending := range pendings {
+		m := pending.message
+		select {
+		case sendack := <-pending.waitC:
+			if sendack.ReasonCode != wkproto.ReasonSuccess {
+				sm.Warn("scheduled message send failed", zap.String("reasonCode", sendack.ReasonCode.String()), zap.Uint64("id", m.Id), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType))
+			}
+			err = sm.s.store.DeleteScheduledMessage(m.ChannelId, m.ChannelType, m.Id)
+			if err != nil {
+				sm.Error("delete scheduled message failed", zap.Error(err), zap.Uint64("id", m.Id), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType))
+			}
+		case <-timeoutCtx.Done():
+			sm.s.sendackWaiter.remove(m.FromUid, m.ClientMsgNo)
+			sm.Warn("wait scheduled message sendack timeout, retry later", zap.Uint64("id", m.Id), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType))
+		}
+	}
+}
+
+// scheduledPending 已发送等待回执的定时消息
+type scheduledPending struct {
+	message wkdb.ScheduledMessage
+	waitC   chan *wkproto.SendackPacket
+}
+
+func (sm *scheduledManager) send(m wkdb.ScheduledMessage) error {
+	return sm.s.channelReactor.proposeSend(m.FromUid, m.FromUid, 0, sm.s.opts.Cluster.NodeId, false, &wkproto.SendPacket{
+		Framer: wkproto.Framer{
+			RedDot:    m.RedDot,
+			SyncOnce:  m.SyncOnce,
+			NoPersist: m.NoPersist,
+		},
+		Expire:      m.Expire,
+		ClientMsgNo: m.ClientMsgNo,
+		ChannelID:   scheduledMessageChannelId(m),
+		ChannelType: m.ChannelType,
+		Payload:     m.Payload,
+	})
+}
+
+// scheduledMessageChannelId 定时消息发送者视角的频道ID（个人频道存储的是双方共同的频道ID，发送时需要转换为接收者的uid）
+func scheduledMessageChannelId(m wkdb.ScheduledMessage) string {
+	if m.ChannelType != wkproto.ChannelTypePerson {
+		return m.ChannelId
+	}
+	from, to := GetFromUIDAndToUIDWith(m.ChannelId)
+	if from == m.FromUid {
+		return to
+	}
+	return from
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"sync"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+type sendackWaitKey struct {
+	fromUid     string
+	clientMsgNo string
+}
+
+// sendackWaiter 等待api发送的消息回执（api发送的消息没有连接，回执通过发送者和客户端消息编号匹配）
+type sendackWaiter struct {
+	mu    sync.Mutex
+	waits map[sendackWaitKey]chan *wkproto.SendackPacket
+}
+
+func newSendackWaiter() *sendackWaiter {
+	return &sendackWaiter{
+		waits: make(map[sendackWaitKey]chan *wkproto.SendackPacket),
+	}
+}
+
+// add 添加等待，如果相同的消息已在等待中则返回false
+func (w *sendackWaiter) add(fromUid string, clientMsgNo string) (chan *wkproto.SendackPacket, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := sendackWaitKey{fromUid: fromUid, clientMsgNo: clientMsgNo}
+	if _, ok := w.waits[key]; ok {
+		return nil, false
+	}
+	waitC := make(chan *wkproto.SendackPacket, 1)
+	w.waits[key] = waitC
+	return waitC, true
+}
+
+func (w *sendackWaiter) remove(fromUid string, clientMsgNo string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.waits, sendackWaitKey{fromUid: fromUid, clientMsgNo: clientMsgNo})
+}
+
+// notify 通知等待者，没有等待者返回false
+func (w *sendackWaiter) notify(fromUid string, sendack *wkproto.SendackPacket) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := sendackWaitKey{fromUid: fromUid, clientMsgNo: sendack.ClientMsgNo}
+	waitC, ok := w.waits[key]
+	if !ok {
+		return false
+	}
+	delete(w.waits, key)
+	waitC <- sendack
+	return true
+}
@@ -0,0 +1,362 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+)
+
+const (
+	sensitiveActionReject uint8 = 1 // 拒绝发送
+	sensitiveActionMask   uint8 = 2 // 敏感词替换为*后放行
+	sensitiveActionFlag   uint8 = 3 // 放行，并通过webhook通知第三方（msg.sensitive事件）
+)
+
+// sensitiveWordManager 敏感词管理
+// 规则存储在每个节点本地，通过api修改时会同步给所有节点，修改后立即重建过滤器
+type sensitiveWordManager struct {
+	s      *Server
+	mu     sync.Mutex // 规则修改锁
+	filter atomic.Pointer[sensitiveFilter]
+	wklog.Log
+}
+
+func newSensitiveWordManager(s *Server) *sensitiveWordManager {
+	return &sensitiveWordManager{
+		s:   s,
+		Log: wklog.NewWKLog("sensitiveWordManager"),
+	}
+}
+
+func (sm *sensitiveWordManager) start() error {
+	return sm.reload()
+}
+
+// reload 从数据库重新加载规则
+func (sm *sensitiveWordManager) reload() error {
+	words, err := sm.s.store.DB().GetSensitiveWords()
+	if err != nil {
+		return err
+	}
+	filter, err := newSensitiveFilter(words)
+	if err != nil {
+		return err
+	}
+	sm.filter.Store(filter)
+	sm.Info("sensitive words loaded", zap.Int("count", len(words)))
+	return nil
+}
+
+// update 添加和移除本节点的规则
+func (sm *sensitiveWordManager) update(addWords []wkdb.SensitiveWord, removeWords []string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if err := sm.s.store.DB().AddOrUpdateSensitiveWords(addWords); err != nil {
+		return err
+	}
+	if err := sm.s.store.DB().RemoveSensitiveWords(removeWords); err != nil {
+		return err
+	}
+	return sm.reload()
+}
+
+// filterMessages 过滤消息内容，返回被拒绝（设置了ReasonCode）或被替换内容（替换了SendPacket）的消息
+func (sm *sensitiveWordManager) filterMessages(messages []ReactorChannelMessage) []ReactorChannelMessage {
+	filter := sm.filter.Load()
+	if filter == nil || filter.empty() {
+		return nil
+	}
+	var filteredMsgs []ReactorChannelMessage
+	for _, msg := range messages {
+		result := filter.check(msg.SendPacket.Payload)
+		if len(result.flagWords) > 0 {
+			sm.s.webhook.notifySensitiveMsg(msg, result.flagWords)
+		}
+		if result.reject {
+			msg.ReasonCode = wkproto.ReasonNotAllowSend
+			filteredMsgs = append(filteredMsgs, msg)
+			continue
+		}
+		if result.payload != nil {
+			packet := *msg.SendPacket
+			packet.Payload = result.payload
+			msg.SendPacket = &packet
+			filteredMsgs = append(filteredMsgs, msg)
+		}
+	}
+	return filteredMsgs
+}
+
+// sensitiveFilter 敏感词过滤器，普通词通过AC自动机匹配，正则规则逐条匹配，构建后只读
+type sensitiveFilter struct {
+	ac      *wkutil.AhoCorasick
+	words   []wkdb.SensitiveWord // AC自动机模式串对应的规则
+	regexes []sensitiveRegex
+}
+
+type sensitiveRegex struct {
+	re   *regexp.Regexp
+	word wkdb.SensitiveWord
+}
+
+func newSensitiveFilter(words []wkdb.SensitiveWord) (*sensitiveFilter, error) {
+	f := &sensitiveFilter{}
+	patterns := make([]string, 0, len(words))
+	for _, word := range words {
+		if word.Regex {
+			re, err := regexp.Compile(word.Word)
+			if err != nil {
+				return nil, fmt.Errorf("sensitive word regex[%s] compile failed: %w", word.Word, err)
+			}
+			f.regexes = append(f.regexes, sensitiveRegex{re: re, word: word})
+			continue
+		}
+		f.words = append(f.words, word)
+		patterns = append(patterns, word.Word)
+	}
+	f.ac = wkutil.NewAhoCorasick(patterns)
+	return f, nil
+}
+
+func (f *sensitiveFilter) empty() bool {
+	return f.ac.Empty() && len(f.regexes) == 0
+}
+
+type sensitiveResult struct {
+	reject    bool
+	payload   []byte   // 替换后的内容，为nil表示未替换
+	flagWords []string // 需要通知的敏感词
+}
+
+// check 检查消息内容，json对象只检查其中的字符串值（避免替换掉字段名），其他内容按文本检查，非utf8内容不检查
+func (f *sensitiveFilter) check(payload []byte) sensitiveResult {
+	var result sensitiveResult
+	if len(payload) == 0 || !utf8.Valid(payload) {
+		return result
+	}
+	c := &sensitiveChecker{f: f}
+
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var obj map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err == nil {
+			c.checkValue(obj)
+			result.reject = c.reject
+			result.flagWords = c.flagWords
+			if !c.reject && c.masked {
+				buff := new(bytes.Buffer)
+				enc := json.NewEncoder(buff)
+				enc.SetEscapeHTML(false)
+				if err = enc.Encode(obj); err == nil {
+					result.payload = bytes.TrimRight(buff.Bytes(), "\n")
+				}
+			}
+			return result
+		}
+	}
+
+	text := c.checkText(string(payload))
+	result.reject = c.reject
+	result.flagWords = c.flagWords
+	if !c.reject && c.masked {
+		result.payload = []byte(text)
+	}
+	return result
+}
+
+type sensitiveChecker struct {
+	f         *sensitiveFilter
+	reject    bool
+	masked    bool
+	flagWords []string
+}
+
+// checkValue 检查json值中的字符串，替换后直接修改原值
+func (c *sensitiveChecker) checkValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return c.checkText(value)
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = c.checkValue(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = c.checkValue(item)
+		}
+	}
+	return v
+}
+
+// checkText 检查文本，返回替换后的文本
+func (c *sensitiveChecker) checkText(text string) string {
+	if c.reject || text == "" {
+		return text
+	}
+	var (
+		runes []rune
+		masks []bool // 需要替换的rune
+	)
+	mask := func(start, end int) {
+		if masks == nil {
+			masks = make([]bool, len(runes))
+		}
+		for i := start; i < end; i++ {
+			masks[i] = true
+		}
+	}
+
+	if !c.f.ac.Empty() {
+		runes = []rune(text)
+		for _, match := range c.f.ac.FindAll(runes) {
+			if !c.hit(c.f.words[match.Pattern]) {
+				return text
+			}
+			if c.f.words[match.Pattern].Action == sensitiveActionMask {
+				mask(match.Start, match.End)
+			}
+		}
+	}
+
+	for _, reg := range c.f.regexes {
+		locs := reg.re.FindAllStringIndex(text, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		if !c.hit(reg.word) {
+			return text
+		}
+		if reg.word.Action != sensitiveActionMask {
+			continue
+		}
+		if runes == nil {
+			runes = []rune(text)
+		}
+		for _, loc := range locs {
+			start := utf8.RuneCountInString(text[:loc[0]])
+			mask(start, start+utf8.RuneCountInString(text[loc[0]:loc[1]]))
+		}
+	}
+
+	if masks == nil {
+		return text
+	}
+	c.masked = true
+	for i, masked := range masks {
+		if masked {
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
+
+// hit 命中规则，返回false表示消息被拒绝，不需要继续检查
+func (c *sensitiveChecker) hit(word wkdb.SensitiveWord) bool {
+	switch word.Action {
+	case sensitiveActionReject:
+		c.reject = true
+		return false
+	case sensitiveActionFlag:
+		for _, w := range c.flagWords {
+			if w == word.Word {
+				return true
+			}
+		}
+		c.flagWords = append(c.flagWords, word.Word)
+	}
+	return true
+}
+
+// sensitiveWordReq 节点间同步敏感词的请求
+type sensitiveWordReq struct {
+	addWords    []wkdb.SensitiveWord
+	removeWords []string
+}
+
+func (r *sensitiveWordReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.addWords)))
+	for _, word := range r.addWords {
+		enc.WriteString(word.Word)
+		enc.WriteUint8(wkutil.BoolToUint8(word.Regex))
+		enc.WriteUint8(word.Action)
+		enc.WriteInt64(word.CreatedAt)
+	}
+	enc.WriteUint32(uint32(len(r.removeWords)))
+	for _, word := range r.removeWords {
+		enc.WriteString(word)
+	}
+	return enc.Bytes()
+}
+
+func (r *sensitiveWordReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	addCount, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(addCount); i++ {
+		var (
+			word  wkdb.SensitiveWord
+			regex uint8
+		)
+		if word.Word, err = dec.String(); err != nil {
+			return err
+		}
+		if regex, err = dec.Uint8(); err != nil {
+			return err
+		}
+		word.Regex = regex == 1
+		if word.Action, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if word.CreatedAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		r.addWords = append(r.addWords, word)
+	}
+	removeCount, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(removeCount); i++ {
+		word, err := dec.String()
+		if err != nil {
+			return err
+		}
+		r.removeWords = append(r.removeWords, word)
+	}
+	return nil
+}
+
+// checkSensitiveWord 校验敏感词规则
+func checkSensitiveWord(word wkdb.SensitiveWord) error {
+	if strings.TrimSpace(word.Word) == "" {
+		return errors.New("敏感词不能为空！")
+	}
+	if word.Action != sensitiveActionReject && word.Action != sensitiveActionMask && word.Action != sensitiveActionFlag {
+		return fmt.Errorf("敏感词[%s]的action不支持！", word.Word)
+	}
+	if word.Regex {
+		if _, err := regexp.Compile(word.Word); err != nil {
+			return fmt.Errorf("敏感词[%s]不是有效的正则表达式！", word.Word)
+		}
+	}
+	return nil
+}
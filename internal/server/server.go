@@ -59,6 +59,17 @@ type Server struct {
 	deliverManager *deliverManager // 消息投递管理
 	retryManager   *retryManager   // 消息重试管理
 
+	retentionManager         *retentionManager         // 消息保留策略管理
+	expireManager            *expireManager            // 过期消息清理
+	channelMessageCmdManager *channelMessageCmdManager // 频道消息命令（撤回、编辑、清理）同步
+	scheduledManager         *scheduledManager         // 定时消息管理
+	sendackWaiter            *sendackWaiter            // api发送消息的回执等待
+	broadcastManager         *broadcastManager         // 系统广播管理
+	sensitiveWordManager     *sensitiveWordManager     // 敏感词管理
+	rateLimitManager         *rateLimitManager         // 消息发送频率限制
+	userBanManager           *userBanManager           // 用户封禁管理
+	pushManager              *pushManager              // 离线推送管理
+
 	conversationManager *ConversationManager // 会话管理
 }
 
@@ -99,7 +110,15 @@ func New(opts *Options) *Server {
 	storeOpts.SlotCount = uint32(s.opts.Cluster.SlotCount)
 	storeOpts.GetSlotId = s.getSlotId
 	storeOpts.IsCmdChannel = opts.IsCmdChannel
+	storeOpts.PullChannelMessageCmds = func(channelId string, channelType uint8, index uint64, limit int) ([]wkdb.ChannelMessageCmd, error) {
+		return s.channelMessageCmdManager.pull(channelId, channelType, index, limit)
+	}
+	storeOpts.NotifyChannelMessageCmds = func(channelId string, channelType uint8) {
+		s.channelMessageCmdManager.notify(channelId, channelType)
+	}
 	storeOpts.Db.ShardNum = s.opts.Db.ShardNum
+	storeOpts.Db.FullTextIndex = s.opts.Db.FullTextIndex
+	storeOpts.Db.MessageDedupWindow = s.opts.Db.MessageDedupWindow
 	s.store = clusterstore.NewStore(storeOpts)
 
 	// 初始化tag管理
@@ -118,15 +137,25 @@ func New(opts *Options) *Server {
 			trace.GlobalTrace.Metrics.System().ExtranetOutgoingAdd(int64(n))
 		}),
 	)
-	s.webhook = newWebhook(s)                         // webhook
-	s.channelReactor = newChannelReactor(s, opts)     // 频道的reactor
-	s.userReactor = newUserReactor(s)                 // 用户的reactor
-	s.demoServer = NewDemoServer(s)                   // demo server
-	s.systemUIDManager = NewSystemUIDManager(s)       // 系统账号管理
-	s.apiServer = NewAPIServer(s)                     // api服务
-	s.managerServer = NewManagerServer(s)             // 管理者的api服务
-	s.retryManager = newRetryManager(s)               // 消息重试管理
-	s.conversationManager = NewConversationManager(s) // 会话管理
+	s.webhook = newWebhook(s)                                   // webhook
+	s.channelReactor = newChannelReactor(s, opts)               // 频道的reactor
+	s.userReactor = newUserReactor(s)                           // 用户的reactor
+	s.demoServer = NewDemoServer(s)                             // demo server
+	s.systemUIDManager = NewSystemUIDManager(s)                 // 系统账号管理
+	s.apiServer = NewAPIServer(s)                               // api服务
+	s.managerServer = NewManagerServer(s)                       // 管理者的api服务
+	s.retryManager = newRetryManager(s)                         // 消息重试管理
+	s.retentionManager = newRetentionManager(s)                 // 消息保留策略管理
+	s.expireManager = newExpireManager(s)                       // 过期消息清理
+	s.channelMessageCmdManager = newChannelMessageCmdManager(s) // 频道消息命令同步
+	s.scheduledManager = newScheduledManager(s)                 // 定时消息管理
+	s.sendackWaiter = newSendackWaiter()                        // api发送消息的回执等待
+	s.broadcastManager = newBroadcastManager(s)                 // 系统广播管理
+	s.sensitiveWordManager = newSensitiveWordManager(s)         // 敏感词管理
+	s.rateLimitManager = newRateLimitManager(s)                 // 消息发送频率限制
+	s.userBanManager = newUserBanManager(s)                     // 用户封禁管理
+	s.pushManager = newPushManager(s)                           // 离线推送管理
+	s.conversationManager = NewConversationManager(s)           // 会话管理
 
 	// 初始化分布式服务
 	initNodes := make(map[uint64]string)
@@ -162,6 +191,10 @@ func New(opts *Options) *Server {
 
 				return s.store.OnMetaApply(slotId, logs)
 			}),
+			cluster.WithOnChannelApply(func(channelId string, channelType uint8, startIndex, endIndex uint64) error {
+				s.channelMessageCmdManager.onChannelApply(channelId, channelType)
+				return nil
+			}),
 			cluster.WithChannelClusterStorage(clusterstore.NewChannelClusterConfigStore(s.store)),
 			cluster.WithElectionIntervalTick(s.opts.Cluster.ElectionIntervalTick),
 			cluster.WithHeartbeatIntervalTick(s.opts.Cluster.HeartbeatIntervalTick),
@@ -253,6 +286,31 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	err = s.sensitiveWordManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.rateLimitManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.userBanManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.pushManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.webhook.Start()
+	if err != nil {
+		return err
+	}
+
 	s.setClusterRoutes()
 	err = s.cluster.Start()
 	if err != nil {
@@ -296,6 +354,21 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	err = s.retentionManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.expireManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.scheduledManager.start()
+	if err != nil {
+		return err
+	}
+
 	s.conversationManager.Start()
 
 	return nil
@@ -315,6 +388,14 @@ func (s *Server) Stop() error {
 	s.deliverManager.stop()
 
 	s.retryManager.stop()
+	s.retentionManager.stop()
+	s.expireManager.stop()
+	s.channelMessageCmdManager.stop()
+	s.scheduledManager.stop()
+	s.rateLimitManager.stop()
+	s.userBanManager.stop()
+	s.pushManager.stop()
+	s.webhook.Stop()
 	s.conversationManager.Stop()
 	s.cluster.Stop()
 	s.apiServer.Stop()
@@ -2,9 +2,13 @@ package server
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"time"
 
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -56,6 +60,40 @@ func (s *Server) setClusterRoutes() {
 	// 是否允许发送消息
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
 
+	// 开始本节点的系统广播
+	s.cluster.Route("/wk/broadcast", s.handleBroadcast)
+	// 获取本节点的系统广播进度
+	s.cluster.Route("/wk/broadcastProgress", s.handleBroadcastProgress)
+
+	// 更新本节点的敏感词
+	s.cluster.Route("/wk/sensitiveWords", s.handleSensitiveWords)
+	s.cluster.Route("/wk/webhookEndpoints", s.handleWebhookEndpoints)
+
+	// 重新加载本节点的发送频率限制规则
+	s.cluster.Route("/wk/rateLimits", s.handleRateLimits)
+	// 获取发送频率限制规则（系统数据所在槽的领导节点）
+	s.cluster.Route("/wk/rateLimitList", s.handleRateLimitList)
+
+	// 刷新本节点缓存的用户封禁
+	s.cluster.Route("/wk/userBans", s.handleUserBans)
+	// 获取用户的封禁（槽领导节点）
+	s.cluster.Route("/wk/userBan", s.handleUserBan)
+	// 获取本节点为槽领导的封禁
+	s.cluster.Route("/wk/userBanList", s.handleUserBanList)
+
+	// 获取本节点频道的最新消息序号（用于记录订阅者的加入点）
+	s.cluster.Route("/wk/channelLastMsgSeq", s.handleChannelLastMsgSeq)
+	// 获取消息流（槽领导节点）
+	s.cluster.Route("/wk/stream", s.handleStream)
+	// 获取消息的已读数量（槽领导节点）
+	s.cluster.Route("/wk/messageReadCounts", s.handleMessageReadCounts)
+	// 按保留策略清理本节点为领导的频道消息
+	s.cluster.Route("/wk/retention", s.handleRetention)
+	// 获取频道的消息命令（槽领导节点）
+	s.cluster.Route("/wk/channelMessageCmds", s.handleChannelMessageCmds)
+	// 同步频道的消息命令（频道副本节点）
+	s.cluster.Route("/wk/channelMessageCmdSync", s.handleChannelMessageCmdSync)
+
 }
 
 func (s *Server) handleChannelForward(c *wkserver.Context) {
@@ -90,7 +128,7 @@ func (s *Server) handleChannelForward(c *wkserver.Context) {
 		sendPacket := reactorChannelMessage.SendPacket
 		// 提案频道消息
 		ch := s.channelReactor.loadOrCreateChannel(req.ChannelId, req.ChannelType)
-		_, err = ch.proposeSend(reactorChannelMessage.FromUid, reactorChannelMessage.FromDeviceId, reactorChannelMessage.FromConnId, reactorChannelMessage.FromNodeId, false, sendPacket)
+		_, err = ch.proposeStreamSend(reactorChannelMessage.FromUid, reactorChannelMessage.FromDeviceId, reactorChannelMessage.FromConnId, reactorChannelMessage.FromNodeId, false, reactorChannelMessage.StreamFlag, reactorChannelMessage.StreamSeq, sendPacket)
 		if err != nil {
 			s.Error("handleChannelForward: proposeSend failed")
 			c.WriteErr(err)
@@ -117,6 +155,12 @@ func (s *Server) handleForwardSendack(c *wkserver.Context) {
 	}
 
 	for _, forwardSendackPacket := range forwardSendackPacketSet {
+		if s.sendackWaiter.notify(forwardSendackPacket.Uid, forwardSendackPacket.Sendack) { // api发送的消息
+			continue
+		}
+		if forwardSendackPacket.Uid == s.opts.SystemUID { // 系统消息没有连接
+			continue
+		}
 		conn := s.userReactor.getConnContext(forwardSendackPacket.Uid, forwardSendackPacket.DeviceId)
 		if conn == nil {
 			s.Error("handleForwardSendack: conn not found", zap.String("uid", forwardSendackPacket.Uid), zap.String("deviceId", forwardSendackPacket.DeviceId))
@@ -479,3 +523,222 @@ func (s *Server) handleAllowSend(c *wkserver.Context) {
 	}
 	c.WriteErrorAndStatus(errors.New("not allow send"), proto.Status(reasonCode))
 }
+
+func (s *Server) handleBroadcast(c *wkserver.Context) {
+	req := &broadcastReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleBroadcast Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.broadcastManager.start(req)
+	c.WriteOk()
+}
+
+func (s *Server) handleBroadcastProgress(c *wkserver.Context) {
+	id := string(c.Body())
+	progress := s.broadcastManager.progress(id)
+	if progress == nil {
+		c.WriteErr(errors.New("broadcast not found"))
+		return
+	}
+	c.Write(progress.Marshal())
+}
+
+func (s *Server) handleSensitiveWords(c *wkserver.Context) {
+	req := &sensitiveWordReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleSensitiveWords Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if err := s.sensitiveWordManager.update(req.addWords, req.removeWords); err != nil {
+		s.Error("update sensitive words failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleWebhookEndpoints(c *wkserver.Context) {
+	req := &webhookEndpointReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleWebhookEndpoints Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if err := s.webhook.updateEndpoints(req.addEndpoints, req.removeNames); err != nil {
+		s.Error("update webhook endpoints failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleRateLimits(c *wkserver.Context) {
+	if err := s.rateLimitManager.reload(); err != nil {
+		s.Error("reload rate limits failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleRateLimitList(c *wkserver.Context) {
+	limits, err := s.store.GetRateLimits()
+	if err != nil {
+		s.Error("get rate limits failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &rateLimitResp{limits: limits}
+	c.Write(resp.Marshal())
+}
+
+func (s *Server) handleUserBans(c *wkserver.Context) {
+	req := &userBanReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleUserBans Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if err := s.userBanManager.refresh(req.uids); err != nil {
+		s.Error("refresh user bans failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleUserBan(c *wkserver.Context) {
+	req := &userBanReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleUserBan Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &userBanResp{}
+	for _, uid := range req.uids {
+		ban, exist, err := s.userBanManager.loadOfLocal(uid)
+		if err != nil {
+			s.Error("get user ban failed", zap.Error(err), zap.String("uid", uid))
+			c.WriteErr(err)
+			return
+		}
+		if exist {
+			resp.bans = append(resp.bans, ban)
+		}
+	}
+	c.Write(resp.Marshal())
+}
+
+func (s *Server) handleUserBanList(c *wkserver.Context) {
+	bans, err := s.userBanManager.activeBansOfLocal()
+	if err != nil {
+		s.Error("get user bans failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &userBanResp{bans: bans}
+	c.Write(resp.Marshal())
+}
+
+// channelLastMsgSeq 获取频道最新的消息序号，分布式下从频道领导节点获取
+func (s *Server) channelLastMsgSeq(channelId string, channelType uint8) (uint64, error) {
+	if s.opts.ClusterOn() {
+		leaderInfo, err := s.cluster.LeaderOfChannelForRead(channelId, channelType)
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 频道集群从未初始化，没有消息
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if leaderInfo.Id != s.opts.Cluster.NodeId {
+			enc := wkproto.NewEncoder()
+			defer enc.End()
+			enc.WriteString(channelId)
+			enc.WriteUint8(channelType)
+			resp, err := s.requestNode(leaderInfo.Id, "/wk/channelLastMsgSeq", enc.Bytes())
+			if err != nil {
+				return 0, err
+			}
+			if len(resp) < 8 {
+				return 0, errors.New("invalid channel last msg seq response")
+			}
+			return binary.BigEndian.Uint64(resp), nil
+		}
+	}
+	return s.store.GetLastMsgSeq(channelId, channelType)
+}
+
+func (s *Server) handleChannelLastMsgSeq(c *wkserver.Context) {
+	dec := wkproto.NewDecoder(c.Body())
+	channelId, err := dec.String()
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	channelType, err := dec.Uint8()
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	lastMsgSeq, err := s.store.GetLastMsgSeq(channelId, channelType)
+	if err != nil {
+		s.Error("get last msg seq failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.WriteErr(err)
+		return
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, lastMsgSeq)
+	c.Write(data)
+}
+
+// clusterNodeIds 所有节点的id，未开启分布式时只有本节点
+func (s *Server) clusterNodeIds() []uint64 {
+	if !s.opts.ClusterOn() {
+		return []uint64{s.opts.Cluster.NodeId}
+	}
+	nodes := s.GetClusterConfig().Nodes
+	nodeIds := make([]uint64, 0, len(nodes))
+	for _, node := range nodes {
+		nodeIds = append(nodeIds, node.Id)
+	}
+	if len(nodeIds) == 0 {
+		nodeIds = append(nodeIds, s.opts.Cluster.NodeId)
+	}
+	return nodeIds
+}
+
+// requestNode 请求指定节点，返回响应内容
+func (s *Server) requestNode(nodeId uint64, path string, data []byte) ([]byte, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, time.Second*5)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, path, data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("request %s failed, status: %d err: %s", path, resp.Status, string(resp.Body))
+	}
+	return resp.Body, nil
+}
+
+// forwardToSlotLeaderIfNeed 如果当前节点不是频道所属槽的领导节点，则将api请求转发给槽领导节点，已处理（转发或返回错误）时返回true
+func (s *Server) forwardToSlotLeaderIfNeed(c *wkhttp.Context, channelId string, channelType uint8, bodyBytes []byte) bool {
+	if !s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := s.cluster.SlotLeaderOfChannel(channelId, channelType) // 获取频道的槽领导节点
+	if err != nil {
+		s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelID", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	if leaderInfo.Id != s.opts.Cluster.NodeId {
+		s.Debug("转发请求：", zap.String("url", fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path)))
+		c.ForwardWithBody(fmt.Sprintf("%s%s", leaderInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+		return true
+	}
+	return false
+}
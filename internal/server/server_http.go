@@ -102,10 +102,22 @@ func (s *APIServer) setRoutes() {
 	message := NewMessageAPI(s.s)
 	message.Route(s.r)
 
+	// 敏感词api
+	sensitiveWord := NewSensitiveWordAPI(s.s)
+	sensitiveWord.Route(s.r)
+
+	// 发送频率限制api
+	rateLimit := NewRateLimitAPI(s.s)
+	rateLimit.Route(s.r)
+
 	// 路由api
 	routeapi := NewRouteAPI(s.s)
 	routeapi.Route(s.r)
 
+	// webhook api
+	webhookapi := NewWebhookAPI(s.s)
+	webhookapi.Route(s.r)
+
 	// 分布式api
 	clusterServer, ok := s.s.cluster.(*cluster.Server)
 	if ok {
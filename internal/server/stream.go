@@ -0,0 +1,188 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// getStream 获取消息流的元数据和内容，不存在返回wkdb.ErrNotFound
+// 消息流通过频道所属槽的raft存储，槽的追随者可能还未应用，所以从槽领导节点读取
+func (s *Server) getStream(fakeChannelId string, channelType uint8, streamNo string) (wkdb.StreamMeta, []wkdb.StreamItem, error) {
+	if s.opts.ClusterOn() {
+		leaderId, err := s.cluster.SlotLeaderIdOfChannel(fakeChannelId, channelType)
+		if err != nil {
+			return wkdb.StreamMeta{}, nil, err
+		}
+		if leaderId != s.opts.Cluster.NodeId {
+			req := &streamReq{
+				channelId:   fakeChannelId,
+				channelType: channelType,
+				streamNo:    streamNo,
+			}
+			data, err := s.requestNode(leaderId, "/wk/stream", req.Marshal())
+			if err != nil {
+				return wkdb.StreamMeta{}, nil, err
+			}
+			resp := &streamResp{}
+			if err = resp.Unmarshal(data); err != nil {
+				return wkdb.StreamMeta{}, nil, err
+			}
+			if !resp.exist {
+				return wkdb.StreamMeta{}, nil, wkdb.ErrNotFound
+			}
+			return resp.meta, resp.items, nil
+		}
+	}
+	return s.getStreamOfLocal(fakeChannelId, channelType, streamNo)
+}
+
+func (s *Server) getStreamOfLocal(fakeChannelId string, channelType uint8, streamNo string) (wkdb.StreamMeta, []wkdb.StreamItem, error) {
+	meta, err := s.store.GetStreamMeta(fakeChannelId, channelType, streamNo)
+	if err != nil {
+		return wkdb.StreamMeta{}, nil, err
+	}
+	items, err := s.store.GetStreamItems(fakeChannelId, channelType, streamNo)
+	if err != nil {
+		return wkdb.StreamMeta{}, nil, err
+	}
+	return meta, items, nil
+}
+
+func (s *Server) handleStream(c *wkserver.Context) {
+	req := &streamReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("handleStream Unmarshal err", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	meta, items, err := s.getStreamOfLocal(req.channelId, req.channelType, req.streamNo)
+	if err != nil && !errors.Is(err, wkdb.ErrNotFound) {
+		s.Error("get stream failed", zap.Error(err), zap.String("streamNo", req.streamNo))
+		c.WriteErr(err)
+		return
+	}
+	resp := &streamResp{
+		exist: err == nil,
+		meta:  meta,
+		items: items,
+	}
+	c.Write(resp.Marshal())
+}
+
+type streamReq struct {
+	channelId   string
+	channelType uint8
+	streamNo    string
+}
+
+func (r *streamReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(r.channelId)
+	enc.WriteUint8(r.channelType)
+	enc.WriteString(r.streamNo)
+	return enc.Bytes()
+}
+
+func (r *streamReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if r.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.streamNo, err = dec.String(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type streamResp struct {
+	exist bool // 消息流是否存在
+	meta  wkdb.StreamMeta
+	items []wkdb.StreamItem
+}
+
+func (r *streamResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	if !r.exist {
+		enc.WriteUint8(0)
+		return enc.Bytes()
+	}
+	enc.WriteUint8(1)
+	enc.WriteString(r.meta.StreamNo)
+	enc.WriteString(r.meta.ChannelId)
+	enc.WriteUint8(r.meta.ChannelType)
+	enc.WriteInt64(r.meta.MessageId)
+	enc.WriteString(r.meta.FromUid)
+	enc.WriteUint8(uint8(r.meta.StreamFlag))
+	enc.WriteInt64(r.meta.CreatedAt)
+	enc.WriteUint32(uint32(len(r.items)))
+	for _, item := range r.items {
+		enc.WriteUint32(item.StreamSeq)
+		enc.WriteString(item.ClientMsgNo)
+		enc.WriteBinary(item.Payload)
+	}
+	return enc.Bytes()
+}
+
+func (r *streamResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	exist, err := dec.Uint8()
+	if err != nil {
+		return err
+	}
+	r.exist = exist == 1
+	if !r.exist {
+		return nil
+	}
+	if r.meta.StreamNo, err = dec.String(); err != nil {
+		return err
+	}
+	if r.meta.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if r.meta.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if r.meta.MessageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if r.meta.FromUid, err = dec.String(); err != nil {
+		return err
+	}
+	var streamFlag uint8
+	if streamFlag, err = dec.Uint8(); err != nil {
+		return err
+	}
+	r.meta.StreamFlag = wkproto.StreamFlag(streamFlag)
+	if r.meta.CreatedAt, err = dec.Int64(); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	r.items = make([]wkdb.StreamItem, 0, count)
+	for i := 0; i < int(count); i++ {
+		var item wkdb.StreamItem
+		if item.StreamSeq, err = dec.Uint32(); err != nil {
+			return err
+		}
+		if item.ClientMsgNo, err = dec.String(); err != nil {
+			return err
+		}
+		if item.Payload, err = dec.Binary(); err != nil {
+			return err
+		}
+		r.items = append(r.items, item)
+	}
+	return nil
+}
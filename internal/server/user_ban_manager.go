@@ -0,0 +1,311 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	userBanCheckInterval = time.Second * 10 // 检查到期封禁的间隔
+	userBanCacheTTL      = time.Minute      // 封禁缓存的有效期（没有收到刷新通知的节点最多延迟这么久生效）
+)
+
+// userBanManager 用户封禁管理
+// 封禁通过用户所在槽的raft存储，节点按需从槽领导节点加载并缓存，通过api修改后会通知所有节点刷新缓存，
+// 每个节点都会断开被封禁用户在本节点的真实连接，连接认证和消息发送时检查封禁，到期后由槽领导节点移除
+type userBanManager struct {
+	s          *Server
+	mu         sync.RWMutex
+	bans       map[string]userBanCache // key为用户uid
+	checkTimer *timingwheel.Timer
+	wklog.Log
+}
+
+// userBanCache 用户封禁的缓存（包含不存在封禁的结果）
+type userBanCache struct {
+	ban      wkdb.UserBan
+	exist    bool      // 是否存在封禁
+	loadedAt time.Time // 加载时间
+}
+
+func newUserBanManager(s *Server) *userBanManager {
+	return &userBanManager{
+		s:    s,
+		bans: make(map[string]userBanCache),
+		Log:  wklog.NewWKLog("userBanManager"),
+	}
+}
+
+func (um *userBanManager) start() error {
+	um.checkTimer = um.s.Schedule(userBanCheckInterval, um.check)
+	return nil
+}
+
+func (um *userBanManager) stop() {
+	if um.checkTimer != nil {
+		um.checkTimer.Stop()
+	}
+}
+
+// refresh 重新加载用户的封禁，并断开被封禁用户在本节点的连接
+func (um *userBanManager) refresh(uids []string) error {
+	now := time.Now().Unix()
+	for _, uid := range uids {
+		ban, exist, err := um.load(uid)
+		if err != nil {
+			return err
+		}
+		um.mu.Lock()
+		um.bans[uid] = userBanCache{ban: ban, exist: exist, loadedAt: time.Now()}
+		um.mu.Unlock()
+
+		if exist && !ban.Expired(now) {
+			um.disconnect(ban)
+		}
+	}
+	return nil
+}
+
+// getBan 获取用户未到期的封禁，缓存失效时从槽领导节点加载
+func (um *userBanManager) getBan(uid string) (wkdb.UserBan, bool) {
+	um.mu.RLock()
+	cache, ok := um.bans[uid]
+	um.mu.RUnlock()
+	if !ok || time.Since(cache.loadedAt) > userBanCacheTTL {
+		ban, exist, err := um.load(uid)
+		if err != nil {
+			um.Warn("load user ban failed, use the cache", zap.Error(err), zap.String("uid", uid))
+		} else {
+			cache = userBanCache{ban: ban, exist: exist, loadedAt: time.Now()}
+			um.mu.Lock()
+			um.bans[uid] = cache
+			um.mu.Unlock()
+		}
+	}
+	if !cache.exist || cache.ban.Expired(time.Now().Unix()) {
+		return wkdb.UserBan{}, false
+	}
+	return cache.ban, true
+}
+
+// isBanned 用户是否被封禁
+func (um *userBanManager) isBanned(uid string) bool {
+	_, ok := um.getBan(uid)
+	return ok
+}
+
+// load 从用户所在槽的领导节点加载用户的封禁
+func (um *userBanManager) load(uid string) (wkdb.UserBan, bool, error) {
+	if um.s.opts.ClusterOn() {
+		leaderId, err := um.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			return wkdb.UserBan{}, false, err
+		}
+		if leaderId != um.s.opts.Cluster.NodeId {
+			req := &userBanReq{uids: []string{uid}}
+			data, err := um.s.requestNode(leaderId, "/wk/userBan", req.Marshal())
+			if err != nil {
+				return wkdb.UserBan{}, false, err
+			}
+			resp := &userBanResp{}
+			if err = resp.Unmarshal(data); err != nil {
+				return wkdb.UserBan{}, false, err
+			}
+			if len(resp.bans) == 0 {
+				return wkdb.UserBan{}, false, nil
+			}
+			return resp.bans[0], true, nil
+		}
+	}
+	return um.loadOfLocal(uid)
+}
+
+func (um *userBanManager) loadOfLocal(uid string) (wkdb.UserBan, bool, error) {
+	ban, err := um.s.store.GetUserBan(uid)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return wkdb.UserBan{}, false, nil
+		}
+		return wkdb.UserBan{}, false, err
+	}
+	return ban, true, nil
+}
+
+// activeBansOfLocal 本节点为槽领导的未到期封禁
+func (um *userBanManager) activeBansOfLocal() ([]wkdb.UserBan, error) {
+	bans, err := um.s.store.GetUserBans()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	activeBans := make([]wkdb.UserBan, 0, len(bans))
+	for _, ban := range bans {
+		if ban.Expired(now) {
+			continue
+		}
+		isLeader, err := um.isSlotLeader(ban.Uid)
+		if err != nil {
+			return nil, err
+		}
+		if isLeader {
+			activeBans = append(activeBans, ban)
+		}
+	}
+	return activeBans, nil
+}
+
+func (um *userBanManager) isSlotLeader(uid string) (bool, error) {
+	if !um.s.opts.ClusterOn() {
+		return true, nil
+	}
+	return um.s.cluster.IsSlotLeaderOfChannel(uid, wkproto.ChannelTypePerson)
+}
+
+// disconnect 断开用户在本节点的真实连接（代理连接由真实连接所在节点断开）
+func (um *userBanManager) disconnect(ban wkdb.UserBan) {
+	conns := um.s.userReactor.getConnContexts(ban.Uid)
+	for _, conn := range conns {
+		if !conn.isRealConn {
+			continue
+		}
+		um.Info("disconnect banned user", zap.String("uid", ban.Uid), zap.Int64("connId", conn.connId))
+		_ = conn.writeDirectlyPacket(&wkproto.DisconnectPacket{
+			ReasonCode: wkproto.ReasonBan,
+			Reason:     ban.Reason,
+		})
+		um.s.timingWheel.AfterFunc(time.Second*2, func() {
+			um.s.userReactor.removeConnContextById(conn.uid, conn.connId)
+			conn.close()
+		})
+	}
+}
+
+// check 清理过期的缓存，并由用户所在槽的领导节点移除到期的封禁和通知webhook
+func (um *userBanManager) check() {
+	um.mu.Lock()
+	for uid, cache := range um.bans {
+		if time.Since(cache.loadedAt) > userBanCacheTTL {
+			delete(um.bans, uid)
+		}
+	}
+	um.mu.Unlock()
+
+	bans, err := um.s.store.GetUserBans()
+	if err != nil {
+		um.Error("get user bans failed", zap.Error(err))
+		return
+	}
+	now := time.Now().Unix()
+	for _, ban := range bans {
+		if !ban.Expired(now) {
+			continue
+		}
+		isLeader, err := um.isSlotLeader(ban.Uid)
+		if err != nil {
+			um.Error("get slot leader failed", zap.Error(err), zap.String("uid", ban.Uid))
+			continue
+		}
+		if !isLeader {
+			continue
+		}
+		if err = um.s.store.RemoveUserBan(ban.Uid, ban.CreatedAt); err != nil {
+			um.Error("remove expired user ban failed", zap.Error(err), zap.String("uid", ban.Uid))
+			continue
+		}
+		um.s.webhook.notifyUserUnban(ban.Uid, true)
+	}
+}
+
+// userBanReq 获取或刷新用户封禁的请求
+type userBanReq struct {
+	uids []string
+}
+
+func (r *userBanReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.uids)))
+	for _, uid := range r.uids {
+		enc.WriteString(uid)
+	}
+	return enc.Bytes()
+}
+
+func (r *userBanReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return err
+		}
+		r.uids = append(r.uids, uid)
+	}
+	return nil
+}
+
+type userBanResp struct {
+	bans []wkdb.UserBan
+}
+
+func (r *userBanResp) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.bans)))
+	for _, ban := range r.bans {
+		enc.WriteString(ban.Uid)
+		enc.WriteString(ban.Reason)
+		enc.WriteInt64(ban.ExpireAt)
+		enc.WriteInt64(ban.CreatedAt)
+	}
+	return enc.Bytes()
+}
+
+func (r *userBanResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		var ban wkdb.UserBan
+		if ban.Uid, err = dec.String(); err != nil {
+			return err
+		}
+		if ban.Reason, err = dec.String(); err != nil {
+			return err
+		}
+		if ban.ExpireAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		if ban.CreatedAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		r.bans = append(r.bans, ban)
+	}
+	return nil
+}
+
+// checkUserBanUids 校验封禁或解封的用户
+func checkUserBanUids(uids []string) error {
+	if len(uids) == 0 {
+		return errors.New("uids不能为空！")
+	}
+	for _, uid := range uids {
+		if strings.TrimSpace(uid) == "" {
+			return errors.New("uid不能为空！")
+		}
+	}
+	return nil
+}
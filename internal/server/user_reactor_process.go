@@ -158,8 +158,8 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 		r.authResponseConnackAuthFail(connCtx)
 		return wkproto.ReasonAuthFail, err
 	}
-	ban := false
-	if !wkdb.IsEmptyChannelInfo(userChannelInfo) {
+	ban := r.s.userBanManager.isBanned(uid) // 通过api封禁（可设置时长）
+	if !ban && !wkdb.IsEmptyChannelInfo(userChannelInfo) {
 		ban = userChannelInfo.Ban
 	}
 	if ban {
@@ -6,6 +6,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strconv"
@@ -13,11 +14,11 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
-	"github.com/panjf2000/ants/v2"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -28,23 +29,21 @@ import (
 type webhook struct {
 	s *Server
 	wklog.Log
-	eventPool        *ants.Pool
 	httpClient       *http.Client
-	webhookGRPCPool  *grpcpool.Pool // webhook grpc客户端
+	webhookGRPCPool  *grpcpool.Pool // webhook grpc客户端（内容审核使用）
 	stoped           chan struct{}
 	onlinestatusLock sync.RWMutex
 	onlinestatusList []string
+
+	endpointUpdateLock sync.Mutex                  // 端点修改锁
+	endpointLock       sync.RWMutex                // 端点读写锁
+	endpoints          map[string]*webhookEndpoint // key为端点名称
 }
 
 func newWebhook(s *Server) *webhook {
-	eventPool, err := ants.NewPool(s.opts.EventPoolSize, ants.WithPanicHandler(func(err interface{}) {
-		s.Error("webhook panic", zap.Any("err", err), zap.Stack("stack"))
-	}))
-	if err != nil {
-		panic(err)
-	}
 	var (
 		webhookGRPCPool *grpcpool.Pool
+		err             error
 	)
 	if s.opts.WebhookGRPCOn() {
 		webhookGRPCPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
@@ -61,10 +60,10 @@ func newWebhook(s *Server) *webhook {
 	return &webhook{
 		s:                s,
 		Log:              wklog.NewWKLog("Webhook"),
-		eventPool:        eventPool,
 		webhookGRPCPool:  webhookGRPCPool,
 		onlinestatusList: make([]string, 0),
 		stoped:           make(chan struct{}),
+		endpoints:        make(map[string]*webhookEndpoint),
 		httpClient: &http.Client{
 			Transport: &http.Transport{
 				DialContext: (&net.Dialer{
@@ -83,17 +82,29 @@ func newWebhook(s *Server) *webhook {
 	}
 }
 
-func (w *webhook) Start() {
+func (w *webhook) Start() error {
+	if err := w.reloadEndpoints(); err != nil {
+		return err
+	}
 	go w.notifyQueueLoop()
 	go w.loopOnlineStatus()
+	return nil
 }
 
 func (w *webhook) Stop() {
 	close(w.stoped)
+	w.endpointLock.Lock()
+	defer w.endpointLock.Unlock()
+	for _, endpoint := range w.endpoints {
+		endpoint.stop()
+	}
 }
 
 // Online 用户设备上线通知
 func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.subscribed(EventOnlineStatus) {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 1
@@ -103,6 +114,9 @@ func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64
 }
 
 func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.subscribed(EventOnlineStatus) {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 0
@@ -112,31 +126,18 @@ func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int6
 	w.Debug("User offline", zap.String("uid", uid), zap.String("deviceFlag", deviceFlag.String()))
 }
 
-// TriggerEvent 触发事件
+// TriggerEvent 触发事件，事件先写入订阅了此事件的端点的发件箱再由发件箱推送
 func (w *webhook) TriggerEvent(event *Event) {
-	if !w.s.opts.WebhookOn() { // 没设置webhook直接忽略
+	if !w.subscribed(event.Event) { // 没有端点订阅此事件直接忽略
 		return
 	}
-	err := w.eventPool.Submit(func() {
-		jsonData, err := json.Marshal(event.Data)
-		if err != nil {
-			w.Error("webhook的event数据不能json化！", zap.Error(err))
-			return
-		}
-
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(event.Event, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(event.Event, jsonData)
-		}
-		if err != nil {
-			w.Error("请求webhook失败！", zap.Error(err), zap.String("event", event.Event))
-			return
-		}
-
-	})
+	jsonData, err := json.Marshal(event.Data)
 	if err != nil {
-		w.Error("提交事件失败", zap.Error(err))
+		w.Error("webhook的event数据不能json化！", zap.Error(err))
+		return
+	}
+	if err = w.appendOutbox(event.Event, jsonData); err != nil {
+		w.Error("webhook事件写入发件箱失败！", zap.Error(err), zap.String("event", event.Event))
 	}
 }
 
@@ -188,197 +189,216 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 	})
 }
 
-// 通知上层应用 TODO: 此初报错可以做一个邮件报警处理类的东西，
+// 将通知队列内的消息写入发件箱，由发件箱推送给上层应用
 func (w *webhook) notifyQueueLoop() {
 	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
 	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
-	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
-	if w.s.opts.WebhookOn() {
-		for {
-			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
-			if err != nil {
-				w.Error("获取通知队列内的消息失败！", zap.Error(err))
+	defer ticker.Stop()
+	for {
+		messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+		if err != nil {
+			w.Error("获取通知队列内的消息失败！", zap.Error(err))
+			time.Sleep(errorSleepTime) // 如果报错就休息下
+			continue
+		}
+		if len(messages) > 0 {
+			if err = w.moveNotifyQueueToOutbox(messages); err != nil {
+				w.Error("通知队列内的消息写入发件箱失败！", zap.Error(err))
 				time.Sleep(errorSleepTime) // 如果报错就休息下
 				continue
 			}
-			if len(messages) > 0 {
-				messageResps := make([]*MessageResp, 0, len(messages))
-				for _, msg := range messages {
-					resp := &MessageResp{}
-					resp.from(msg)
-					messageResps = append(messageResps, resp)
-				}
-				messageData, err := json.Marshal(messageResps)
-				if err != nil {
-					w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-
-				if w.s.opts.WebhookGRPCOn() {
-					err = w.sendWebhookForGRPC(EventMsgNotify, messageData)
-				} else {
-					err = w.sendWebhookForHttp(EventMsgNotify, messageData)
-				}
-				if err != nil {
-					w.Error("请求所有消息通知webhook失败！", zap.Error(err))
-					errMessageIDs := make([]int64, 0, len(messages))
-					for _, message := range messages {
-						errCount := errMessageIDMap[message.MessageID]
-						errCount++
-						errMessageIDMap[message.MessageID] = errCount
-						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-							errMessageIDs = append(errMessageIDs, message.MessageID)
-						}
-					}
-					if len(errMessageIDs) > 0 {
-						w.Error("消息通知失败超过最大次数！", zap.Int64s("messageIDs", errMessageIDs))
-						err = w.s.store.RemoveMessagesOfNotifyQueue(errMessageIDs)
-						if err != nil {
-							w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", errMessageIDs))
-						}
-						for _, errMessageID := range errMessageIDs {
-							delete(errMessageIDMap, errMessageID)
-						}
-					}
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-
-				messageIDs := make([]int64, 0, len(messages))
-				for _, message := range messages {
-					messageID := message.MessageID
-					messageIDs = append(messageIDs, messageID)
-
-					delete(errMessageIDMap, messageID)
-				}
-				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
-				if err != nil {
-					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-					time.Sleep(errorSleepTime) // 如果报错就休息下
-					continue
-				}
-			}
+		}
 
-			select {
-			case <-ticker.C:
-			case <-w.stoped:
-				return
-			}
+		select {
+		case <-ticker.C:
+		case <-w.stoped:
+			return
 		}
 	}
 }
 
-func (w *webhook) loopOnlineStatus() {
-	if !w.s.opts.WebhookOn() {
-		return
+// moveNotifyQueueToOutbox 按每次推送数量将消息写入发件箱后从通知队列移除
+func (w *webhook) moveNotifyQueueToOutbox(messages []wkdb.Message) error {
+	countPerPush := w.s.opts.Webhook.MsgNotifyEventCountPerPush
+	if countPerPush <= 0 {
+		countPerPush = len(messages)
 	}
-	opLen := 0    // 最后一次操作在线状态数组的长度
-	errCount := 0 // webhook请求失败重试次数
-	for {
-		if opLen == 0 {
-			w.onlinestatusLock.Lock()
-			opLen = len(w.onlinestatusList)
-			w.onlinestatusLock.Unlock()
+	for start := 0; start < len(messages); start += countPerPush {
+		batchMessages := messages[start:min(start+countPerPush, len(messages))]
+		messageResps := make([]*MessageResp, 0, len(batchMessages))
+		messageIDs := make([]int64, 0, len(batchMessages))
+		for _, msg := range batchMessages {
+			resp := &MessageResp{}
+			resp.from(msg)
+			messageResps = append(messageResps, resp)
+			messageIDs = append(messageIDs, msg.MessageID)
 		}
-		if opLen == 0 {
-			time.Sleep(time.Second * 2) // 没有数据就休息2秒
-			continue
+		messageData, err := json.Marshal(messageResps)
+		if err != nil {
+			return err
+		}
+		if err = w.appendOutbox(EventMsgNotify, messageData); err != nil {
+			return err
+		}
+		// 先写入发件箱再移除，保证消息至少通知一次
+		if err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 定时将用户在线状态写入发件箱
+func (w *webhook) loopOnlineStatus() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.stoped:
+			return
 		}
 		w.onlinestatusLock.Lock()
-		data := w.onlinestatusList[:opLen]
+		data := w.onlinestatusList
+		w.onlinestatusList = make([]string, 0)
 		w.onlinestatusLock.Unlock()
+		if len(data) == 0 {
+			continue
+		}
 		jsonData, err := json.Marshal(data)
 		if err != nil {
 			w.Error("webhook的event数据不能json化！", zap.Error(err))
-			time.Sleep(time.Second * 1)
 			continue
 		}
-
-		if w.s.opts.WebhookGRPCOn() {
-			err = w.sendWebhookForGRPC(EventOnlineStatus, jsonData)
-		} else {
-			err = w.sendWebhookForHttp(EventOnlineStatus, jsonData)
+		if err = w.appendOutbox(EventOnlineStatus, jsonData); err != nil {
+			w.Error("在线状态写入发件箱失败！", zap.Error(err))
+			// 放回去下次重试
+			w.onlinestatusLock.Lock()
+			w.onlinestatusList = append(data, w.onlinestatusList...)
+			w.onlinestatusLock.Unlock()
 		}
-		if err != nil {
-			errCount++
-			w.Error("请求在线状态webhook失败！", zap.Error(err))
-			if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
+	}
+}
 
-				w.onlinestatusLock.Lock()
-				w.onlinestatusList = w.onlinestatusList[opLen:]
-				opLen = 0
-				w.onlinestatusLock.Unlock()
+const (
+	moderationActionAllow  = "allow"  // 放行
+	moderationActionReject = "reject" // 拒绝发送
+	moderationActionModify = "modify" // 修改内容后放行
+)
 
-				errCount = 0
-			}
+// moderationResult 内容审核结果
+type moderationResult struct {
+	Action     string `json:"action"`      // allow/reject/modify
+	ReasonCode uint8  `json:"reason_code"` // 拒绝发送时返回给发送者的原因码，不填则为ReasonNotAllowSend
+	Payload    []byte `json:"payload"`     // 修改后的消息内容（base64编码）
+}
 
-			time.Sleep(time.Second * 1) // 如果报错就休息下
-			continue
-		}
+// notifySensitiveMsg 通知第三方消息命中了需要通知的敏感词
+func (w *webhook) notifySensitiveMsg(msg ReactorChannelMessage, words []string) {
+	w.TriggerEvent(&Event{
+		Event: EventMsgSensitive,
+		Data: MessageSensitiveNotify{
+			MessageResp: newMessageRespWithReactorMessage(msg),
+			Words:       words,
+		},
+	})
+}
 
-		w.onlinestatusLock.Lock()
-		w.onlinestatusList = w.onlinestatusList[opLen:]
-		opLen = 0
-		w.onlinestatusLock.Unlock()
+// notifyUserBan 通知用户被封禁
+func (w *webhook) notifyUserBan(ban wkdb.UserBan) {
+	w.TriggerEvent(&Event{
+		Event: EventUserBan,
+		Data:  ban,
+	})
+}
 
+// notifyUserUnban 通知用户解除封禁，expired表示是否是到期自动解除
+func (w *webhook) notifyUserUnban(uid string, expired bool) {
+	w.TriggerEvent(&Event{
+		Event: EventUserUnban,
+		Data: UserUnbanNotify{
+			UID:     uid,
+			Expired: int(wkutil.BoolToUint8(expired)),
+		},
+	})
+}
+
+// moderate 同步请求第三方审核消息内容
+func (w *webhook) moderate(msg ReactorChannelMessage) (*moderationResult, error) {
+	data := []byte(wkutil.ToJSON(newMessageRespWithReactorMessage(msg)))
+
+	timeout := w.s.opts.Webhook.ModerationTimeout
+	if timeout <= 0 {
+		timeout = time.Second * 2
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		respData []byte
+		err      error
+	)
+	if w.s.opts.WebhookGRPCOn() {
+		respData, err = w.requestWebhookForGRPC(ctx, EventMsgModerate, data)
+	} else {
+		respData, err = w.requestWebhookForHttp(ctx, EventMsgModerate, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &moderationResult{}
+	if len(bytes.TrimSpace(respData)) == 0 { // 没有返回内容视为放行
+		result.Action = moderationActionAllow
+		return result, nil
+	}
+	if err = json.Unmarshal(respData, result); err != nil {
+		return nil, errors.Wrap(err, "解析内容审核结果失败！")
+	}
+	return result, nil
 }
 
-func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
+// requestWebhookForHttp 请求webhook并返回响应内容
+func (w *webhook) requestWebhookForHttp(ctx context.Context, event string, data []byte) ([]byte, error) {
 	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
-	startTime := time.Now().UnixNano() / 1000 / 1000
-	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
-	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
-	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, eventURL, bytes.NewBuffer(data))
 	if err != nil {
-		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
-		return err
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range signHeaders(w.s.opts.Webhook.Secret, 0, event, data) {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
-		return errors.New("第三方消息通知接口返回状态错误！")
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook返回状态错误！status: %d", resp.StatusCode)
 	}
-	return nil
+	return io.ReadAll(resp.Body)
 }
 
-func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
-
-	startNow := time.Now()
-	startTime := startNow.UnixNano() / 1000 / 1000
-	w.Debug("webhook grpc 开始请求", zap.String("event", event))
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
-	defer cancel()
+// requestWebhookForGRPC 请求webhook并返回响应内容
+func (w *webhook) requestWebhookForGRPC(ctx context.Context, event string, data []byte) ([]byte, error) {
 	clientConn, err := w.webhookGRPCPool.Get(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer clientConn.Close()
-	// cliConn, err := grpc.Dial(l.opts.WebhookGRPC, grpc.WithInsecure())
-	// if err != nil {
-	// 	return err
-	// }
 	cli := wkhook.NewWebhookServiceClient(clientConn)
-
-	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer sendCancel()
-	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
+	ctx = signGRPCContext(ctx, w.s.opts.Webhook.Secret, 0, event, data)
+	resp, err := cli.SendWebhook(ctx, &wkhook.EventReq{
 		Event: event,
 		Data:  data,
 	})
-	w.Debug("webhook grpc 请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
-
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.Status != wkhook.EventStatus_Success {
-		return errors.New("grpc返回状态错误！")
+		return nil, errors.New("grpc返回状态错误！")
 	}
-	return nil
+	return resp.Data, nil
 }
 
 const (
@@ -388,6 +408,14 @@ const (
 	EventMsgNotify = "msg.notify"
 	// EventOnlineStatus 用户在线状态
 	EventOnlineStatus = "user.onlinestatus"
+	// EventMsgSensitive 消息命中敏感词（处理方式为通知的规则）
+	EventMsgSensitive = "msg.sensitive"
+	// EventMsgModerate 消息内容审核（消息存储前同步请求，返回allow/reject/modify）
+	EventMsgModerate = "msg.moderate"
+	// EventUserBan 用户被封禁
+	EventUserBan = "user.ban"
+	// EventUserUnban 用户解除封禁（包含到期自动解除）
+	EventUserUnban = "user.unban"
 )
 
 // Event Event
@@ -399,3 +427,25 @@ type Event struct {
 func (e *Event) String() string {
 	return fmt.Sprintf("Event:%s Data:%v", e.Event, e.Data)
 }
+
+// newMessageRespWithReactorMessage 未存储的消息（没有消息序号）转换为webhook的消息数据
+func newMessageRespWithReactorMessage(msg ReactorChannelMessage) MessageResp {
+	return MessageResp{
+		Header: MessageHeader{
+			RedDot:    wkutil.BoolToInt(msg.SendPacket.RedDot),
+			SyncOnce:  wkutil.BoolToInt(msg.SendPacket.SyncOnce),
+			NoPersist: wkutil.BoolToInt(msg.SendPacket.NoPersist),
+		},
+		Setting:      msg.SendPacket.Setting.Uint8(),
+		ClientMsgNo:  msg.SendPacket.ClientMsgNo,
+		MessageId:    msg.MessageId,
+		MessageIdStr: strconv.FormatInt(msg.MessageId, 10),
+		FromUID:      msg.FromUid,
+		ChannelID:    msg.SendPacket.ChannelID,
+		ChannelType:  msg.SendPacket.ChannelType,
+		Topic:        msg.SendPacket.Topic,
+		Expire:       msg.SendPacket.Expire,
+		Timestamp:    int32(time.Now().Unix()),
+		Payload:      msg.SendPacket.Payload,
+	}
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookEndpoint            = "default" // 通过webhook.httpAddr或webhook.grpcAddr配置的端点
+	defaultWebhookEndpointConcurrency = 10        // 端点默认推送并发数
+)
+
+// staticEndpoints 配置文件内的端点
+func (w *webhook) staticEndpoints() []wkdb.WebhookEndpoint {
+	opts := w.s.opts
+	endpoints := make([]wkdb.WebhookEndpoint, 0, len(opts.Webhook.Endpoints)+1)
+	if opts.WebhookOn() {
+		addr := opts.Webhook.HTTPAddr
+		if opts.WebhookGRPCOn() {
+			addr = opts.Webhook.GRPCAddr
+		}
+		endpoints = append(endpoints, wkdb.WebhookEndpoint{
+			Name:        defaultWebhookEndpoint,
+			Addr:        addr,
+			Concurrency: opts.EventPoolSize,
+		})
+	}
+	return append(endpoints, opts.Webhook.Endpoints...)
+}
+
+// reloadEndpoints 根据配置文件和数据库内的端点重建端点，未变化的端点不会重建
+func (w *webhook) reloadEndpoints() error {
+	dbEndpoints, err := w.s.store.DB().GetWebhookEndpoints()
+	if err != nil {
+		return err
+	}
+	cfgs := make(map[string]wkdb.WebhookEndpoint)
+	statics := make(map[string]bool)
+	for _, cfg := range w.staticEndpoints() {
+		if err = checkWebhookEndpoint(cfg); err != nil {
+			return err
+		}
+		if statics[cfg.Name] {
+			return fmt.Errorf("webhook端点[%s]重复！", cfg.Name)
+		}
+		cfgs[cfg.Name] = withWebhookEndpointDefault(cfg)
+		statics[cfg.Name] = true
+	}
+	for _, cfg := range dbEndpoints {
+		if statics[cfg.Name] { // 配置文件内的端点优先
+			w.Warn("webhook endpoint is defined in config, ignore the one in db", zap.String("endpoint", cfg.Name))
+			continue
+		}
+		cfgs[cfg.Name] = withWebhookEndpointDefault(cfg)
+	}
+
+	w.endpointLock.Lock()
+	defer w.endpointLock.Unlock()
+
+	for name, endpoint := range w.endpoints {
+		cfg, ok := cfgs[name]
+		if ok && webhookEndpointEqual(endpoint.cfg, cfg) {
+			continue
+		}
+		endpoint.stop()
+		delete(w.endpoints, name)
+	}
+	for name, cfg := range cfgs {
+		if _, ok := w.endpoints[name]; ok {
+			continue
+		}
+		endpoint, err := newWebhookEndpoint(w, cfg, statics[name])
+		if err != nil {
+			return err
+		}
+		endpoint.start()
+		w.endpoints[name] = endpoint
+	}
+	w.Info("webhook endpoints loaded", zap.Int("count", len(w.endpoints)))
+	return nil
+}
+
+// updateEndpoints 添加和移除本节点通过api管理的端点，移除的端点的发件箱和死信也会删除
+func (w *webhook) updateEndpoints(addEndpoints []wkdb.WebhookEndpoint, removeNames []string) error {
+	w.endpointUpdateLock.Lock()
+	defer w.endpointUpdateLock.Unlock()
+
+	if err := w.checkUpdateEndpoints(addEndpoints, removeNames); err != nil {
+		return err
+	}
+
+	db := w.s.store.DB()
+	if err := db.AddOrUpdateWebhookEndpoints(addEndpoints); err != nil {
+		return err
+	}
+	if err := db.RemoveWebhookEndpoints(removeNames); err != nil {
+		return err
+	}
+	if err := w.reloadEndpoints(); err != nil {
+		return err
+	}
+	for _, name := range removeNames {
+		if err := db.RemoveWebhookEventsOfEndpoint(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUpdateEndpoints 配置文件内的端点不能通过api修改和移除
+func (w *webhook) checkUpdateEndpoints(addEndpoints []wkdb.WebhookEndpoint, removeNames []string) error {
+	for _, cfg := range w.staticEndpoints() {
+		for _, endpoint := range addEndpoints {
+			if endpoint.Name == cfg.Name {
+				return fmt.Errorf("webhook端点[%s]为配置文件内的端点，不能修改！", cfg.Name)
+			}
+		}
+		for _, name := range removeNames {
+			if name == cfg.Name {
+				return fmt.Errorf("webhook端点[%s]为配置文件内的端点，不能移除！", cfg.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *webhook) getEndpoint(name string) *webhookEndpoint {
+	w.endpointLock.RLock()
+	defer w.endpointLock.RUnlock()
+	return w.endpoints[name]
+}
+
+func (w *webhook) endpointList() []*webhookEndpoint {
+	w.endpointLock.RLock()
+	defer w.endpointLock.RUnlock()
+	endpoints := make([]*webhookEndpoint, 0, len(w.endpoints))
+	for _, endpoint := range w.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// subscribed 是否有端点订阅了事件
+func (w *webhook) subscribed(event string) bool {
+	w.endpointLock.RLock()
+	defer w.endpointLock.RUnlock()
+	for _, endpoint := range w.endpoints {
+		if endpoint.cfg.Subscribed(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribedEndpoints 订阅了事件的端点
+func (w *webhook) subscribedEndpoints(event string) []*webhookEndpoint {
+	w.endpointLock.RLock()
+	defer w.endpointLock.RUnlock()
+	endpoints := make([]*webhookEndpoint, 0, len(w.endpoints))
+	for _, endpoint := range w.endpoints {
+		if endpoint.cfg.Subscribed(event) {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+func withWebhookEndpointDefault(cfg wkdb.WebhookEndpoint) wkdb.WebhookEndpoint {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultWebhookEndpointConcurrency
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = nil
+	}
+	return cfg
+}
+
+// webhookEndpointEqual 端点配置是否相同（不比较创建时间）
+func webhookEndpointEqual(a, b wkdb.WebhookEndpoint) bool {
+	a.CreatedAt, b.CreatedAt = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+// checkWebhookEndpoint 校验端点配置
+func checkWebhookEndpoint(endpoint wkdb.WebhookEndpoint) error {
+	if strings.TrimSpace(endpoint.Name) == "" {
+		return errors.New("webhook端点名称不能为空！")
+	}
+	if strings.TrimSpace(endpoint.Addr) == "" {
+		return fmt.Errorf("webhook端点[%s]的地址不能为空！", endpoint.Name)
+	}
+	for _, event := range endpoint.Events {
+		if strings.TrimSpace(event) == "" || strings.Contains(event, ",") {
+			return fmt.Errorf("webhook端点[%s]的事件[%s]不合法！", endpoint.Name, event)
+		}
+	}
+	return nil
+}
+
+type webhookEndpointReq struct {
+	addEndpoints []wkdb.WebhookEndpoint
+	removeNames  []string
+}
+
+func (r *webhookEndpointReq) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(r.addEndpoints)))
+	for _, endpoint := range r.addEndpoints {
+		enc.WriteString(endpoint.Name)
+		enc.WriteString(endpoint.Addr)
+		enc.WriteString(strings.Join(endpoint.Events, ","))
+		enc.WriteString(endpoint.Secret)
+		enc.WriteUint32(uint32(endpoint.Concurrency))
+		enc.WriteInt64(endpoint.CreatedAt)
+	}
+	enc.WriteUint32(uint32(len(r.removeNames)))
+	for _, name := range r.removeNames {
+		enc.WriteString(name)
+	}
+	return enc.Bytes()
+}
+
+func (r *webhookEndpointReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	addCount, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(addCount); i++ {
+		var (
+			endpoint    wkdb.WebhookEndpoint
+			events      string
+			concurrency uint32
+		)
+		if endpoint.Name, err = dec.String(); err != nil {
+			return err
+		}
+		if endpoint.Addr, err = dec.String(); err != nil {
+			return err
+		}
+		if events, err = dec.String(); err != nil {
+			return err
+		}
+		if events != "" {
+			endpoint.Events = strings.Split(events, ",")
+		}
+		if endpoint.Secret, err = dec.String(); err != nil {
+			return err
+		}
+		if concurrency, err = dec.Uint32(); err != nil {
+			return err
+		}
+		endpoint.Concurrency = int(concurrency)
+		if endpoint.CreatedAt, err = dec.Int64(); err != nil {
+			return err
+		}
+		r.addEndpoints = append(r.addEndpoints, endpoint)
+	}
+	removeCount, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(removeCount); i++ {
+		name, err := dec.String()
+		if err != nil {
+			return err
+		}
+		r.removeNames = append(r.removeNames, name)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhook"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/lni/goutils/syncutil"
+	"github.com/panjf2000/ants/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	webhookHeaderEventId   = "X-WK-Event-Id"  // 事件唯一id，可用于去重
+	webhookHeaderTimestamp = "X-WK-Timestamp" // 签名时间戳（秒）
+	webhookHeaderSignature = "X-WK-Signature" // 签名 sha256=hex(hmac_sha256(secret, timestamp.event.body))
+	webhookOutboxPageSize  = 100              // 发件箱每次读取的事件数量
+	webhookOutboxMaxTick   = time.Second      // 发件箱最长检查间隔
+)
+
+// webhookEndpoint webhook端点
+// 每个端点有独立的发件箱、推送协程和并发池，某个端点推送慢或失败不影响其他端点
+type webhookEndpoint struct {
+	w        *webhook
+	cfg      wkdb.WebhookEndpoint
+	static   bool           // 是否为配置文件内的端点（不能通过api修改）
+	pool     *ants.Pool     // 推送并发池
+	grpcPool *grpcpool.Pool // grpc端点的客户端
+	wakeC    chan struct{}  // 唤醒发件箱推送
+	stopper  *syncutil.Stopper
+	wklog.Log
+}
+
+func newWebhookEndpoint(w *webhook, cfg wkdb.WebhookEndpoint, static bool) (*webhookEndpoint, error) {
+	e := &webhookEndpoint{
+		w:       w,
+		cfg:     cfg,
+		static:  static,
+		wakeC:   make(chan struct{}, 1),
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog(fmt.Sprintf("webhookEndpoint[%s]", cfg.Name)),
+	}
+	pool, err := ants.NewPool(cfg.Concurrency, ants.WithPanicHandler(func(err interface{}) {
+		e.Error("webhook panic", zap.Any("err", err), zap.Stack("stack"))
+	}))
+	if err != nil {
+		return nil, err
+	}
+	e.pool = pool
+	if e.isGRPC() {
+		e.grpcPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
+			return grpc.Dial(cfg.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:    5 * time.Minute,
+				Timeout: 2 * time.Second,
+			}))
+		}, 1, min(cfg.Concurrency, 20), time.Minute*5) // 最多20个连接
+		if err != nil {
+			pool.Release()
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *webhookEndpoint) start() {
+	e.stopper.RunWorker(e.loop)
+}
+
+func (e *webhookEndpoint) stop() {
+	e.stopper.Stop()
+	e.pool.Release()
+	if e.grpcPool != nil {
+		e.grpcPool.Close()
+	}
+}
+
+// isGRPC 非http(s)地址为grpc端点
+func (e *webhookEndpoint) isGRPC() bool {
+	return !strings.HasPrefix(e.cfg.Addr, "http://") && !strings.HasPrefix(e.cfg.Addr, "https://")
+}
+
+func (e *webhookEndpoint) secret() string {
+	if e.cfg.Secret != "" {
+		return e.cfg.Secret
+	}
+	return e.w.s.opts.Webhook.Secret
+}
+
+func (e *webhookEndpoint) wake() {
+	select {
+	case e.wakeC <- struct{}{}:
+	default:
+	}
+}
+
+// loop 推送发件箱内到期的事件
+func (e *webhookEndpoint) loop() {
+	opts := e.w.s.opts.Webhook
+	tick := webhookOutboxMaxTick
+	if opts.RetryInterval > 0 && opts.RetryInterval < tick {
+		tick = opts.RetryInterval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		e.dispatch()
+		select {
+		case <-ticker.C:
+		case <-e.wakeC:
+		case <-e.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// dispatch 分页读取发件箱，并发推送到期的事件
+func (e *webhookEndpoint) dispatch() {
+	db := e.w.s.store.DB()
+	var startId uint64
+	for {
+		events, err := db.GetWebhookEvents(wkdb.WebhookQueueOutbox, e.cfg.Name, startId, webhookOutboxPageSize)
+		if err != nil {
+			e.Error("获取发件箱事件失败！", zap.Error(err))
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+		startId = events[len(events)-1].Id
+
+		nowMill := time.Now().UnixMilli()
+		wg := &sync.WaitGroup{}
+		for _, event := range events {
+			if event.NextRetryAt > nowMill {
+				continue
+			}
+			event := event
+			wg.Add(1)
+			err = e.pool.Submit(func() {
+				defer wg.Done()
+				e.deliver(event)
+			})
+			if err != nil {
+				wg.Done()
+				e.Error("提交webhook事件失败！", zap.Error(err), zap.Uint64("eventId", event.Id))
+			}
+		}
+		wg.Wait()
+
+		if len(events) < webhookOutboxPageSize {
+			return
+		}
+		select {
+		case <-e.stopper.ShouldStop():
+			return
+		default:
+		}
+	}
+}
+
+// deliver 推送事件，成功则从发件箱移除，失败则延迟重试，超过最大重试次数移入死信队列
+func (e *webhookEndpoint) deliver(event wkdb.WebhookEvent) {
+	err := e.send(event)
+	db := e.w.s.store.DB()
+	if err == nil {
+		if err = db.RemoveWebhookEvents(wkdb.WebhookQueueOutbox, e.cfg.Name, []uint64{event.Id}); err != nil {
+			e.Error("从发件箱移除事件失败！", zap.Error(err), zap.Uint64("eventId", event.Id))
+		}
+		return
+	}
+	event.Attempts++
+	event.LastError = err.Error()
+	if event.Attempts >= e.w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
+		e.Warn("webhook事件推送失败次数超过最大重试次数，移入死信队列！", zap.Uint64("eventId", event.Id), zap.String("event", event.Event), zap.Int("attempts", event.Attempts), zap.Error(err))
+		event.DeadAt = time.Now().Unix()
+		if err = db.MoveWebhookEvents(wkdb.WebhookQueueOutbox, wkdb.WebhookQueueDeadLetter, []wkdb.WebhookEvent{event}); err != nil {
+			e.Error("webhook事件移入死信队列失败！", zap.Error(err), zap.Uint64("eventId", event.Id))
+		}
+		return
+	}
+	nextRetryAt := time.Now().Add(e.retryBackoff(event.Attempts)).UnixMilli()
+	if err = db.UpdateWebhookEventRetry(e.cfg.Name, event.Id, event.Attempts, nextRetryAt, event.LastError); err != nil {
+		e.Error("更新webhook事件重试信息失败！", zap.Error(err), zap.Uint64("eventId", event.Id))
+	}
+}
+
+// retryBackoff 第attempts次失败后的重试间隔，每次翻倍，不超过最大重试间隔
+func (e *webhookEndpoint) retryBackoff(attempts int) time.Duration {
+	interval := e.w.s.opts.Webhook.RetryInterval
+	maxInterval := e.w.s.opts.Webhook.RetryMaxInterval
+	for i := 1; i < attempts; i++ {
+		interval *= 2
+		if maxInterval > 0 && interval >= maxInterval {
+			return maxInterval
+		}
+	}
+	return interval
+}
+
+func (e *webhookEndpoint) send(event wkdb.WebhookEvent) error {
+	if e.isGRPC() {
+		return e.sendForGRPC(event)
+	}
+	return e.sendForHttp(event)
+}
+
+func (e *webhookEndpoint) sendForHttp(event wkdb.WebhookEvent) error {
+	eventURL := fmt.Sprintf("%s?event=%s", e.cfg.Addr, event.Event)
+	startTime := time.Now().UnixNano() / 1000 / 1000
+	e.Debug("webhook开始请求", zap.String("eventURL", eventURL))
+	req, err := http.NewRequest(http.MethodPost, eventURL, bytes.NewBuffer(event.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range signHeaders(e.secret(), event.Id, event.Event, event.Data) {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.w.httpClient.Do(req)
+	e.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+	if err != nil {
+		e.Warn("调用第三方消息通知失败！", zap.String("Webhook", e.cfg.Addr), zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		e.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", e.cfg.Addr))
+		return fmt.Errorf("第三方消息通知接口返回状态错误！status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *webhookEndpoint) sendForGRPC(event wkdb.WebhookEvent) error {
+	startTime := time.Now().UnixNano() / 1000 / 1000
+	e.Debug("webhook grpc 开始请求", zap.String("event", event.Event))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	clientConn, err := e.grpcPool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+	cli := wkhook.NewWebhookServiceClient(clientConn)
+
+	sendCtx, sendCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer sendCancel()
+	sendCtx = signGRPCContext(sendCtx, e.secret(), event.Id, event.Event, event.Data)
+	resp, err := cli.SendWebhook(sendCtx, &wkhook.EventReq{
+		Event: event.Event,
+		Data:  event.Data,
+	})
+	e.Debug("webhook grpc 请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
+
+	if err != nil {
+		return err
+	}
+	if resp.Status != wkhook.EventStatus_Success {
+		return errors.New("grpc返回状态错误！")
+	}
+	return nil
+}
+
+// replayDeadLetters 将死信队列内的事件重新放入发件箱，返回重放的事件id
+func (e *webhookEndpoint) replayDeadLetters(ids []uint64) ([]uint64, error) {
+	db := e.w.s.store.DB()
+	events := make([]wkdb.WebhookEvent, 0, len(ids))
+	for _, id := range ids {
+		event, err := db.GetWebhookEvent(wkdb.WebhookQueueDeadLetter, e.cfg.Name, id)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		event.Attempts = 0
+		event.NextRetryAt = 0
+		event.LastError = ""
+		event.DeadAt = 0
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return []uint64{}, nil
+	}
+	if err := db.MoveWebhookEvents(wkdb.WebhookQueueDeadLetter, wkdb.WebhookQueueOutbox, events); err != nil {
+		return nil, err
+	}
+	e.wake()
+	replayed := make([]uint64, 0, len(events))
+	for _, event := range events {
+		replayed = append(replayed, event.Id)
+	}
+	return replayed, nil
+}
+
+// appendOutbox 将事件写入订阅了此事件的端点的发件箱并唤醒推送，同一事件在各端点的id相同
+func (w *webhook) appendOutbox(event string, data []byte) error {
+	endpoints := w.subscribedEndpoints(event)
+	if len(endpoints) == 0 {
+		return nil
+	}
+	var (
+		id        = w.s.store.DB().NextPrimaryKey()
+		createdAt = time.Now().Unix()
+		events    = make([]wkdb.WebhookEvent, 0, len(endpoints))
+	)
+	for _, endpoint := range endpoints {
+		events = append(events, wkdb.WebhookEvent{
+			Id:        id,
+			Endpoint:  endpoint.cfg.Name,
+			Event:     event,
+			Data:      data,
+			CreatedAt: createdAt,
+		})
+	}
+	if err := w.s.store.DB().AppendWebhookEvents(wkdb.WebhookQueueOutbox, events); err != nil {
+		return err
+	}
+	for _, endpoint := range endpoints {
+		endpoint.wake()
+	}
+	return nil
+}
+
+// signHeaders webhook请求的事件id和签名头，eventId为0时不带事件id，secret为空时不签名
+func signHeaders(secret string, eventId uint64, event string, body []byte) map[string]string {
+	headers := make(map[string]string, 3)
+	if eventId != 0 {
+		headers[webhookHeaderEventId] = strconv.FormatUint(eventId, 10)
+	}
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[webhookHeaderTimestamp] = timestamp
+		headers[webhookHeaderSignature] = "sha256=" + webhookSignature(secret, timestamp, event, body)
+	}
+	return headers
+}
+
+// signGRPCContext 将签名头以metadata的形式附加到grpc请求
+func signGRPCContext(ctx context.Context, secret string, eventId uint64, event string, body []byte) context.Context {
+	headers := signHeaders(secret, eventId, event, body)
+	if len(headers) == 0 {
+		return ctx
+	}
+	kv := make([]string, 0, len(headers)*2)
+	for k, v := range headers {
+		kv = append(kv, k, v) // metadata的key会被转成小写
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// webhookSignature hex(hmac_sha256(secret, timestamp + "." + event + "." + body))
+func webhookSignature(secret, timestamp, event string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(event))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
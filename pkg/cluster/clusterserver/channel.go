@@ -27,7 +27,8 @@ type channel struct {
 	wklog.Log
 	mu             sync.Mutex
 	cfg            wkdb.ChannelClusterConfig
-	pausePropopose atomic.Bool // 是否暂停提案
+	pausePropopose atomic.Bool   // 是否暂停提案
+	syncedIndex    atomic.Uint64 // 所有副本都已存储的日志下标（只在领导节点有值，在reactor的tick中更新）
 
 	sendConfigTick        int // 发送配置计数器
 	sendConfigTimeoutTick int // 发送配置超时（达到这个tick表示，需要发送配置请求了）
@@ -66,6 +67,8 @@ func newChannel(channelId string, channelType uint8, s *Server) *channel {
 		replica.WithAutoRoleSwith(true),
 		replica.WithLastIndex(lastIndex),
 		replica.WithLastTerm(lastTerm),
+		// 频道日志会被保留策略清理，同步请求的日志早于第一条日志时返回ErrCompacted，避免向副本发送不连续的日志
+		replica.WithStorage(newProxyReplicaStorage(c.key, c.opts.MessageLogStorage)),
 	)
 	c.rc = rc
 	return c
@@ -137,6 +140,16 @@ func (c *channel) configVersion() uint64 {
 
 }
 
+// replicaIds 频道的副本和学习者节点
+func (c *channel) replicaIds() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodeIds := make([]uint64, 0, len(c.cfg.Replicas)+len(c.cfg.Learners))
+	nodeIds = append(nodeIds, c.cfg.Replicas...)
+	nodeIds = append(nodeIds, c.cfg.Learners...)
+	return nodeIds
+}
+
 // --------------------------IHandler-------------------------------
 
 func (c *channel) LastLogIndexAndTerm() (uint64, uint32) {
@@ -156,6 +169,13 @@ func (c *channel) GetLogs(startIndex uint64, endIndex uint64) ([]replica.Log, er
 }
 
 func (c *channel) ApplyLogs(startIndex, endIndex uint64) (uint64, error) {
+	if c.opts.OnChannelApply != nil {
+		err := c.opts.OnChannelApply(c.channelId, c.channelType, startIndex, endIndex)
+		if err != nil {
+			c.Error("on channel apply error", zap.Error(err), zap.Uint64("startIndex", startIndex), zap.Uint64("endIndex", endIndex))
+			return 0, err
+		}
+	}
 	return 0, nil
 }
 
@@ -180,6 +200,7 @@ func (c *channel) SetHardState(hd replica.HardState) {
 
 func (c *channel) Tick() {
 	c.rc.Tick()
+	c.syncedIndex.Store(c.rc.SyncedIndex())
 
 	// if c.isLeader() {
 	// 	c.sendConfigTick++
@@ -1026,17 +1026,18 @@ type messageRespTotal struct {
 }
 
 type messageResp struct {
-	MessageId       string `json:"message_id"`       // 服务端的消息ID(全局唯一)
-	MessageSeq      uint32 `json:"message_seq"`      // 消息序列号 （用户唯一，有序递增）
-	ClientMsgNo     string `json:"client_msg_no"`    // 客户端唯一标示
-	Timestamp       int32  `json:"timestamp"`        // 服务器消息时间戳(10位，到秒)
-	TimestampForamt string `json:"timestamp_format"` // 服务器消息时间戳格式化
-	ChannelId       string `json:"channel_id"`       // 频道ID
-	ChannelType     uint8  `json:"channel_type"`     // 频道类型
-	Topic           string `json:"topic"`            // 话题ID
-	FromUid         string `json:"from_uid"`         // 发送者UID
-	Payload         []byte `json:"payload"`          // 消息内容
-	Expire          uint32 `json:"expire"`           // 消息过期时间 0 表示永不过期
+	MessageId       string  `json:"message_id"`       // 服务端的消息ID(全局唯一)
+	MessageSeq      uint32  `json:"message_seq"`      // 消息序列号 （用户唯一，有序递增）
+	ClientMsgNo     string  `json:"client_msg_no"`    // 客户端唯一标示
+	Timestamp       int32   `json:"timestamp"`        // 服务器消息时间戳(10位，到秒)
+	TimestampForamt string  `json:"timestamp_format"` // 服务器消息时间戳格式化
+	ChannelId       string  `json:"channel_id"`       // 频道ID
+	ChannelType     uint8   `json:"channel_type"`     // 频道类型
+	Topic           string  `json:"topic"`            // 话题ID
+	FromUid         string  `json:"from_uid"`         // 发送者UID
+	Payload         []byte  `json:"payload"`          // 消息内容
+	Expire          uint32  `json:"expire"`           // 消息过期时间 0 表示永不过期
+	Score           float64 `json:"score,omitempty"`  // 全文搜索的相关度
 }
 
 func newMessageResp(m wkdb.Message) *messageResp {
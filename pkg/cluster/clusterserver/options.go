@@ -59,6 +59,8 @@ type Options struct {
 	// MessageLogStorage 消息日志存储
 	MessageLogStorage IShardLogStorage
 	OnSlotApply       func(slotId uint32, logs []replica.Log) error
+	// OnChannelApply 频道日志应用（应用[startIndex,endIndex)之间的日志）
+	OnChannelApply func(channelId string, channelType uint8, startIndex, endIndex uint64) error
 	// Send 发送消息
 	Send func(shardType ShardType, m reactor.Message)
 	// ChannelElectionPoolSize 频道选举协程池大小(意味着同时在选举的频道数量)
@@ -164,6 +166,12 @@ func WithOnSlotApply(fn func(slotId uint32, logs []replica.Log) error) Option {
 	}
 }
 
+func WithOnChannelApply(fn func(channelId string, channelType uint8, startIndex, endIndex uint64) error) Option {
+	return func(o *Options) {
+		o.OnChannelApply = fn
+	}
+}
+
 func WithLogSyncLimitSizeOfEach(size int) Option {
 	return func(o *Options) {
 		o.LogSyncLimitSizeOfEach = size
@@ -32,27 +32,28 @@ func (s *Server) ServerAPI(route *wkhttp.WKHttp, prefix string) {
 	route.GET(s.formatPath("/nodes/:id/channels"), s.nodeChannelsGet) // 获取节点的所有频道信息
 
 	// route.GET(s.formatPath("/channels/:channel_id/:channel_type/config"), s.channelClusterConfigGet) // 获取频道分布式配置
-	route.GET(s.formatPath("/slots"), s.slotsGet)                                                      // 获取指定的槽信息
-	route.GET(s.formatPath("/allslot"), s.allSlotsGet)                                                 // 获取所有槽信息
-	route.GET(s.formatPath("/slots/:id/config"), s.slotClusterConfigGet)                               // 槽分布式配置
-	route.GET(s.formatPath("/slots/:id/channels"), s.slotChannelsGet)                                  // 获取某个槽的所有频道信息
-	route.POST(s.formatPath("/slots/:id/migrate"), s.slotMigrate)                                      // 迁移槽
-	route.GET(s.formatPath("/info"), s.clusterInfoGet)                                                 // 获取集群信息
-	route.GET(s.formatPath("/messages"), s.messageSearch)                                              // 搜索消息
-	route.GET(s.formatPath("/channels"), s.channelSearch)                                              // 频道搜索
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/subscribers"), s.subscribersGet)       // 获取频道的订阅者列表
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/denylist"), s.denylistGet)             // 获取黑名单列表
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/allowlist"), s.allowlistGet)           // 获取白名单列表
-	route.GET(s.formatPath("/users"), s.userSearch)                                                    // 用户搜索
-	route.GET(s.formatPath("/devices"), s.deviceSearch)                                                // 设备搜索
-	route.GET(s.formatPath("/conversations"), s.conversationSearch)                                    // 搜索最近会话消息
-	route.POST(s.formatPath("/channels/:channel_id/:channel_type/migrate"), s.channelMigrate)          // 迁移频道
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/config"), s.channelClusterConfig)      // 获取频道的分布式配置
-	route.POST(s.formatPath("/channels/:channel_id/:channel_type/start"), s.channelStart)              // 开始频道
-	route.POST(s.formatPath("/channels/:channel_id/:channel_type/stop"), s.channelStop)                // 停止频道
-	route.POST(s.formatPath("/channel/status"), s.channelStatus)                                       // 获取频道状态
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/replicas"), s.channelReplicas)         // 获取频道副本信息
-	route.GET(s.formatPath("/channels/:channel_id/:channel_type/localReplica"), s.channelLocalReplica) // 获取频道在本节点的副本信息
+	route.GET(s.formatPath("/slots"), s.slotsGet)                                                          // 获取指定的槽信息
+	route.GET(s.formatPath("/allslot"), s.allSlotsGet)                                                     // 获取所有槽信息
+	route.GET(s.formatPath("/slots/:id/config"), s.slotClusterConfigGet)                                   // 槽分布式配置
+	route.GET(s.formatPath("/slots/:id/channels"), s.slotChannelsGet)                                      // 获取某个槽的所有频道信息
+	route.POST(s.formatPath("/slots/:id/migrate"), s.slotMigrate)                                          // 迁移槽
+	route.GET(s.formatPath("/info"), s.clusterInfoGet)                                                     // 获取集群信息
+	route.GET(s.formatPath("/messages"), s.messageSearch)                                                  // 搜索消息
+	route.GET(s.formatPath("/channels"), s.channelSearch)                                                  // 频道搜索
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/subscribers"), s.subscribersGet)           // 获取频道的订阅者列表，支持cursor和limit分页
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/subscribers/count"), s.subscriberCountGet) // 获取频道的订阅者数量
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/denylist"), s.denylistGet)                 // 获取黑名单列表
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/allowlist"), s.allowlistGet)               // 获取白名单列表
+	route.GET(s.formatPath("/users"), s.userSearch)                                                        // 用户搜索
+	route.GET(s.formatPath("/devices"), s.deviceSearch)                                                    // 设备搜索
+	route.GET(s.formatPath("/conversations"), s.conversationSearch)                                        // 搜索最近会话消息
+	route.POST(s.formatPath("/channels/:channel_id/:channel_type/migrate"), s.channelMigrate)              // 迁移频道
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/config"), s.channelClusterConfig)          // 获取频道的分布式配置
+	route.POST(s.formatPath("/channels/:channel_id/:channel_type/start"), s.channelStart)                  // 开始频道
+	route.POST(s.formatPath("/channels/:channel_id/:channel_type/stop"), s.channelStop)                    // 停止频道
+	route.POST(s.formatPath("/channel/status"), s.channelStatus)                                           // 获取频道状态
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/replicas"), s.channelReplicas)             // 获取频道副本信息
+	route.GET(s.formatPath("/channels/:channel_id/:channel_type/localReplica"), s.channelLocalReplica)     // 获取频道在本节点的副本信息
 
 	route.GET(s.formatPath("/logs"), s.clusterLogs) // 获取节点日志
 
@@ -692,6 +693,11 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 	payloadStr := strings.TrimSpace(c.Query("payload"))                   // base64编码的消息内容
 	messageId := wkutil.ParseInt64(c.Query("message_id"))
 	clientMsgNo := strings.TrimSpace(c.Query("client_msg_no"))
+	keyword := strings.TrimSpace(c.Query("keyword"))      // 全文搜索关键字
+	orderBy := strings.TrimSpace(c.Query("order"))        // 全文搜索的排序方式 time: 按时间 relevance: 按相关度
+	startTime := wkutil.ParseInt64(c.Query("start_time")) // 消息开始时间（10位，到秒）
+	endTime := wkutil.ParseInt64(c.Query("end_time"))     // 消息结束时间（10位，到秒，包含）
+	byRelevance := keyword != "" && orderBy == "relevance"
 
 	// 解密payload
 	var payload []byte
@@ -722,8 +728,40 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 		return
 	}
 
+	// 通过全文索引搜索本地消息
+	var searchLocalMessageByKeyword = func() ([]*messageResp, error) {
+		req := wkdb.MessageKeywordSearchReq{
+			Keyword:         keyword,
+			ChannelId:       channelId,
+			ChannelType:     channelType,
+			FromUid:         fromUid,
+			StartTime:       startTime,
+			EndTime:         endTime,
+			OffsetMessageId: offsetMessageId,
+			Limit:           limit,
+		}
+		if byRelevance {
+			req.OrderBy = wkdb.MessageSearchOrderRelevance
+		}
+		results, err := s.opts.DB.SearchMessagesByKeyword(req)
+		if err != nil {
+			s.Error("全文搜索消息失败！", zap.Error(err))
+			return nil, err
+		}
+		resps := make([]*messageResp, 0, len(results))
+		for _, result := range results {
+			resp := newMessageResp(result.Message)
+			resp.Score = result.Score
+			resps = append(resps, resp)
+		}
+		return resps, nil
+	}
+
 	// 搜索本地消息
 	var searchLocalMessage = func() ([]*messageResp, error) {
+		if keyword != "" {
+			return searchLocalMessageByKeyword()
+		}
 		messages, err := s.opts.DB.SearchMessages(wkdb.MessageSearchReq{
 			MessageId:        messageId,
 			FromUid:          fromUid,
@@ -735,6 +773,8 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 			Pre:              pre == 1,
 			Payload:          payload,
 			ClientMsgNo:      clientMsgNo,
+			StartTime:        startTime,
+			EndTime:          endTime,
 		})
 		if err != nil {
 			s.Error("查询消息失败！", zap.Error(err))
@@ -834,7 +874,9 @@ func (s *Server) messageSearch(c *wkhttp.Context) {
 	}
 
 	sort.Slice(messages, func(i, j int) bool {
-
+		if byRelevance && messages[i].Score != messages[j].Score {
+			return messages[i].Score > messages[j].Score
+		}
 		return messages[i].MessageId > messages[j].MessageId
 	})
 
@@ -1143,15 +1185,57 @@ func (s *Server) subscribersGet(c *wkhttp.Context) {
 		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
 		return
 	}
-	subscribers, err := s.opts.DB.GetSubscribers(channelId, channelType)
+	cursor := c.Query("cursor") // 上一页最后一个订阅者的uid
+	limit := wkutil.ParseInt(c.Query("limit"))
+	if cursor == "" && limit <= 0 {
+		subscribers, err := s.opts.DB.GetSubscribersDetail(channelId, channelType)
+		if err != nil {
+			s.Error("GetSubscribersDetail error", zap.Error(err))
+			c.ResponseError(err)
+			return
+		}
+		c.JSON(http.StatusOK, subscribers)
+		return
+	}
+
+	if limit <= 0 {
+		limit = s.opts.PageSize
+	}
+	subscribers, err := s.opts.DB.GetSubscribersPage(channelId, channelType, cursor, limit)
 	if err != nil {
-		s.Error("GetSubscribers error", zap.Error(err))
+		s.Error("GetSubscribersPage error", zap.Error(err))
 		c.ResponseError(err)
 		return
 	}
 	c.JSON(http.StatusOK, subscribers)
 }
 
+func (s *Server) subscriberCountGet(c *wkhttp.Context) {
+	channelId := c.Param("channel_id")
+	channelType := wkutil.ParseUint8(c.Param("channel_type"))
+
+	leaderNode, err := s.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		s.Error("SlotLeaderOfChannel error", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+
+	if leaderNode.Id != s.opts.NodeId {
+		c.Forward(fmt.Sprintf("%s%s", leaderNode.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+	count, err := s.opts.DB.GetSubscriberCount(channelId, channelType)
+	if err != nil {
+		s.Error("GetSubscriberCount error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"count": count,
+	})
+}
+
 func (s *Server) denylistGet(c *wkhttp.Context) {
 	channelId := c.Param("channel_id")
 	channelType := wkutil.ParseUint8(c.Param("channel_type"))
@@ -58,6 +58,23 @@ func (s *Server) LeaderOfChannelForRead(channelId string, channelType uint8) (*p
 	return node, nil
 }
 
+// ChannelSyncedIndex 获取频道所有副本都已存储的日志下标，本节点不是频道领导或频道未加载时返回0
+func (s *Server) ChannelSyncedIndex(channelId string, channelType uint8) uint64 {
+	handler := s.channelManager.get(channelId, channelType)
+	if handler == nil {
+		return 0
+	}
+	return handler.(*channel).syncedIndex.Load()
+}
+
+func (s *Server) ChannelReplicaIds(channelId string, channelType uint8) []uint64 {
+	handler := s.channelManager.get(channelId, channelType)
+	if handler == nil {
+		return nil
+	}
+	return handler.(*channel).replicaIds()
+}
+
 func (s *Server) SlotLeaderIdOfChannel(channelId string, channelType uint8) (nodeID uint64, err error) {
 	slotId := s.getSlotId(channelId)
 	slot := s.clusterEventServer.Slot(slotId)
@@ -87,6 +104,15 @@ func (s *Server) SlotLeaderOfChannel(channelId string, channelType uint8) (*pb.N
 	return node, nil
 }
 
+func (s *Server) SlotLeaderTermOfChannel(channelId string, channelType uint8) (uint32, error) {
+	slotId := s.getSlotId(channelId)
+	slot := s.clusterEventServer.Slot(slotId)
+	if slot == nil {
+		return 0, ErrSlotNotFound
+	}
+	return slot.Term, nil
+}
+
 func (s *Server) IsSlotLeaderOfChannel(channelID string, channelType uint8) (bool, error) {
 	slotId := s.getSlotId(channelID)
 	slot := s.clusterEventServer.Slot(slotId)
@@ -20,6 +20,8 @@ type IShardLogStorage interface {
 	Logs(shardNo string, startLogIndex uint64, endLogIndex uint64, limitSize uint64) ([]replica.Log, error)
 	// 最后一条日志的索引
 	LastIndex(shardNo string) (uint64, error)
+	// FirstIndex 第一条日志的索引（之前的日志已被清理），0表示日志未被清理过
+	FirstIndex(shardNo string) (uint64, error)
 	// LastIndexAndTerm 获取最后一条日志的索引和任期
 	LastIndexAndTerm(shardNo string) (uint64, uint32, error)
 	// SetLastIndex 设置最后一条日志的索引
@@ -104,6 +106,10 @@ func (m *MemoryShardLogStorage) LastIndex(shardNo string) (uint64, error) {
 	return uint64(len(logs) - 1), nil
 }
 
+func (m *MemoryShardLogStorage) FirstIndex(shardNo string) (uint64, error) {
+	return 0, nil
+}
+
 func (m *MemoryShardLogStorage) SetLastIndex(shardNo string, index uint64) error {
 
 	return nil
@@ -195,7 +201,7 @@ func (p *proxyReplicaStorage) LastIndexAndTerm() (uint64, uint32, error) {
 }
 
 func (p *proxyReplicaStorage) FirstIndex() (uint64, error) {
-	return 0, nil
+	return p.storage.FirstIndex(p.shardNo)
 }
 
 func (p *proxyReplicaStorage) LastIndexAndAppendTime() (uint64, uint64, error) {
@@ -327,6 +327,11 @@ func (p *PebbleShardLogStorage) GetLogsInReverseOrder(shardNo string, startLogIn
 	return logs, nil
 }
 
+// FirstIndex 槽日志不会被清理
+func (p *PebbleShardLogStorage) FirstIndex(shardNo string) (uint64, error) {
+	return 0, nil
+}
+
 func (p *PebbleShardLogStorage) LastIndex(shardNo string) (uint64, error) {
 	lastIndex, _, err := p.getMaxIndex(shardNo)
 	return lastIndex, err
@@ -2,6 +2,7 @@ package clusterstore
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -76,6 +77,50 @@ const (
 	CMDBatchUpdateConversation
 	// 	// 添加或更新用户和设备
 	CMDAddOrUpdateUserAndDevice
+	// 撤回消息
+	CMDMessageRevoke
+	// 编辑消息
+	CMDMessageEdit
+	// 删除频道指定序号之前的消息
+	CMDMessageDeleteBefore
+	// 添加定时消息
+	CMDAddScheduledMessage
+	// 删除定时消息
+	CMDDeleteScheduledMessage
+	// 添加或更新消息回应
+	CMDAddOrUpdateReaction
+	// 添加消息已读回执
+	CMDAddMessageReceipt
+	// 添加或更新频道禁言
+	CMDAddOrUpdateChannelMutes
+	// 解除频道禁言
+	CMDRemoveChannelMutes
+	// 设置订阅者角色
+	CMDSetSubscriberRole
+	// 添加订阅者（包含属性），已存在的订阅者更新属性
+	CMDAddOrUpdateSubscribers
+	// 更新订阅者属性
+	CMDUpdateSubscribers
+	// 添加或更新设备推送令牌
+	CMDAddOrUpdateDevicePush
+	// 移除设备推送令牌
+	CMDRemoveDevicePush
+	// 添加或更新会话免打扰
+	CMDAddOrUpdateConversationMutes
+	// 移除会话免打扰
+	CMDRemoveConversationMutes
+	// 添加或更新用户封禁
+	CMDAddOrUpdateUserBan
+	// 移除用户封禁
+	CMDRemoveUserBan
+	// 添加或更新发送频率限制规则
+	CMDAddOrUpdateRateLimits
+	// 移除发送频率限制规则
+	CMDRemoveRateLimits
+	// 保存频道的消息命令（撤回、编辑、清理等，在频道的每个副本上应用）
+	CMDAppendChannelMessageCmd
+	// 清理频道的过期消息
+	CMDMessageDeleteExpired
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -146,6 +191,50 @@ func (c CMDType) String() string {
 		return "CMDDeleteConversations"
 	case CMDAddOrUpdateUserAndDevice:
 		return "CMDAddOrUpdateUserAndDevice"
+	case CMDMessageRevoke:
+		return "CMDMessageRevoke"
+	case CMDMessageEdit:
+		return "CMDMessageEdit"
+	case CMDMessageDeleteBefore:
+		return "CMDMessageDeleteBefore"
+	case CMDAddScheduledMessage:
+		return "CMDAddScheduledMessage"
+	case CMDDeleteScheduledMessage:
+		return "CMDDeleteScheduledMessage"
+	case CMDAddOrUpdateReaction:
+		return "CMDAddOrUpdateReaction"
+	case CMDAddMessageReceipt:
+		return "CMDAddMessageReceipt"
+	case CMDAddOrUpdateChannelMutes:
+		return "CMDAddOrUpdateChannelMutes"
+	case CMDRemoveChannelMutes:
+		return "CMDRemoveChannelMutes"
+	case CMDSetSubscriberRole:
+		return "CMDSetSubscriberRole"
+	case CMDAddOrUpdateSubscribers:
+		return "CMDAddOrUpdateSubscribers"
+	case CMDUpdateSubscribers:
+		return "CMDUpdateSubscribers"
+	case CMDAddOrUpdateDevicePush:
+		return "CMDAddOrUpdateDevicePush"
+	case CMDRemoveDevicePush:
+		return "CMDRemoveDevicePush"
+	case CMDAddOrUpdateConversationMutes:
+		return "CMDAddOrUpdateConversationMutes"
+	case CMDRemoveConversationMutes:
+		return "CMDRemoveConversationMutes"
+	case CMDAddOrUpdateUserBan:
+		return "CMDAddOrUpdateUserBan"
+	case CMDRemoveUserBan:
+		return "CMDRemoveUserBan"
+	case CMDAddOrUpdateRateLimits:
+		return "CMDAddOrUpdateRateLimits"
+	case CMDRemoveRateLimits:
+		return "CMDRemoveRateLimits"
+	case CMDAppendChannelMessageCmd:
+		return "CMDAppendChannelMessageCmd"
+	case CMDMessageDeleteExpired:
+		return "CMDMessageDeleteExpired"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -393,6 +482,239 @@ func (c *CMD) CMDContent() (string, error) {
 		}
 		return wkutil.ToJSON(channelClusterConfig), nil
 
+	case CMDMessageRevoke:
+		channelId, channelType, messageSeq, err := c.DecodeCMDMessageRevoke()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"messageSeq":  messageSeq,
+		}), nil
+
+	case CMDMessageEdit:
+		req, err := c.DecodeCMDMessageEdit()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(req), nil
+
+	case CMDMessageDeleteBefore:
+		channelId, channelType, messageSeq, err := c.DecodeCMDMessageDeleteBefore()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"messageSeq":  messageSeq,
+		}), nil
+
+	case CMDMessageDeleteExpired:
+		channelId, channelType, messageSeqs, err := c.DecodeCMDMessageDeleteExpired()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"messageSeqs": messageSeqs,
+		}), nil
+
+	case CMDSaveStreamMeta:
+		meta, err := c.DecodeCMDSaveStreamMeta()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(meta), nil
+
+	case CMDStreamEnd:
+		channelId, channelType, streamNo, err := c.DecodeCMDStreamEnd()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"streamNo":    streamNo,
+		}), nil
+
+	case CMDAppendStreamItem:
+		channelId, channelType, streamNo, item, err := c.DecodeCMDAppendStreamItem()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"streamNo":    streamNo,
+			"streamSeq":   item.StreamSeq,
+			"clientMsgNo": item.ClientMsgNo,
+			"payload":     item.Payload,
+		}), nil
+
+	case CMDAddScheduledMessage:
+		m, err := c.DecodeCMDAddScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(m), nil
+
+	case CMDDeleteScheduledMessage:
+		channelId, channelType, id, err := c.DecodeCMDDeleteScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"id":          id,
+		}), nil
+
+	case CMDAddOrUpdateReaction:
+		reaction, err := c.DecodeCMDAddOrUpdateReaction()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(reaction), nil
+
+	case CMDAddMessageReceipt:
+		receipt, onlyCount, err := c.DecodeCMDAddMessageReceipt()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"receipt":   receipt,
+			"onlyCount": onlyCount,
+		}), nil
+
+	case CMDAddOrUpdateChannelMutes:
+		channelId, channelType, mutes, err := c.DecodeCMDAddOrUpdateChannelMutes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"mutes":       mutes,
+		}), nil
+
+	case CMDRemoveChannelMutes:
+		channelId, channelType, uids, err := c.DecodeCMDRemoveChannelMutes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"uids":        uids,
+		}), nil
+
+	case CMDSetSubscriberRole:
+		channelId, channelType, uids, role, err := c.DecodeCMDSetSubscriberRole()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"uids":        uids,
+			"role":        role,
+		}), nil
+
+	case CMDAddOrUpdateSubscribers, CMDUpdateSubscribers:
+		channelId, channelType, subscribers, err := c.DecodeCMDSubscribersWithAttrs()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"subscribers": subscribers,
+		}), nil
+
+	case CMDAddOrUpdateDevicePush:
+		push, err := c.DecodeCMDDevicePush()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(push), nil
+
+	case CMDRemoveDevicePush:
+		uid, deviceFlag, err := c.DecodeCMDRemoveDevicePush()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":        uid,
+			"deviceFlag": deviceFlag,
+		}), nil
+
+	case CMDAddOrUpdateConversationMutes:
+		uid, mutes, err := c.DecodeCMDAddOrUpdateConversationMutes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":   uid,
+			"mutes": mutes,
+		}), nil
+
+	case CMDRemoveConversationMutes:
+		uid, channels, err := c.DecodeCMDRemoveConversationMutes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":      uid,
+			"channels": channels,
+		}), nil
+
+	case CMDAddOrUpdateUserBan:
+		ban, err := c.DecodeCMDAddOrUpdateUserBan()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(ban), nil
+
+	case CMDRemoveUserBan:
+		uid, createdAt, err := c.DecodeCMDRemoveUserBan()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":       uid,
+			"createdAt": createdAt,
+		}), nil
+
+	case CMDAddOrUpdateRateLimits, CMDRemoveRateLimits:
+		limits, err := c.DecodeCMDRateLimits()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(limits), nil
+
+	case CMDAppendChannelMessageCmd:
+		channelId, channelType, data, err := c.DecodeCMDAppendChannelMessageCmd()
+		if err != nil {
+			return "", err
+		}
+		innerCmd := &CMD{}
+		if err = innerCmd.Unmarshal(data); err != nil {
+			return "", err
+		}
+		innerJson, err := innerCmd.CMDContent()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"cmdType":     innerCmd.CmdType.String(),
+			"cmd":         innerJson,
+		}), nil
+
 	}
 
 	return "", nil
@@ -720,36 +1042,83 @@ func (c *CMD) DecodeCMDStreamEnd() (channelID string, channelType uint8, streamN
 	return
 }
 
-// func EncodeCMDAppendStreamItem(channelID string, channelType uint8, streamNo string, item *wkstore.StreamItem) []byte {
-// 	encoder := wkproto.NewEncoder()
-// 	defer encoder.End()
-
-// 	encoder.WriteString(channelID)
-// 	encoder.WriteUint8(channelType)
-// 	encoder.WriteString(streamNo)
-// 	encoder.WriteBinary(wkstore.EncodeStreamItem(item))
-
-// 	return encoder.Bytes()
-// }
-// func (c *CMD) DecodeCMDAppendStreamItem() (channelID string, channelType uint8, streamNo string, item *wkstore.StreamItem, err error) {
-// 	decoder := wkproto.NewDecoder(c.Data)
-// 	if channelID, err = decoder.String(); err != nil {
-// 		return
-// 	}
-// 	if channelType, err = decoder.Uint8(); err != nil {
-// 		return
-// 	}
-// 	if streamNo, err = decoder.String(); err != nil {
-// 		return
-// 	}
-// 	var itemBytes []byte
-// 	itemBytes, err = decoder.Binary()
-// 	if err != nil {
-// 		return
-// 	}
-// 	item, err = wkstore.DecodeStreamItem(itemBytes)
-// 	return
-// }
+func EncodeCMDSaveStreamMeta(meta wkdb.StreamMeta) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(meta.StreamNo)
+	encoder.WriteString(meta.ChannelId)
+	encoder.WriteUint8(meta.ChannelType)
+	encoder.WriteInt64(meta.MessageId)
+	encoder.WriteString(meta.FromUid)
+	encoder.WriteUint8(uint8(meta.StreamFlag))
+	encoder.WriteInt64(meta.CreatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSaveStreamMeta() (meta wkdb.StreamMeta, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if meta.StreamNo, err = decoder.String(); err != nil {
+		return
+	}
+	if meta.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if meta.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if meta.MessageId, err = decoder.Int64(); err != nil {
+		return
+	}
+	if meta.FromUid, err = decoder.String(); err != nil {
+		return
+	}
+	var streamFlag uint8
+	if streamFlag, err = decoder.Uint8(); err != nil {
+		return
+	}
+	meta.StreamFlag = wkproto.StreamFlag(streamFlag)
+	if meta.CreatedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAppendStreamItem(channelID string, channelType uint8, streamNo string, item wkdb.StreamItem) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+
+	encoder.WriteString(channelID)
+	encoder.WriteUint8(channelType)
+	encoder.WriteString(streamNo)
+	encoder.WriteUint32(item.StreamSeq)
+	encoder.WriteString(item.ClientMsgNo)
+	encoder.WriteBinary(item.Payload)
+
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAppendStreamItem() (channelID string, channelType uint8, streamNo string, item wkdb.StreamItem, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelID, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if streamNo, err = decoder.String(); err != nil {
+		return
+	}
+	if item.StreamSeq, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if item.ClientMsgNo, err = decoder.String(); err != nil {
+		return
+	}
+	if item.Payload, err = decoder.Binary(); err != nil {
+		return
+	}
+	return
+}
 
 func EncodeCMDChannelClusterConfigSave(channelID string, channelType uint8, data []byte) ([]byte, error) {
 	encoder := wkproto.NewEncoder()
@@ -937,4 +1306,725 @@ func (c *CMD) DecodeCMDBatchUpdateConversation() (models []*wkdb.BatchUpdateConv
 	return
 }
 
-var ErrStoreStopped = fmt.Errorf("store stopped")
+func EncodeCMDMessageRevoke(channelId string, channelType uint8, messageSeq uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint64(messageSeq)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMessageRevoke() (channelId string, channelType uint8, messageSeq uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if messageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDMessageEdit(req wkdb.MessageEditReq) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(req.ChannelId)
+	encoder.WriteUint8(req.ChannelType)
+	encoder.WriteInt64(req.MessageId)
+	encoder.WriteUint64(req.MessageSeq)
+	encoder.WriteInt32(req.EditedAt)
+	encoder.WriteBinary(req.Payload)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMessageEdit() (req wkdb.MessageEditReq, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if req.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if req.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if req.MessageId, err = decoder.Int64(); err != nil {
+		return
+	}
+	if req.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if req.EditedAt, err = decoder.Int32(); err != nil {
+		return
+	}
+	if req.Payload, err = decoder.Binary(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDMessageDeleteBefore(channelId string, channelType uint8, messageSeq uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint64(messageSeq)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMessageDeleteBefore() (channelId string, channelType uint8, messageSeq uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if messageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDMessageDeleteExpired(channelId string, channelType uint8, messageSeqs []uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint32(uint32(len(messageSeqs)))
+	for _, messageSeq := range messageSeqs {
+		encoder.WriteUint64(messageSeq)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDMessageDeleteExpired() (channelId string, channelType uint8, messageSeqs []uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	messageSeqs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var messageSeq uint64
+		if messageSeq, err = decoder.Uint64(); err != nil {
+			return
+		}
+		messageSeqs = append(messageSeqs, messageSeq)
+	}
+	return
+}
+
+func EncodeCMDAddScheduledMessage(m wkdb.ScheduledMessage) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(m.Id)
+	encoder.WriteInt64(m.SendAt)
+	encoder.WriteString(m.FromUid)
+	encoder.WriteString(m.ChannelId)
+	encoder.WriteUint8(m.ChannelType)
+	encoder.WriteString(m.ClientMsgNo)
+	encoder.WriteUint8(wkutil.BoolToUint8(m.RedDot))
+	encoder.WriteUint8(wkutil.BoolToUint8(m.SyncOnce))
+	encoder.WriteUint8(wkutil.BoolToUint8(m.NoPersist))
+	encoder.WriteUint32(m.Expire)
+	encoder.WriteBinary(m.Payload)
+	encoder.WriteInt64(m.CreatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddScheduledMessage() (m wkdb.ScheduledMessage, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if m.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if m.SendAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	if m.FromUid, err = decoder.String(); err != nil {
+		return
+	}
+	if m.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if m.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if m.ClientMsgNo, err = decoder.String(); err != nil {
+		return
+	}
+	var redDot, syncOnce, noPersist uint8
+	if redDot, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if syncOnce, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if noPersist, err = decoder.Uint8(); err != nil {
+		return
+	}
+	m.RedDot = wkutil.Uint8ToBool(redDot)
+	m.SyncOnce = wkutil.Uint8ToBool(syncOnce)
+	m.NoPersist = wkutil.Uint8ToBool(noPersist)
+	if m.Expire, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if m.Payload, err = decoder.Binary(); err != nil {
+		return
+	}
+	if m.CreatedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDDeleteScheduledMessage(channelId string, channelType uint8, id uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint64(id)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteScheduledMessage() (channelId string, channelType uint8, id uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateReaction(reaction wkdb.Reaction) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(reaction.ChannelId)
+	encoder.WriteUint8(reaction.ChannelType)
+	encoder.WriteInt64(reaction.MessageId)
+	encoder.WriteUint64(reaction.MessageSeq)
+	encoder.WriteString(reaction.Uid)
+	encoder.WriteString(reaction.Emoji)
+	encoder.WriteUint8(wkutil.BoolToUint8(reaction.IsDeleted))
+	encoder.WriteInt64(reaction.CreatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateReaction() (reaction wkdb.Reaction, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if reaction.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if reaction.MessageId, err = decoder.Int64(); err != nil {
+		return
+	}
+	if reaction.MessageSeq, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if reaction.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if reaction.Emoji, err = decoder.String(); err != nil {
+		return
+	}
+	var isDeleted uint8
+	if isDeleted, err = decoder.Uint8(); err != nil {
+		return
+	}
+	reaction.IsDeleted = wkutil.Uint8ToBool(isDeleted)
+	if reaction.CreatedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddMessageReceipt(receipt wkdb.MessageReceipt, onlyCount bool) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(receipt.ChannelId)
+	encoder.WriteUint8(receipt.ChannelType)
+	encoder.WriteString(receipt.Uid)
+	encoder.WriteInt64(receipt.ReadedAt)
+	encoder.WriteUint8(wkutil.BoolToUint8(onlyCount))
+	encoder.WriteUint32(uint32(len(receipt.MessageSeqs)))
+	for _, messageSeq := range receipt.MessageSeqs {
+		encoder.WriteUint64(messageSeq)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddMessageReceipt() (receipt wkdb.MessageReceipt, onlyCount bool, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if receipt.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if receipt.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if receipt.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if receipt.ReadedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	var onlyCountI uint8
+	if onlyCountI, err = decoder.Uint8(); err != nil {
+		return
+	}
+	onlyCount = wkutil.Uint8ToBool(onlyCountI)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	receipt.MessageSeqs = make([]uint64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var messageSeq uint64
+		if messageSeq, err = decoder.Uint64(); err != nil {
+			return
+		}
+		receipt.MessageSeqs = append(receipt.MessageSeqs, messageSeq)
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateChannelMutes(channelId string, channelType uint8, mutes []wkdb.ChannelMute) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint32(uint32(len(mutes)))
+	for _, mute := range mutes {
+		encoder.WriteString(mute.Uid)
+		encoder.WriteInt64(mute.ExpireAt)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateChannelMutes() (channelId string, channelType uint8, mutes []wkdb.ChannelMute, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	mutes = make([]wkdb.ChannelMute, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var mute wkdb.ChannelMute
+		if mute.Uid, err = decoder.String(); err != nil {
+			return
+		}
+		if mute.ExpireAt, err = decoder.Int64(); err != nil {
+			return
+		}
+		mutes = append(mutes, mute)
+	}
+	return
+}
+
+func EncodeCMDRemoveChannelMutes(channelId string, channelType uint8, uids []string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint32(uint32(len(uids)))
+	for _, uid := range uids {
+		encoder.WriteString(uid)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveChannelMutes() (channelId string, channelType uint8, uids []string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	uids = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var uid string
+		if uid, err = decoder.String(); err != nil {
+			return
+		}
+		uids = append(uids, uid)
+	}
+	return
+}
+
+func EncodeCMDSetSubscriberRole(channelId string, channelType uint8, uids []string, role uint8) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint8(role)
+	encoder.WriteUint32(uint32(len(uids)))
+	for _, uid := range uids {
+		encoder.WriteString(uid)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSetSubscriberRole() (channelId string, channelType uint8, uids []string, role uint8, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if role, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	uids = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var uid string
+		if uid, err = decoder.String(); err != nil {
+			return
+		}
+		uids = append(uids, uid)
+	}
+	return
+}
+
+// EncodeCMDSubscribersWithAttrs 编码订阅者及其属性（不包含角色）
+func EncodeCMDSubscribersWithAttrs(channelId string, channelType uint8, subscribers []wkdb.Subscriber) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteUint32(uint32(len(subscribers)))
+	for _, subscriber := range subscribers {
+		encoder.WriteString(subscriber.Uid)
+		encoder.WriteInt64(subscriber.JoinedAt)
+		encoder.WriteUint64(subscriber.JoinSeq)
+		encoder.WriteString(subscriber.InviterUid)
+		encoder.WriteString(subscriber.Nickname)
+		encoder.WriteBinary(subscriber.Extra)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSubscribersWithAttrs() (channelId string, channelType uint8, subscribers []wkdb.Subscriber, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	subscribers = make([]wkdb.Subscriber, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var subscriber wkdb.Subscriber
+		if subscriber.Uid, err = decoder.String(); err != nil {
+			return
+		}
+		if subscriber.JoinedAt, err = decoder.Int64(); err != nil {
+			return
+		}
+		if subscriber.JoinSeq, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if subscriber.InviterUid, err = decoder.String(); err != nil {
+			return
+		}
+		if subscriber.Nickname, err = decoder.String(); err != nil {
+			return
+		}
+		var extra []byte
+		if extra, err = decoder.Binary(); err != nil {
+			return
+		}
+		if len(extra) > 0 {
+			subscriber.Extra = extra
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+	return
+}
+
+func EncodeCMDDevicePush(push wkdb.DevicePush) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(push.Uid)
+	encoder.WriteUint64(push.DeviceFlag)
+	encoder.WriteUint8(push.PushType)
+	encoder.WriteString(push.PushToken)
+	encoder.WriteInt64(push.UpdatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDevicePush() (push wkdb.DevicePush, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if push.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if push.DeviceFlag, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if push.PushType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if push.PushToken, err = decoder.String(); err != nil {
+		return
+	}
+	if push.UpdatedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDRemoveDevicePush(uid string, deviceFlag uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint64(deviceFlag)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveDevicePush() (uid string, deviceFlag uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	if deviceFlag, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateConversationMutes(uid string, mutes []wkdb.ConversationMute) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint32(uint32(len(mutes)))
+	for _, mute := range mutes {
+		encoder.WriteString(mute.ChannelId)
+		encoder.WriteUint8(mute.ChannelType)
+		encoder.WriteInt64(mute.ExpireAt)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateConversationMutes() (uid string, mutes []wkdb.ConversationMute, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	mutes = make([]wkdb.ConversationMute, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var mute wkdb.ConversationMute
+		if mute.ChannelId, err = decoder.String(); err != nil {
+			return
+		}
+		if mute.ChannelType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		if mute.ExpireAt, err = decoder.Int64(); err != nil {
+			return
+		}
+		mutes = append(mutes, mute)
+	}
+	return
+}
+
+func EncodeCMDRemoveConversationMutes(uid string, channels []wkdb.Channel) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteUint32(uint32(len(channels)))
+	for _, channel := range channels {
+		encoder.WriteString(channel.ChannelId)
+		encoder.WriteUint8(channel.ChannelType)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveConversationMutes() (uid string, channels []wkdb.Channel, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	channels = make([]wkdb.Channel, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var channel wkdb.Channel
+		if channel.ChannelId, err = decoder.String(); err != nil {
+			return
+		}
+		if channel.ChannelType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		channels = append(channels, channel)
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateUserBan(ban wkdb.UserBan) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(ban.Uid)
+	encoder.WriteString(ban.Reason)
+	encoder.WriteInt64(ban.ExpireAt)
+	encoder.WriteInt64(ban.CreatedAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateUserBan() (ban wkdb.UserBan, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if ban.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if ban.Reason, err = decoder.String(); err != nil {
+		return
+	}
+	if ban.ExpireAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	if ban.CreatedAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDRemoveUserBan(uid string, createdAt int64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteInt64(createdAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveUserBan() (uid string, createdAt int64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	if createdAt, err = decoder.Int64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDRateLimits(limits []wkdb.RateLimit) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint32(uint32(len(limits)))
+	for _, limit := range limits {
+		encoder.WriteUint8(limit.TargetType)
+		encoder.WriteString(limit.Target)
+		encoder.WriteUint8(limit.ChannelType)
+		encoder.WriteUint64(math.Float64bits(limit.Rate))
+		encoder.WriteUint32(uint32(limit.Burst))
+		encoder.WriteUint64(math.Float64bits(limit.MemberRate))
+		encoder.WriteUint32(uint32(limit.MemberBurst))
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRateLimits() (limits []wkdb.RateLimit, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	limits = make([]wkdb.RateLimit, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var (
+			limit wkdb.RateLimit
+			v64   uint64
+			v32   uint32
+		)
+		if limit.TargetType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		if limit.Target, err = decoder.String(); err != nil {
+			return
+		}
+		if limit.ChannelType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		if v64, err = decoder.Uint64(); err != nil {
+			return
+		}
+		limit.Rate = math.Float64frombits(v64)
+		if v32, err = decoder.Uint32(); err != nil {
+			return
+		}
+		limit.Burst = int(int32(v32))
+		if v64, err = decoder.Uint64(); err != nil {
+			return
+		}
+		limit.MemberRate = math.Float64frombits(v64)
+		if v32, err = decoder.Uint32(); err != nil {
+			return
+		}
+		limit.MemberBurst = int(int32(v32))
+		limits = append(limits, limit)
+	}
+	return
+}
+
+var ErrStoreStopped = fmt.Errorf("store stopped")
+
+func EncodeCMDAppendChannelMessageCmd(channelId string, channelType uint8, data []byte) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encoder.WriteBinary(data)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAppendChannelMessageCmd() (channelId string, channelType uint8, data []byte, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if data, err = decoder.Binary(); err != nil {
+		return
+	}
+	return
+}
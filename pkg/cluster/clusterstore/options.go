@@ -1,7 +1,10 @@
 package clusterstore
 
 import (
+	"time"
+
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 )
 
 type Options struct {
@@ -14,8 +17,15 @@ type Options struct {
 
 	IsCmdChannel func(string) bool // 是否是cmd频道
 
+	// PullChannelMessageCmds 从频道所属槽的领导拉取序号大于index的频道消息命令（本节点是槽领导时返回空）
+	PullChannelMessageCmds func(channelId string, channelType uint8, index uint64, limit int) ([]wkdb.ChannelMessageCmd, error)
+	// NotifyChannelMessageCmds 通知频道的其他副本同步频道消息命令（异步执行）
+	NotifyChannelMessageCmds func(channelId string, channelType uint8)
+
 	Db struct {
-		ShardNum int // 分片数量
+		ShardNum           int           // 分片数量
+		FullTextIndex      bool          // 是否开启消息全文索引
+		MessageDedupWindow time.Duration // 消息去重窗口，为0表示不去重
 	}
 }
 
@@ -32,9 +42,13 @@ func newOptions() *Options {
 	return &Options{
 		SlotCount: 64,
 		Db: struct {
-			ShardNum int
+			ShardNum           int
+			FullTextIndex      bool
+			MessageDedupWindow time.Duration
 		}{
-			ShardNum: 16,
+			ShardNum:           16,
+			FullTextIndex:      true,
+			MessageDedupWindow: time.Minute * 10,
 		},
 	}
 }
@@ -65,6 +79,18 @@ func WithIsCmdChannel(f func(string) bool) Option {
 	}
 }
 
+func WithPullChannelMessageCmds(f func(channelId string, channelType uint8, index uint64, limit int) ([]wkdb.ChannelMessageCmd, error)) Option {
+	return func(o *Options) {
+		o.PullChannelMessageCmds = f
+	}
+}
+
+func WithNotifyChannelMessageCmds(f func(channelId string, channelType uint8)) Option {
+	return func(o *Options) {
+		o.NotifyChannelMessageCmds = f
+	}
+}
+
 func WithGetSlotId(f func(uid string) uint32) Option {
 	return func(o *Options) {
 		o.GetSlotId = f
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/WuKongIM/WuKongIM/pkg/keylock"
+	"github.com/WuKongIM/WuKongIM/pkg/wait"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/lni/goutils/syncutil"
@@ -19,6 +20,8 @@ type Store struct {
 	lock *keylock.KeyLock
 	ctx  context.Context
 
+	messageCmdWait wait.Wait // 等待频道消息命令在本节点的应用结果
+
 	messageShardLogStorage *MessageShardLogStorage
 
 	saveChannelClusterConfigReq chan *saveChannelClusterConfigReq
@@ -33,6 +36,7 @@ func NewStore(opts *Options) *Store {
 		opts:                        opts,
 		Log:                         wklog.NewWKLog(fmt.Sprintf("clusterStore[%d]", opts.NodeID)),
 		lock:                        keylock.NewKeyLock(),
+		messageCmdWait:              wait.New(),
 		saveChannelClusterConfigReq: make(chan *saveChannelClusterConfigReq, 1024),
 		stopper:                     syncutil.NewStopper(),
 	}
@@ -42,7 +46,7 @@ func NewStore(opts *Options) *Store {
 		s.Panic("create data dir err", zap.Error(err))
 	}
 
-	s.wdb = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithIsCmdChannel(opts.IsCmdChannel), wkdb.WithShardNum(opts.Db.ShardNum), wkdb.WithDir(opts.DataDir), wkdb.WithNodeId(opts.NodeID), wkdb.WithSlotCount(int(opts.SlotCount))))
+	s.wdb = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithIsCmdChannel(opts.IsCmdChannel), wkdb.WithShardNum(opts.Db.ShardNum), wkdb.WithFullTextIndex(opts.Db.FullTextIndex), wkdb.WithMessageDedupWindow(opts.Db.MessageDedupWindow), wkdb.WithDir(opts.DataDir), wkdb.WithNodeId(opts.NodeID), wkdb.WithSlotCount(int(opts.SlotCount))))
 	s.messageShardLogStorage = NewMessageShardLogStorage(s.wdb)
 	return s
 }
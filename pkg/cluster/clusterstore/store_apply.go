@@ -1,10 +1,13 @@
 package clusterstore
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/zap"
 )
 
@@ -77,6 +80,48 @@ func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 		return s.handleBatchUpdateConversation(cmd)
 	case CMDAddOrUpdateUserAndDevice: // 添加或更新用户和设备
 		return s.handleAddOrUpdateUserAndDevice(cmd)
+	case CMDSaveStreamMeta: // 保存消息流元数据
+		return s.handleSaveStreamMeta(cmd)
+	case CMDStreamEnd: // 结束消息流
+		return s.handleStreamEnd(cmd)
+	case CMDAppendStreamItem: // 追加消息流
+		return s.handleAppendStreamItem(cmd)
+	case CMDAddScheduledMessage: // 添加定时消息
+		return s.handleAddScheduledMessage(cmd)
+	case CMDDeleteScheduledMessage: // 删除定时消息
+		return s.handleDeleteScheduledMessage(cmd)
+	case CMDAddOrUpdateReaction: // 添加或更新消息回应
+		return s.handleAddOrUpdateReaction(cmd, log.Index)
+	case CMDAddMessageReceipt: // 添加消息已读回执
+		return s.handleAddMessageReceipt(cmd)
+	case CMDAddOrUpdateChannelMutes: // 添加或更新频道禁言
+		return s.handleAddOrUpdateChannelMutes(cmd)
+	case CMDRemoveChannelMutes: // 解除频道禁言
+		return s.handleRemoveChannelMutes(cmd)
+	case CMDSetSubscriberRole: // 设置订阅者角色
+		return s.handleSetSubscriberRole(cmd)
+	case CMDAddOrUpdateSubscribers: // 添加订阅者（包含属性）
+		return s.handleAddOrUpdateSubscribers(cmd)
+	case CMDUpdateSubscribers: // 更新订阅者属性
+		return s.handleUpdateSubscribers(cmd)
+	case CMDAddOrUpdateDevicePush: // 添加或更新设备推送令牌
+		return s.handleAddOrUpdateDevicePush(cmd)
+	case CMDRemoveDevicePush: // 移除设备推送令牌
+		return s.handleRemoveDevicePush(cmd)
+	case CMDAddOrUpdateConversationMutes: // 添加或更新会话免打扰
+		return s.handleAddOrUpdateConversationMutes(cmd)
+	case CMDRemoveConversationMutes: // 移除会话免打扰
+		return s.handleRemoveConversationMutes(cmd)
+	case CMDAddOrUpdateUserBan: // 添加或更新用户封禁
+		return s.handleAddOrUpdateUserBan(cmd)
+	case CMDRemoveUserBan: // 移除用户封禁
+		return s.handleRemoveUserBan(cmd)
+	case CMDAddOrUpdateRateLimits: // 添加或更新发送频率限制规则
+		return s.handleAddOrUpdateRateLimits(cmd)
+	case CMDRemoveRateLimits: // 移除发送频率限制规则
+		return s.handleRemoveRateLimits(cmd)
+	case CMDAppendChannelMessageCmd: // 保存频道的消息命令
+		return s.handleAppendChannelMessageCmd(cmd, log.Index)
 		// case CMDChannelClusterConfigDelete: // 删除频道分布式配置
 		// return s.handleChannelClusterConfigDelete(cmd)
 
@@ -84,6 +129,104 @@ func (s *Store) onMetaApply(slotId uint32, log replica.Log) error {
 	return nil
 }
 
+// channelMessageCmdApplyBatch 每次从存储读取的频道消息命令数量
+const channelMessageCmdApplyBatch = 100
+
+// ApplyChannelMessageCmds 按序号顺序应用本节点已保存的频道消息命令
+// 命令依赖的消息还未同步到本节点时停止应用，频道日志同步后再次调用时继续
+func (s *Store) ApplyChannelMessageCmds(channelId string, channelType uint8) error {
+	lockKey := wkutil.ChannelToKey(channelId, channelType)
+	s.lock.Lock(lockKey)
+	defer s.lock.Unlock(lockKey)
+	return s.applyChannelMessageCmds(channelId, channelType)
+}
+
+func (s *Store) applyChannelMessageCmds(channelId string, channelType uint8) error {
+	appliedIndex, err := s.wdb.GetChannelMessageCmdAppliedIndex(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	lastSeq, _, err := s.wdb.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	for {
+		cmds, err := s.wdb.GetChannelMessageCmds(channelId, channelType, appliedIndex, channelMessageCmdApplyBatch)
+		if err != nil {
+			return err
+		}
+		if len(cmds) == 0 {
+			return nil
+		}
+		var (
+			newAppliedIndex = appliedIndex
+			applyErr        error
+			notReady        bool
+		)
+		for _, m := range cmds {
+			if applyErr = s.applyChannelMessageCmd(m, lastSeq); applyErr != nil {
+				if errors.Is(applyErr, errMessageCmdNotReady) {
+					notReady = true
+					applyErr = nil
+				}
+				break
+			}
+			newAppliedIndex = m.Index
+		}
+		if newAppliedIndex > appliedIndex {
+			if err = s.wdb.SetChannelMessageCmdAppliedIndex(channelId, channelType, newAppliedIndex); err != nil {
+				return err
+			}
+			appliedIndex = newAppliedIndex
+		}
+		if applyErr != nil {
+			return applyErr
+		}
+		if notReady || len(cmds) < channelMessageCmdApplyBatch {
+			return nil
+		}
+	}
+}
+
+func (s *Store) applyChannelMessageCmd(m wkdb.ChannelMessageCmd, lastSeq uint64) error {
+	// 每个副本应用同一条命令的结果都一样，确定性的错误重试也不会成功，跳过这条命令，避免阻塞频道后续命令的应用
+	cmd := &CMD{}
+	err := cmd.Unmarshal(m.Data)
+	if err != nil {
+		s.Error("unmarshal channel message cmd err, skip it", zap.Error(err), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType), zap.Uint64("index", m.Index))
+		return nil
+	}
+	switch cmd.CmdType {
+	case CMDMessageRevoke: // 撤回消息
+		err = s.handleMessageRevoke(cmd, lastSeq)
+	case CMDMessageEdit: // 编辑消息
+		err = s.handleMessageEdit(cmd, m.Index, lastSeq)
+	case CMDMessageDeleteBefore: // 删除频道指定序号之前的消息
+		err = s.handleMessageDeleteBefore(cmd, lastSeq)
+	case CMDMessageDeleteExpired: // 清理频道的过期消息
+		err = s.handleMessageDeleteExpired(cmd, lastSeq)
+	default:
+		s.Warn("unknown channel message cmd, skip it", zap.String("cmdType", cmd.CmdType.String()), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType), zap.Uint64("index", m.Index))
+	}
+	if err != nil && isDeterministicMessageCmdErr(err) { // 比如消息已被清理
+		s.Warn("apply channel message cmd failed, skip it", zap.Error(err), zap.String("cmdType", cmd.CmdType.String()), zap.String("channelId", m.ChannelId), zap.Uint8("channelType", m.ChannelType), zap.Uint64("index", m.Index))
+		return nil
+	}
+	return err
+}
+
+var (
+	// errInvalidMessageCmd 消息命令的数据无法解析
+	errInvalidMessageCmd = errors.New("invalid channel message cmd")
+	// errMessageCmdNotReady 消息命令依赖的消息还未同步到本节点
+	errMessageCmdNotReady = errors.New("message of channel message cmd not synced")
+)
+
+// isDeterministicMessageCmdErr 是否是确定性的错误（与存储无关，重试结果不变）
+func isDeterministicMessageCmdErr(err error) bool {
+	return errors.Is(err, wkdb.ErrNotFound) || errors.Is(err, wkdb.ErrMessageMismatch) || errors.Is(err, errInvalidMessageCmd)
+}
+
 func (s *Store) handleAddSubscribers(cmd *CMD) error {
 	channelId, channelType, subscribers, err := cmd.DecodeSubscribers()
 	if err != nil {
@@ -326,3 +469,252 @@ func (s *Store) handleAddOrUpdateUserAndDevice(cmd *CMD) error {
 		Token:       token,
 	})
 }
+
+func (s *Store) handleMessageRevoke(cmd *CMD, lastSeq uint64) error {
+	channelId, channelType, messageSeq, err := cmd.DecodeCMDMessageRevoke()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidMessageCmd, err)
+	}
+	if messageSeq > lastSeq {
+		return errMessageCmdNotReady
+	}
+	return s.wdb.RevokeMessage(channelId, channelType, messageSeq)
+}
+
+// handleMessageEdit 新版本号在应用时按命令顺序分配，并发的编辑不会得到相同的版本号，分配的版本通知给等待的提交者
+func (s *Store) handleMessageEdit(cmd *CMD, cmdIndex uint64, lastSeq uint64) error {
+	req, err := cmd.DecodeCMDMessageEdit()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidMessageCmd, err)
+	}
+	if req.MessageSeq > lastSeq {
+		return errMessageCmdNotReady
+	}
+	req.LogSeq = cmdIndex
+	edit, err := s.wdb.EditMessage(req)
+	if err != nil {
+		return err
+	}
+	if edit.Version > 0 {
+		s.messageCmdWait.Trigger(channelMessageCmdWaitKey(req.ChannelId, req.ChannelType, cmdIndex), edit)
+	}
+	return nil
+}
+
+// handleMessageDeleteBefore 需要删除的消息都同步到本节点后才执行，避免之后同步过来的旧消息没有被删除
+func (s *Store) handleMessageDeleteBefore(cmd *CMD, lastSeq uint64) error {
+	channelId, channelType, messageSeq, err := cmd.DecodeCMDMessageDeleteBefore()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidMessageCmd, err)
+	}
+	if messageSeq > lastSeq+1 {
+		return errMessageCmdNotReady
+	}
+	_, err = s.wdb.DeleteMessagesBefore(channelId, channelType, messageSeq)
+	return err
+}
+
+// handleMessageDeleteExpired 过期消息由频道领导按自己的时间判断，副本只按命令清理，各副本清理的消息一致
+func (s *Store) handleMessageDeleteExpired(cmd *CMD, lastSeq uint64) error {
+	channelId, channelType, messageSeqs, err := cmd.DecodeCMDMessageDeleteExpired()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidMessageCmd, err)
+	}
+	for _, messageSeq := range messageSeqs {
+		if messageSeq > lastSeq {
+			return errMessageCmdNotReady
+		}
+	}
+	_, err = s.wdb.DeleteExpiredMessages(channelId, channelType, messageSeqs)
+	return err
+}
+
+func (s *Store) handleSaveStreamMeta(cmd *CMD) error {
+	meta, err := cmd.DecodeCMDSaveStreamMeta()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SaveStreamMeta(meta)
+}
+
+func (s *Store) handleStreamEnd(cmd *CMD) error {
+	channelId, channelType, streamNo, err := cmd.DecodeCMDStreamEnd()
+	if err != nil {
+		return err
+	}
+	return s.wdb.StreamEnd(channelId, channelType, streamNo)
+}
+
+func (s *Store) handleAppendStreamItem(cmd *CMD) error {
+	channelId, channelType, streamNo, item, err := cmd.DecodeCMDAppendStreamItem()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AppendStreamItem(channelId, channelType, streamNo, item)
+}
+
+func (s *Store) handleAddScheduledMessage(cmd *CMD) error {
+	m, err := cmd.DecodeCMDAddScheduledMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddScheduledMessage(m)
+}
+
+func (s *Store) handleDeleteScheduledMessage(cmd *CMD) error {
+	channelId, channelType, id, err := cmd.DecodeCMDDeleteScheduledMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteScheduledMessage(channelId, channelType, id)
+}
+
+// handleAddOrUpdateReaction 回应的版本号使用槽日志的下标，各副本应用后版本号一致
+func (s *Store) handleAddOrUpdateReaction(cmd *CMD, logIndex uint64) error {
+	reaction, err := cmd.DecodeCMDAddOrUpdateReaction()
+	if err != nil {
+		return err
+	}
+	reaction.Version = logIndex
+	return s.wdb.AddOrUpdateReaction(reaction)
+}
+
+// handleAppendChannelMessageCmd 消息命令的序号使用槽日志的下标，频道内递增且各副本一致（这里只保存，由频道的副本拉取后应用）
+func (s *Store) handleAppendChannelMessageCmd(cmd *CMD, logIndex uint64) error {
+	channelId, channelType, data, err := cmd.DecodeCMDAppendChannelMessageCmd()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AppendChannelMessageCmds([]wkdb.ChannelMessageCmd{
+		{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			Index:       logIndex,
+			Data:        data,
+		},
+	})
+}
+
+func (s *Store) handleAddMessageReceipt(cmd *CMD) error {
+	receipt, onlyCount, err := cmd.DecodeCMDAddMessageReceipt()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddMessageReceipt(receipt, onlyCount)
+}
+
+func (s *Store) handleAddOrUpdateChannelMutes(cmd *CMD) error {
+	channelId, channelType, mutes, err := cmd.DecodeCMDAddOrUpdateChannelMutes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateChannelMutes(channelId, channelType, mutes)
+}
+
+func (s *Store) handleRemoveChannelMutes(cmd *CMD) error {
+	channelId, channelType, uids, err := cmd.DecodeCMDRemoveChannelMutes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveChannelMutes(channelId, channelType, uids)
+}
+
+func (s *Store) handleSetSubscriberRole(cmd *CMD) error {
+	channelId, channelType, uids, role, err := cmd.DecodeCMDSetSubscriberRole()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetSubscriberRole(channelId, channelType, uids, role)
+}
+
+func (s *Store) handleAddOrUpdateSubscribers(cmd *CMD) error {
+	channelId, channelType, subscribers, err := cmd.DecodeCMDSubscribersWithAttrs()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateSubscribers(channelId, channelType, subscribers)
+}
+
+func (s *Store) handleUpdateSubscribers(cmd *CMD) error {
+	channelId, channelType, subscribers, err := cmd.DecodeCMDSubscribersWithAttrs()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateSubscribers(channelId, channelType, subscribers)
+}
+
+func (s *Store) handleAddOrUpdateDevicePush(cmd *CMD) error {
+	push, err := cmd.DecodeCMDDevicePush()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateDevicePush(push)
+}
+
+func (s *Store) handleRemoveDevicePush(cmd *CMD) error {
+	uid, deviceFlag, err := cmd.DecodeCMDRemoveDevicePush()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveDevicePush(uid, deviceFlag)
+}
+
+func (s *Store) handleAddOrUpdateConversationMutes(cmd *CMD) error {
+	uid, mutes, err := cmd.DecodeCMDAddOrUpdateConversationMutes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateConversationMutes(uid, mutes)
+}
+
+func (s *Store) handleRemoveConversationMutes(cmd *CMD) error {
+	uid, channels, err := cmd.DecodeCMDRemoveConversationMutes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveConversationMutes(uid, channels)
+}
+
+func (s *Store) handleAddOrUpdateUserBan(cmd *CMD) error {
+	ban, err := cmd.DecodeCMDAddOrUpdateUserBan()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateUserBans([]wkdb.UserBan{ban})
+}
+
+func (s *Store) handleRemoveUserBan(cmd *CMD) error {
+	uid, createdAt, err := cmd.DecodeCMDRemoveUserBan()
+	if err != nil {
+		return err
+	}
+	if createdAt > 0 { // 只移除指定的封禁，避免误删重新封禁的记录
+		ban, err := s.wdb.GetUserBan(uid)
+		if err != nil {
+			if err == wkdb.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		if ban.CreatedAt != createdAt {
+			return nil
+		}
+	}
+	return s.wdb.RemoveUserBans([]string{uid})
+}
+
+func (s *Store) handleAddOrUpdateRateLimits(cmd *CMD) error {
+	limits, err := cmd.DecodeCMDRateLimits()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateRateLimits(limits)
+}
+
+func (s *Store) handleRemoveRateLimits(cmd *CMD) error {
+	limits, err := cmd.DecodeCMDRateLimits()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveRateLimits(limits)
+}
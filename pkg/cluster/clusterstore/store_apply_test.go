@@ -0,0 +1,194 @@
+package clusterstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyChannelMessageCmds(t *testing.T) {
+	opts := clusterstore.NewOptions(1)
+	opts.DataDir = t.TempDir()
+	opts.SlotCount = 1
+	opts.Db.ShardNum = 1
+	s := clusterstore.NewStore(opts)
+	err := s.Open()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	channelId := "test"
+	channelType := uint8(2)
+
+	appendMessage := func(seq uint32) {
+		err := s.DB().AppendMessages(channelId, channelType, []wkdb.Message{
+			{
+				RecvPacket: wkproto.RecvPacket{
+					MessageID:   int64(seq),
+					ChannelID:   channelId,
+					ChannelType: channelType,
+					MessageSeq:  seq,
+					Payload:     []byte("hello"),
+				},
+			},
+		})
+		assert.NoError(t, err)
+	}
+	appendCmd := func(logIndex uint64, cmdType clusterstore.CMDType, data []byte) {
+		inner, err := clusterstore.NewCMD(cmdType, data).Marshal()
+		assert.NoError(t, err)
+		cmdData, err := clusterstore.NewCMD(clusterstore.CMDAppendChannelMessageCmd, clusterstore.EncodeCMDAppendChannelMessageCmd(channelId, channelType, inner)).Marshal()
+		assert.NoError(t, err)
+		err = s.OnMetaApply(0, []replica.Log{{Index: logIndex, Data: cmdData}})
+		assert.NoError(t, err)
+	}
+
+	appendMessage(1)
+
+	// 撤回还未同步到本节点的消息2，之后的编辑也要等撤回应用后才能应用
+	appendCmd(10, clusterstore.CMDMessageRevoke, clusterstore.EncodeCMDMessageRevoke(channelId, channelType, 2))
+	appendCmd(11, clusterstore.CMDMessageEdit, clusterstore.EncodeCMDMessageEdit(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   1,
+		MessageSeq:  1,
+		Payload:     []byte("edited"),
+	}))
+
+	err = s.ApplyChannelMessageCmds(channelId, channelType)
+	assert.NoError(t, err)
+
+	msg, err := s.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), msg.Version)
+
+	// 命令不占用消息序号
+	lastSeq, err := s.GetLastMsgSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), lastSeq)
+
+	appendMessage(2)
+	err = s.ApplyChannelMessageCmds(channelId, channelType)
+	assert.NoError(t, err)
+
+	msg, err = s.LoadMsg(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.True(t, msg.Revoke)
+
+	msg, err = s.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), msg.Version)
+
+	edits, err := s.GetMessageEdits(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, edits, 1)
+	assert.Equal(t, uint64(11), edits[0].LogSeq)
+
+	// 重复应用不会产生新版本
+	err = s.ApplyChannelMessageCmds(channelId, channelType)
+	assert.NoError(t, err)
+	msg, err = s.LoadMsg(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), msg.Version)
+}
+
+func TestEditMessageReturnsAppliedVersion(t *testing.T) {
+	opts := clusterstore.NewOptions(1)
+	opts.DataDir = t.TempDir()
+	opts.SlotCount = 1
+	opts.Db.ShardNum = 1
+	opts.GetSlotId = func(v string) uint32 { return 0 }
+	s := clusterstore.NewStore(opts)
+	opts.Cluster = &testPropose{s: s}
+	err := s.Open()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	channelId := "test"
+	channelType := uint8(2)
+
+	err = s.DB().AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   1,
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  1,
+				Payload:     []byte("hello"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		edit, err := s.EditMessage(wkdb.MessageEditReq{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageId:   1,
+			MessageSeq:  1,
+			Payload:     []byte("edited"),
+			EditedAt:    int32(100 + i),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(i), edit.Version)
+		assert.Equal(t, int32(100+i), edit.EditedAt)
+	}
+
+	// 消息不存在，编辑命令应用时被忽略
+	_, err = s.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   100,
+		MessageSeq:  1,
+		Payload:     []byte("edited"),
+	})
+	assert.ErrorIs(t, err, clusterstore.ErrMessageEditNotApplied)
+}
+
+// testPropose 提案到槽后直接在本节点应用
+type testPropose struct {
+	s     *clusterstore.Store
+	index uint64
+}
+
+func (p *testPropose) ProposeChannelMessages(ctx context.Context, channelId string, channelType uint8, logs []replica.Log) ([]icluster.ProposeResult, error) {
+	return nil, nil
+}
+
+func (p *testPropose) ProposeToSlot(ctx context.Context, slotId uint32, logs []replica.Log) ([]icluster.ProposeResult, error) {
+	return nil, nil
+}
+
+func (p *testPropose) ProposeDataToSlot(ctx context.Context, slotId uint32, data []byte) (icluster.ProposeResult, error) {
+	p.index++
+	err := p.s.OnMetaApply(slotId, []replica.Log{{Index: p.index, Data: data}})
+	if err != nil {
+		return nil, err
+	}
+	return testProposeResult(p.index), nil
+}
+
+type testProposeResult uint64
+
+func (r testProposeResult) LogId() uint64 {
+	return uint64(r)
+}
+
+func (r testProposeResult) LogIndex() uint64 {
+	return uint64(r)
+}
+
+func TestRateLimitsCMDKeepsInherit(t *testing.T) {
+	limits := []wkdb.RateLimit{
+		{TargetType: 2, Target: "g1", ChannelType: 2, Rate: -1, Burst: -1, MemberRate: 1, MemberBurst: -1},
+	}
+	cmd := clusterstore.NewCMD(clusterstore.CMDAddOrUpdateRateLimits, clusterstore.EncodeCMDRateLimits(limits))
+	decodedLimits, err := cmd.DecodeCMDRateLimits()
+	assert.NoError(t, err)
+	assert.Equal(t, limits, decodedLimits)
+}
@@ -50,6 +50,49 @@ func (s *Store) GetSubscribers(channelID string, channelType uint8) ([]string, e
 	return s.wdb.GetSubscribers(channelID, channelType)
 }
 
+// GetSubscribersDetail 获取订阅者（包含角色）
+func (s *Store) GetSubscribersDetail(channelId string, channelType uint8) ([]wkdb.Subscriber, error) {
+	return s.wdb.GetSubscribersDetail(channelId, channelType)
+}
+
+// GetSubscriber 获取订阅者（包含角色），不是订阅者返回wkdb.ErrNotFound
+func (s *Store) GetSubscriber(channelId string, channelType uint8, uid string) (wkdb.Subscriber, error) {
+	return s.wdb.GetSubscriber(channelId, channelType, uid)
+}
+
+// GetSubscribersPage 分页获取订阅者，cursor为上一页最后一个订阅者的uid
+func (s *Store) GetSubscribersPage(channelId string, channelType uint8, cursor string, limit int) ([]wkdb.Subscriber, error) {
+	return s.wdb.GetSubscribersPage(channelId, channelType, cursor, limit)
+}
+
+// GetSubscriberCount 获取订阅者数量
+func (s *Store) GetSubscriberCount(channelId string, channelType uint8) (int, error) {
+	return s.wdb.GetSubscriberCount(channelId, channelType)
+}
+
+// IterateSubscribers 遍历订阅者，iterFnc返回false停止遍历
+func (s *Store) IterateSubscribers(channelId string, channelType uint8, iterFnc func(subscriber wkdb.Subscriber) bool) error {
+	return s.wdb.IterateSubscribers(channelId, channelType, iterFnc)
+}
+
+// SetSubscriberRole 设置订阅者的角色
+func (s *Store) SetSubscriberRole(channelId string, channelType uint8, uids []string, role uint8) error {
+	data := EncodeCMDSetSubscriberRole(channelId, channelType, uids, role)
+	return s.proposeChannelCMD(channelId, CMDSetSubscriberRole, data)
+}
+
+// AddOrUpdateSubscribers 添加订阅者（包含属性），已存在的订阅者更新属性
+func (s *Store) AddOrUpdateSubscribers(channelId string, channelType uint8, subscribers []wkdb.Subscriber) error {
+	data := EncodeCMDSubscribersWithAttrs(channelId, channelType, subscribers)
+	return s.proposeChannelCMD(channelId, CMDAddOrUpdateSubscribers, data)
+}
+
+// UpdateSubscribers 更新订阅者属性，不是订阅者的忽略
+func (s *Store) UpdateSubscribers(channelId string, channelType uint8, subscribers []wkdb.Subscriber) error {
+	data := EncodeCMDSubscribersWithAttrs(channelId, channelType, subscribers)
+	return s.proposeChannelCMD(channelId, CMDUpdateSubscribers, data)
+}
+
 // AddOrUpdateChannel add or update channel
 func (s *Store) AddOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
 	data, err := EncodeAddOrUpdateChannel(channelInfo)
@@ -180,6 +223,28 @@ func (s *Store) HasAllowlist(channelId string, channelType uint8) (bool, error)
 	return s.wdb.HasAllowlist(channelId, channelType)
 }
 
+// AddOrUpdateChannelMutes 添加或更新频道禁言（uid为空表示全员禁言）
+func (s *Store) AddOrUpdateChannelMutes(channelId string, channelType uint8, mutes []wkdb.ChannelMute) error {
+	data := EncodeCMDAddOrUpdateChannelMutes(channelId, channelType, mutes)
+	return s.proposeChannelCMD(channelId, CMDAddOrUpdateChannelMutes, data)
+}
+
+// RemoveChannelMutes 解除频道禁言（uid为空表示全员禁言）
+func (s *Store) RemoveChannelMutes(channelId string, channelType uint8, uids []string) error {
+	data := EncodeCMDRemoveChannelMutes(channelId, channelType, uids)
+	return s.proposeChannelCMD(channelId, CMDRemoveChannelMutes, data)
+}
+
+// GetChannelMutes 获取频道内未到期的禁言
+func (s *Store) GetChannelMutes(channelId string, channelType uint8) ([]wkdb.ChannelMute, error) {
+	return s.wdb.GetChannelMutes(channelId, channelType)
+}
+
+// GetChannelMute 获取用户在频道内的禁言，不存在或已到期返回wkdb.ErrNotFound
+func (s *Store) GetChannelMute(channelId string, channelType uint8, uid string) (wkdb.ChannelMute, error) {
+	return s.wdb.GetChannelMute(channelId, channelType, uid)
+}
+
 // func (s *Store) DeleteChannelClusterConfig(channelID string, channelType uint8) error {
 // 	cmd := NewCMD(CMDChannelClusterConfigDelete, nil)
 // 	cmdData, err := cmd.Marshal()
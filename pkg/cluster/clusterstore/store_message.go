@@ -2,7 +2,10 @@ package clusterstore
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
@@ -14,6 +17,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrMessageEditNotApplied 编辑命令应用时被忽略
+var ErrMessageEditNotApplied = errors.New("message edit not applied")
+
+const (
+	channelMessageCmdPullLimit = 100 // 每次从槽领导拉取的频道消息命令数量
+	messageSeqBeforeLoadLimit  = 100 // 往前数消息时每次加载的消息数量
+)
+
 func (s *Store) AppendMessages(ctx context.Context, channelId string, channelType uint8, msgs []wkdb.Message) ([]icluster.ProposeResult, error) {
 
 	if len(msgs) == 0 {
@@ -58,11 +69,119 @@ func (s *Store) LoadPrevRangeMsgs(channelID string, channelType uint8, start, en
 	return s.wdb.LoadPrevRangeMsgs(channelID, channelType, start, end, limit)
 }
 
+// GetLastMsgSeq 获取频道最后一条消息的序号
 func (s *Store) GetLastMsgSeq(channelID string, channelType uint8) (uint64, error) {
 	seq, _, err := s.wdb.GetChannelLastMessageSeq(channelID, channelType)
 	return seq, err
 }
 
+// RevokeMessage 撤回消息（作为频道消息命令复制到频道的每个副本，本节点应用后返回）
+func (s *Store) RevokeMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	data := EncodeCMDMessageRevoke(channelId, channelType, messageSeq)
+	_, err := s.proposeChannelMessageCmd(channelId, channelType, CMDMessageRevoke, data)
+	return err
+}
+
+// EditMessage 编辑消息（作为频道消息命令复制到频道的每个副本），返回本节点应用时分配的新版本
+func (s *Store) EditMessage(req wkdb.MessageEditReq) (wkdb.MessageEdit, error) {
+	data := EncodeCMDMessageEdit(req)
+	applied, err := s.proposeChannelMessageCmd(req.ChannelId, req.ChannelType, CMDMessageEdit, data)
+	if err != nil {
+		return wkdb.MessageEdit{}, err
+	}
+	edit, ok := applied.(wkdb.MessageEdit)
+	if !ok { // 应用时被忽略了（比如消息已被清理）
+		return wkdb.MessageEdit{}, ErrMessageEditNotApplied
+	}
+	return edit, nil
+}
+
+// DeleteMessagesBefore 删除频道内序号小于messageSeq的消息（作为频道消息命令复制到频道的每个副本，副本存储了这些消息后才执行）
+func (s *Store) DeleteMessagesBefore(channelId string, channelType uint8, messageSeq uint64) error {
+	data := EncodeCMDMessageDeleteBefore(channelId, channelType, messageSeq)
+	_, err := s.proposeChannelMessageCmd(channelId, channelType, CMDMessageDeleteBefore, data)
+	return err
+}
+
+// DeleteExpiredMessages 清理频道的过期消息（作为频道消息命令复制到频道的每个副本，副本存储了这些消息后才执行）
+func (s *Store) DeleteExpiredMessages(channelId string, channelType uint8, messageSeqs []uint64) error {
+	data := EncodeCMDMessageDeleteExpired(channelId, channelType, messageSeqs)
+	_, err := s.proposeChannelMessageCmd(channelId, channelType, CMDMessageDeleteExpired, data)
+	return err
+}
+
+// GetExpiredMessages 获取本节点已过期且还未清理的消息
+func (s *Store) GetExpiredMessages(now time.Time, limit int) ([]wkdb.Message, error) {
+	return s.wdb.GetExpiredMessages(now, limit)
+}
+
+// GetChannelFirstMessageSeq 获取频道第一条可用的消息序号，0表示频道消息未被清理过
+func (s *Store) GetChannelFirstMessageSeq(channelId string, channelType uint8) (uint64, error) {
+	return s.wdb.GetChannelFirstMessageSeq(channelId, channelType)
+}
+
+// GetMessageSeqBefore 从endMessageSeq（包含）往前数count条未过期的消息，返回最早一条的序号，不足count条时返回频道第一条可用的消息序号
+// 过期清理后的消息仍占用序号，所以需要逐条读取，不能按序号计算
+func (s *Store) GetMessageSeqBefore(channelId string, channelType uint8, endMessageSeq uint64, count uint64) (uint64, error) {
+	if count == 0 {
+		return endMessageSeq + 1, nil
+	}
+	firstSeq, err := s.wdb.GetChannelFirstMessageSeq(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	if firstSeq == 0 {
+		firstSeq = 1
+	}
+	var (
+		now      = time.Now()
+		startSeq = endMessageSeq
+		found    uint64
+	)
+	for startSeq >= firstSeq {
+		messages, err := s.wdb.LoadPrevRangeMsgs(channelId, channelType, startSeq, firstSeq-1, messageSeqBeforeLoadLimit)
+		if err != nil {
+			return 0, err
+		}
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].IsExpired(now) {
+				continue
+			}
+			found++
+			if found >= count {
+				return uint64(messages[i].MessageSeq), nil
+			}
+		}
+		if len(messages) < messageSeqBeforeLoadLimit {
+			break
+		}
+		startSeq = uint64(messages[0].MessageSeq) - 1
+	}
+	return firstSeq, nil
+}
+
+func (s *Store) GetMessageEdits(channelId string, channelType uint8, messageId int64) ([]wkdb.MessageEdit, error) {
+	return s.wdb.GetMessageEdits(channelId, channelType, messageId)
+}
+
+func (s *Store) GetMessage(messageId uint64) (wkdb.Message, error) {
+	return s.wdb.GetMessage(messageId)
+}
+
+func (s *Store) SearchMessages(req wkdb.MessageSearchReq) ([]wkdb.Message, error) {
+	return s.wdb.SearchMessages(req)
+}
+
+// SearchMessagesByKeyword 通过全文索引搜索本节点的消息
+func (s *Store) SearchMessagesByKeyword(req wkdb.MessageKeywordSearchReq) ([]wkdb.MessageSearchResult, error) {
+	return s.wdb.SearchMessagesByKeyword(req)
+}
+
+// GetMessageDedup 获取去重窗口内发送者相同客户端消息编号的已存储消息（读取本节点，需要在频道领导节点调用）
+func (s *Store) GetMessageDedup(channelId string, channelType uint8, fromUid string, clientMsgNo string) (wkdb.MessageDedup, error) {
+	return s.wdb.GetMessageDedup(channelId, channelType, fromUid, clientMsgNo)
+}
+
 func (s *Store) GetMessagesOfNotifyQueue(count int) ([]wkdb.Message, error) {
 	return s.wdb.GetMessagesOfNotifyQueue(count)
 }
@@ -79,28 +198,186 @@ func (s *Store) GetMessageShardLogStorage() *MessageShardLogStorage {
 	return s.messageShardLogStorage
 }
 
-// // SaveStreamMeta 保存消息流元数据
-// func (s *Store) SaveStreamMeta(meta *wkstore.StreamMeta) error {
-// 	return nil
-// }
+// SaveStreamMeta 保存消息流元数据
+func (s *Store) SaveStreamMeta(meta wkdb.StreamMeta) error {
+	data := EncodeCMDSaveStreamMeta(meta)
+	return s.proposeChannelCMD(meta.ChannelId, CMDSaveStreamMeta, data)
+}
 
-// // StreamEnd 结束流
-// func (s *Store) StreamEnd(channelID string, channelType uint8, streamNo string) error {
-// 	return nil
-// }
+// StreamEnd 结束流
+func (s *Store) StreamEnd(channelId string, channelType uint8, streamNo string) error {
+	data := EncodeCMDStreamEnd(channelId, channelType, streamNo)
+	return s.proposeChannelCMD(channelId, CMDStreamEnd, data)
+}
 
-// func (s *Store) GetStreamMeta(channelID string, channelType uint8, streamNo string) (*wkstore.StreamMeta, error) {
-// 	return nil, nil
-// }
+// AppendStreamItem 追加消息流
+func (s *Store) AppendStreamItem(channelId string, channelType uint8, streamNo string, item wkdb.StreamItem) error {
+	data := EncodeCMDAppendStreamItem(channelId, channelType, streamNo, item)
+	return s.proposeChannelCMD(channelId, CMDAppendStreamItem, data)
+}
 
-// func (s *Store) GetStreamItems(channelID string, channelType uint8, streamNo string) ([]*wkstore.StreamItem, error) {
-// 	return nil, nil
-// }
+// proposeChannelCMD 将命令提交到频道所属的槽
+func (s *Store) proposeChannelCMD(channelId string, cmdType CMDType, data []byte) error {
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
 
-// // AppendStreamItem 追加消息流
-// func (s *Store) AppendStreamItem(channelID string, channelType uint8, streamNo string, item *wkstore.StreamItem) (uint32, error) {
-// 	return 0, nil
-// }
+// proposeChannelMessageCmd 将命令作为频道消息命令保存到频道所属的槽（不占用频道的消息序号），同步到本节点应用后通知频道的其他副本，返回命令在本节点应用的结果（没有结果时为nil）
+func (s *Store) proposeChannelMessageCmd(channelId string, channelType uint8, cmdType CMDType, data []byte) (interface{}, error) {
+	cmdData, err := NewCMD(cmdType, data).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	appendCmdData, err := NewCMD(CMDAppendChannelMessageCmd, EncodeCMDAppendChannelMessageCmd(channelId, channelType, cmdData)).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	// 提交到同步应用期间持有频道锁，命令不会在注册等待之前被其他协程应用
+	lockKey := wkutil.ChannelToKey(channelId, channelType)
+	s.lock.Lock(lockKey)
+	slotId := s.opts.GetSlotId(channelId)
+	result, err := s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, appendCmdData)
+	if err != nil {
+		s.lock.Unlock(lockKey)
+		return nil, err
+	}
+	waitKey := channelMessageCmdWaitKey(channelId, channelType, result.LogIndex())
+	appliedC := s.messageCmdWait.Register(waitKey)
+	err = s.syncChannelMessageCmds(channelId, channelType)
+	s.lock.Unlock(lockKey)
+
+	var applied interface{}
+	select {
+	case applied = <-appliedC:
+	default:
+		s.messageCmdWait.Trigger(waitKey, nil) // 命令没有应用结果，取消等待
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.NotifyChannelMessageCmds != nil {
+		s.opts.NotifyChannelMessageCmds(channelId, channelType)
+	}
+	return applied, nil
+}
+
+// channelMessageCmdWaitKey 等待频道消息命令应用结果的key（命令序号只在槽内唯一）
+func channelMessageCmdWaitKey(channelId string, channelType uint8, cmdIndex uint64) string {
+	return fmt.Sprintf("%s-%d", wkutil.ChannelToKey(channelId, channelType), cmdIndex)
+}
+
+// SyncChannelMessageCmds 从槽领导拉取本节点还没有的频道消息命令并应用
+func (s *Store) SyncChannelMessageCmds(channelId string, channelType uint8) error {
+	lockKey := wkutil.ChannelToKey(channelId, channelType)
+	s.lock.Lock(lockKey)
+	defer s.lock.Unlock(lockKey)
+	return s.syncChannelMessageCmds(channelId, channelType)
+}
+
+func (s *Store) syncChannelMessageCmds(channelId string, channelType uint8) error {
+	if s.opts.PullChannelMessageCmds != nil {
+		for {
+			lastIndex, err := s.wdb.GetChannelMessageCmdLastIndex(channelId, channelType)
+			if err != nil {
+				return err
+			}
+			cmds, err := s.opts.PullChannelMessageCmds(channelId, channelType, lastIndex, channelMessageCmdPullLimit)
+			if err != nil {
+				return err
+			}
+			if len(cmds) == 0 {
+				break
+			}
+			if err = s.wdb.AppendChannelMessageCmds(cmds); err != nil {
+				return err
+			}
+			if len(cmds) < channelMessageCmdPullLimit {
+				break
+			}
+		}
+	}
+	return s.applyChannelMessageCmds(channelId, channelType)
+}
+
+// GetChannelMessageCmds 获取频道内序号大于index的消息命令（读取本节点，需要在槽领导节点调用）
+func (s *Store) GetChannelMessageCmds(channelId string, channelType uint8, index uint64, limit int) ([]wkdb.ChannelMessageCmd, error) {
+	return s.wdb.GetChannelMessageCmds(channelId, channelType, index, limit)
+}
+
+func (s *Store) GetStreamMeta(channelId string, channelType uint8, streamNo string) (wkdb.StreamMeta, error) {
+	return s.wdb.GetStreamMeta(channelId, channelType, streamNo)
+}
+
+func (s *Store) GetStreamItems(channelId string, channelType uint8, streamNo string) ([]wkdb.StreamItem, error) {
+	return s.wdb.GetStreamItems(channelId, channelType, streamNo)
+}
+
+// GetStreamLastSeq 获取消息流最后一个流序号
+func (s *Store) GetStreamLastSeq(channelId string, channelType uint8, streamNo string) (uint32, error) {
+	return s.wdb.GetStreamLastSeq(channelId, channelType, streamNo)
+}
+
+// AddScheduledMessage 添加定时消息
+func (s *Store) AddScheduledMessage(m wkdb.ScheduledMessage) error {
+	data := EncodeCMDAddScheduledMessage(m)
+	return s.proposeChannelCMD(m.ChannelId, CMDAddScheduledMessage, data)
+}
+
+// DeleteScheduledMessage 删除定时消息
+func (s *Store) DeleteScheduledMessage(channelId string, channelType uint8, id uint64) error {
+	data := EncodeCMDDeleteScheduledMessage(channelId, channelType, id)
+	return s.proposeChannelCMD(channelId, CMDDeleteScheduledMessage, data)
+}
+
+func (s *Store) GetScheduledMessage(channelId string, channelType uint8, id uint64) (wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessage(channelId, channelType, id)
+}
+
+func (s *Store) GetScheduledMessages(channelId string, channelType uint8) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessages(channelId, channelType)
+}
+
+// GetDueScheduledMessages 获取本节点已到发送时间的定时消息
+func (s *Store) GetDueScheduledMessages(sendAt int64, limit int) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetDueScheduledMessages(sendAt, limit)
+}
+
+// AddOrUpdateReaction 添加或更新（IsDeleted为true表示删除）消息回应，版本号在槽应用日志时分配
+func (s *Store) AddOrUpdateReaction(reaction wkdb.Reaction) error {
+	data := EncodeCMDAddOrUpdateReaction(reaction)
+	return s.proposeChannelCMD(reaction.ChannelId, CMDAddOrUpdateReaction, data)
+}
+
+func (s *Store) GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]wkdb.Reaction, error) {
+	return s.wdb.GetReactions(channelId, channelType, messageSeq)
+}
+
+// GetReactionsAfterVersion 获取频道内版本号大于version的回应变更（读取本节点，需要在槽领导节点调用）
+func (s *Store) GetReactionsAfterVersion(channelId string, channelType uint8, version uint64, limit int) ([]wkdb.Reaction, error) {
+	return s.wdb.GetReactionsAfterVersion(channelId, channelType, version, limit)
+}
+
+// AddMessageReceipt 添加消息已读回执，onlyCount为true时只记录已读数量
+func (s *Store) AddMessageReceipt(receipt wkdb.MessageReceipt, onlyCount bool) error {
+	data := EncodeCMDAddMessageReceipt(receipt, onlyCount)
+	return s.proposeChannelCMD(receipt.ChannelId, CMDAddMessageReceipt, data)
+}
+
+// GetMessageReaders 获取消息的已读用户（读取本节点，需要在槽领导节点调用）
+func (s *Store) GetMessageReaders(channelId string, channelType uint8, messageSeq uint64) ([]wkdb.MessageReader, error) {
+	return s.wdb.GetMessageReaders(channelId, channelType, messageSeq)
+}
+
+// GetMessageReadCounts 获取消息的已读数量（读取本节点，需要在槽领导节点调用）
+func (s *Store) GetMessageReadCounts(channelId string, channelType uint8, messageSeqs []uint64) ([]wkdb.MessageReadCount, error) {
+	return s.wdb.GetMessageReadCounts(channelId, channelType, messageSeqs)
+}
 
 func (s *Store) UpdateMessageOfUserCursorIfNeed(uid string, messageSeq uint64) error {
 	return nil
@@ -289,16 +566,17 @@ func (m *MessageShardLogStorage) LastIndexAndTerm(shardNo string) (uint64, uint3
 	queryIndex := lastIndex
 	var lastMsg wkdb.Message
 	for queryIndex > 0 {
-		lastMsg, err = m.db.LoadMsg(channelId, channelType, queryIndex)
+		msgs, err := m.db.LoadNextRangeMsgsForSize(channelId, channelType, queryIndex, queryIndex+1, 0)
 		if err != nil {
-			if err == wkdb.ErrNotFound {
-				queryIndex--
-				m.Warn("load last msg not found", zap.String("shardNo", shardNo), zap.Uint64("queryIndex", queryIndex))
-				continue
-			}
 			m.Error("load last msg err", zap.Error(err), zap.String("shardNo", shardNo), zap.Uint64("lastIndex", lastIndex))
 			return 0, 0, err
 		}
+		if len(msgs) == 0 {
+			queryIndex--
+			m.Warn("load last msg not found", zap.String("shardNo", shardNo), zap.Uint64("queryIndex", queryIndex))
+			continue
+		}
+		lastMsg = msgs[0]
 		break
 
 	}
@@ -311,9 +589,10 @@ func (m *MessageShardLogStorage) LastIndexAndTerm(shardNo string) (uint64, uint3
 // 	return s.db.SetChannelLastMessageSeq(channelId, channelType, index)
 // }
 
-// 获取第一条日志的索引
+// 获取第一条日志的索引，0表示日志未被清理过
 func (m *MessageShardLogStorage) FirstIndex(shardNo string) (uint64, error) {
-	return 0, nil
+	channelId, channelType := wkutil.ChannelFromlKey(shardNo)
+	return m.db.GetChannelFirstMessageSeq(channelId, channelType)
 }
 
 // 设置成功被状态机应用的日志索引
@@ -2,6 +2,12 @@ package clusterstore_test
 
 import (
 	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterstore"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAppendMessage(t *testing.T) {
@@ -47,3 +53,73 @@ func TestAppendMessage(t *testing.T) {
 	// assert.Equal(t, 1, len(messages))
 	// assert.Equal(t, msg.data, messages[0].(*testMessage).data)
 }
+
+func TestGetMessageSeqBefore(t *testing.T) {
+	opts := clusterstore.NewOptions(1)
+	opts.DataDir = t.TempDir()
+	opts.SlotCount = 1
+	opts.Db.ShardNum = 1
+	opts.GetSlotId = func(v string) uint32 { return 0 }
+	s := clusterstore.NewStore(opts)
+	opts.Cluster = &testPropose{s: s}
+	err := s.Open()
+	assert.NoError(t, err)
+	defer s.Close()
+
+	channelId := "test"
+	channelType := uint8(2)
+
+	// 6 已过期
+	messages := make([]wkdb.Message, 0)
+	for i := 1; i <= 6; i++ {
+		var expire uint32
+		if i == 6 {
+			expire = 10
+		}
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i),
+				Timestamp:   int32(time.Now().Add(-time.Minute).Unix()),
+				Expire:      expire,
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = s.DB().AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 加入前撤回和编辑了消息，不影响往前数的结果
+	err = s.RevokeMessage(channelId, channelType, 5)
+	assert.NoError(t, err)
+	_, err = s.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   4,
+		MessageSeq:  4,
+		Payload:     []byte("edited"),
+	})
+	assert.NoError(t, err)
+
+	// 加入点为6，最近2条可见的消息是4、5
+	seq, err := s.GetMessageSeqBefore(channelId, channelType, 6, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), seq)
+
+	seq, err = s.GetMessageSeqBefore(channelId, channelType, 6, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), seq)
+
+	// 不足count条时从第一条可用的消息开始
+	seq, err = s.GetMessageSeqBefore(channelId, channelType, 6, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), seq)
+
+	err = s.DeleteMessagesBefore(channelId, channelType, 3)
+	assert.NoError(t, err)
+	seq, err = s.GetMessageSeqBefore(channelId, channelType, 6, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), seq)
+}
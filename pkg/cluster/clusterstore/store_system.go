@@ -0,0 +1,25 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+)
+
+// SystemDataKey 系统数据（发送频率限制规则等全局配置）存储在这个key所属的槽
+const SystemDataKey = "__wk_system"
+
+// AddOrUpdateRateLimits 添加或更新发送频率限制规则
+func (s *Store) AddOrUpdateRateLimits(limits []wkdb.RateLimit) error {
+	data := EncodeCMDRateLimits(limits)
+	return s.proposeChannelCMD(SystemDataKey, CMDAddOrUpdateRateLimits, data)
+}
+
+// RemoveRateLimits 移除发送频率限制规则
+func (s *Store) RemoveRateLimits(limits []wkdb.RateLimit) error {
+	data := EncodeCMDRateLimits(limits)
+	return s.proposeChannelCMD(SystemDataKey, CMDRemoveRateLimits, data)
+}
+
+// GetRateLimits 获取本节点存储的发送频率限制规则（系统数据所在槽的副本才有数据）
+func (s *Store) GetRateLimits() ([]wkdb.RateLimit, error) {
+	return s.wdb.GetRateLimits()
+}
@@ -60,3 +60,77 @@ func (s *Store) AddOrUpdateUserAndDevice(uid string, deviceFlag wkproto.DeviceFl
 func (s *Store) GetDevice(uid string, deviceFlag uint64) (wkdb.Device, error) {
 	return s.wdb.GetDevice(uid, deviceFlag)
 }
+
+// AddOrUpdateDevicePush 添加或更新设备推送令牌
+func (s *Store) AddOrUpdateDevicePush(push wkdb.DevicePush) error {
+	data := EncodeCMDDevicePush(push)
+	return s.proposeUserCMD(push.Uid, CMDAddOrUpdateDevicePush, data)
+}
+
+// RemoveDevicePush 移除设备推送令牌
+func (s *Store) RemoveDevicePush(uid string, deviceFlag uint64) error {
+	data := EncodeCMDRemoveDevicePush(uid, deviceFlag)
+	return s.proposeUserCMD(uid, CMDRemoveDevicePush, data)
+}
+
+// GetDevicePushes 获取用户所有设备的推送令牌
+func (s *Store) GetDevicePushes(uid string) ([]wkdb.DevicePush, error) {
+	return s.wdb.GetDevicePushes(uid)
+}
+
+// AddOrUpdateConversationMutes 添加或更新用户的会话免打扰
+func (s *Store) AddOrUpdateConversationMutes(uid string, mutes []wkdb.ConversationMute) error {
+	data := EncodeCMDAddOrUpdateConversationMutes(uid, mutes)
+	return s.proposeUserCMD(uid, CMDAddOrUpdateConversationMutes, data)
+}
+
+// RemoveConversationMutes 移除用户的会话免打扰
+func (s *Store) RemoveConversationMutes(uid string, channels []wkdb.Channel) error {
+	data := EncodeCMDRemoveConversationMutes(uid, channels)
+	return s.proposeUserCMD(uid, CMDRemoveConversationMutes, data)
+}
+
+// GetConversationMutes 获取用户所有的会话免打扰（包含已到期的）
+func (s *Store) GetConversationMutes(uid string) ([]wkdb.ConversationMute, error) {
+	return s.wdb.GetConversationMutes(uid)
+}
+
+// GetConversationMute 获取用户对频道的免打扰，不存在或已到期返回wkdb.ErrNotFound
+func (s *Store) GetConversationMute(uid string, channelId string, channelType uint8) (wkdb.ConversationMute, error) {
+	return s.wdb.GetConversationMute(uid, channelId, channelType)
+}
+
+// AddOrUpdateUserBan 添加或更新用户封禁（存储在用户所在的槽）
+func (s *Store) AddOrUpdateUserBan(ban wkdb.UserBan) error {
+	data := EncodeCMDAddOrUpdateUserBan(ban)
+	return s.proposeUserCMD(ban.Uid, CMDAddOrUpdateUserBan, data)
+}
+
+// RemoveUserBan 移除用户封禁，createdAt大于0时只移除创建时间相同的封禁（用于移除到期的封禁）
+func (s *Store) RemoveUserBan(uid string, createdAt int64) error {
+	data := EncodeCMDRemoveUserBan(uid, createdAt)
+	return s.proposeUserCMD(uid, CMDRemoveUserBan, data)
+}
+
+// GetUserBan 获取用户的封禁（包含已到期的），不存在返回wkdb.ErrNotFound
+func (s *Store) GetUserBan(uid string) (wkdb.UserBan, error) {
+	return s.wdb.GetUserBan(uid)
+}
+
+// GetUserBans 获取本节点存储的用户封禁（包含已到期的）
+func (s *Store) GetUserBans() ([]wkdb.UserBan, error) {
+	return s.wdb.GetUserBans()
+}
+
+// proposeUserCMD 提交用户数据的命令到用户所在的槽
+func (s *Store) proposeUserCMD(uid string, cmdType CMDType, data []byte) error {
+	cmd := NewCMD(cmdType, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
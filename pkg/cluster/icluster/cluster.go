@@ -20,10 +20,16 @@ type Cluster interface {
 	LeaderOfChannel(ctx context.Context, channelId string, channelType uint8) (nodeInfo *pb.Node, err error)
 	// SlotLeaderIdOfChannel 获取channel的leader节点信息(不激活频道)
 	LeaderOfChannelForRead(channelId string, channelType uint8) (nodeInfo *pb.Node, err error)
+	// ChannelSyncedIndex 获取频道所有副本都已存储的日志下标（只在频道领导节点有值）
+	ChannelSyncedIndex(channelId string, channelType uint8) uint64
+	// ChannelReplicaIds 获取频道的副本和学习者节点（频道在本节点未激活时返回空）
+	ChannelReplicaIds(channelId string, channelType uint8) []uint64
 	// SlotLeaderIdOfChannel 获取频道所属槽的领导
 	SlotLeaderIdOfChannel(channelId string, channelType uint8) (nodeId uint64, err error)
 	// SlotLeaderOfChannel 获取频道所属槽的领导
 	SlotLeaderOfChannel(channelId string, channelType uint8) (nodeInfo *pb.Node, err error)
+	// SlotLeaderTermOfChannel 获取频道所属槽的领导任期
+	SlotLeaderTermOfChannel(channelId string, channelType uint8) (term uint32, err error)
 	// IsSlotLeaderOfChannel 当前节点是否是channel槽的leader节点
 	IsSlotLeaderOfChannel(channelId string, channelType uint8) (isLeader bool, err error)
 	// IsLeaderNodeOfChannel 当前节点是否是channel的leader节点
@@ -219,6 +219,23 @@ func (r *Replica) LastLogIndex() uint64 {
 	return r.replicaLog.lastLogIndex
 }
 
+// SyncedIndex 所有副本（包括学习者）都已存储的最大日志下标，只有领导节点有效，非领导返回0
+func (r *Replica) SyncedIndex() uint64 {
+	if !r.isLeader() {
+		return 0
+	}
+	index := r.replicaLog.storagedIndex
+	for _, syncInfo := range r.lastSyncInfoMap {
+		if syncInfo.LastSyncIndex == 0 { // 副本还未来同步过
+			return 0
+		}
+		if syncInfo.LastSyncIndex-1 < index {
+			index = syncInfo.LastSyncIndex - 1
+		}
+	}
+	return index
+}
+
 func (r *Replica) Term() uint32 {
 	return r.term
 }
@@ -209,6 +209,18 @@ type IAppMetrics interface {
 	ConnackPacketBytesAdd(v int64)
 	// ConnackPacketCountAdd 连接应答包数量
 	ConnackPacketCountAdd(v int64)
+
+	// MessageRateLimitedCountAdd 因发送频率限制被拒绝的消息数量
+	MessageRateLimitedCountAdd(v int64)
+
+	// PushSuccessCountAdd 离线推送成功数量
+	PushSuccessCountAdd(v int64)
+	// PushFailedCountAdd 离线推送失败数量（重试后仍失败）
+	PushFailedCountAdd(v int64)
+	// PushRetryCountAdd 离线推送重试次数
+	PushRetryCountAdd(v int64)
+	// PushMutedCountAdd 因会话免打扰跳过的离线推送数量
+	PushMutedCountAdd(v int64)
 }
 
 // IClusterMetrics 分布式监控
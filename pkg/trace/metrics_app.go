@@ -33,6 +33,13 @@ type appMetrics struct {
 	connPacketCount    atomic.Int64
 	connackPacketBytes atomic.Int64
 	connackPacketCount atomic.Int64
+
+	messageRateLimitedCount atomic.Int64
+
+	pushSuccessCount atomic.Int64
+	pushFailedCount  atomic.Int64
+	pushRetryCount   atomic.Int64
+	pushMutedCount   atomic.Int64
 }
 
 func newAppMetrics(opts *Options) *appMetrics {
@@ -61,6 +68,11 @@ func newAppMetrics(opts *Options) *appMetrics {
 	connPacketCount := NewInt64ObservableCounter("app_conn_packet_count")
 	connackPacketBytes := NewInt64ObservableCounter("app_connack_packet_bytes")
 	connackPacketCount := NewInt64ObservableCounter("app_connack_packet_count")
+	messageRateLimitedCount := NewInt64ObservableCounter("app_message_rate_limited_count")
+	pushSuccessCount := NewInt64ObservableCounter("app_push_success_count")
+	pushFailedCount := NewInt64ObservableCounter("app_push_failed_count")
+	pushRetryCount := NewInt64ObservableCounter("app_push_retry_count")
+	pushMutedCount := NewInt64ObservableCounter("app_push_muted_count")
 
 	RegisterCallback(func(ctx context.Context, obs metric.Observer) error {
 		obs.ObserveInt64(connCount, a.connCount.Load())
@@ -82,8 +94,13 @@ func newAppMetrics(opts *Options) *appMetrics {
 		obs.ObserveInt64(connPacketCount, a.connPacketCount.Load())
 		obs.ObserveInt64(connackPacketBytes, a.connackPacketBytes.Load())
 		obs.ObserveInt64(connackPacketCount, a.connackPacketCount.Load())
+		obs.ObserveInt64(messageRateLimitedCount, a.messageRateLimitedCount.Load())
+		obs.ObserveInt64(pushSuccessCount, a.pushSuccessCount.Load())
+		obs.ObserveInt64(pushFailedCount, a.pushFailedCount.Load())
+		obs.ObserveInt64(pushRetryCount, a.pushRetryCount.Load())
+		obs.ObserveInt64(pushMutedCount, a.pushMutedCount.Load())
 		return nil
-	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount)
+	}, connCount, onlineUserCount, onlineDeviceCount, pingBytes, pingCount, pongBytes, pongCount, sendPacketBytes, sendPacketCount, sendackPacketBytes, sendackPacketCount, recvPacketBytes, recvPacketCount, recvackPacketBytes, recvackPacketCount, connPacketBytes, connPacketCount, connackPacketBytes, connackPacketCount, messageRateLimitedCount, pushSuccessCount, pushFailedCount, pushRetryCount, pushMutedCount)
 	var err error
 	a.messageLatency, err = meter.Int64Histogram("app_message_latency", metric.WithDescription("The latency of message processing in the app layer"), metric.WithUnit("ms"))
 	if err != nil {
@@ -170,3 +187,23 @@ func (a *appMetrics) ConnackPacketBytesAdd(v int64) {
 func (a *appMetrics) ConnackPacketCountAdd(v int64) {
 	a.connackPacketCount.Add(v)
 }
+
+func (a *appMetrics) MessageRateLimitedCountAdd(v int64) {
+	a.messageRateLimitedCount.Add(v)
+}
+
+func (a *appMetrics) PushSuccessCountAdd(v int64) {
+	a.pushSuccessCount.Add(v)
+}
+
+func (a *appMetrics) PushFailedCountAdd(v int64) {
+	a.pushFailedCount.Add(v)
+}
+
+func (a *appMetrics) PushRetryCountAdd(v int64) {
+	a.pushRetryCount.Add(v)
+}
+
+func (a *appMetrics) PushMutedCountAdd(v int64) {
+	a.pushMutedCount.Add(v)
+}
@@ -402,6 +402,39 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 		return err
 	}
 
+	// retentionMaxAge
+	retentionMaxAgeBytes := make([]byte, 4)
+	wk.endian.PutUint32(retentionMaxAgeBytes, channelInfo.RetentionMaxAge)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.RetentionMaxAge), retentionMaxAgeBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// retentionMaxCount
+	retentionMaxCountBytes := make([]byte, 4)
+	wk.endian.PutUint32(retentionMaxCountBytes, channelInfo.RetentionMaxCount)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.RetentionMaxCount), retentionMaxCountBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// onlyAdminSend
+	onlyAdminSendBytes := make([]byte, 1)
+	onlyAdminSendBytes[0] = wkutil.BoolToUint8(channelInfo.OnlyAdminSend)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.OnlyAdminSend), onlyAdminSendBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// historyVisibility
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.HistoryVisibility), []byte{channelInfo.HistoryVisibility}, wk.noSync); err != nil {
+		return err
+	}
+
+	// historyVisibleCount
+	historyVisibleCountBytes := make([]byte, 4)
+	wk.endian.PutUint32(historyVisibleCountBytes, channelInfo.HistoryVisibleCount)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.HistoryVisibleCount), historyVisibleCountBytes, wk.noSync); err != nil {
+		return err
+	}
+
 	// channel index
 	idBytes := make([]byte, 8)
 	wk.endian.PutUint64(idBytes, primaryKey)
@@ -464,6 +497,16 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 			preChannelInfo.AllowlistCount = int(wk.endian.Uint32(iter.Value()))
 		case key.TableChannelInfo.Column.DenylistCount:
 			preChannelInfo.DenylistCount = int(wk.endian.Uint32(iter.Value()))
+		case key.TableChannelInfo.Column.RetentionMaxAge:
+			preChannelInfo.RetentionMaxAge = wk.endian.Uint32(iter.Value())
+		case key.TableChannelInfo.Column.RetentionMaxCount:
+			preChannelInfo.RetentionMaxCount = wk.endian.Uint32(iter.Value())
+		case key.TableChannelInfo.Column.OnlyAdminSend:
+			preChannelInfo.OnlyAdminSend = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelInfo.Column.HistoryVisibility:
+			preChannelInfo.HistoryVisibility = iter.Value()[0]
+		case key.TableChannelInfo.Column.HistoryVisibleCount:
+			preChannelInfo.HistoryVisibleCount = wk.endian.Uint32(iter.Value())
 
 		}
 		hasData = true
@@ -0,0 +1,86 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AppendChannelMessageCmds(cmds []ChannelMessageCmd) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	// 同一批命令属于同一个频道
+	db := wk.channelDb(cmds[0].ChannelId, cmds[0].ChannelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, cmd := range cmds {
+		if err := batch.Set(key.NewChannelMessageCmdColumnKey(cmd.ChannelId, cmd.ChannelType, cmd.Index, key.TableChannelMessageCmd.Column.Data), cmd.Data, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetChannelMessageCmds(channelId string, channelType uint8, index uint64, limit int) ([]ChannelMessageCmd, error) {
+	if index == math.MaxUint64 {
+		return nil, nil
+	}
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelMessageCmdColumnKey(channelId, channelType, index+1, key.MinColumnKey),
+		UpperBound: key.NewChannelMessageCmdColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	cmds := make([]ChannelMessageCmd, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		cmdIndex, columnName, err := key.ParseChannelMessageCmdColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if columnName != key.TableChannelMessageCmd.Column.Data {
+			continue
+		}
+		data := make([]byte, len(iter.Value()))
+		copy(data, iter.Value())
+		cmds = append(cmds, ChannelMessageCmd{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			Index:       cmdIndex,
+			Data:        data,
+		})
+		if limit > 0 && len(cmds) >= limit {
+			break
+		}
+	}
+	return cmds, nil
+}
+
+func (wk *wukongDB) GetChannelMessageCmdLastIndex(channelId string, channelType uint8) (uint64, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelMessageCmdColumnKey(channelId, channelType, 0, key.MinColumnKey),
+		UpperBound: key.NewChannelMessageCmdColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	if !iter.Last() {
+		return 0, nil
+	}
+	index, _, err := key.ParseChannelMessageCmdColumnKey(iter.Key())
+	if err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+func (wk *wukongDB) SetChannelMessageCmdAppliedIndex(channelId string, channelType uint8, index uint64) error {
+	indexBytes := make([]byte, 8)
+	wk.endian.PutUint64(indexBytes, index)
+	return wk.channelDb(channelId, channelType).Set(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.MessageCmdAppliedIndex), indexBytes, wk.sync)
+}
+
+func (wk *wukongDB) GetChannelMessageCmdAppliedIndex(channelId string, channelType uint8) (uint64, error) {
+	db := wk.channelDb(channelId, channelType)
+	return wk.getUint64Value(db, key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.MessageCmdAppliedIndex))
+}
@@ -0,0 +1,73 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelMessageCmds(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	lastIndex, err := d.GetChannelMessageCmdLastIndex(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), lastIndex)
+
+	cmds := []wkdb.ChannelMessageCmd{
+		{ChannelId: channelId, ChannelType: channelType, Index: 3, Data: []byte("revoke")},
+		{ChannelId: channelId, ChannelType: channelType, Index: 8, Data: []byte("edit")},
+		{ChannelId: channelId, ChannelType: channelType, Index: 12, Data: []byte("delete")},
+	}
+	err = d.AppendChannelMessageCmds(cmds)
+	assert.NoError(t, err)
+
+	// 重复保存是幂等的
+	err = d.AppendChannelMessageCmds(cmds[1:2])
+	assert.NoError(t, err)
+
+	// 其他频道的命令互不影响
+	err = d.AppendChannelMessageCmds([]wkdb.ChannelMessageCmd{{ChannelId: "channel2", ChannelType: channelType, Index: 20, Data: []byte("other")}})
+	assert.NoError(t, err)
+
+	lastIndex, err = d.GetChannelMessageCmdLastIndex(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), lastIndex)
+
+	results, err := d.GetChannelMessageCmds(channelId, channelType, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, uint64(3), results[0].Index)
+	assert.Equal(t, []byte("revoke"), results[0].Data)
+	assert.Equal(t, channelId, results[0].ChannelId)
+
+	results, err = d.GetChannelMessageCmds(channelId, channelType, 3, 1)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, uint64(8), results[0].Index)
+	assert.Equal(t, []byte("edit"), results[0].Data)
+
+	results, err = d.GetChannelMessageCmds(channelId, channelType, 12, 0)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	appliedIndex, err := d.GetChannelMessageCmdAppliedIndex(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), appliedIndex)
+
+	err = d.SetChannelMessageCmdAppliedIndex(channelId, channelType, 8)
+	assert.NoError(t, err)
+	appliedIndex, err = d.GetChannelMessageCmdAppliedIndex(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), appliedIndex)
+}
@@ -0,0 +1,141 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateChannelMutes(channelId string, channelType uint8, mutes []ChannelMute) error {
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 清理已到期的禁言
+	now := time.Now().Unix()
+	allMutes, err := wk.getChannelMutes(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	for _, mute := range allMutes {
+		if mute.Expired(now) {
+			if err = wk.deleteChannelMute(channelId, channelType, mute.Uid, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, mute := range mutes {
+		uidHash := key.HashWithString(mute.Uid)
+
+		// uid
+		if err = batch.Set(key.NewChannelMuteColumnKey(channelId, channelType, uidHash, key.TableChannelMute.Column.Uid), []byte(mute.Uid), wk.noSync); err != nil {
+			return err
+		}
+
+		// expireAt
+		expireAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(expireAtBytes, uint64(mute.ExpireAt))
+		if err = batch.Set(key.NewChannelMuteColumnKey(channelId, channelType, uidHash, key.TableChannelMute.Column.ExpireAt), expireAtBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveChannelMutes(channelId string, channelType uint8, uids []string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	batch := wk.channelDb(channelId, channelType).NewBatch()
+	defer batch.Close()
+	for _, uid := range uids {
+		if err := wk.deleteChannelMute(channelId, channelType, uid, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetChannelMutes(channelId string, channelType uint8) ([]ChannelMute, error) {
+	allMutes, err := wk.getChannelMutes(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	mutes := make([]ChannelMute, 0, len(allMutes))
+	for _, mute := range allMutes {
+		if !mute.Expired(now) {
+			mutes = append(mutes, mute)
+		}
+	}
+	return mutes, nil
+}
+
+func (wk *wukongDB) GetChannelMute(channelId string, channelType uint8, uid string) (ChannelMute, error) {
+	expireAtKey := key.NewChannelMuteColumnKey(channelId, channelType, key.HashWithString(uid), key.TableChannelMute.Column.ExpireAt)
+	value, closer, err := wk.channelDb(channelId, channelType).Get(expireAtKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return ChannelMute{}, ErrNotFound
+		}
+		return ChannelMute{}, err
+	}
+	defer closer.Close()
+
+	mute := ChannelMute{
+		Uid:      uid,
+		ExpireAt: int64(wk.endian.Uint64(value)),
+	}
+	if mute.Expired(time.Now().Unix()) {
+		return ChannelMute{}, ErrNotFound
+	}
+	return mute, nil
+}
+
+// getChannelMutes 获取频道内所有禁言（包含已到期的）
+func (wk *wukongDB) getChannelMutes(channelId string, channelType uint8) ([]ChannelMute, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewChannelMuteColumnKey(channelId, channelType, 0, key.MinColumnKey),
+		UpperBound: key.NewChannelMuteColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		mutes       = make([]ChannelMute, 0)
+		preHash     uint64
+		preMute     ChannelMute
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		uidHash, columnName, err := key.ParseChannelMuteColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if uidHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				mutes = append(mutes, preMute)
+			}
+			preHash = uidHash
+			preMute = ChannelMute{}
+		}
+		switch columnName {
+		case key.TableChannelMute.Column.Uid:
+			preMute.Uid = string(iter.Value())
+		case key.TableChannelMute.Column.ExpireAt:
+			preMute.ExpireAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		mutes = append(mutes, preMute)
+	}
+	return mutes, nil
+}
+
+func (wk *wukongDB) deleteChannelMute(channelId string, channelType uint8, uid string, w pebble.Writer) error {
+	uidHash := key.HashWithString(uid)
+	return w.DeleteRange(key.NewChannelMuteColumnKey(channelId, channelType, uidHash, key.MinColumnKey), key.NewChannelMuteColumnKey(channelId, channelType, uidHash, key.MaxColumnKey), wk.noSync)
+}
@@ -0,0 +1,56 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelMutes(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	now := time.Now().Unix()
+
+	err = d.AddOrUpdateChannelMutes(channelId, channelType, []wkdb.ChannelMute{
+		{Uid: "", ExpireAt: 0},          // 全员禁言
+		{Uid: "u1", ExpireAt: now + 60}, // 未到期
+		{Uid: "u2", ExpireAt: now - 1},  // 已到期
+	})
+	assert.NoError(t, err)
+
+	mute, err := d.GetChannelMute(channelId, channelType, "")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), mute.ExpireAt)
+
+	mute, err = d.GetChannelMute(channelId, channelType, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, now+60, mute.ExpireAt)
+
+	_, err = d.GetChannelMute(channelId, channelType, "u2")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	mutes, err := d.GetChannelMutes(channelId, channelType)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.ChannelMute{{Uid: "", ExpireAt: 0}, {Uid: "u1", ExpireAt: now + 60}}, mutes)
+
+	// 解除全员禁言
+	err = d.RemoveChannelMutes(channelId, channelType, []string{""})
+	assert.NoError(t, err)
+	_, err = d.GetChannelMute(channelId, channelType, "")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// 其他频道不受影响
+	_, err = d.GetChannelMute("channel2", channelType, "u1")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
@@ -1,6 +1,7 @@
 package wkdb_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
@@ -132,11 +133,15 @@ func TestGetChannel(t *testing.T) {
 	}()
 
 	channelInfo := wkdb.ChannelInfo{
-		ChannelId:   "channel1",
-		ChannelType: 1,
-		Ban:         true,
-		Large:       true,
-		Disband:     true,
+		ChannelId:           "channel1",
+		ChannelType:         1,
+		Ban:                 true,
+		Large:               true,
+		Disband:             true,
+		RetentionMaxAge:     3600,
+		RetentionMaxCount:   100,
+		HistoryVisibility:   2,
+		HistoryVisibleCount: 20,
 	}
 	_, err = d.AddOrUpdateChannel(channelInfo)
 	assert.NoError(t, err)
@@ -149,6 +154,10 @@ func TestGetChannel(t *testing.T) {
 	assert.Equal(t, channelInfo.Ban, channelInfo2.Ban)
 	assert.Equal(t, channelInfo.Large, channelInfo2.Large)
 	assert.Equal(t, channelInfo.Disband, channelInfo2.Disband)
+	assert.Equal(t, channelInfo.RetentionMaxAge, channelInfo2.RetentionMaxAge)
+	assert.Equal(t, channelInfo.RetentionMaxCount, channelInfo2.RetentionMaxCount)
+	assert.Equal(t, channelInfo.HistoryVisibility, channelInfo2.HistoryVisibility)
+	assert.Equal(t, channelInfo.HistoryVisibleCount, channelInfo2.HistoryVisibleCount)
 }
 
 func TestExistChannel(t *testing.T) {
@@ -392,3 +401,182 @@ func TestRemoveAllAllowlist(t *testing.T) {
 
 	assert.Equal(t, 0, len(uids2))
 }
+
+func TestSubscriberRole(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	_, err = d.AddOrUpdateChannel(wkdb.NewChannelInfo(channelId, channelType))
+	assert.NoError(t, err)
+
+	err = d.AddSubscribers(channelId, channelType, []string{"u1", "u2", "u3"})
+	assert.NoError(t, err)
+
+	err = d.SetSubscriberRole(channelId, channelType, []string{"u1"}, wkdb.SubscriberRoleOwner)
+	assert.NoError(t, err)
+	err = d.SetSubscriberRole(channelId, channelType, []string{"u2", "notExist"}, wkdb.SubscriberRoleAdmin)
+	assert.NoError(t, err)
+
+	subscriber, err := d.GetSubscriber(channelId, channelType, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.SubscriberRoleOwner, subscriber.Role)
+	assert.True(t, subscriber.IsAdmin())
+
+	subscriber, err = d.GetSubscriber(channelId, channelType, "u3")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.SubscriberRoleMember, subscriber.Role)
+	assert.False(t, subscriber.IsAdmin())
+
+	_, err = d.GetSubscriber(channelId, channelType, "notExist")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	subscribers, err := d.GetSubscribersDetail(channelId, channelType)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.Subscriber{
+		{Uid: "u1", Role: wkdb.SubscriberRoleOwner},
+		{Uid: "u2", Role: wkdb.SubscriberRoleAdmin},
+		{Uid: "u3", Role: wkdb.SubscriberRoleMember},
+	}, subscribers)
+
+	// 移除后重新添加，角色重置为普通成员
+	err = d.RemoveSubscribers(channelId, channelType, []string{"u2"})
+	assert.NoError(t, err)
+	err = d.AddSubscribers(channelId, channelType, []string{"u2"})
+	assert.NoError(t, err)
+	subscriber, err = d.GetSubscriber(channelId, channelType, "u2")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.SubscriberRoleMember, subscriber.Role)
+}
+
+func TestSubscribersPage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	_, err = d.AddOrUpdateChannel(wkdb.NewChannelInfo(channelId, channelType))
+	assert.NoError(t, err)
+
+	uids := make([]string, 0, 25)
+	for i := 0; i < 25; i++ {
+		uids = append(uids, fmt.Sprintf("u%d", i))
+	}
+	err = d.AddSubscribers(channelId, channelType, uids)
+	assert.NoError(t, err)
+	// 重复添加不重复计数
+	err = d.AddSubscribers(channelId, channelType, []string{"u1", "u2", "u2"})
+	assert.NoError(t, err)
+
+	count, err := d.GetSubscriberCount(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 25, count)
+
+	var (
+		cursor    string
+		pageCount int
+		pageUids  []string
+	)
+	for {
+		subscribers, err := d.GetSubscribersPage(channelId, channelType, cursor, 10)
+		assert.NoError(t, err)
+		if len(subscribers) == 0 {
+			break
+		}
+		pageCount++
+		for _, subscriber := range subscribers {
+			pageUids = append(pageUids, subscriber.Uid)
+		}
+		cursor = subscribers[len(subscribers)-1].Uid
+	}
+	assert.Equal(t, 3, pageCount)
+	assert.ElementsMatch(t, uids, pageUids)
+
+	var iterUids []string
+	err = d.IterateSubscribers(channelId, channelType, func(subscriber wkdb.Subscriber) bool {
+		iterUids = append(iterUids, subscriber.Uid)
+		return len(iterUids) < 5
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, pageUids[:5], iterUids)
+
+	err = d.RemoveSubscribers(channelId, channelType, []string{"u1", "notExist"})
+	assert.NoError(t, err)
+	count, err = d.GetSubscriberCount(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 24, count)
+
+	count, err = d.GetSubscriberCount("notExist", channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestSubscriberAttrs(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	_, err = d.AddOrUpdateChannel(wkdb.NewChannelInfo(channelId, channelType))
+	assert.NoError(t, err)
+
+	err = d.AddOrUpdateSubscribers(channelId, channelType, []wkdb.Subscriber{
+		{Uid: "u1", JoinedAt: 100, JoinSeq: 10, InviterUid: "u0", Nickname: "n1", Extra: []byte(`{"k":"v"}`)},
+		{Uid: "u2", JoinedAt: 200, JoinSeq: 20},
+	})
+	assert.NoError(t, err)
+	err = d.SetSubscriberRole(channelId, channelType, []string{"u1"}, wkdb.SubscriberRoleAdmin)
+	assert.NoError(t, err)
+
+	subscriber, err := d.GetSubscriber(channelId, channelType, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.Subscriber{Uid: "u1", Role: wkdb.SubscriberRoleAdmin, JoinedAt: 100, JoinSeq: 10, InviterUid: "u0", Nickname: "n1", Extra: []byte(`{"k":"v"}`)}, subscriber)
+
+	// 只更新订阅者的属性，不是订阅者的忽略
+	err = d.UpdateSubscribers(channelId, channelType, []wkdb.Subscriber{
+		{Uid: "u1", JoinedAt: 100, JoinSeq: 10, InviterUid: "u0", Nickname: "n1-new"},
+		{Uid: "notExist", Nickname: "n"},
+	})
+	assert.NoError(t, err)
+
+	subscribers, err := d.GetSubscribersDetail(channelId, channelType)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.Subscriber{
+		{Uid: "u1", Role: wkdb.SubscriberRoleAdmin, JoinedAt: 100, JoinSeq: 10, InviterUid: "u0", Nickname: "n1-new"},
+		{Uid: "u2", JoinedAt: 200, JoinSeq: 20},
+	}, subscribers)
+
+	count, err := d.GetSubscriberCount(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	err = d.RemoveSubscribers(channelId, channelType, []string{"u1"})
+	assert.NoError(t, err)
+	err = d.AddSubscribers(channelId, channelType, []string{"u1"})
+	assert.NoError(t, err)
+	subscriber, err = d.GetSubscriber(channelId, channelType, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.Subscriber{Uid: "u1"}, subscriber)
+}
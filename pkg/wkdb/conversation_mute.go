@@ -0,0 +1,115 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateConversationMutes(uid string, mutes []ConversationMute) error {
+	if len(mutes) == 0 {
+		return nil
+	}
+	batch := wk.shardDB(uid).NewBatch()
+	defer batch.Close()
+
+	for _, mute := range mutes {
+		// channelId
+		if err := batch.Set(key.NewConversationMuteColumnKey(uid, mute.ChannelId, mute.ChannelType, key.TableConversationMute.Column.ChannelId), []byte(mute.ChannelId), wk.noSync); err != nil {
+			return err
+		}
+
+		// channelType
+		if err := batch.Set(key.NewConversationMuteColumnKey(uid, mute.ChannelId, mute.ChannelType, key.TableConversationMute.Column.ChannelType), []byte{mute.ChannelType}, wk.noSync); err != nil {
+			return err
+		}
+
+		// expireAt
+		expireAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(expireAtBytes, uint64(mute.ExpireAt))
+		if err := batch.Set(key.NewConversationMuteColumnKey(uid, mute.ChannelId, mute.ChannelType, key.TableConversationMute.Column.ExpireAt), expireAtBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveConversationMutes(uid string, channels []Channel) error {
+	if len(channels) == 0 {
+		return nil
+	}
+	batch := wk.shardDB(uid).NewBatch()
+	defer batch.Close()
+
+	for _, channel := range channels {
+		if err := batch.DeleteRange(key.NewConversationMuteColumnKey(uid, channel.ChannelId, channel.ChannelType, key.MinColumnKey), key.NewConversationMuteColumnKey(uid, channel.ChannelId, channel.ChannelType, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetConversationMutes(uid string) ([]ConversationMute, error) {
+	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewConversationMuteColumnKeyWithHash(uid, 0, key.MinColumnKey),
+		UpperBound: key.NewConversationMuteColumnKeyWithHash(uid, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		mutes       = make([]ConversationMute, 0)
+		preHash     uint64
+		preMute     ConversationMute
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		channelHash, columnName, err := key.ParseConversationMuteColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if channelHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				mutes = append(mutes, preMute)
+			}
+			preHash = channelHash
+			preMute = ConversationMute{}
+		}
+		switch columnName {
+		case key.TableConversationMute.Column.ChannelId:
+			preMute.ChannelId = string(iter.Value())
+		case key.TableConversationMute.Column.ChannelType:
+			preMute.ChannelType = iter.Value()[0]
+		case key.TableConversationMute.Column.ExpireAt:
+			preMute.ExpireAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		mutes = append(mutes, preMute)
+	}
+	return mutes, nil
+}
+
+func (wk *wukongDB) GetConversationMute(uid string, channelId string, channelType uint8) (ConversationMute, error) {
+	expireAtKey := key.NewConversationMuteColumnKey(uid, channelId, channelType, key.TableConversationMute.Column.ExpireAt)
+	value, closer, err := wk.shardDB(uid).Get(expireAtKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return ConversationMute{}, ErrNotFound
+		}
+		return ConversationMute{}, err
+	}
+	defer closer.Close()
+
+	mute := ConversationMute{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		ExpireAt:    int64(wk.endian.Uint64(value)),
+	}
+	if mute.Expired(time.Now().Unix()) {
+		return ConversationMute{}, ErrNotFound
+	}
+	return mute, nil
+}
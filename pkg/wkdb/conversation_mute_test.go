@@ -0,0 +1,50 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationMutes(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateConversationMutes("u1", []wkdb.ConversationMute{
+		{ChannelId: "g1", ChannelType: 2},
+		{ChannelId: "g2", ChannelType: 2, ExpireAt: 100}, // 已到期
+		{ChannelId: "u2", ChannelType: 1, ExpireAt: 4102444800},
+	})
+	assert.NoError(t, err)
+
+	mutes, err := d.GetConversationMutes("u1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.ConversationMute{
+		{ChannelId: "g1", ChannelType: 2},
+		{ChannelId: "g2", ChannelType: 2, ExpireAt: 100},
+		{ChannelId: "u2", ChannelType: 1, ExpireAt: 4102444800},
+	}, mutes)
+
+	mute, err := d.GetConversationMute("u1", "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.ConversationMute{ChannelId: "g1", ChannelType: 2}, mute)
+
+	_, err = d.GetConversationMute("u1", "g2", 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	_, err = d.GetConversationMute("u2", "g1", 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	err = d.RemoveConversationMutes("u1", []wkdb.Channel{{ChannelId: "g1", ChannelType: 2}})
+	assert.NoError(t, err)
+
+	_, err = d.GetConversationMute("u1", "g1", 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
@@ -1,5 +1,7 @@
 package wkdb
 
+import "time"
+
 type DB interface {
 	Open() error
 	Close() error
@@ -23,6 +25,33 @@ type DB interface {
 	// SessionDB
 	// 数据统计
 	TotalDB
+	// 流消息
+	StreamDB
+	// 定时消息
+	ScheduledMessageDB
+	// 消息回应
+	ReactionDB
+	// 消息已读回执
+	MessageReceiptDB
+	// 消息去重
+	MessageDedupDB
+	// 频道的消息命令
+	ChannelMessageCmdDB
+	// 敏感词
+	SensitiveWordDB
+	// 发送频率限制
+	RateLimitDB
+	// 频道禁言
+	ChannelMuteDB
+	// 用户封禁
+	UserBanDB
+	// 设备推送令牌
+	DevicePushDB
+	// 会话免打扰
+	ConversationMuteDB
+	// webhook发件箱
+	WebhookEventDB
+	WebhookEndpointDB
 }
 
 type MessageDB interface {
@@ -77,6 +106,33 @@ type MessageDB interface {
 
 	// 搜索消息
 	SearchMessages(req MessageSearchReq) ([]Message, error)
+
+	// SearchMessagesByKeyword 通过全文索引搜索消息
+	SearchMessagesByKeyword(req MessageKeywordSearchReq) ([]MessageSearchResult, error)
+
+	// RebuildMessageSearchIndex 清空并重建所有消息的全文索引，同时补齐发送者+消息时间索引（需要在服务停止时执行）
+	RebuildMessageSearchIndex() error
+
+	// RevokeMessage 撤回消息（本节点不存在此消息时忽略）
+	RevokeMessage(channelId string, channelType uint8, messageSeq uint64) error
+
+	// EditMessage 编辑消息，保存一个新版本的消息内容，新版本号为当前版本号+1，返回新的版本（编辑命令已应用过时忽略，返回空的版本）
+	EditMessage(req MessageEditReq) (MessageEdit, error)
+
+	// GetMessageEdits 获取消息的编辑历史（按版本升序）
+	GetMessageEdits(channelId string, channelType uint8, messageId int64) ([]MessageEdit, error)
+
+	// GetExpiredMessages 获取本节点截止到now已过期且还未清理的消息，最多limit条
+	GetExpiredMessages(now time.Time, limit int) ([]Message, error)
+
+	// DeleteExpiredMessages 清理频道内指定序号的过期消息：删除消息内容和所有索引，保留序号、任期等日志信息，返回清理的消息数量
+	DeleteExpiredMessages(channelId string, channelType uint8, messageSeqs []uint64) (int, error)
+
+	// DeleteMessagesBefore 删除频道内序号小于messageSeq的消息及其索引（频道最后一条消息不会删除），返回删除的消息数量
+	DeleteMessagesBefore(channelId string, channelType uint8, messageSeq uint64) (int, error)
+
+	// GetChannelFirstMessageSeq 获取频道第一条可用的消息序号，0表示频道消息未被清理过
+	GetChannelFirstMessageSeq(channelId string, channelType uint8) (uint64, error)
 }
 
 type DeviceDB interface {
@@ -126,6 +182,30 @@ type ChannelDB interface {
 	// GetSubscribers 获取订阅者
 	GetSubscribers(channelId string, channelType uint8) ([]string, error)
 
+	// GetSubscribersDetail 获取订阅者（包含角色）
+	GetSubscribersDetail(channelId string, channelType uint8) ([]Subscriber, error)
+
+	// GetSubscribersPage 分页获取订阅者（按存储顺序），cursor为上一页最后一个订阅者的uid，为空表示从头开始，limit为0表示不限制
+	GetSubscribersPage(channelId string, channelType uint8, cursor string, limit int) ([]Subscriber, error)
+
+	// GetSubscriberCount 获取订阅者数量（读取频道信息中的计数，不遍历订阅者）
+	GetSubscriberCount(channelId string, channelType uint8) (int, error)
+
+	// AddOrUpdateSubscribers 添加订阅者并写入属性（加入时间、邀请人、昵称等，不包含角色），已存在的订阅者只更新属性
+	AddOrUpdateSubscribers(channelId string, channelType uint8, subscribers []Subscriber) error
+
+	// UpdateSubscribers 更新订阅者的属性（不包含角色），不是订阅者的忽略
+	UpdateSubscribers(channelId string, channelType uint8, subscribers []Subscriber) error
+
+	// IterateSubscribers 遍历订阅者，iterFnc返回false停止遍历
+	IterateSubscribers(channelId string, channelType uint8, iterFnc func(subscriber Subscriber) bool) error
+
+	// GetSubscriber 获取订阅者（包含角色），不是订阅者返回ErrNotFound
+	GetSubscriber(channelId string, channelType uint8, uid string) (Subscriber, error)
+
+	// SetSubscriberRole 设置订阅者的角色，不是订阅者的uid忽略
+	SetSubscriberRole(channelId string, channelType uint8, uids []string, role uint8) error
+
 	// AddOrUpdateChannel  添加或更新channel
 	AddOrUpdateChannel(channelInfo ChannelInfo) (uint64, error)
 
@@ -337,6 +417,46 @@ type MessageSearchReq struct {
 	Pre              bool   // 是否向前搜索
 
 	ClientMsgNo string // 客户端消息编号
+
+	StartTime int64 // 消息开始时间（10位，到秒），0表示不限制
+	EndTime   int64 // 消息结束时间（10位，到秒，包含），0表示不限制
+}
+
+// MessageSearchOrder 消息搜索结果的排序方式
+type MessageSearchOrder int
+
+const (
+	MessageSearchOrderTime      MessageSearchOrder = iota // 按时间倒序
+	MessageSearchOrderRelevance                           // 按相关度倒序
+)
+
+// MessageKeywordSearchReq 消息全文搜索请求
+type MessageKeywordSearchReq struct {
+	Keyword         string             // 搜索关键字（所有词都需要匹配）
+	ChannelId       string             // 频道id
+	ChannelType     uint8              // 频道类型
+	FromUid         string             // 发送者uid
+	StartTime       int64              // 消息开始时间（10位，到秒），0表示不限制
+	EndTime         int64              // 消息结束时间（10位，到秒，包含），0表示不限制
+	OrderBy         MessageSearchOrder // 排序方式
+	OffsetMessageId int64              // 按时间排序分页时传入上一页最后一条消息的id
+	Limit           int                // 消息限制
+}
+
+// MessageSearchResult 消息搜索结果
+type MessageSearchResult struct {
+	Message
+	Score float64 // 相关度（按相关度排序时有值）
+}
+
+type MessageEditReq struct {
+	ChannelId   string // 频道id
+	ChannelType uint8  // 频道类型
+	MessageId   int64  // 消息id
+	MessageSeq  uint64 // 消息seq
+	Payload     []byte // 新的消息内容
+	EditedAt    int32  // 编辑时间(10位，到秒)
+	LogSeq      uint64 // 编辑命令的序号（重复应用同一条编辑命令时忽略）
 }
 
 type ChannelSearchReq struct {
@@ -389,3 +509,194 @@ type ChannelClusterConfigSearchReq struct {
 	SlotLeaderId uint64 // 槽领导者id
 
 }
+
+type StreamDB interface {
+	// SaveStreamMeta 保存流元数据
+	SaveStreamMeta(meta StreamMeta) error
+
+	// GetStreamMeta 获取流元数据，不存在返回ErrNotFound
+	GetStreamMeta(channelId string, channelType uint8, streamNo string) (StreamMeta, error)
+
+	// StreamEnd 结束流
+	StreamEnd(channelId string, channelType uint8, streamNo string) error
+
+	// AppendStreamItem 追加流元素
+	AppendStreamItem(channelId string, channelType uint8, streamNo string, item StreamItem) error
+
+	// GetStreamItems 获取流的所有元素（按streamSeq升序）
+	GetStreamItems(channelId string, channelType uint8, streamNo string) ([]StreamItem, error)
+
+	// GetStreamLastSeq 获取流最后一个元素的序号，没有元素返回0
+	GetStreamLastSeq(channelId string, channelType uint8, streamNo string) (uint32, error)
+}
+
+type ScheduledMessageDB interface {
+	// AddScheduledMessage 添加定时消息
+	AddScheduledMessage(m ScheduledMessage) error
+
+	// DeleteScheduledMessage 删除定时消息，不存在则忽略
+	DeleteScheduledMessage(channelId string, channelType uint8, id uint64) error
+
+	// GetScheduledMessage 获取定时消息，不存在返回ErrNotFound
+	GetScheduledMessage(channelId string, channelType uint8, id uint64) (ScheduledMessage, error)
+
+	// GetScheduledMessages 获取频道的所有定时消息（按id升序）
+	GetScheduledMessages(channelId string, channelType uint8) ([]ScheduledMessage, error)
+
+	// GetDueScheduledMessages 获取发送时间小于等于sendAt的定时消息（按发送时间升序）
+	GetDueScheduledMessages(sendAt int64, limit int) ([]ScheduledMessage, error)
+}
+
+type ReactionDB interface {
+	// AddOrUpdateReaction 添加或更新（IsDeleted为true表示删除）消息回应，回应有变化时频道回应版本号递增
+	AddOrUpdateReaction(reaction Reaction) error
+
+	// GetReactions 获取消息的所有有效回应
+	GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error)
+
+	// GetReactionsAfterVersion 获取频道内版本号大于version的回应变更（按版本号升序，包含已删除的）
+	GetReactionsAfterVersion(channelId string, channelType uint8, version uint64, limit int) ([]Reaction, error)
+}
+
+type MessageReceiptDB interface {
+	// AddMessageReceipt 添加用户的消息已读回执，已回执过的消息忽略
+	// onlyCount为true时（超大群）不保存已读用户只累加已读数量，并通过用户的回执游标去重（小于等于游标的消息忽略）
+	AddMessageReceipt(receipt MessageReceipt, onlyCount bool) error
+
+	// GetMessageReaders 获取消息的已读用户（超大群不保存已读用户）
+	GetMessageReaders(channelId string, channelType uint8, messageSeq uint64) ([]MessageReader, error)
+
+	// GetMessageReadCounts 获取消息的已读数量
+	GetMessageReadCounts(channelId string, channelType uint8, messageSeqs []uint64) ([]MessageReadCount, error)
+}
+
+type MessageDedupDB interface {
+	// GetMessageDedup 获取去重窗口内发送者相同客户端消息编号的已存储消息，不存在或已超出窗口返回ErrNotFound
+	GetMessageDedup(channelId string, channelType uint8, fromUid string, clientMsgNo string) (MessageDedup, error)
+}
+
+type ChannelMessageCmdDB interface {
+	// AppendChannelMessageCmds 保存同一个频道的消息命令（相同序号的命令重复保存时覆盖）
+	AppendChannelMessageCmds(cmds []ChannelMessageCmd) error
+
+	// GetChannelMessageCmds 获取频道内序号大于index的消息命令（按序号升序），limit为0表示不限制
+	GetChannelMessageCmds(channelId string, channelType uint8, index uint64, limit int) ([]ChannelMessageCmd, error)
+
+	// GetChannelMessageCmdLastIndex 获取频道已保存的消息命令的最大序号，没有命令时返回0
+	GetChannelMessageCmdLastIndex(channelId string, channelType uint8) (uint64, error)
+
+	// SetChannelMessageCmdAppliedIndex 设置频道已应用的消息命令的最大序号
+	SetChannelMessageCmdAppliedIndex(channelId string, channelType uint8, index uint64) error
+
+	// GetChannelMessageCmdAppliedIndex 获取频道已应用的消息命令的最大序号
+	GetChannelMessageCmdAppliedIndex(channelId string, channelType uint8) (uint64, error)
+}
+
+type SensitiveWordDB interface {
+	// AddOrUpdateSensitiveWords 添加或更新敏感词规则（按词覆盖）
+	AddOrUpdateSensitiveWords(words []SensitiveWord) error
+
+	// RemoveSensitiveWords 移除敏感词规则，不存在则忽略
+	RemoveSensitiveWords(words []string) error
+
+	// GetSensitiveWords 获取所有敏感词规则
+	GetSensitiveWords() ([]SensitiveWord, error)
+}
+
+type RateLimitDB interface {
+	// AddOrUpdateRateLimits 添加或更新频率限制规则（按规则类型+目标覆盖）
+	AddOrUpdateRateLimits(limits []RateLimit) error
+
+	// RemoveRateLimits 移除频率限制规则（按规则类型+目标），不存在则忽略
+	RemoveRateLimits(limits []RateLimit) error
+
+	// GetRateLimits 获取所有频率限制规则
+	GetRateLimits() ([]RateLimit, error)
+}
+
+type ChannelMuteDB interface {
+	// AddOrUpdateChannelMutes 添加或更新频道禁言（uid为空表示全员禁言），同时清理频道内已到期的禁言
+	AddOrUpdateChannelMutes(channelId string, channelType uint8, mutes []ChannelMute) error
+
+	// RemoveChannelMutes 解除频道禁言（uid为空表示全员禁言），不存在则忽略
+	RemoveChannelMutes(channelId string, channelType uint8, uids []string) error
+
+	// GetChannelMutes 获取频道内未到期的禁言
+	GetChannelMutes(channelId string, channelType uint8) ([]ChannelMute, error)
+
+	// GetChannelMute 获取用户在频道内的禁言（uid为空表示全员禁言），不存在或已到期返回ErrNotFound
+	GetChannelMute(channelId string, channelType uint8, uid string) (ChannelMute, error)
+}
+
+type UserBanDB interface {
+	// AddOrUpdateUserBans 添加或更新用户封禁（按uid覆盖）
+	AddOrUpdateUserBans(bans []UserBan) error
+
+	// RemoveUserBans 移除用户封禁，不存在则忽略
+	RemoveUserBans(uids []string) error
+
+	// GetUserBans 获取所有用户封禁（包含已到期的）
+	GetUserBans() ([]UserBan, error)
+
+	// GetUserBan 获取用户的封禁（包含已到期的），不存在返回ErrNotFound
+	GetUserBan(uid string) (UserBan, error)
+}
+
+type DevicePushDB interface {
+	// AddOrUpdateDevicePush 添加或更新设备推送令牌（按uid+设备标识覆盖）
+	AddOrUpdateDevicePush(push DevicePush) error
+
+	// RemoveDevicePush 移除设备推送令牌，不存在则忽略
+	RemoveDevicePush(uid string, deviceFlag uint64) error
+
+	// GetDevicePushes 获取用户所有设备的推送令牌
+	GetDevicePushes(uid string) ([]DevicePush, error)
+}
+
+type ConversationMuteDB interface {
+	// AddOrUpdateConversationMutes 添加或更新用户的会话免打扰（按频道覆盖）
+	AddOrUpdateConversationMutes(uid string, mutes []ConversationMute) error
+
+	// RemoveConversationMutes 移除用户的会话免打扰，不存在则忽略
+	RemoveConversationMutes(uid string, channels []Channel) error
+
+	// GetConversationMutes 获取用户所有的会话免打扰（包含已到期的）
+	GetConversationMutes(uid string) ([]ConversationMute, error)
+
+	// GetConversationMute 获取用户对频道的免打扰，不存在或已到期返回ErrNotFound
+	GetConversationMute(uid string, channelId string, channelType uint8) (ConversationMute, error)
+}
+
+type WebhookEventDB interface {
+	// AppendWebhookEvents 添加webhook事件到事件所属端点的队列
+	AppendWebhookEvents(queue uint8, events []WebhookEvent) error
+
+	// GetWebhookEvents 按id顺序获取端点队列内id大于startId的webhook事件
+	GetWebhookEvents(queue uint8, endpoint string, startId uint64, limit int) ([]WebhookEvent, error)
+
+	// GetWebhookEvent 获取端点队列内的webhook事件，不存在返回ErrNotFound
+	GetWebhookEvent(queue uint8, endpoint string, id uint64) (WebhookEvent, error)
+
+	// UpdateWebhookEventRetry 更新端点发件箱内webhook事件的重试信息
+	UpdateWebhookEventRetry(endpoint string, id uint64, attempts int, nextRetryAt int64, lastError string) error
+
+	// RemoveWebhookEvents 从端点队列内移除webhook事件，不存在则忽略
+	RemoveWebhookEvents(queue uint8, endpoint string, ids []uint64) error
+
+	// MoveWebhookEvents 将webhook事件从一个队列移到另一个队列，不存在则忽略
+	MoveWebhookEvents(fromQueue, toQueue uint8, events []WebhookEvent) error
+
+	// RemoveWebhookEventsOfEndpoint 移除端点所有队列内的webhook事件
+	RemoveWebhookEventsOfEndpoint(endpoint string) error
+}
+
+type WebhookEndpointDB interface {
+	// AddOrUpdateWebhookEndpoints 添加或更新webhook端点
+	AddOrUpdateWebhookEndpoints(endpoints []WebhookEndpoint) error
+
+	// RemoveWebhookEndpoints 移除webhook端点
+	RemoveWebhookEndpoints(names []string) error
+
+	// GetWebhookEndpoints 获取所有webhook端点
+	GetWebhookEndpoints() ([]WebhookEndpoint, error)
+}
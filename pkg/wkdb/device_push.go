@@ -0,0 +1,97 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateDevicePush(push DevicePush) error {
+	batch := wk.shardDB(push.Uid).NewBatch()
+	defer batch.Close()
+
+	// uid
+	if err := batch.Set(key.NewDevicePushColumnKey(push.Uid, push.DeviceFlag, key.TableDevicePush.Column.Uid), []byte(push.Uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// deviceFlag
+	deviceFlagBytes := make([]byte, 8)
+	wk.endian.PutUint64(deviceFlagBytes, push.DeviceFlag)
+	if err := batch.Set(key.NewDevicePushColumnKey(push.Uid, push.DeviceFlag, key.TableDevicePush.Column.DeviceFlag), deviceFlagBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// pushType
+	if err := batch.Set(key.NewDevicePushColumnKey(push.Uid, push.DeviceFlag, key.TableDevicePush.Column.PushType), []byte{push.PushType}, wk.noSync); err != nil {
+		return err
+	}
+
+	// pushToken
+	if err := batch.Set(key.NewDevicePushColumnKey(push.Uid, push.DeviceFlag, key.TableDevicePush.Column.PushToken), []byte(push.PushToken), wk.noSync); err != nil {
+		return err
+	}
+
+	// updatedAt
+	updatedAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(updatedAtBytes, uint64(push.UpdatedAt))
+	if err := batch.Set(key.NewDevicePushColumnKey(push.Uid, push.DeviceFlag, key.TableDevicePush.Column.UpdatedAt), updatedAtBytes, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveDevicePush(uid string, deviceFlag uint64) error {
+	return wk.shardDB(uid).DeleteRange(key.NewDevicePushColumnKey(uid, deviceFlag, key.MinColumnKey), key.NewDevicePushColumnKey(uid, deviceFlag, key.MaxColumnKey), wk.sync)
+}
+
+func (wk *wukongDB) GetDevicePushes(uid string) ([]DevicePush, error) {
+	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewDevicePushColumnKey(uid, 0, key.MinColumnKey),
+		UpperBound: key.NewDevicePushColumnKey(uid, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		pushes        = make([]DevicePush, 0)
+		preDeviceFlag uint64
+		prePush       DevicePush
+		lastNeedAdd   bool
+	)
+	addPush := func(push DevicePush) {
+		if push.Uid == uid { // uid的hash可能冲突
+			pushes = append(pushes, push)
+		}
+	}
+	for iter.First(); iter.Valid(); iter.Next() {
+		deviceFlag, columnName, err := key.ParseDevicePushColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if deviceFlag != preDeviceFlag || !lastNeedAdd {
+			if lastNeedAdd {
+				addPush(prePush)
+			}
+			preDeviceFlag = deviceFlag
+			prePush = DevicePush{}
+		}
+		switch columnName {
+		case key.TableDevicePush.Column.Uid:
+			prePush.Uid = string(iter.Value())
+		case key.TableDevicePush.Column.DeviceFlag:
+			prePush.DeviceFlag = wk.endian.Uint64(iter.Value())
+		case key.TableDevicePush.Column.PushType:
+			prePush.PushType = iter.Value()[0]
+		case key.TableDevicePush.Column.PushToken:
+			prePush.PushToken = string(iter.Value())
+		case key.TableDevicePush.Column.UpdatedAt:
+			prePush.UpdatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		addPush(prePush)
+	}
+	return pushes, nil
+}
@@ -0,0 +1,44 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevicePushes(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateDevicePush(wkdb.DevicePush{Uid: "u1", DeviceFlag: 0, PushType: wkdb.PushTypeFCM, PushToken: "t1", UpdatedAt: 10})
+	assert.NoError(t, err)
+	err = d.AddOrUpdateDevicePush(wkdb.DevicePush{Uid: "u1", DeviceFlag: 1, PushType: wkdb.PushTypeAPNs, PushToken: "t2", UpdatedAt: 20})
+	assert.NoError(t, err)
+	err = d.AddOrUpdateDevicePush(wkdb.DevicePush{Uid: "u2", DeviceFlag: 0, PushType: wkdb.PushTypeFCM, PushToken: "t3", UpdatedAt: 30})
+	assert.NoError(t, err)
+
+	// 覆盖
+	err = d.AddOrUpdateDevicePush(wkdb.DevicePush{Uid: "u1", DeviceFlag: 0, PushType: wkdb.PushTypeFCM, PushToken: "t4", UpdatedAt: 40})
+	assert.NoError(t, err)
+
+	pushes, err := d.GetDevicePushes("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.DevicePush{
+		{Uid: "u1", DeviceFlag: 0, PushType: wkdb.PushTypeFCM, PushToken: "t4", UpdatedAt: 40},
+		{Uid: "u1", DeviceFlag: 1, PushType: wkdb.PushTypeAPNs, PushToken: "t2", UpdatedAt: 20},
+	}, pushes)
+
+	err = d.RemoveDevicePush("u1", 0)
+	assert.NoError(t, err)
+
+	pushes, err = d.GetDevicePushes("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.DevicePush{{Uid: "u1", DeviceFlag: 1, PushType: wkdb.PushTypeAPNs, PushToken: "t2", UpdatedAt: 20}}, pushes)
+}
@@ -11,4 +11,6 @@ var (
 	ErrNotFound        = errors.New("not found")
 	ErrInvalidUserId   = errors.New("invalid user id")
 	ErrInvalidDeviceId = errors.New("invalid device id")
+	// ErrMessageMismatch 序号对应的消息和请求的消息不一致
+	ErrMessageMismatch = errors.New("message mismatch")
 )
@@ -207,6 +207,45 @@ func NewMessageIndexTimestampKey(timestamp uint64, primaryKey [16]byte) []byte {
 
 }
 
+// NewMessageSecondIndexExpireKey 消息过期索引，expireAt为消息过期的时间点（秒）
+func NewMessageSecondIndexExpireKey(expireAt uint64, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessage.SecondIndexSize)
+	key[0] = TableMessage.Id[0]
+	key[1] = TableMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessage.SecondIndex.Expire[0]
+	key[5] = TableMessage.SecondIndex.Expire[1]
+	binary.BigEndian.PutUint64(key[6:], expireAt)
+	copy(key[14:], primaryKey[:])
+	return key
+}
+
+// NewMessageSecondIndexFromUidTimestampKey 发送者+消息时间索引，用于按发送者和时间范围查询消息
+func NewMessageSecondIndexFromUidTimestampKey(uid string, timestamp uint64, primaryKey [16]byte) []byte {
+	key := make([]byte, TableMessage.SecondIndexTimeSize)
+	key[0] = TableMessage.Id[0]
+	key[1] = TableMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessage.SecondIndex.FromUidTimestamp[0]
+	key[5] = TableMessage.SecondIndex.FromUidTimestamp[1]
+	binary.BigEndian.PutUint64(key[6:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[14:], timestamp)
+	copy(key[22:], primaryKey[:])
+	return key
+}
+
+func ParseMessageSecondIndexTimeKey(key []byte) (timestamp uint64, primaryKey [16]byte, err error) {
+	if len(key) != TableMessage.SecondIndexTimeSize {
+		err = fmt.Errorf("message: invalid time index key length, keyLen: %d", len(key))
+		return
+	}
+	timestamp = binary.BigEndian.Uint64(key[14:])
+	copy(primaryKey[:], key[22:])
+	return
+}
+
 func ParseMessageSecondIndexKey(key []byte) (primaryKey [16]byte, err error) {
 	if len(key) != TableMessage.SecondIndexSize {
 		return [16]byte{}, fmt.Errorf("message: invalid index key length, keyLen: %d", len(key))
@@ -957,3 +996,621 @@ func NewTotalColumnKey(columnName [2]byte) []byte {
 	key[13] = columnName[1]
 	return key
 }
+
+// ---------------------- DBMeta ----------------------
+
+func NewDBMetaColumnKey(columnName [2]byte) []byte {
+	key := make([]byte, TableDBMeta.Size)
+	key[0] = TableDBMeta.Id[0]
+	key[1] = TableDBMeta.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	key[4] = columnName[0]
+	key[5] = columnName[1]
+	return key
+}
+
+// ---------------------- MessageEdit ----------------------
+
+func NewMessageEditColumnKey(messageId uint64, version uint32, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageEdit.Size)
+	key[0] = TableMessageEdit.Id[0]
+	key[1] = TableMessageEdit.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], messageId)
+	binary.BigEndian.PutUint32(key[12:], version)
+	key[16] = columnName[0]
+	key[17] = columnName[1]
+	return key
+}
+
+func NewMessageEditPrimaryKey(messageId uint64, version uint32) []byte {
+	key := make([]byte, 16)
+	key[0] = TableMessageEdit.Id[0]
+	key[1] = TableMessageEdit.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], messageId)
+	binary.BigEndian.PutUint32(key[12:], version)
+	return key
+}
+
+func ParseMessageEditColumnKey(key []byte) (messageId uint64, version uint32, columnName [2]byte, err error) {
+	if len(key) != TableMessageEdit.Size {
+		err = fmt.Errorf("messageEdit: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageId = binary.BigEndian.Uint64(key[4:])
+	version = binary.BigEndian.Uint32(key[12:])
+	columnName[0] = key[16]
+	columnName[1] = key[17]
+	return
+}
+
+// ---------------------- StreamMeta ----------------------
+
+func NewStreamMetaColumnKey(channelId string, channelType uint8, streamNo string, columnName [2]byte) []byte {
+	key := make([]byte, TableStreamMeta.Size)
+	key[0] = TableStreamMeta.Id[0]
+	key[1] = TableStreamMeta.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(streamNo))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseStreamMetaColumnKey(key []byte) (columnName [2]byte, err error) {
+	if len(key) != TableStreamMeta.Size {
+		err = fmt.Errorf("streamMeta: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- StreamItem ----------------------
+
+func NewStreamItemColumnKey(channelId string, channelType uint8, streamNo string, streamSeq uint32, columnName [2]byte) []byte {
+	key := make([]byte, TableStreamItem.Size)
+	key[0] = TableStreamItem.Id[0]
+	key[1] = TableStreamItem.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(streamNo))
+	binary.BigEndian.PutUint32(key[20:], streamSeq)
+	key[24] = columnName[0]
+	key[25] = columnName[1]
+	return key
+}
+
+func ParseStreamItemColumnKey(key []byte) (streamSeq uint32, columnName [2]byte, err error) {
+	if len(key) != TableStreamItem.Size {
+		err = fmt.Errorf("streamItem: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	streamSeq = binary.BigEndian.Uint32(key[20:])
+	columnName[0] = key[24]
+	columnName[1] = key[25]
+	return
+}
+
+// ---------------------- ScheduledMessage ----------------------
+
+func NewScheduledMessageColumnKey(channelId string, channelType uint8, id uint64, columnName [2]byte) []byte {
+	return NewScheduledMessageColumnKeyWithHash(channelIdToNum(channelId, channelType), id, columnName)
+}
+
+func NewScheduledMessageColumnKeyWithHash(channelHash uint64, id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableScheduledMessage.Size)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelHash)
+	binary.BigEndian.PutUint64(key[12:], id)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseScheduledMessageColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableScheduledMessage.Size {
+		err = fmt.Errorf("scheduledMessage: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+func NewScheduledMessageSecondIndexSendAtKey(sendAt uint64, channelHash uint64, id uint64) []byte {
+	key := make([]byte, TableScheduledMessage.SecondIndexSize)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableScheduledMessage.SecondIndex.SendAt[0]
+	key[5] = TableScheduledMessage.SecondIndex.SendAt[1]
+	binary.BigEndian.PutUint64(key[6:], sendAt)
+	binary.BigEndian.PutUint64(key[14:], channelHash)
+	binary.BigEndian.PutUint64(key[22:], id)
+	return key
+}
+
+func ParseScheduledMessageSecondIndexSendAtKey(key []byte) (sendAt uint64, channelHash uint64, id uint64, err error) {
+	if len(key) != TableScheduledMessage.SecondIndexSize {
+		err = fmt.Errorf("scheduledMessage: invalid index key length, keyLen: %d", len(key))
+		return
+	}
+	sendAt = binary.BigEndian.Uint64(key[6:])
+	channelHash = binary.BigEndian.Uint64(key[14:])
+	id = binary.BigEndian.Uint64(key[22:])
+	return
+}
+
+// ---------------------- Reaction ----------------------
+
+// NewReactionPrimaryKey 回应的主键 messageSeq + uid hash + emoji hash
+func NewReactionPrimaryKey(messageSeq uint64, uid string, emoji string) [24]byte {
+	var primaryKey [24]byte
+	binary.BigEndian.PutUint64(primaryKey[0:], messageSeq)
+	binary.BigEndian.PutUint64(primaryKey[8:], HashWithString(uid))
+	binary.BigEndian.PutUint64(primaryKey[16:], HashWithString(emoji))
+	return primaryKey
+}
+
+// NewReactionMessagePrimaryKeyRange 某条消息所有回应的主键范围
+func NewReactionMessagePrimaryKeyRange(messageSeq uint64) (min [24]byte, max [24]byte) {
+	binary.BigEndian.PutUint64(min[0:], messageSeq)
+	binary.BigEndian.PutUint64(max[0:], messageSeq)
+	binary.BigEndian.PutUint64(max[8:], math.MaxUint64)
+	binary.BigEndian.PutUint64(max[16:], math.MaxUint64)
+	return
+}
+
+func NewReactionColumnKey(channelId string, channelType uint8, primaryKey [24]byte, columnName [2]byte) []byte {
+	key := make([]byte, TableReaction.Size)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	copy(key[12:], primaryKey[:])
+	key[36] = columnName[0]
+	key[37] = columnName[1]
+	return key
+}
+
+func ParseReactionColumnKey(key []byte) (primaryKey [24]byte, columnName [2]byte, err error) {
+	if len(key) != TableReaction.Size {
+		err = fmt.Errorf("reaction: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	copy(primaryKey[:], key[12:36])
+	columnName[0] = key[36]
+	columnName[1] = key[37]
+	return
+}
+
+func NewReactionSecondIndexVersionKey(channelId string, channelType uint8, version uint64, primaryKey [24]byte) []byte {
+	key := make([]byte, TableReaction.SecondIndexSize)
+	key[0] = TableReaction.Id[0]
+	key[1] = TableReaction.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableReaction.SecondIndex.Version[0]
+	key[5] = TableReaction.SecondIndex.Version[1]
+	binary.BigEndian.PutUint64(key[6:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[14:], version)
+	copy(key[22:], primaryKey[:])
+	return key
+}
+
+func ParseReactionSecondIndexVersionKey(key []byte) (version uint64, primaryKey [24]byte, err error) {
+	if len(key) != TableReaction.SecondIndexSize {
+		err = fmt.Errorf("reaction: invalid index key length, keyLen: %d", len(key))
+		return
+	}
+	version = binary.BigEndian.Uint64(key[14:])
+	copy(primaryKey[:], key[22:])
+	return
+}
+
+// ======================== MessageReceipt ========================
+
+func NewMessageReceiptColumnKey(channelId string, channelType uint8, messageSeq uint64, uid string, columnName [2]byte) []byte {
+	return newMessageReceiptColumnKey(channelId, channelType, messageSeq, HashWithString(uid), columnName)
+}
+
+// NewMessageReceiptMessageKeyRange 某条消息所有已读用户的key范围
+func NewMessageReceiptMessageKeyRange(channelId string, channelType uint8, messageSeq uint64) (min []byte, max []byte) {
+	min = newMessageReceiptColumnKey(channelId, channelType, messageSeq, 0, MinColumnKey)
+	max = newMessageReceiptColumnKey(channelId, channelType, messageSeq, math.MaxUint64, MaxColumnKey)
+	return
+}
+
+func newMessageReceiptColumnKey(channelId string, channelType uint8, messageSeq uint64, uidHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageReceipt.Size)
+	key[0] = TableMessageReceipt.Id[0]
+	key[1] = TableMessageReceipt.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	binary.BigEndian.PutUint64(key[20:], uidHash)
+	key[28] = columnName[0]
+	key[29] = columnName[1]
+	return key
+}
+
+func ParseMessageReceiptColumnKey(key []byte) (uidHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableMessageReceipt.Size {
+		err = fmt.Errorf("messageReceipt: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[20:])
+	columnName[0] = key[28]
+	columnName[1] = key[29]
+	return
+}
+
+// ======================== MessageReadCount ========================
+
+func NewMessageReadCountColumnKey(channelId string, channelType uint8, messageSeq uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageReadCount.Size)
+	key[0] = TableMessageReadCount.Id[0]
+	key[1] = TableMessageReadCount.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+// ======================== MessageReceiptCursor ========================
+
+func NewMessageReceiptCursorColumnKey(channelId string, channelType uint8, uid string, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageReceiptCursor.Size)
+	key[0] = TableMessageReceiptCursor.Id[0]
+	key[1] = TableMessageReceiptCursor.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(uid))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+// ======================== MessageTerm ========================
+
+func NewMessageTermKey(term string, messageId uint64) []byte {
+	return NewMessageTermKeyWithHash(HashWithString(term), messageId)
+}
+
+func NewMessageTermKeyWithHash(termHash uint64, messageId uint64) []byte {
+	key := make([]byte, TableMessageTerm.Size)
+	key[0] = TableMessageTerm.Id[0]
+	key[1] = TableMessageTerm.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], termHash)
+	binary.BigEndian.PutUint64(key[12:], messageId)
+	return key
+}
+
+func ParseMessageTermKey(key []byte) (messageId uint64, err error) {
+	if len(key) != TableMessageTerm.Size {
+		err = fmt.Errorf("messageTerm: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageId = binary.BigEndian.Uint64(key[12:])
+	return
+}
+
+// NewMessageTermLowKey 全文索引的最小key
+func NewMessageTermLowKey() []byte {
+	return NewMessageTermKeyWithHash(0, 0)
+}
+
+// NewMessageTermHighKey 全文索引的最大key
+func NewMessageTermHighKey() []byte {
+	return NewMessageTermKeyWithHash(math.MaxUint64, math.MaxUint64)
+}
+
+// ======================== MessageDedup ========================
+
+// MessageDedupHash 消息去重的hash（发送者+客户端消息编号）
+func MessageDedupHash(fromUid string, clientMsgNo string) uint64 {
+	return HashWithString(fromUid + "@" + clientMsgNo)
+}
+
+func NewMessageDedupKey(channelId string, channelType uint8, dedupHash uint64) []byte {
+	key := make([]byte, TableMessageDedup.Size)
+	key[0] = TableMessageDedup.Id[0]
+	key[1] = TableMessageDedup.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], dedupHash)
+	return key
+}
+
+func NewMessageDedupTimeIndexKey(channelId string, channelType uint8, timestamp uint64, dedupHash uint64) []byte {
+	key := make([]byte, TableMessageDedup.TimeIndexSize)
+	key[0] = TableMessageDedup.Id[0]
+	key[1] = TableMessageDedup.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], timestamp)
+	binary.BigEndian.PutUint64(key[20:], dedupHash)
+	return key
+}
+
+func ParseMessageDedupTimeIndexKey(key []byte) (timestamp uint64, dedupHash uint64, err error) {
+	if len(key) != TableMessageDedup.TimeIndexSize {
+		err = fmt.Errorf("messageDedup: invalid time index key length, keyLen: %d", len(key))
+		return
+	}
+	timestamp = binary.BigEndian.Uint64(key[12:])
+	dedupHash = binary.BigEndian.Uint64(key[20:])
+	return
+}
+
+// ======================== SensitiveWord ========================
+
+func NewSensitiveWordColumnKey(word string, columnName [2]byte) []byte {
+	return NewSensitiveWordColumnKeyWithHash(HashWithString(word), columnName)
+}
+
+func NewSensitiveWordColumnKeyWithHash(wordHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableSensitiveWord.Size)
+	key[0] = TableSensitiveWord.Id[0]
+	key[1] = TableSensitiveWord.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], wordHash)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseSensitiveWordColumnKey(key []byte) (wordHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableSensitiveWord.Size {
+		err = fmt.Errorf("sensitiveWord: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	wordHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ======================== RateLimit ========================
+
+// RateLimitHash 频率限制规则的hash（规则类型+用户uid或频道）
+func RateLimitHash(targetType uint8, target string, channelType uint8) uint64 {
+	return HashWithString(fmt.Sprintf("%d@%s@%d", targetType, target, channelType))
+}
+
+func NewRateLimitColumnKeyWithHash(targetHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableRateLimit.Size)
+	key[0] = TableRateLimit.Id[0]
+	key[1] = TableRateLimit.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], targetHash)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseRateLimitColumnKey(key []byte) (targetHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableRateLimit.Size {
+		err = fmt.Errorf("rateLimit: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	targetHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ======================== ChannelMute ========================
+
+func NewChannelMuteColumnKey(channelId string, channelType uint8, uidHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableChannelMute.Size)
+	key[0] = TableChannelMute.Id[0]
+	key[1] = TableChannelMute.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], uidHash)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseChannelMuteColumnKey(key []byte) (uidHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableChannelMute.Size {
+		err = fmt.Errorf("channelMute: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ======================== UserBan ========================
+
+func NewUserBanColumnKey(uid string, columnName [2]byte) []byte {
+	return NewUserBanColumnKeyWithHash(HashWithString(uid), columnName)
+}
+
+func NewUserBanColumnKeyWithHash(uidHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableUserBan.Size)
+	key[0] = TableUserBan.Id[0]
+	key[1] = TableUserBan.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], uidHash)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseUserBanColumnKey(key []byte) (uidHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableUserBan.Size {
+		err = fmt.Errorf("userBan: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ======================== DevicePush ========================
+
+func NewDevicePushColumnKey(uid string, deviceFlag uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableDevicePush.Size)
+	key[0] = TableDevicePush.Id[0]
+	key[1] = TableDevicePush.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], deviceFlag)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseDevicePushColumnKey(key []byte) (deviceFlag uint64, columnName [2]byte, err error) {
+	if len(key) != TableDevicePush.Size {
+		err = fmt.Errorf("devicePush: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	deviceFlag = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ======================== ConversationMute ========================
+
+func NewConversationMuteColumnKey(uid string, channelId string, channelType uint8, columnName [2]byte) []byte {
+	return NewConversationMuteColumnKeyWithHash(uid, channelIdToNum(channelId, channelType), columnName)
+}
+
+func NewConversationMuteColumnKeyWithHash(uid string, channelHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableConversationMute.Size)
+	key[0] = TableConversationMute.Id[0]
+	key[1] = TableConversationMute.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], channelHash)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseConversationMuteColumnKey(key []byte) (channelHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableConversationMute.Size {
+		err = fmt.Errorf("conversationMute: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	channelHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ======================== WebhookEvent ========================
+
+func NewWebhookEventColumnKey(queue uint8, endpointHash uint64, id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableWebhookEvent.Size)
+	key[0] = TableWebhookEvent.Id[0]
+	key[1] = TableWebhookEvent.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	key[4] = queue
+	binary.BigEndian.PutUint64(key[5:], endpointHash)
+	binary.BigEndian.PutUint64(key[13:], id)
+	key[21] = columnName[0]
+	key[22] = columnName[1]
+	return key
+}
+
+func ParseWebhookEventColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableWebhookEvent.Size {
+		err = fmt.Errorf("webhookEvent: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[13:])
+	columnName[0] = key[21]
+	columnName[1] = key[22]
+	return
+}
+
+// ======================== WebhookEndpoint ========================
+
+func NewWebhookEndpointColumnKey(nameHash uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableWebhookEndpoint.Size)
+	key[0] = TableWebhookEndpoint.Id[0]
+	key[1] = TableWebhookEndpoint.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], nameHash)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseWebhookEndpointColumnKey(key []byte) (nameHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableWebhookEndpoint.Size {
+		err = fmt.Errorf("webhookEndpoint: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	nameHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ======================== ChannelMessageCmd ========================
+
+func NewChannelMessageCmdColumnKey(channelId string, channelType uint8, index uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableChannelMessageCmd.Size)
+	key[0] = TableChannelMessageCmd.Id[0]
+	key[1] = TableChannelMessageCmd.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], index)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseChannelMessageCmdColumnKey(key []byte) (index uint64, columnName [2]byte, err error) {
+	if len(key) != TableChannelMessageCmd.Size {
+		err = fmt.Errorf("channel message cmd: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	index = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
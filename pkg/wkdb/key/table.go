@@ -21,24 +21,30 @@ var (
 // ---------------------
 
 var TableMessage = struct {
-	Id              [2]byte
-	Size            int
-	IndexSize       int
-	SecondIndexSize int
-	Column          struct {
-		Header      [2]byte
-		Setting     [2]byte
-		Expire      [2]byte
-		MessageId   [2]byte
-		MessageSeq  [2]byte
-		ClientMsgNo [2]byte
-		Timestamp   [2]byte
-		ChannelId   [2]byte
-		ChannelType [2]byte
-		Topic       [2]byte
-		FromUid     [2]byte
-		Payload     [2]byte
-		Term        [2]byte
+	Id                  [2]byte
+	Size                int
+	IndexSize           int
+	SecondIndexSize     int
+	SecondIndexTimeSize int
+	Column              struct {
+		Header        [2]byte
+		Setting       [2]byte
+		Expire        [2]byte
+		MessageId     [2]byte
+		MessageSeq    [2]byte
+		ClientMsgNo   [2]byte
+		Timestamp     [2]byte
+		ChannelId     [2]byte
+		ChannelType   [2]byte
+		Topic         [2]byte
+		FromUid       [2]byte
+		Payload       [2]byte
+		Term          [2]byte
+		Revoke        [2]byte
+		Version       [2]byte
+		EditedAt      [2]byte
+		EditedPayload [2]byte
+		StreamNo      [2]byte
 	}
 	Index struct {
 		MessageId [2]byte
@@ -48,40 +54,54 @@ var TableMessage = struct {
 		ClientMsgNo [2]byte
 		Timestamp   [2]byte
 		Channel     [2]byte
+		Expire      [2]byte
+		// 发送者+消息时间
+		FromUidTimestamp [2]byte
 	}
 }{
-	Id:              [2]byte{0x01, 0x01},
-	Size:            2 + 2 + 8 + 8 + 2,  // tableId + dataType + channel hash + messageSeq + columnKey
-	IndexSize:       2 + 2 + 2 + 8,      // tableId + dataType + indexName + columnHash
-	SecondIndexSize: 2 + 2 + 2 + 8 + 16, // tableId + dataType + secondIndexName + columnValue + primaryKey
+	Id:                  [2]byte{0x01, 0x01},
+	Size:                2 + 2 + 8 + 8 + 2,      // tableId + dataType + channel hash + messageSeq + columnKey
+	IndexSize:           2 + 2 + 2 + 8,          // tableId + dataType + indexName + columnHash
+	SecondIndexSize:     2 + 2 + 2 + 8 + 16,     // tableId + dataType + secondIndexName + columnValue + primaryKey
+	SecondIndexTimeSize: 2 + 2 + 2 + 8 + 8 + 16, // tableId + dataType + secondIndexName + columnValue + timestamp + primaryKey
 	Column: struct {
-		Header      [2]byte
-		Setting     [2]byte
-		Expire      [2]byte
-		MessageId   [2]byte
-		MessageSeq  [2]byte
-		ClientMsgNo [2]byte
-		Timestamp   [2]byte
-		ChannelId   [2]byte
-		ChannelType [2]byte
-		Topic       [2]byte
-		FromUid     [2]byte
-		Payload     [2]byte
-		Term        [2]byte
-	}{
-		Header:      [2]byte{0x01, 0x01},
-		Setting:     [2]byte{0x01, 0x02},
-		Expire:      [2]byte{0x01, 0x03},
-		MessageId:   [2]byte{0x01, 0x04},
-		MessageSeq:  [2]byte{0x01, 0x05},
-		ClientMsgNo: [2]byte{0x01, 0x06},
-		Timestamp:   [2]byte{0x01, 0x07},
-		ChannelId:   [2]byte{0x01, 0x08},
-		ChannelType: [2]byte{0x01, 0x09},
-		Topic:       [2]byte{0x01, 0x0A},
-		FromUid:     [2]byte{0x01, 0x0B},
-		Payload:     [2]byte{0x01, 0x0C},
-		Term:        [2]byte{0x01, 0x0D},
+		Header        [2]byte
+		Setting       [2]byte
+		Expire        [2]byte
+		MessageId     [2]byte
+		MessageSeq    [2]byte
+		ClientMsgNo   [2]byte
+		Timestamp     [2]byte
+		ChannelId     [2]byte
+		ChannelType   [2]byte
+		Topic         [2]byte
+		FromUid       [2]byte
+		Payload       [2]byte
+		Term          [2]byte
+		Revoke        [2]byte
+		Version       [2]byte
+		EditedAt      [2]byte
+		EditedPayload [2]byte
+		StreamNo      [2]byte
+	}{
+		Header:        [2]byte{0x01, 0x01},
+		Setting:       [2]byte{0x01, 0x02},
+		Expire:        [2]byte{0x01, 0x03},
+		MessageId:     [2]byte{0x01, 0x04},
+		MessageSeq:    [2]byte{0x01, 0x05},
+		ClientMsgNo:   [2]byte{0x01, 0x06},
+		Timestamp:     [2]byte{0x01, 0x07},
+		ChannelId:     [2]byte{0x01, 0x08},
+		ChannelType:   [2]byte{0x01, 0x09},
+		Topic:         [2]byte{0x01, 0x0A},
+		FromUid:       [2]byte{0x01, 0x0B},
+		Payload:       [2]byte{0x01, 0x0C},
+		Term:          [2]byte{0x01, 0x0D},
+		Revoke:        [2]byte{0x01, 0x0E},
+		Version:       [2]byte{0x01, 0x0F},
+		EditedAt:      [2]byte{0x01, 0x10},
+		EditedPayload: [2]byte{0x01, 0x11},
+		StreamNo:      [2]byte{0x01, 0x12},
 	},
 	Index: struct {
 		MessageId [2]byte
@@ -89,15 +109,19 @@ var TableMessage = struct {
 		MessageId: [2]byte{0x01, 0x01},
 	},
 	SecondIndex: struct {
-		FromUid     [2]byte
-		ClientMsgNo [2]byte
-		Timestamp   [2]byte
-		Channel     [2]byte
+		FromUid          [2]byte
+		ClientMsgNo      [2]byte
+		Timestamp        [2]byte
+		Channel          [2]byte
+		Expire           [2]byte
+		FromUidTimestamp [2]byte
 	}{
-		FromUid:     [2]byte{0x01, 0x01},
-		ClientMsgNo: [2]byte{0x01, 0x02},
-		Timestamp:   [2]byte{0x01, 0x03},
-		Channel:     [2]byte{0x01, 0x04},
+		FromUid:          [2]byte{0x01, 0x01},
+		ClientMsgNo:      [2]byte{0x01, 0x02},
+		Timestamp:        [2]byte{0x01, 0x03},
+		Channel:          [2]byte{0x01, 0x04},
+		Expire:           [2]byte{0x01, 0x05},
+		FromUidTimestamp: [2]byte{0x01, 0x06},
 	},
 }
 
@@ -225,7 +249,13 @@ var TableSubscriber = struct {
 	Size      int
 	IndexSize int
 	Column    struct {
-		Uid [2]byte
+		Uid        [2]byte
+		Role       [2]byte // 角色
+		JoinedAt   [2]byte // 加入时间
+		JoinSeq    [2]byte // 加入时频道的最新消息序号
+		InviterUid [2]byte // 邀请人
+		Nickname   [2]byte // 频道内昵称
+		Extra      [2]byte // 扩展数据
 	}
 	Index struct {
 		Uid [2]byte
@@ -235,9 +265,21 @@ var TableSubscriber = struct {
 	Size:      2 + 2 + 8 + 8 + 2, // tableId + dataType  + channel hash + primaryKey + columnKey
 	IndexSize: 2 + 2 + 2 + 8 + 8, // tableId + dataType + indexName + channel hash + columnHash
 	Column: struct {
-		Uid [2]byte
+		Uid        [2]byte
+		Role       [2]byte
+		JoinedAt   [2]byte
+		JoinSeq    [2]byte
+		InviterUid [2]byte
+		Nickname   [2]byte
+		Extra      [2]byte
 	}{
-		Uid: [2]byte{0x04, 0x01},
+		Uid:        [2]byte{0x04, 0x01},
+		Role:       [2]byte{0x04, 0x02},
+		JoinedAt:   [2]byte{0x04, 0x03},
+		JoinSeq:    [2]byte{0x04, 0x04},
+		InviterUid: [2]byte{0x04, 0x05},
+		Nickname:   [2]byte{0x04, 0x06},
+		Extra:      [2]byte{0x04, 0x07},
 	},
 	Index: struct {
 		Uid [2]byte
@@ -275,15 +317,21 @@ var TableChannelInfo = struct {
 	IndexSize       int
 	SecondIndexSize int
 	Column          struct {
-		Id              [2]byte
-		ChannelId       [2]byte
-		ChannelType     [2]byte
-		Ban             [2]byte
-		Large           [2]byte
-		Disband         [2]byte
-		SubscriberCount [2]byte // 订阅者数量
-		AllowlistCount  [2]byte // 白名单数量
-		DenylistCount   [2]byte // 黑名单数量
+		Id                [2]byte
+		ChannelId         [2]byte
+		ChannelType       [2]byte
+		Ban               [2]byte
+		Large             [2]byte
+		Disband           [2]byte
+		SubscriberCount   [2]byte // 订阅者数量
+		AllowlistCount    [2]byte // 白名单数量
+		DenylistCount     [2]byte // 黑名单数量
+		RetentionMaxAge   [2]byte // 消息保留最长时间
+		RetentionMaxCount [2]byte // 消息保留最大数量
+		OnlyAdminSend     [2]byte // 仅管理员可发言
+		// 历史消息可见策略
+		HistoryVisibility   [2]byte
+		HistoryVisibleCount [2]byte
 	}
 	Index struct {
 		Channel [2]byte
@@ -301,25 +349,37 @@ var TableChannelInfo = struct {
 	IndexSize:       2 + 2 + 2 + 8,     // tableId + dataType + indexName + columnValue
 	SecondIndexSize: 2 + 2 + 2 + 8 + 8, // tableId + dataType + secondIndexName + columnValue + primaryKey
 	Column: struct {
-		Id              [2]byte
-		ChannelId       [2]byte
-		ChannelType     [2]byte
-		Ban             [2]byte
-		Large           [2]byte
-		Disband         [2]byte
-		SubscriberCount [2]byte
-		AllowlistCount  [2]byte
-		DenylistCount   [2]byte
+		Id                [2]byte
+		ChannelId         [2]byte
+		ChannelType       [2]byte
+		Ban               [2]byte
+		Large             [2]byte
+		Disband           [2]byte
+		SubscriberCount   [2]byte
+		AllowlistCount    [2]byte
+		DenylistCount     [2]byte
+		RetentionMaxAge   [2]byte
+		RetentionMaxCount [2]byte
+		OnlyAdminSend     [2]byte
+		// 历史消息可见策略
+		HistoryVisibility   [2]byte
+		HistoryVisibleCount [2]byte
 	}{
-		Id:              [2]byte{0x06, 0x01},
-		ChannelId:       [2]byte{0x06, 0x02},
-		ChannelType:     [2]byte{0x06, 0x03},
-		Ban:             [2]byte{0x06, 0x04},
-		Large:           [2]byte{0x06, 0x05},
-		Disband:         [2]byte{0x06, 0x06},
-		SubscriberCount: [2]byte{0x06, 0x07},
-		AllowlistCount:  [2]byte{0x06, 0x08},
-		DenylistCount:   [2]byte{0x06, 0x09},
+		Id:                [2]byte{0x06, 0x01},
+		ChannelId:         [2]byte{0x06, 0x02},
+		ChannelType:       [2]byte{0x06, 0x03},
+		Ban:               [2]byte{0x06, 0x04},
+		Large:             [2]byte{0x06, 0x05},
+		Disband:           [2]byte{0x06, 0x06},
+		SubscriberCount:   [2]byte{0x06, 0x07},
+		AllowlistCount:    [2]byte{0x06, 0x08},
+		DenylistCount:     [2]byte{0x06, 0x09},
+		RetentionMaxAge:   [2]byte{0x06, 0x0A},
+		RetentionMaxCount: [2]byte{0x06, 0x0B},
+		OnlyAdminSend:     [2]byte{0x06, 0x0C},
+		// 历史消息可见策略
+		HistoryVisibility:   [2]byte{0x06, 0x0D},
+		HistoryVisibleCount: [2]byte{0x06, 0x0E},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -568,15 +628,24 @@ var TableChannelCommon = struct {
 	Id     [2]byte
 	Size   int
 	Column struct {
-		AppliedIndex [2]byte
+		AppliedIndex           [2]byte
+		FirstMessageSeq        [2]byte // 第一条可用的消息序号（之前的消息已被清理）
+		ReactionVersion        [2]byte // 消息回应的最新版本号
+		MessageCmdAppliedIndex [2]byte // 已应用的消息命令的最大序号
 	}
 }{
 	Id:   [2]byte{0x0D, 0x01},
 	Size: 2 + 2 + 8 + 2, // tableId + dataType  + channel hash + columnKey
 	Column: struct {
-		AppliedIndex [2]byte
+		AppliedIndex           [2]byte
+		FirstMessageSeq        [2]byte
+		ReactionVersion        [2]byte
+		MessageCmdAppliedIndex [2]byte
 	}{
-		AppliedIndex: [2]byte{0x0D, 0x01},
+		AppliedIndex:           [2]byte{0x0D, 0x01},
+		FirstMessageSeq:        [2]byte{0x0D, 0x02},
+		ReactionVersion:        [2]byte{0x0D, 0x03},
+		MessageCmdAppliedIndex: [2]byte{0x0D, 0x04},
 	},
 }
 
@@ -674,3 +743,576 @@ var TableTotal = struct {
 		ChannelClusterConfig: [2]byte{0x0F, 0x07},
 	},
 }
+
+// ======================== MessageEdit ========================
+// ---------------------
+// | tableID  | dataType	| messageId | version   | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	 |  4 字节	 | 2 字节		|
+// ---------------------
+
+var TableMessageEdit = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Payload  [2]byte
+		EditedAt [2]byte
+		LogSeq   [2]byte
+	}
+}{
+	Id:   [2]byte{0x10, 0x01},
+	Size: 2 + 2 + 8 + 4 + 2, // tableId + dataType + messageId + version + columnKey
+	Column: struct {
+		Payload  [2]byte
+		EditedAt [2]byte
+		LogSeq   [2]byte
+	}{
+		Payload:  [2]byte{0x10, 0x01},
+		EditedAt: [2]byte{0x10, 0x02},
+		LogSeq:   [2]byte{0x10, 0x03},
+	},
+}
+
+// ======================== StreamMeta ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | streamNo hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	    |  8 字节	     | 2 字节		 |
+// ---------------------
+
+var TableStreamMeta = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		StreamNo    [2]byte
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		MessageId   [2]byte
+		FromUid     [2]byte
+		StreamFlag  [2]byte
+		CreatedAt   [2]byte
+	}
+}{
+	Id:   [2]byte{0x11, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + streamNo hash + columnKey
+	Column: struct {
+		StreamNo    [2]byte
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		MessageId   [2]byte
+		FromUid     [2]byte
+		StreamFlag  [2]byte
+		CreatedAt   [2]byte
+	}{
+		StreamNo:    [2]byte{0x11, 0x01},
+		ChannelId:   [2]byte{0x11, 0x02},
+		ChannelType: [2]byte{0x11, 0x03},
+		MessageId:   [2]byte{0x11, 0x04},
+		FromUid:     [2]byte{0x11, 0x05},
+		StreamFlag:  [2]byte{0x11, 0x06},
+		CreatedAt:   [2]byte{0x11, 0x07},
+	},
+}
+
+// ======================== StreamItem ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | streamNo hash | streamSeq | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	    |  8 字节	     | 4 字节     | 2 字节		 |
+// ---------------------
+
+var TableStreamItem = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ClientMsgNo [2]byte
+		Payload     [2]byte
+	}
+}{
+	Id:   [2]byte{0x12, 0x01},
+	Size: 2 + 2 + 8 + 8 + 4 + 2, // tableId + dataType + channel hash + streamNo hash + streamSeq + columnKey
+	Column: struct {
+		ClientMsgNo [2]byte
+		Payload     [2]byte
+	}{
+		ClientMsgNo: [2]byte{0x12, 0x01},
+		Payload:     [2]byte{0x12, 0x02},
+	},
+}
+
+// ======================== ScheduledMessage ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | id     | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	    |  8 字节 | 2 字节		 |
+// ---------------------
+
+var TableScheduledMessage = struct {
+	Id              [2]byte
+	Size            int
+	SecondIndexSize int
+	Column          struct {
+		SendAt      [2]byte
+		FromUid     [2]byte
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		ClientMsgNo [2]byte
+		Header      [2]byte
+		Expire      [2]byte
+		Payload     [2]byte
+		CreatedAt   [2]byte
+	}
+	SecondIndex struct {
+		SendAt [2]byte
+	}
+}{
+	Id:              [2]byte{0x13, 0x01},
+	Size:            2 + 2 + 8 + 8 + 2,     // tableId + dataType + channel hash + id + columnKey
+	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + secondIndexName + sendAt + channel hash + id
+	Column: struct {
+		SendAt      [2]byte
+		FromUid     [2]byte
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		ClientMsgNo [2]byte
+		Header      [2]byte
+		Expire      [2]byte
+		Payload     [2]byte
+		CreatedAt   [2]byte
+	}{
+		SendAt:      [2]byte{0x13, 0x01},
+		FromUid:     [2]byte{0x13, 0x02},
+		ChannelId:   [2]byte{0x13, 0x03},
+		ChannelType: [2]byte{0x13, 0x04},
+		ClientMsgNo: [2]byte{0x13, 0x05},
+		Header:      [2]byte{0x13, 0x06},
+		Expire:      [2]byte{0x13, 0x07},
+		Payload:     [2]byte{0x13, 0x08},
+		CreatedAt:   [2]byte{0x13, 0x09},
+	},
+	SecondIndex: struct {
+		SendAt [2]byte
+	}{
+		SendAt: [2]byte{0x13, 0x01},
+	},
+}
+
+// ======================== Reaction ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | messageSeq | uid hash | emoji hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	    |  8 字节	  | 8 字节    | 8 字节     | 2 字节		 |
+// ---------------------
+
+var TableReaction = struct {
+	Id              [2]byte
+	Size            int
+	SecondIndexSize int
+	Column          struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		MessageId   [2]byte
+		MessageSeq  [2]byte
+		Uid         [2]byte
+		Emoji       [2]byte
+		IsDeleted   [2]byte
+		Version     [2]byte
+		CreatedAt   [2]byte
+	}
+	SecondIndex struct {
+		Version [2]byte
+	}
+}{
+	Id:              [2]byte{0x14, 0x01},
+	Size:            2 + 2 + 8 + 24 + 2,     // tableId + dataType + channel hash + primaryKey + columnKey
+	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 24, // tableId + dataType + secondIndexName + channel hash + version + primaryKey
+	Column: struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		MessageId   [2]byte
+		MessageSeq  [2]byte
+		Uid         [2]byte
+		Emoji       [2]byte
+		IsDeleted   [2]byte
+		Version     [2]byte
+		CreatedAt   [2]byte
+	}{
+		ChannelId:   [2]byte{0x14, 0x01},
+		ChannelType: [2]byte{0x14, 0x02},
+		MessageId:   [2]byte{0x14, 0x03},
+		MessageSeq:  [2]byte{0x14, 0x04},
+		Uid:         [2]byte{0x14, 0x05},
+		Emoji:       [2]byte{0x14, 0x06},
+		IsDeleted:   [2]byte{0x14, 0x07},
+		Version:     [2]byte{0x14, 0x08},
+		CreatedAt:   [2]byte{0x14, 0x09},
+	},
+	SecondIndex: struct {
+		Version [2]byte
+	}{
+		Version: [2]byte{0x14, 0x01},
+	},
+}
+
+// ======================== MessageReceipt ========================
+
+// TableMessageReceipt 消息的已读用户
+var TableMessageReceipt = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid      [2]byte
+		ReadedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x15, 0x01},
+	Size: 2 + 2 + 8 + 8 + 8 + 2, // tableId + dataType + channel hash + messageSeq + uid hash + columnKey
+	Column: struct {
+		Uid      [2]byte
+		ReadedAt [2]byte
+	}{
+		Uid:      [2]byte{0x15, 0x01},
+		ReadedAt: [2]byte{0x15, 0x02},
+	},
+}
+
+// ======================== MessageReadCount ========================
+
+// TableMessageReadCount 消息的已读数量
+var TableMessageReadCount = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Count [2]byte
+	}
+}{
+	Id:   [2]byte{0x16, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + messageSeq + columnKey
+	Column: struct {
+		Count [2]byte
+	}{
+		Count: [2]byte{0x16, 0x01},
+	},
+}
+
+// ======================== MessageReceiptCursor ========================
+
+// TableMessageReceiptCursor 用户在频道内已回执至的消息序号（超大群只记录已读数量，通过游标去重）
+var TableMessageReceiptCursor = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ReadedToMsgSeq [2]byte
+	}
+}{
+	Id:   [2]byte{0x17, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + uid hash + columnKey
+	Column: struct {
+		ReadedToMsgSeq [2]byte
+	}{
+		ReadedToMsgSeq: [2]byte{0x17, 0x01},
+	},
+}
+
+// ======================== MessageTerm ========================
+
+// TableMessageTerm 消息全文索引（倒排表），value为消息主键+发送者hash+消息时间+词频
+var TableMessageTerm = struct {
+	Id   [2]byte
+	Size int
+}{
+	Id:   [2]byte{0x18, 0x01},
+	Size: 2 + 2 + 8 + 8, // tableId + dataType + term hash + messageId
+}
+
+// ======================== MessageDedup ========================
+
+// TableMessageDedup 消息去重索引（频道内发送者+客户端消息编号），value为消息ID+消息序号+消息时间
+// 时间索引用于清理超出去重窗口的索引
+var TableMessageDedup = struct {
+	Id            [2]byte
+	Size          int
+	TimeIndexSize int
+}{
+	Id:            [2]byte{0x19, 0x01},
+	Size:          2 + 2 + 8 + 8,     // tableId + dataType + channel hash + dedup hash
+	TimeIndexSize: 2 + 2 + 8 + 8 + 8, // tableId + dataType + channel hash + timestamp + dedup hash
+}
+
+// ======================== SensitiveWord ========================
+
+// TableSensitiveWord 敏感词规则（节点级配置，存储在默认分片）
+var TableSensitiveWord = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Word      [2]byte
+		Regex     [2]byte
+		Action    [2]byte
+		CreatedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + word hash + columnKey
+	Column: struct {
+		Word      [2]byte
+		Regex     [2]byte
+		Action    [2]byte
+		CreatedAt [2]byte
+	}{
+		Word:      [2]byte{0x1A, 0x01},
+		Regex:     [2]byte{0x1A, 0x02},
+		Action:    [2]byte{0x1A, 0x03},
+		CreatedAt: [2]byte{0x1A, 0x04},
+	},
+}
+
+// ======================== RateLimit ========================
+
+// TableRateLimit 用户或频道的发送频率限制规则（节点级配置，存储在默认分片）
+var TableRateLimit = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Target      [2]byte
+		TargetType  [2]byte
+		ChannelType [2]byte
+		Rate        [2]byte
+		Burst       [2]byte
+		MemberRate  [2]byte
+		MemberBurst [2]byte
+	}
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + target hash + columnKey
+	Column: struct {
+		Target      [2]byte
+		TargetType  [2]byte
+		ChannelType [2]byte
+		Rate        [2]byte
+		Burst       [2]byte
+		MemberRate  [2]byte
+		MemberBurst [2]byte
+	}{
+		Target:      [2]byte{0x1B, 0x01},
+		TargetType:  [2]byte{0x1B, 0x02},
+		ChannelType: [2]byte{0x1B, 0x03},
+		Rate:        [2]byte{0x1B, 0x04},
+		Burst:       [2]byte{0x1B, 0x05},
+		MemberRate:  [2]byte{0x1B, 0x06},
+		MemberBurst: [2]byte{0x1B, 0x07},
+	},
+}
+
+// ======================== ChannelMute ========================
+
+// TableChannelMute 频道禁言（uid为空表示全员禁言）
+var TableChannelMute = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid      [2]byte
+		ExpireAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x1C, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + uid hash + columnKey
+	Column: struct {
+		Uid      [2]byte
+		ExpireAt [2]byte
+	}{
+		Uid:      [2]byte{0x1C, 0x01},
+		ExpireAt: [2]byte{0x1C, 0x02},
+	},
+}
+
+// ======================== UserBan ========================
+
+// TableUserBan 用户封禁
+var TableUserBan = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid       [2]byte
+		Reason    [2]byte
+		ExpireAt  [2]byte
+		CreatedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x1D, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + uid hash + columnKey
+	Column: struct {
+		Uid       [2]byte
+		Reason    [2]byte
+		ExpireAt  [2]byte
+		CreatedAt [2]byte
+	}{
+		Uid:       [2]byte{0x1D, 0x01},
+		Reason:    [2]byte{0x1D, 0x02},
+		ExpireAt:  [2]byte{0x1D, 0x03},
+		CreatedAt: [2]byte{0x1D, 0x04},
+	},
+}
+
+// ======================== DevicePush ========================
+
+// TableDevicePush 设备推送令牌（按uid+设备标识存储）
+var TableDevicePush = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid        [2]byte
+		DeviceFlag [2]byte
+		PushType   [2]byte
+		PushToken  [2]byte
+		UpdatedAt  [2]byte
+	}
+}{
+	Id:   [2]byte{0x1E, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + uid hash + deviceFlag + columnKey
+	Column: struct {
+		Uid        [2]byte
+		DeviceFlag [2]byte
+		PushType   [2]byte
+		PushToken  [2]byte
+		UpdatedAt  [2]byte
+	}{
+		Uid:        [2]byte{0x1E, 0x01},
+		DeviceFlag: [2]byte{0x1E, 0x02},
+		PushType:   [2]byte{0x1E, 0x03},
+		PushToken:  [2]byte{0x1E, 0x04},
+		UpdatedAt:  [2]byte{0x1E, 0x05},
+	},
+}
+
+// ======================== ConversationMute ========================
+
+// TableConversationMute 会话免打扰（按uid+频道存储）
+var TableConversationMute = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		ExpireAt    [2]byte
+	}
+}{
+	Id:   [2]byte{0x1F, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + uid hash + channel hash + columnKey
+	Column: struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		ExpireAt    [2]byte
+	}{
+		ChannelId:   [2]byte{0x1F, 0x01},
+		ChannelType: [2]byte{0x1F, 0x02},
+		ExpireAt:    [2]byte{0x1F, 0x03},
+	},
+}
+
+// ======================== WebhookEvent ========================
+
+// TableWebhookEvent webhook事件（按队列区分发件箱和死信，每个端点独立，节点本地数据）
+var TableWebhookEvent = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Event       [2]byte
+		Data        [2]byte
+		CreatedAt   [2]byte
+		Attempts    [2]byte
+		NextRetryAt [2]byte
+		LastError   [2]byte
+		DeadAt      [2]byte
+		Endpoint    [2]byte
+	}
+}{
+	Id:   [2]byte{0x20, 0x01},
+	Size: 2 + 2 + 1 + 8 + 8 + 2, // tableId + dataType + queue + endpointHash + id + columnKey
+	Column: struct {
+		Event       [2]byte
+		Data        [2]byte
+		CreatedAt   [2]byte
+		Attempts    [2]byte
+		NextRetryAt [2]byte
+		LastError   [2]byte
+		DeadAt      [2]byte
+		Endpoint    [2]byte
+	}{
+		Event:       [2]byte{0x20, 0x01},
+		Data:        [2]byte{0x20, 0x02},
+		CreatedAt:   [2]byte{0x20, 0x03},
+		Attempts:    [2]byte{0x20, 0x04},
+		NextRetryAt: [2]byte{0x20, 0x05},
+		LastError:   [2]byte{0x20, 0x06},
+		DeadAt:      [2]byte{0x20, 0x07},
+		Endpoint:    [2]byte{0x20, 0x08},
+	},
+}
+
+// ======================== WebhookEndpoint ========================
+
+// TableWebhookEndpoint webhook端点（节点级配置，存储在默认分片）
+var TableWebhookEndpoint = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Name        [2]byte
+		Addr        [2]byte
+		Events      [2]byte
+		Secret      [2]byte
+		Concurrency [2]byte
+		CreatedAt   [2]byte
+	}
+}{
+	Id:   [2]byte{0x21, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + nameHash + columnKey
+	Column: struct {
+		Name        [2]byte
+		Addr        [2]byte
+		Events      [2]byte
+		Secret      [2]byte
+		Concurrency [2]byte
+		CreatedAt   [2]byte
+	}{
+		Name:        [2]byte{0x21, 0x01},
+		Addr:        [2]byte{0x21, 0x02},
+		Events:      [2]byte{0x21, 0x03},
+		Secret:      [2]byte{0x21, 0x04},
+		Concurrency: [2]byte{0x21, 0x05},
+		CreatedAt:   [2]byte{0x21, 0x06},
+	},
+}
+
+// ======================== DBMeta ========================
+
+// TableDBMeta 分片数据库的元数据（每个分片一份，比如数据迁移的进度）
+var TableDBMeta = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		FromUidTimestampIndexed [2]byte // 旧消息的发送者+消息时间索引是否已补齐
+	}
+}{
+	Id:   [2]byte{0x22, 0x01},
+	Size: 2 + 2 + 2, // tableId + dataType + columnKey
+	Column: struct {
+		FromUidTimestampIndexed [2]byte
+	}{
+		FromUidTimestampIndexed: [2]byte{0x22, 0x01},
+	},
+}
+
+// ======================== ChannelMessageCmd ========================
+
+// TableChannelMessageCmd 频道的消息命令（撤回、编辑、清理等），序号为槽日志的下标，各副本按序号顺序应用
+var TableChannelMessageCmd = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Data [2]byte
+	}
+}{
+	Id:   [2]byte{0x23, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + cmd index + columnKey
+	Column: struct {
+		Data [2]byte
+	}{
+		Data: [2]byte{0x23, 0x01},
+	},
+}
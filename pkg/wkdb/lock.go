@@ -16,6 +16,8 @@ type dblock struct {
 
 	updateSessionUpdatedAtLock sync.Mutex
 	userLock                   *userLock
+	reactionLock               *reactionLock
+	receiptLock                *receiptLock
 }
 
 func newDBLock() *dblock {
@@ -26,6 +28,8 @@ func newDBLock() *dblock {
 		denylistCountLock:    newDenylistCountLock(),
 		userLock:             newUserLock(),
 		totalLock:            newTotalLock(),
+		reactionLock:         newReactionLock(),
+		receiptLock:          newReceiptLock(),
 	}
 
 }
@@ -36,6 +40,8 @@ func (d *dblock) start() {
 	d.allowlistCountLock.StartCleanLoop()
 	d.denylistCountLock.StartCleanLoop()
 	d.userLock.StartCleanLoop()
+	d.reactionLock.StartCleanLoop()
+	d.receiptLock.StartCleanLoop()
 }
 
 func (d *dblock) stop() {
@@ -44,6 +50,8 @@ func (d *dblock) stop() {
 	d.allowlistCountLock.StopCleanLoop()
 	d.denylistCountLock.StopCleanLoop()
 	d.userLock.StopCleanLoop()
+	d.reactionLock.StopCleanLoop()
+	d.receiptLock.StopCleanLoop()
 }
 
 type channelClusterConfigLock struct {
@@ -139,6 +147,42 @@ func (u *userLock) unlock(uid string) {
 	u.Unlock(uid)
 }
 
+type reactionLock struct {
+	*keylock.KeyLock
+}
+
+func newReactionLock() *reactionLock {
+	return &reactionLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (r *reactionLock) lockByChannel(channelId string, channelType uint8) {
+	r.Lock(channelId + strconv.FormatInt(int64(channelType), 10))
+}
+
+func (r *reactionLock) unlockByChannel(channelId string, channelType uint8) {
+	r.Unlock(channelId + strconv.FormatInt(int64(channelType), 10))
+}
+
+type receiptLock struct {
+	*keylock.KeyLock
+}
+
+func newReceiptLock() *receiptLock {
+	return &receiptLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (r *receiptLock) lockByChannel(channelId string, channelType uint8) {
+	r.Lock(channelId + strconv.FormatInt(int64(channelType), 10))
+}
+
+func (r *receiptLock) unlockByChannel(channelId string, channelType uint8) {
+	r.Unlock(channelId + strconv.FormatInt(int64(channelType), 10))
+}
+
 type totalLock struct {
 	*keylock.KeyLock
 }
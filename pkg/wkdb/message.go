@@ -41,6 +41,9 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 			return err
 		}
 	}
+	if err := wk.writeMessageDedups(channelId, channelType, msgs, batch); err != nil {
+		return err
+	}
 
 	// 消息总数量+1
 	// err := wk.IncMessageCount(len(msgs))
@@ -141,6 +144,9 @@ func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq)
 		if err != nil {
 			return err
 		}
+		if err = wk.writeMessageDedups(req.ChannelId, req.ChannelType, req.Messages, batch); err != nil {
+			return err
+		}
 	}
 	if err := batch.Commit(wk.sync); err != nil {
 		return err
@@ -204,24 +210,11 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 		return nil, fmt.Errorf("end messageSeq[%d] must be less than start messageSeq[%d]", endMessageSeq, startMessageSeq)
 	}
 
-	var minSeq uint64
-	var maxSeq uint64
-
-	if endMessageSeq == 0 {
-		maxSeq = startMessageSeq + 1
-		if startMessageSeq < uint64(limit) {
-			minSeq = 1
-		} else {
-			minSeq = startMessageSeq - uint64(limit) + 1
-		}
-	} else {
-		maxSeq = startMessageSeq + 1
-		if startMessageSeq-endMessageSeq > uint64(limit) {
-			minSeq = startMessageSeq - uint64(limit) + 1
-		} else {
-			minSeq = endMessageSeq + 1
-		}
-
+	// 已清理的消息会留下序号空洞，所以从startMessageSeq往前倒序取limit条，而不是按序号计算范围
+	var minSeq uint64 = 1
+	var maxSeq = startMessageSeq + 1
+	if endMessageSeq != 0 {
+		minSeq = endMessageSeq + 1
 	}
 
 	// 获取频道的最大的messageSeq，超过这个的消息都视为无效
@@ -243,13 +236,17 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 	defer iter.Close()
 
 	msgs := make([]Message, 0)
-	err = wk.iteratorChannelMessages(iter, limit, func(m Message) bool {
+	err = wk.iteratorChannelMessagesDirection(iter, limit, true, func(m Message) bool {
 		msgs = append(msgs, m)
 		return true
 	})
 	if err != nil {
 		return nil, err
 	}
+	// 按序号升序返回
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
 	return msgs, nil
 }
 
@@ -399,6 +396,451 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 	return batch.Commit(wk.sync)
 }
 
+func (wk *wukongDB) RevokeMessage(channelId string, channelType uint8, messageSeq uint64) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			wk.Info("revokeMessage done", zap.Duration("cost", time.Since(start)), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("messageSeq", messageSeq))
+		}()
+	}
+	db := wk.channelDb(channelId, channelType)
+
+	msg, err := wk.LoadMsg(channelId, channelType, messageSeq)
+	if err != nil {
+		return err
+	}
+	if msg.Revoke { // 已撤回
+		return nil
+	}
+	batch := db.NewBatch()
+	defer batch.Close()
+	if err = batch.Set(key.NewMessageColumnKey(channelId, channelType, messageSeq, key.TableMessage.Column.Revoke), []byte{1}, wk.noSync); err != nil {
+		return err
+	}
+	// 撤回的消息不再被全文搜索到
+	if err = wk.deleteMessageTerms(channelId, msg, msg.Payload, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) EditMessage(req MessageEditReq) (MessageEdit, error) {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			wk.Info("editMessage done", zap.Duration("cost", time.Since(start)), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Int64("messageId", req.MessageId), zap.Uint64("logSeq", req.LogSeq))
+		}()
+	}
+	db := wk.channelDb(req.ChannelId, req.ChannelType)
+
+	msg, err := wk.LoadMsg(req.ChannelId, req.ChannelType, req.MessageSeq)
+	if err != nil {
+		return MessageEdit{}, err
+	}
+	if msg.MessageID != req.MessageId {
+		return MessageEdit{}, fmt.Errorf("%w: editMessage messageId not match, expect: %d, actual: %d", ErrMessageMismatch, req.MessageId, msg.MessageID)
+	}
+	// 版本号在应用时分配，同一条编辑命令重复应用时不产生新版本
+	if msg.Version > 0 && req.LogSeq > 0 {
+		lastLogSeq, err := wk.getMessageEditLogSeq(req.MessageId, msg.Version, db)
+		if err != nil {
+			return MessageEdit{}, err
+		}
+		if lastLogSeq >= req.LogSeq {
+			return MessageEdit{}, nil
+		}
+	}
+	version := msg.Version + 1
+
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	editedAtBytes := make([]byte, 4)
+	wk.endian.PutUint32(editedAtBytes, uint32(req.EditedAt))
+	versionBytes := make([]byte, 4)
+	wk.endian.PutUint32(versionBytes, version)
+	logSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(logSeqBytes, req.LogSeq)
+
+	// 版本记录
+	if err = batch.Set(key.NewMessageEditColumnKey(uint64(req.MessageId), version, key.TableMessageEdit.Column.Payload), req.Payload, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = batch.Set(key.NewMessageEditColumnKey(uint64(req.MessageId), version, key.TableMessageEdit.Column.EditedAt), editedAtBytes, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = batch.Set(key.NewMessageEditColumnKey(uint64(req.MessageId), version, key.TableMessageEdit.Column.LogSeq), logSeqBytes, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+
+	// 消息的最新版本
+	if err = batch.Set(key.NewMessageColumnKey(req.ChannelId, req.ChannelType, req.MessageSeq, key.TableMessage.Column.Version), versionBytes, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = batch.Set(key.NewMessageColumnKey(req.ChannelId, req.ChannelType, req.MessageSeq, key.TableMessage.Column.EditedAt), editedAtBytes, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = batch.Set(key.NewMessageColumnKey(req.ChannelId, req.ChannelType, req.MessageSeq, key.TableMessage.Column.EditedPayload), req.Payload, wk.noSync); err != nil {
+		return MessageEdit{}, err
+	}
+
+	// 全文索引改为编辑后的内容
+	if err = wk.deleteMessageTerms(req.ChannelId, msg, msg.Payload, batch); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = wk.writeMessageTerms(req.ChannelId, req.ChannelType, msg, req.Payload, batch); err != nil {
+		return MessageEdit{}, err
+	}
+	if err = batch.Commit(wk.sync); err != nil {
+		return MessageEdit{}, err
+	}
+	return MessageEdit{
+		MessageId: req.MessageId,
+		Version:   version,
+		Payload:   req.Payload,
+		EditedAt:  req.EditedAt,
+		LogSeq:    req.LogSeq,
+	}, nil
+}
+
+// getMessageEditLogSeq 获取产生指定版本的编辑命令序号
+func (wk *wukongDB) getMessageEditLogSeq(messageId int64, version uint32, db *pebble.DB) (uint64, error) {
+	data, closer, err := db.Get(key.NewMessageEditColumnKey(uint64(messageId), version, key.TableMessageEdit.Column.LogSeq))
+	if closer != nil {
+		defer closer.Close()
+	}
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return wk.endian.Uint64(data), nil
+}
+
+func (wk *wukongDB) GetMessageEdits(channelId string, channelType uint8, messageId int64) ([]MessageEdit, error) {
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageEditPrimaryKey(uint64(messageId), 0),
+		UpperBound: key.NewMessageEditPrimaryKey(uint64(messageId), math.MaxUint32),
+	})
+	defer iter.Close()
+
+	var (
+		edits       = make([]MessageEdit, 0)
+		preVersion  uint32
+		preEdit     MessageEdit
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, version, columnName, err := key.ParseMessageEditColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if version != preVersion {
+			if preVersion != 0 {
+				edits = append(edits, preEdit)
+			}
+			preVersion = version
+			preEdit = MessageEdit{
+				MessageId: messageId,
+				Version:   version,
+			}
+		}
+		switch columnName {
+		case key.TableMessageEdit.Column.Payload:
+			// 这里必须复制一份，否则会被pebble覆盖
+			var payload = make([]byte, len(iter.Value()))
+			copy(payload, iter.Value())
+			preEdit.Payload = payload
+		case key.TableMessageEdit.Column.EditedAt:
+			preEdit.EditedAt = int32(wk.endian.Uint32(iter.Value()))
+		case key.TableMessageEdit.Column.LogSeq:
+			preEdit.LogSeq = wk.endian.Uint64(iter.Value())
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		edits = append(edits, preEdit)
+	}
+	return edits, nil
+}
+
+// 消息每批删除的数量
+const messageDeleteBatchSize = 1000
+
+// GetExpiredMessages 获取本节点截止到now已过期且还未清理的消息
+func (wk *wukongDB) GetExpiredMessages(now time.Time, limit int) ([]Message, error) {
+	messages := make([]Message, 0)
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&pebble.IterOptions{
+			LowerBound: key.NewMessageSecondIndexExpireKey(0, minMessagePrimaryKey),
+			UpperBound: key.NewMessageSecondIndexExpireKey(uint64(now.Unix())+1, minMessagePrimaryKey),
+		})
+		for iter.First(); iter.Valid() && len(messages) < limit; iter.Next() {
+			primaryBytes, err := key.ParseMessageSecondIndexKey(iter.Key())
+			if err != nil {
+				wk.Error("parseMessageExpireIndexKey", zap.Error(err))
+				continue
+			}
+			msgIter := db.NewIter(&pebble.IterOptions{
+				LowerBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MinColumnKey),
+				UpperBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MaxColumnKey),
+			})
+			var msg Message
+			err = wk.iteratorChannelMessages(msgIter, 1, func(m Message) bool {
+				msg = m
+				return false
+			})
+			msgIter.Close()
+			if err != nil {
+				iter.Close()
+				return nil, err
+			}
+			if IsEmptyMessage(msg) {
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		iter.Close()
+		if len(messages) >= limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// DeleteExpiredMessages 清理频道内已过期的消息
+// 消息内容和所有索引都会删除，序号、任期、时间等列保留，频道的日志不会出现空洞（落后的副本仍能从领导同步这段日志）
+func (wk *wukongDB) DeleteExpiredMessages(channelId string, channelType uint8, messageSeqs []uint64) (int, error) {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			wk.Info("deleteExpiredMessages done", zap.Duration("cost", time.Since(start)), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("count", len(messageSeqs)))
+		}()
+	}
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	var (
+		count   = 0
+		primary [16]byte
+	)
+	wk.endian.PutUint64(primary[:], key.ChannelIdToNum(channelId, channelType))
+	for _, messageSeq := range messageSeqs {
+		msg, err := wk.LoadMsg(channelId, channelType, messageSeq)
+		if err != nil {
+			if err == ErrNotFound { // 已被删除
+				continue
+			}
+			return 0, err
+		}
+		if msg.Expire == 0 {
+			continue
+		}
+		wk.endian.PutUint64(primary[8:], messageSeq)
+		// 过期索引不存在说明已经清理过了
+		_, closer, err := db.Get(key.NewMessageSecondIndexExpireKey(uint64(msg.Timestamp)+uint64(msg.Expire), primary))
+		if err != nil {
+			if err == pebble.ErrNotFound {
+				continue
+			}
+			return 0, err
+		}
+		closer.Close()
+
+		for _, columnName := range expiredMessageDeleteColumns {
+			if err = batch.Delete(key.NewMessageColumnKey(channelId, channelType, messageSeq, columnName), wk.noSync); err != nil {
+				return 0, err
+			}
+		}
+		if err = wk.deleteMessageIndexes(msg, primary, batch); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	return count, batch.Commit(wk.sync)
+}
+
+// expiredMessageDeleteColumns 清理过期消息时删除的列
+var expiredMessageDeleteColumns = [][2]byte{
+	key.TableMessage.Column.Payload,
+	key.TableMessage.Column.EditedPayload,
+	key.TableMessage.Column.ClientMsgNo,
+	key.TableMessage.Column.FromUid,
+	key.TableMessage.Column.Topic,
+	key.TableMessage.Column.StreamNo,
+}
+
+// DeleteMessagesBefore 删除频道内序号小于messageSeq的消息，频道的最后一条消息不会被删除
+func (wk *wukongDB) DeleteMessagesBefore(channelId string, channelType uint8, messageSeq uint64) (int, error) {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			wk.Info("deleteMessagesBefore done", zap.Duration("cost", time.Since(start)), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("messageSeq", messageSeq))
+		}()
+	}
+
+	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	if messageSeq > lastSeq {
+		messageSeq = lastSeq
+	}
+	firstSeq, err := wk.GetChannelFirstMessageSeq(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	if messageSeq <= 1 || messageSeq <= firstSeq {
+		return 0, nil
+	}
+
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, firstSeq),
+		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, messageSeq),
+	})
+	defer iter.Close()
+
+	var (
+		batch      = db.NewBatch()
+		batchCount = 0
+		total      = 0
+		primary    [16]byte
+		deleteErr  error
+	)
+	defer func() {
+		_ = batch.Close()
+	}()
+
+	wk.endian.PutUint64(primary[:], key.ChannelIdToNum(channelId, channelType))
+	err = wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		wk.endian.PutUint64(primary[8:], uint64(m.MessageSeq))
+		if deleteErr = wk.deleteMessage(m, primary, batch); deleteErr != nil {
+			return false
+		}
+		batchCount++
+		if batchCount >= messageDeleteBatchSize {
+			if deleteErr = batch.Commit(wk.sync); deleteErr != nil {
+				return false
+			}
+			total += batchCount
+			batchCount = 0
+			batch.Reset()
+		}
+		return true
+	})
+	if err != nil {
+		return total, err
+	}
+	if deleteErr != nil {
+		return total, deleteErr
+	}
+
+	// 记录第一条可用的消息序号
+	firstSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(firstSeqBytes, messageSeq)
+	if err = batch.Set(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.FirstMessageSeq), firstSeqBytes, wk.noSync); err != nil {
+		return total, err
+	}
+	if err = batch.Commit(wk.sync); err != nil {
+		return total, err
+	}
+	total += batchCount
+	return total, nil
+}
+
+// GetChannelFirstMessageSeq 获取频道第一条可用的消息序号，0表示频道消息未被清理过
+func (wk *wukongDB) GetChannelFirstMessageSeq(channelId string, channelType uint8) (uint64, error) {
+	data, closer, err := wk.channelDb(channelId, channelType).Get(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.FirstMessageSeq))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return wk.endian.Uint64(data), nil
+}
+
+// deleteMessage 删除消息及其索引和编辑历史
+func (wk *wukongDB) deleteMessage(msg Message, primaryBytes [16]byte, w pebble.Writer) error {
+	// 消息
+	if err := w.DeleteRange(key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MinColumnKey), key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+	return wk.deleteMessageIndexes(msg, primaryBytes, w)
+}
+
+// deleteMessageIndexes 删除消息的索引以及依附于消息的数据（编辑历史、回应、已读回执）
+func (wk *wukongDB) deleteMessageIndexes(msg Message, primaryBytes [16]byte, w pebble.Writer) error {
+	var err error
+	// index messageId
+	if err = w.Delete(key.NewMessageIndexMessageIdKey(uint64(msg.MessageID)), wk.noSync); err != nil {
+		return err
+	}
+
+	// index fromUid
+	if err = w.Delete(key.NewMessageSecondIndexFromUidKey(msg.FromUID, primaryBytes), wk.noSync); err != nil {
+		return err
+	}
+
+	// index clientMsgNo
+	if err = w.Delete(key.NewMessageSecondIndexClientMsgNoKey(msg.ClientMsgNo, primaryBytes), wk.noSync); err != nil {
+		return err
+	}
+
+	// index timestamp
+	if err = w.Delete(key.NewMessageIndexTimestampKey(uint64(msg.Timestamp), primaryBytes), wk.noSync); err != nil {
+		return err
+	}
+
+	// index fromUid + timestamp
+	if err = w.Delete(key.NewMessageSecondIndexFromUidTimestampKey(msg.FromUID, uint64(msg.Timestamp), primaryBytes), wk.noSync); err != nil {
+		return err
+	}
+
+	// index expire
+	if msg.Expire > 0 {
+		if err = w.Delete(key.NewMessageSecondIndexExpireKey(uint64(msg.Timestamp)+uint64(msg.Expire), primaryBytes), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// 全文索引
+	if err = wk.deleteMessageTerms(msg.ChannelID, msg, msg.Payload, w); err != nil {
+		return err
+	}
+
+	// 去重索引
+	if err = wk.deleteMessageDedup(msg, w); err != nil {
+		return err
+	}
+
+	// 编辑历史
+	if err = w.DeleteRange(key.NewMessageEditPrimaryKey(uint64(msg.MessageID), 0), key.NewMessageEditPrimaryKey(uint64(msg.MessageID), math.MaxUint32), wk.noSync); err != nil {
+		return err
+	}
+
+	// 回应（版本索引在同步时会跳过已不存在的回应）
+	minReactionKey, maxReactionKey := key.NewReactionMessagePrimaryKeyRange(uint64(msg.MessageSeq))
+	if err = w.DeleteRange(key.NewReactionColumnKey(msg.ChannelID, msg.ChannelType, minReactionKey, key.MinColumnKey), key.NewReactionColumnKey(msg.ChannelID, msg.ChannelType, maxReactionKey, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+
+	// 已读回执
+	minReceiptKey, maxReceiptKey := key.NewMessageReceiptMessageKeyRange(msg.ChannelID, msg.ChannelType, uint64(msg.MessageSeq))
+	if err = w.DeleteRange(minReceiptKey, maxReceiptKey, wk.noSync); err != nil {
+		return err
+	}
+	return w.Delete(key.NewMessageReadCountColumnKey(msg.ChannelID, msg.ChannelType, uint64(msg.MessageSeq), key.TableMessageReadCount.Column.Count), wk.noSync)
+}
+
 func min(x, y uint64) uint64 {
 	if x < y {
 		return x
@@ -475,16 +917,36 @@ func (wk *wukongDB) SetChannellastMessageSeqBatch(reqs []SetChannelLastMessageSe
 var minMessagePrimaryKey = [16]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 var maxMessagePrimaryKey = [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
 
+// timeRange 时间索引的查询范围
+func (m MessageSearchReq) timeRange() (uint64, uint64) {
+	var startTime uint64
+	var endTime uint64 = math.MaxUint32
+	if m.StartTime > 0 {
+		startTime = uint64(m.StartTime)
+	}
+	if m.EndTime > 0 {
+		endTime = uint64(m.EndTime)
+	}
+	return startTime, endTime
+}
+
 func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, iterFnc func(m Message) bool) (bool, error) {
 	var lowKey []byte
 	var highKey []byte
 
 	var existKey = false
 
-	if strings.TrimSpace(req.FromUid) != "" {
+	startTime, endTime := req.timeRange()
+	hasTimeRange := req.StartTime > 0 || req.EndTime > 0
 
-		lowKey = key.NewMessageSecondIndexFromUidKey(req.FromUid, minMessagePrimaryKey)
-		highKey = key.NewMessageSecondIndexFromUidKey(req.FromUid, maxMessagePrimaryKey)
+	if strings.TrimSpace(req.FromUid) != "" {
+		if hasTimeRange && wk.fromUidTimestampIndexReady(db) { // 按发送者和时间范围查询（旧消息的索引补齐之前按发送者查询，时间由调用方过滤）
+			lowKey = key.NewMessageSecondIndexFromUidTimestampKey(req.FromUid, startTime, minMessagePrimaryKey)
+			highKey = key.NewMessageSecondIndexFromUidTimestampKey(req.FromUid, endTime, maxMessagePrimaryKey)
+		} else {
+			lowKey = key.NewMessageSecondIndexFromUidKey(req.FromUid, minMessagePrimaryKey)
+			highKey = key.NewMessageSecondIndexFromUidKey(req.FromUid, maxMessagePrimaryKey)
+		}
 		existKey = true
 	}
 
@@ -500,6 +962,12 @@ func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, it
 		existKey = true
 	}
 
+	if hasTimeRange && !existKey { // 按时间范围查询
+		lowKey = key.NewMessageIndexTimestampKey(startTime, minMessagePrimaryKey)
+		highKey = key.NewMessageIndexTimestampKey(endTime, maxMessagePrimaryKey)
+		existKey = true
+	}
+
 	if !existKey {
 		return false, nil
 	}
@@ -511,7 +979,15 @@ func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, it
 	defer iter.Close()
 
 	for iter.Last(); iter.Valid(); iter.Prev() {
-		primaryBytes, err := key.ParseMessageSecondIndexKey(iter.Key())
+		var (
+			primaryBytes [16]byte
+			err          error
+		)
+		if len(iter.Key()) == key.TableMessage.SecondIndexTimeSize {
+			_, primaryBytes, err = key.ParseMessageSecondIndexTimeKey(iter.Key())
+		} else {
+			primaryBytes, err = key.ParseMessageSecondIndexKey(iter.Key())
+		}
 		if err != nil {
 			wk.Error("parseMessageIndexKey", zap.Error(err))
 			continue
@@ -553,12 +1029,21 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 			}
 			return nil, err
 		}
+		if msg.IsExpired(time.Now()) {
+			return nil, nil
+		}
 		return []Message{msg}, nil
 	}
 
-	iterFnc := func(msgs *[]Message) func(m Message) bool {
+	now := time.Now()
+	// byIndex: 通过二级索引查询时消息不是按消息id排序的
+	iterFnc := func(msgs *[]Message, byIndex bool) func(m Message) bool {
 		currSize := 0
 		return func(m Message) bool {
+			if m.IsExpired(now) {
+				return true
+			}
+
 			if strings.TrimSpace(req.ChannelId) != "" && m.ChannelID != req.ChannelId {
 				return true
 			}
@@ -571,6 +1056,18 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 				return true
 			}
 
+			if req.StartTime > 0 && int64(m.Timestamp) < req.StartTime {
+				return true
+			}
+
+			if req.EndTime > 0 && int64(m.Timestamp) > req.EndTime {
+				return true
+			}
+
+			if strings.TrimSpace(req.ClientMsgNo) != "" && m.ClientMsgNo != req.ClientMsgNo {
+				return true
+			}
+
 			if len(req.Payload) > 0 && !bytes.Contains(m.Payload, req.Payload) {
 				return true
 			}
@@ -579,13 +1076,14 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 				return true
 			}
 
+			// 按消息id顺序查询时超出偏移范围就停止查询，通过索引查询时消息不是按消息id排序的，只跳过不在偏移范围内的消息
 			if req.Pre {
 				if req.OffsetMessageId > 0 && m.MessageID <= req.OffsetMessageId { // 当前消息小于等于req.MessageId时停止查询
-					return false
+					return byIndex
 				}
 			} else {
 				if req.OffsetMessageId > 0 && m.MessageID >= req.OffsetMessageId { // 当前消息小于等于req.MessageId时停止查询
-					return false
+					return byIndex
 				}
 			}
 
@@ -603,7 +1101,7 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 	if strings.TrimSpace(req.ChannelId) != "" && req.ChannelType != 0 {
 		db := wk.channelDb(req.ChannelId, req.ChannelType)
 		msgs := make([]Message, 0, req.Limit)
-		fnc := iterFnc(&msgs)
+		fnc := iterFnc(&msgs, false)
 
 		startSeq := req.OffsetMessageSeq
 		var endSeq uint64 = math.MaxUint64
@@ -636,14 +1134,14 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 	allMsgs := make([]Message, 0, req.Limit*len(wk.dbs))
 	for _, db := range wk.dbs {
 		msgs := make([]Message, 0)
-		fnc := iterFnc(&msgs)
 		// 通过索引查询
-		has, err := wk.searchMessageByIndex(req, db, fnc)
+		has, err := wk.searchMessageByIndex(req, db, iterFnc(&msgs, true))
 		if err != nil {
 			return nil, err
 		}
 
 		if !has { // 如果有触发索引，则无需全局查询
+			fnc := iterFnc(&msgs, false)
 			startMessageId := uint64(req.OffsetMessageId)
 			var endMessageId uint64 = math.MaxUint64
 
@@ -734,29 +1232,24 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 		size           int
 		preMessageSeq  uint64
 		preMessage     Message
-		lastNeedAppend bool = true
-		hasData        bool = false
+		editedPayload  []byte // 编辑后的最新消息内容
+		lastNeedAppend bool   = true
+		hasData        bool   = false
 	)
 
+	var iterStepFnc func() bool
 	if reverse {
 		if !iter.Last() {
 			return nil
 		}
+		iterStepFnc = iter.Prev
 	} else {
 		if !iter.First() {
 			return nil
 		}
+		iterStepFnc = iter.Next
 	}
-	for iter.Valid() {
-		if reverse {
-			if !iter.Prev() {
-				break
-			}
-		} else {
-			if !iter.Next() {
-				break
-			}
-		}
+	for ; iter.Valid(); iterStepFnc() {
 		messageSeq, coulmnName, err := key.ParseMessageColumnKey(iter.Key())
 		if err != nil {
 			return err
@@ -765,6 +1258,9 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 		if preMessageSeq != messageSeq {
 			if preMessageSeq != 0 {
 				size++
+				if editedPayload != nil {
+					preMessage.Payload = editedPayload
+				}
 				if iterFnc != nil {
 					if !iterFnc(preMessage) {
 						lastNeedAppend = false
@@ -780,6 +1276,7 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessageSeq = messageSeq
 			preMessage = Message{}
 			preMessage.MessageSeq = uint32(messageSeq)
+			editedPayload = nil
 		}
 
 		switch coulmnName {
@@ -810,11 +1307,25 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.Revoke:
+			preMessage.Revoke = iter.Value()[0] == 1
+		case key.TableMessage.Column.Version:
+			preMessage.Version = wk.endian.Uint32(iter.Value())
+		case key.TableMessage.Column.EditedAt:
+			preMessage.EditedAt = int32(wk.endian.Uint32(iter.Value()))
+		case key.TableMessage.Column.EditedPayload:
+			editedPayload = make([]byte, len(iter.Value()))
+			copy(editedPayload, iter.Value())
+		case key.TableMessage.Column.StreamNo:
+			preMessage.StreamNo = string(iter.Value())
 
 		}
 		hasData = true
 	}
 	if lastNeedAppend && hasData {
+		if editedPayload != nil {
+			preMessage.Payload = editedPayload
+		}
 		if iterFnc != nil {
 
 			_ = iterFnc(preMessage)
@@ -888,6 +1399,10 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 			preMessage.Payload = payload
 		case key.TableMessage.Column.Term:
 			preMessage.Term = wk.endian.Uint64(iter.Value())
+		case key.TableMessage.Column.Revoke:
+			preMessage.Revoke = iter.Value()[0] == 1
+		case key.TableMessage.Column.StreamNo:
+			preMessage.StreamNo = string(iter.Value())
 		}
 	}
 
@@ -982,6 +1497,20 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
+	// revoke
+	if msg.Revoke {
+		if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.Revoke), []byte{1}, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// streamNo
+	if msg.StreamNo != "" {
+		if err = w.Set(key.NewMessageColumnKey(channelId, channelType, uint64(msg.MessageSeq), key.TableMessage.Column.StreamNo), []byte(msg.StreamNo), wk.noSync); err != nil {
+			return err
+		}
+	}
+
 	var primaryValue = [16]byte{}
 	wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(channelId, channelType))
 	wk.endian.PutUint64(primaryValue[8:], uint64(msg.MessageSeq))
@@ -1006,5 +1535,18 @@ func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Messag
 		return err
 	}
 
-	return nil
+	// index fromUid + timestamp
+	if err = w.Set(key.NewMessageSecondIndexFromUidTimestampKey(msg.FromUID, uint64(msg.Timestamp), primaryValue), nil, wk.noSync); err != nil {
+		return err
+	}
+
+	// index expire
+	if msg.Expire > 0 {
+		if err = w.Set(key.NewMessageSecondIndexExpireKey(uint64(msg.Timestamp)+uint64(msg.Expire), primaryValue), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// 全文索引
+	return wk.writeMessageTerms(channelId, channelType, msg, msg.Payload, w)
 }
@@ -0,0 +1,140 @@
+package wkdb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+const maxDeleteExpiredDedupsOnce = 1000 // 每次写入最多清理的过期去重索引数量
+
+func (wk *wukongDB) GetMessageDedup(channelId string, channelType uint8, fromUid string, clientMsgNo string) (MessageDedup, error) {
+	if wk.opts.MessageDedupWindow <= 0 || strings.TrimSpace(clientMsgNo) == "" {
+		return MessageDedup{}, ErrNotFound
+	}
+	db := wk.channelDb(channelId, channelType)
+	dedup, err := wk.getMessageDedup(db, key.NewMessageDedupKey(channelId, channelType, key.MessageDedupHash(fromUid, clientMsgNo)))
+	if err != nil {
+		return MessageDedup{}, err
+	}
+	if dedup.Timestamp < wk.messageDedupExpireAt() {
+		return MessageDedup{}, ErrNotFound
+	}
+
+	// 日志被截断后原消息可能已不存在
+	lastSeq, _, err := wk.GetChannelLastMessageSeq(channelId, channelType)
+	if err != nil {
+		return MessageDedup{}, err
+	}
+	if dedup.MessageSeq > lastSeq {
+		return MessageDedup{}, ErrNotFound
+	}
+	return dedup, nil
+}
+
+func (wk *wukongDB) getMessageDedup(db *pebble.DB, dedupKey []byte) (MessageDedup, error) {
+	data, closer, err := db.Get(dedupKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return MessageDedup{}, ErrNotFound
+		}
+		return MessageDedup{}, err
+	}
+	defer closer.Close()
+	return MessageDedup{
+		MessageId:  int64(wk.endian.Uint64(data)),
+		MessageSeq: wk.endian.Uint64(data[8:]),
+		Timestamp:  int64(wk.endian.Uint64(data[16:])),
+	}, nil
+}
+
+// writeMessageDedups 写入消息的去重索引，并清理频道内超出去重窗口的索引
+func (wk *wukongDB) writeMessageDedups(channelId string, channelType uint8, msgs []Message, w pebble.Writer) error {
+	if wk.opts.MessageDedupWindow <= 0 {
+		return nil
+	}
+	if err := wk.deleteExpiredMessageDedups(channelId, channelType, w); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if strings.TrimSpace(msg.ClientMsgNo) == "" {
+			continue
+		}
+		dedupHash := key.MessageDedupHash(msg.FromUID, msg.ClientMsgNo)
+		value := make([]byte, 24)
+		wk.endian.PutUint64(value, uint64(msg.MessageID))
+		wk.endian.PutUint64(value[8:], uint64(msg.MessageSeq))
+		wk.endian.PutUint64(value[16:], uint64(msg.Timestamp))
+		if err := w.Set(key.NewMessageDedupKey(channelId, channelType, dedupHash), value, wk.noSync); err != nil {
+			return err
+		}
+		if err := w.Set(key.NewMessageDedupTimeIndexKey(channelId, channelType, uint64(msg.Timestamp), dedupHash), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExpiredMessageDedups 清理频道内超出去重窗口的索引
+func (wk *wukongDB) deleteExpiredMessageDedups(channelId string, channelType uint8, w pebble.Writer) error {
+	expireAt := wk.messageDedupExpireAt()
+	if expireAt <= 0 {
+		return nil
+	}
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageDedupTimeIndexKey(channelId, channelType, 0, 0),
+		UpperBound: key.NewMessageDedupTimeIndexKey(channelId, channelType, uint64(expireAt), 0),
+	})
+	defer iter.Close()
+
+	count := 0
+	for iter.First(); iter.Valid() && count < maxDeleteExpiredDedupsOnce; iter.Next() {
+		_, dedupHash, err := key.ParseMessageDedupTimeIndexKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		dedupKey := key.NewMessageDedupKey(channelId, channelType, dedupHash)
+		dedup, err := wk.getMessageDedup(db, dedupKey)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		// 相同的编号在窗口外可能被重新使用，只删除已过期的
+		if err == nil && dedup.Timestamp < expireAt {
+			if err = w.Delete(dedupKey, wk.noSync); err != nil {
+				return err
+			}
+		}
+		if err = w.Delete(iter.Key(), wk.noSync); err != nil {
+			return err
+		}
+		count++
+	}
+	return nil
+}
+
+// deleteMessageDedup 删除消息对应的去重索引（时间索引过期后清理）
+func (wk *wukongDB) deleteMessageDedup(msg Message, w pebble.Writer) error {
+	if strings.TrimSpace(msg.ClientMsgNo) == "" {
+		return nil
+	}
+	dedupKey := key.NewMessageDedupKey(msg.ChannelID, msg.ChannelType, key.MessageDedupHash(msg.FromUID, msg.ClientMsgNo))
+	dedup, err := wk.getMessageDedup(wk.channelDb(msg.ChannelID, msg.ChannelType), dedupKey)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if dedup.MessageId != msg.MessageID {
+		return nil
+	}
+	return w.Delete(dedupKey, wk.noSync)
+}
+
+// 去重窗口的开始时间，早于此时间的索引已过期
+func (wk *wukongDB) messageDedupExpireAt() int64 {
+	return time.Now().Add(-wk.opts.MessageDedupWindow).Unix()
+}
@@ -0,0 +1,72 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMessageDedup(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	now := int32(time.Now().Unix())
+
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   100,
+				MessageSeq:  1,
+				ClientMsgNo: "no1",
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Timestamp:   now,
+				Payload:     []byte("hello"),
+			},
+		},
+		{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   101,
+				MessageSeq:  2,
+				ClientMsgNo: "no2",
+				FromUID:     "u1",
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				Timestamp:   now - 3600, // 超出去重窗口
+				Payload:     []byte("hello"),
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	dedup, err := d.GetMessageDedup(channelId, channelType, "u1", "no1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), dedup.MessageId)
+	assert.Equal(t, uint64(1), dedup.MessageSeq)
+
+	// 不同的发送者不算重复
+	_, err = d.GetMessageDedup(channelId, channelType, "u2", "no1")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// 超出去重窗口
+	_, err = d.GetMessageDedup(channelId, channelType, "u1", "no2")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// 消息删除后去重索引也删除
+	_, err = d.DeleteMessagesBefore(channelId, channelType, 2)
+	assert.NoError(t, err)
+	_, err = d.GetMessageDedup(channelId, channelType, "u1", "no1")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
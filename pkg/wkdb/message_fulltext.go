@@ -0,0 +1,485 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+	"go.uber.org/zap"
+)
+
+const (
+	messageTermValueSize = 16 + 8 + 4 + 2 // 消息主键 + 发送者hash + 消息时间 + 词频
+	maxSearchScanCount   = 100000         // 每个分区每次搜索最多扫描的倒排记录数量
+	rebuildBatchCount    = 1000           // 重建索引时每批提交的消息数量
+)
+
+// 是否需要为消息建立全文索引
+func (wk *wukongDB) needFullTextIndex(channelId string) bool {
+	if !wk.opts.FullTextIndex {
+		return false
+	}
+	if wk.opts.IsCmdChannel != nil && wk.opts.IsCmdChannel(channelId) { // 命令消息不索引
+		return false
+	}
+	return true
+}
+
+// writeMessageTerms 写入消息的全文索引
+func (wk *wukongDB) writeMessageTerms(channelId string, channelType uint8, msg Message, payload []byte, w pebble.Writer) error {
+	if !wk.needFullTextIndex(channelId) {
+		return nil
+	}
+	terms := tokenize(payloadText(payload))
+	if len(terms) == 0 {
+		return nil
+	}
+	value := make([]byte, messageTermValueSize)
+	wk.endian.PutUint64(value[0:], key.ChannelIdToNum(channelId, channelType))
+	wk.endian.PutUint64(value[8:], uint64(msg.MessageSeq))
+	wk.endian.PutUint64(value[16:], key.HashWithString(msg.FromUID))
+	wk.endian.PutUint32(value[24:], uint32(msg.Timestamp))
+	for term, tf := range terms {
+		wk.endian.PutUint16(value[28:], tf)
+		if err := w.Set(key.NewMessageTermKey(term, uint64(msg.MessageID)), value, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteMessageTerms 删除消息的全文索引
+func (wk *wukongDB) deleteMessageTerms(channelId string, msg Message, payload []byte, w pebble.Writer) error {
+	if !wk.needFullTextIndex(channelId) {
+		return nil
+	}
+	for term := range tokenize(payloadText(payload)) {
+		if err := w.Delete(key.NewMessageTermKey(term, uint64(msg.MessageID)), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) SearchMessagesByKeyword(req MessageKeywordSearchReq) ([]MessageSearchResult, error) {
+	terms := tokenizeQuery(req.Keyword)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+
+	dbs := wk.dbs
+	if strings.TrimSpace(req.ChannelId) != "" && req.ChannelType != 0 {
+		dbs = []*pebble.DB{wk.channelDb(req.ChannelId, req.ChannelType)}
+	}
+
+	results := make([]MessageSearchResult, 0, req.Limit)
+	for _, db := range dbs {
+		dbResults, err := wk.searchMessagesByKeyword(db, terms, req)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, dbResults...)
+	}
+	sortMessageSearchResults(results, req.OrderBy)
+	if len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) searchMessagesByKeyword(db *pebble.DB, terms []string, req MessageKeywordSearchReq) ([]MessageSearchResult, error) {
+	termHashes := make([]uint64, 0, len(terms))
+	for _, term := range terms {
+		termHashes = append(termHashes, key.HashWithString(term))
+	}
+
+	// 按相关度排序时需要各个词的文档频率，以文档频率最小的词驱动查询
+	var dfs []int
+	if req.OrderBy == MessageSearchOrderRelevance {
+		dfs = make([]int, len(termHashes))
+		for i, termHash := range termHashes {
+			df, err := wk.countMessageTerm(db, termHash)
+			if err != nil {
+				return nil, err
+			}
+			if df == 0 { // 所有的词都需要匹配
+				return nil, nil
+			}
+			dfs[i] = df
+		}
+		minIdx := 0
+		for i, df := range dfs {
+			if df < dfs[minIdx] {
+				minIdx = i
+			}
+		}
+		termHashes[0], termHashes[minIdx] = termHashes[minIdx], termHashes[0]
+		dfs[0], dfs[minIdx] = dfs[minIdx], dfs[0]
+	}
+
+	var (
+		channelHash uint64
+		fromUidHash uint64
+		now         = time.Now()
+		results     = make([]MessageSearchResult, 0)
+		scanCount   int
+	)
+	if strings.TrimSpace(req.ChannelId) != "" && req.ChannelType != 0 {
+		channelHash = key.ChannelIdToNum(req.ChannelId, req.ChannelType)
+	}
+	if strings.TrimSpace(req.FromUid) != "" {
+		fromUidHash = key.HashWithString(req.FromUid)
+	}
+
+	upperMessageId := uint64(math.MaxUint64)
+	if req.OrderBy == MessageSearchOrderTime && req.OffsetMessageId > 0 {
+		upperMessageId = uint64(req.OffsetMessageId)
+	}
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageTermKeyWithHash(termHashes[0], 0),
+		UpperBound: key.NewMessageTermKeyWithHash(termHashes[0], upperMessageId),
+	})
+	defer iter.Close()
+
+	// 从最新的消息开始扫描
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		scanCount++
+		if scanCount > maxSearchScanCount {
+			wk.Warn("searchMessagesByKeyword: too many postings, stop scan", zap.String("keyword", req.Keyword), zap.Int("scanCount", scanCount))
+			break
+		}
+		messageId, err := key.ParseMessageTermKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		value := iter.Value()
+		if len(value) != messageTermValueSize {
+			continue
+		}
+		if channelHash != 0 && wk.endian.Uint64(value[0:]) != channelHash {
+			continue
+		}
+		if fromUidHash != 0 && wk.endian.Uint64(value[16:]) != fromUidHash {
+			continue
+		}
+		timestamp := int64(wk.endian.Uint32(value[24:]))
+		if req.StartTime > 0 && timestamp < req.StartTime {
+			continue
+		}
+		if req.EndTime > 0 && timestamp > req.EndTime {
+			continue
+		}
+
+		// 其他的词也需要匹配
+		tfs := make([]uint16, len(termHashes))
+		tfs[0] = wk.endian.Uint16(value[28:])
+		matched := true
+		for i := 1; i < len(termHashes); i++ {
+			tf, err := wk.getMessageTermFrequency(db, termHashes[i], messageId)
+			if err != nil {
+				return nil, err
+			}
+			if tf == 0 {
+				matched = false
+				break
+			}
+			tfs[i] = tf
+		}
+		if !matched {
+			continue
+		}
+
+		var primaryKey [16]byte
+		copy(primaryKey[:], value[:16])
+		msg, err := wk.loadMessageByPrimaryKey(db, primaryKey)
+		if err != nil {
+			if err == ErrNotFound { // 消息已删除
+				continue
+			}
+			return nil, err
+		}
+		if msg.IsExpired(now) || msg.Revoke || uint64(msg.MessageID) != messageId {
+			continue
+		}
+		if channelHash != 0 && (msg.ChannelID != req.ChannelId || msg.ChannelType != req.ChannelType) {
+			continue
+		}
+		if fromUidHash != 0 && msg.FromUID != req.FromUid {
+			continue
+		}
+
+		result := MessageSearchResult{
+			Message: msg,
+		}
+		if req.OrderBy == MessageSearchOrderRelevance {
+			result.Score = messageSearchScore(tfs, dfs)
+		}
+		results = append(results, result)
+
+		if req.OrderBy == MessageSearchOrderTime && len(results) >= req.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+// messageSearchScore 计算消息的相关度（BM25，不考虑消息长度）
+// 所有词的文档频率之和近似为文档总数
+func messageSearchScore(tfs []uint16, dfs []int) float64 {
+	const k1 = 1.2
+	var total int
+	for _, df := range dfs {
+		total += df
+	}
+	var score float64
+	for i, tf := range tfs {
+		idf := math.Log(1 + float64(total)/float64(dfs[i]))
+		score += idf * float64(tf) * (k1 + 1) / (float64(tf) + k1)
+	}
+	return score
+}
+
+func sortMessageSearchResults(results []MessageSearchResult, orderBy MessageSearchOrder) {
+	sort.Slice(results, func(i, j int) bool {
+		if orderBy == MessageSearchOrderRelevance && results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].MessageID > results[j].MessageID
+	})
+}
+
+// countMessageTerm 获取包含词的消息数量（最多统计maxSearchScanCount条）
+func (wk *wukongDB) countMessageTerm(db *pebble.DB, termHash uint64) (int, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageTermKeyWithHash(termHash, 0),
+		UpperBound: key.NewMessageTermKeyWithHash(termHash, math.MaxUint64),
+	})
+	defer iter.Close()
+	count := 0
+	for iter.First(); iter.Valid() && count < maxSearchScanCount; iter.Next() {
+		count++
+	}
+	return count, iter.Error()
+}
+
+// getMessageTermFrequency 获取词在消息中的词频，不包含返回0
+func (wk *wukongDB) getMessageTermFrequency(db *pebble.DB, termHash uint64, messageId uint64) (uint16, error) {
+	value, closer, err := db.Get(key.NewMessageTermKeyWithHash(termHash, messageId))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(value) != messageTermValueSize {
+		return 0, nil
+	}
+	return wk.endian.Uint16(value[28:]), nil
+}
+
+func (wk *wukongDB) loadMessageByPrimaryKey(db *pebble.DB, primaryKey [16]byte) (Message, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(primaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var msg Message
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		msg = m
+		return false
+	})
+	if err != nil {
+		return EmptyMessage, err
+	}
+	if IsEmptyMessage(msg) {
+		return EmptyMessage, ErrNotFound
+	}
+	return msg, nil
+}
+
+// RebuildMessageSearchIndex 清空并重建所有消息的全文索引，同时补齐发送者+消息时间索引，需要在服务停止时执行
+func (wk *wukongDB) RebuildMessageSearchIndex() error {
+	for i, db := range wk.dbs {
+		start := time.Now()
+		count, err := wk.rebuildMessageSearchIndex(db)
+		if err != nil {
+			return err
+		}
+		if i < len(wk.fromUidTimestampIndexed) {
+			wk.fromUidTimestampIndexed[i].Store(true)
+		}
+		wk.Info("rebuild message search index done", zap.Int("shard", i), zap.Int("messageCount", count), zap.Duration("cost", time.Since(start)))
+	}
+	return nil
+}
+
+func (wk *wukongDB) rebuildMessageSearchIndex(db *pebble.DB) (int, error) {
+	if err := db.DeleteRange(key.NewMessageTermLowKey(), key.NewMessageTermHighKey(), wk.sync); err != nil {
+		return 0, err
+	}
+
+	var maxPrimaryKey [16]byte
+	for i := range maxPrimaryKey {
+		maxPrimaryKey[i] = 0xff
+	}
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary([16]byte{}, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(maxPrimaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		count     int
+		batchSize int
+		writeErr  error
+	)
+	batch := db.NewBatch()
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		if !m.Revoke { // 撤回的消息不索引
+			if writeErr = wk.writeMessageTerms(m.ChannelID, m.ChannelType, m, m.Payload, batch); writeErr != nil {
+				return false
+			}
+		}
+		// 发送者+消息时间索引（旧版本写入的消息没有此索引）
+		var primaryValue [16]byte
+		wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(m.ChannelID, m.ChannelType))
+		wk.endian.PutUint64(primaryValue[8:], uint64(m.MessageSeq))
+		if writeErr = batch.Set(key.NewMessageSecondIndexFromUidTimestampKey(m.FromUID, uint64(m.Timestamp), primaryValue), nil, wk.noSync); writeErr != nil {
+			return false
+		}
+		count++
+		batchSize++
+		if batchSize >= rebuildBatchCount {
+			if writeErr = batch.Commit(wk.sync); writeErr != nil {
+				return false
+			}
+			batch.Close()
+			batch = db.NewBatch()
+			batchSize = 0
+		}
+		return true
+	})
+	defer batch.Close()
+	if err != nil {
+		return 0, err
+	}
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	if batchSize > 0 {
+		if err = batch.Commit(wk.sync); err != nil {
+			return 0, err
+		}
+	}
+	if err = wk.setFromUidTimestampIndexed(db); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// isFromUidTimestampIndexed 分区的旧消息是否已补齐发送者+消息时间索引
+func (wk *wukongDB) isFromUidTimestampIndexed(db *pebble.DB) (bool, error) {
+	_, closer, err := db.Get(key.NewDBMetaColumnKey(key.TableDBMeta.Column.FromUidTimestampIndexed))
+	if closer != nil {
+		defer closer.Close()
+	}
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (wk *wukongDB) setFromUidTimestampIndexed(db *pebble.DB) error {
+	return db.Set(key.NewDBMetaColumnKey(key.TableDBMeta.Column.FromUidTimestampIndexed), []byte{1}, wk.sync)
+}
+
+// fromUidTimestampIndexReady 分区的发送者+消息时间索引是否可用
+func (wk *wukongDB) fromUidTimestampIndexReady(db *pebble.DB) bool {
+	for i, d := range wk.dbs {
+		if d == db {
+			return i < len(wk.fromUidTimestampIndexed) && wk.fromUidTimestampIndexed[i].Load()
+		}
+	}
+	return false
+}
+
+// backfillFromUidTimestampIndex 给分区内旧版本写入的消息补齐发送者+消息时间索引（新消息写入时已有索引，重复写入无影响）
+func (wk *wukongDB) backfillFromUidTimestampIndex(shard int, db *pebble.DB) {
+	defer wk.backfillWg.Done()
+
+	start := time.Now()
+	var maxPrimaryKey [16]byte
+	for i := range maxPrimaryKey {
+		maxPrimaryKey[i] = 0xff
+	}
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary([16]byte{}, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(maxPrimaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		count     int
+		batchSize int
+		writeErr  error
+		canceled  bool
+	)
+	batch := db.NewBatch()
+	defer func() {
+		batch.Close()
+	}()
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		select {
+		case <-wk.cancelCtx.Done():
+			canceled = true
+			return false
+		default:
+		}
+		var primaryValue [16]byte
+		wk.endian.PutUint64(primaryValue[:], key.ChannelIdToNum(m.ChannelID, m.ChannelType))
+		wk.endian.PutUint64(primaryValue[8:], uint64(m.MessageSeq))
+		if writeErr = batch.Set(key.NewMessageSecondIndexFromUidTimestampKey(m.FromUID, uint64(m.Timestamp), primaryValue), nil, wk.noSync); writeErr != nil {
+			return false
+		}
+		count++
+		batchSize++
+		if batchSize >= rebuildBatchCount {
+			if writeErr = batch.Commit(wk.sync); writeErr != nil {
+				return false
+			}
+			batch.Close()
+			batch = db.NewBatch()
+			batchSize = 0
+		}
+		return true
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil && !canceled && batchSize > 0 {
+		err = batch.Commit(wk.sync)
+	}
+	if err == nil && !canceled {
+		err = wk.setFromUidTimestampIndexed(db)
+	}
+	if err != nil {
+		wk.Error("backfill fromUid timestamp index failed", zap.Error(err), zap.Int("shard", shard))
+		return
+	}
+	if canceled {
+		return
+	}
+	wk.fromUidTimestampIndexed[shard].Store(true)
+	wk.Info("backfill fromUid timestamp index done", zap.Int("shard", shard), zap.Int("messageCount", count), zap.Duration("cost", time.Since(start)))
+}
@@ -0,0 +1,177 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchMessagesByKeyword(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	payloads := []string{
+		`{"type":1,"content":"Hello World"}`,
+		`{"type":1,"content":"今天天气很好"}`,
+		`{"type":1,"content":"hello hello wukongim"}`,
+		`{"type":1,"content":"明天天气怎么样"}`,
+	}
+	messages := make([]wkdb.Message, 0, len(payloads))
+	for i, payload := range payloads {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     "u1",
+				Timestamp:   int32(1000 + i),
+				Payload:     []byte(payload),
+			},
+		})
+	}
+	messages[1].FromUID = "u2"
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 拉丁文不区分大小写，按时间倒序
+	results, err := d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "HELLO",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, int64(3), results[0].MessageID)
+	assert.Equal(t, int64(1), results[1].MessageID)
+
+	// 按相关度排序，词频高的在前
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "hello world",
+		OrderBy: wkdb.MessageSearchOrderRelevance,
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(1), results[0].MessageID)
+
+	// 中文
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "天气",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// 发送者过滤
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "天气",
+		FromUid: "u2",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(2), results[0].MessageID)
+
+	// 时间过滤
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword:     "天",
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		StartTime:   1003,
+		Limit:       10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(4), results[0].MessageID)
+
+	// json的key不索引
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "content",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	// 编辑后按新内容索引
+	_, err = d.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   1,
+		MessageSeq:  1,
+		Payload:     []byte(`{"type":1,"content":"goodbye"}`),
+		LogSeq:      2,
+	})
+	assert.NoError(t, err)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "world",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "goodbye",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// 再次编辑，上一个版本的内容不再被搜索到
+	_, err = d.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   1,
+		MessageSeq:  1,
+		Payload:     []byte(`{"type":1,"content":"goodnight"}`),
+		LogSeq:      3,
+	})
+	assert.NoError(t, err)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "goodbye",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "goodnight",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// 撤回后不再被搜索到
+	err = d.RevokeMessage(channelId, channelType, 1)
+	assert.NoError(t, err)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "goodnight",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	// 重建索引
+	err = d.RebuildMessageSearchIndex()
+	assert.NoError(t, err)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "goodnight",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+	results, err = d.SearchMessagesByKeyword(wkdb.MessageKeywordSearchReq{
+		Keyword: "天气",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+}
@@ -1,10 +1,16 @@
 package wkdb_test
 
 import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -210,3 +216,478 @@ func TestSearchMessages(t *testing.T) {
 	assert.Equal(t, 10, len(resultMessages))
 
 }
+
+func TestSearchMessagesByTimeRange(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for c := 0; c < 2; c++ {
+		channelId := fmt.Sprintf("channel%d", c)
+		channelType := uint8(2)
+		messages := []wkdb.Message{}
+		for i := 0; i < 10; i++ {
+			fromUid := "u1"
+			if i%2 == 1 {
+				fromUid = "u2"
+			}
+			messages = append(messages, wkdb.Message{
+				RecvPacket: wkproto.RecvPacket{
+					ChannelID:   channelId,
+					ChannelType: channelType,
+					MessageID:   int64(c*100 + i + 1),
+					MessageSeq:  uint32(i + 1),
+					FromUID:     fromUid,
+					Timestamp:   int32(1000 + i),
+					Payload:     []byte("hello"),
+				},
+			})
+		}
+		err = d.AppendMessages(channelId, channelType, messages)
+		assert.NoError(t, err)
+	}
+
+	// 按时间范围
+	resultMessages, err := d.SearchMessages(wkdb.MessageSearchReq{
+		Limit:     100,
+		StartTime: 1002,
+		EndTime:   1004,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(resultMessages))
+	for _, m := range resultMessages {
+		assert.True(t, m.Timestamp >= 1002 && m.Timestamp <= 1004)
+	}
+
+	// 按发送者和时间范围
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		Limit:     100,
+		FromUid:   "u1",
+		StartTime: 1002,
+		EndTime:   1004,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(resultMessages))
+	for _, m := range resultMessages {
+		assert.Equal(t, "u1", m.FromUID)
+	}
+
+	// 指定频道
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		Limit:       100,
+		ChannelId:   "channel1",
+		ChannelType: 2,
+		StartTime:   1008,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(resultMessages))
+
+	// 分页
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		Limit:           100,
+		FromUid:         "u2",
+		StartTime:       1000,
+		OffsetMessageId: 100,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(resultMessages))
+}
+
+func TestRevokeMessage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 3; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	err = d.RevokeMessage(channelId, channelType, 3)
+	assert.NoError(t, err)
+
+	// 不存在的消息
+	err = d.RevokeMessage(channelId, channelType, 100)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+	assert.False(t, resultMessages[0].Revoke)
+	assert.True(t, resultMessages[2].Revoke)
+
+	msg, err := d.GetMessage(3)
+	assert.NoError(t, err)
+	assert.True(t, msg.Revoke)
+
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Limit:       10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+	assert.True(t, resultMessages[0].Revoke)
+}
+
+func TestEditMessage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 3; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	for i := 1; i <= 2; i++ {
+		edit, err := d.EditMessage(wkdb.MessageEditReq{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageId:   2,
+			MessageSeq:  2,
+			Payload:     []byte(fmt.Sprintf("hello%d", i)),
+			LogSeq:      uint64(10 + i),
+			EditedAt:    int32(100 + i),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(i), edit.Version)
+		assert.Equal(t, uint64(10+i), edit.LogSeq)
+	}
+
+	// 重复应用已应用过的编辑命令忽略
+	edit, err := d.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   2,
+		MessageSeq:  2,
+		Payload:     []byte("hello1"),
+		LogSeq:      11,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), edit.Version)
+
+	// 不存在的消息
+	_, err = d.EditMessage(wkdb.MessageEditReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   100,
+		MessageSeq:  100,
+		Payload:     []byte("hello1"),
+		LogSeq:      13,
+	})
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 3)
+	assert.Equal(t, []byte("hello"), resultMessages[0].Payload)
+	assert.Equal(t, []byte("hello2"), resultMessages[1].Payload)
+	assert.Equal(t, uint32(2), resultMessages[1].Version)
+	assert.Equal(t, int32(102), resultMessages[1].EditedAt)
+
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Payload:     []byte("hello2"),
+		Limit:       10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+	assert.Equal(t, int64(2), resultMessages[0].MessageID)
+
+	edits, err := d.GetMessageEdits(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Len(t, edits, 2)
+	assert.Equal(t, uint32(1), edits[0].Version)
+	assert.Equal(t, []byte("hello1"), edits[0].Payload)
+	assert.Equal(t, uint32(2), edits[1].Version)
+	assert.Equal(t, int32(102), edits[1].EditedAt)
+	assert.Equal(t, uint64(12), edits[1].LogSeq)
+}
+
+func TestExpiredMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	now := time.Now()
+
+	// 1,2,4 已过期，3 永不过期
+	messages := []wkdb.Message{}
+	for i := 0; i < 4; i++ {
+		var expire uint32 = 10
+		if i == 2 {
+			expire = 0
+		}
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				ClientMsgNo: fmt.Sprintf("clientMsgNo%d", i+1),
+				FromUID:     "u1",
+				Timestamp:   int32(now.Add(-time.Minute).Unix()),
+				Expire:      expire,
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 搜索不返回过期消息
+	resultMessages, err := d.SearchMessages(wkdb.MessageSearchReq{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Limit:       10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+	assert.Equal(t, int64(3), resultMessages[0].MessageID)
+
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		FromUid: "u1",
+		Limit:   10,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+
+	// 过期消息只在读取时隐藏，频道日志保持连续
+	resultMessages, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 4)
+	for i, m := range resultMessages {
+		assert.Equal(t, uint32(i+1), m.MessageSeq)
+	}
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	dir := t.TempDir()
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	now := time.Now()
+
+	// 1,2,4 已过期，3 永不过期
+	messages := []wkdb.Message{}
+	for i := 0; i < 4; i++ {
+		var expire uint32 = 10
+		if i == 2 {
+			expire = 0
+		}
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				ClientMsgNo: fmt.Sprintf("clientMsgNo%d", i+1),
+				FromUID:     "u1",
+				Timestamp:   int32(now.Add(-time.Minute).Unix()),
+				Expire:      expire,
+				Payload:     []byte(`{"type":1,"content":"hello"}`),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	expiredMessages, err := d.GetExpiredMessages(now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, expiredMessages, 3)
+	expiredSeqs := make([]uint64, 0, len(expiredMessages))
+	for _, m := range expiredMessages {
+		expiredSeqs = append(expiredSeqs, uint64(m.MessageSeq))
+	}
+	assert.ElementsMatch(t, []uint64{1, 2, 4}, expiredSeqs)
+
+	count, err := d.DeleteExpiredMessages(channelId, channelType, expiredSeqs)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// 重复清理忽略
+	count, err = d.DeleteExpiredMessages(channelId, channelType, expiredSeqs)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	expiredMessages, err = d.GetExpiredMessages(now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, expiredMessages, 0)
+
+	// 频道日志保持连续，清理的消息只保留序号、任期等信息
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 4)
+	for i, m := range resultMessages {
+		assert.Equal(t, uint32(i+1), m.MessageSeq)
+		if i == 2 {
+			assert.Equal(t, messages[i].Payload, m.Payload)
+			continue
+		}
+		assert.Empty(t, m.Payload)
+		assert.Empty(t, m.FromUID)
+		assert.Empty(t, m.ClientMsgNo)
+	}
+
+	err = d.Close()
+	assert.NoError(t, err)
+
+	// 过期消息的所有索引都已删除
+	pdb, err := pebble.Open(filepath.Join(dir, "wukongimdb", "shard000"), &pebble.Options{})
+	assert.NoError(t, err)
+	defer pdb.Close()
+
+	exists := func(k []byte) bool {
+		_, closer, err := pdb.Get(k)
+		if err == pebble.ErrNotFound {
+			return false
+		}
+		assert.NoError(t, err)
+		closer.Close()
+		return true
+	}
+	for _, m := range messages {
+		var primary [16]byte
+		binary.BigEndian.PutUint64(primary[:], key.ChannelIdToNum(channelId, channelType))
+		binary.BigEndian.PutUint64(primary[8:], uint64(m.MessageSeq))
+
+		indexKeys := [][]byte{
+			key.NewMessageIndexMessageIdKey(uint64(m.MessageID)),
+			key.NewMessageSecondIndexFromUidKey(m.FromUID, primary),
+			key.NewMessageSecondIndexClientMsgNoKey(m.ClientMsgNo, primary),
+			key.NewMessageIndexTimestampKey(uint64(m.Timestamp), primary),
+			key.NewMessageSecondIndexFromUidTimestampKey(m.FromUID, uint64(m.Timestamp), primary),
+			key.NewMessageDedupKey(channelId, channelType, key.MessageDedupHash(m.FromUID, m.ClientMsgNo)),
+			key.NewMessageTermKey("hello", uint64(m.MessageID)),
+			key.NewMessageColumnKey(channelId, channelType, uint64(m.MessageSeq), key.TableMessage.Column.Payload),
+		}
+		expired := m.Expire > 0
+		for _, k := range indexKeys {
+			assert.Equal(t, !expired, exists(k), "messageSeq: %d, key: %v", m.MessageSeq, k)
+		}
+		assert.False(t, exists(key.NewMessageSecondIndexExpireKey(uint64(m.Timestamp)+uint64(m.Expire), primary)))
+		assert.True(t, exists(key.NewMessageColumnKey(channelId, channelType, uint64(m.MessageSeq), key.TableMessage.Column.Term)))
+	}
+}
+
+func TestDeleteMessagesBefore(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	messages := []wkdb.Message{}
+	for i := 0; i < 10; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				MessageID:   int64(i + 1),
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageSeq:  uint32(i + 1),
+				FromUID:     "u1",
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	count, err := d.DeleteMessagesBefore(channelId, channelType, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	firstSeq, err := d.GetChannelFirstMessageSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), firstSeq)
+
+	lastSeq, _, err := d.GetChannelLastMessageSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), lastSeq)
+
+	resultMessages, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 5)
+	assert.Equal(t, uint32(6), resultMessages[0].MessageSeq)
+
+	_, err = d.GetMessage(1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	// 最后一条消息不会被删除
+	count, err = d.DeleteMessagesBefore(channelId, channelType, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	resultMessages, err = d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+	assert.Equal(t, uint32(10), resultMessages[0].MessageSeq)
+
+	resultMessages, err = d.SearchMessages(wkdb.MessageSearchReq{
+		FromUid: "u1",
+		Limit:   100,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, resultMessages, 1)
+}
@@ -1,6 +1,7 @@
 package wkdb
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -22,7 +23,18 @@ func IsEmptyMessage(m Message) bool {
 
 type Message struct {
 	wkproto.RecvPacket
-	Term uint64 // raft term
+	Term     uint64 // raft term
+	Revoke   bool   // 是否已撤回
+	Version  uint32 // 编辑版本号（0表示未编辑过）
+	EditedAt int32  // 最后一次编辑时间(10位，到秒)
+}
+
+// IsExpired 消息是否已过期（Expire为0表示永不过期）
+func (m *Message) IsExpired(now time.Time) bool {
+	if m.Expire == 0 {
+		return false
+	}
+	return int64(m.Timestamp)+int64(m.Expire) <= now.Unix()
 }
 
 func (m *Message) Unmarshal(data []byte) error {
@@ -115,6 +127,13 @@ type ChannelInfo struct {
 	AllowlistCount  int    `json:"allowlist_count,omitempty"`  // 白名单数量
 	LastMsgSeq      uint64 `json:"last_msg_seq,omitempty"`     // 最新消息序号
 	LastMsgTime     uint64 `json:"last_msg_time,omitempty"`    // 最后一次消息时间
+	// 消息保留策略，0表示不限制
+	RetentionMaxAge   uint32 `json:"retention_max_age,omitempty"`   // 消息最长保留时间（单位秒）
+	RetentionMaxCount uint32 `json:"retention_max_count,omitempty"` // 消息最多保留数量
+	OnlyAdminSend     bool   `json:"only_admin_send,omitempty"`     // 是否仅管理员（群主和管理员）可发言，用于公告频道
+	// 历史消息可见策略
+	HistoryVisibility   uint8  `json:"history_visibility,omitempty"`    // 成员可见的历史消息 0.全部 1.加入后的消息 2.加入前最近N条及加入后的消息
+	HistoryVisibleCount uint32 `json:"history_visible_count,omitempty"` // 加入前可见的消息数量N（HistoryVisibility为2时有效）
 }
 
 func NewChannelInfo(channelId string, channelType uint8) ChannelInfo {
@@ -137,6 +156,11 @@ func (c *ChannelInfo) Marshal() ([]byte, error) {
 	enc.WriteUint8(wkutil.BoolToUint8(c.Ban))
 	enc.WriteUint8(wkutil.BoolToUint8(c.Large))
 	enc.WriteUint8(wkutil.BoolToUint8(c.Disband))
+	enc.WriteUint32(c.RetentionMaxAge)
+	enc.WriteUint32(c.RetentionMaxCount)
+	enc.WriteUint8(wkutil.BoolToUint8(c.OnlyAdminSend))
+	enc.WriteUint8(c.HistoryVisibility)
+	enc.WriteUint32(c.HistoryVisibleCount)
 	return enc.Bytes(), nil
 }
 
@@ -166,6 +190,33 @@ func (c *ChannelInfo) Unmarshal(data []byte) error {
 	c.Large = wkutil.Uint8ToBool(large)
 
 	c.Disband = wkutil.Uint8ToBool(disband)
+
+	// 兼容旧数据，旧数据没有保留策略
+	if dec.Len() > 0 {
+		if c.RetentionMaxAge, err = dec.Uint32(); err != nil {
+			return err
+		}
+		if c.RetentionMaxCount, err = dec.Uint32(); err != nil {
+			return err
+		}
+	}
+	// 兼容旧数据，旧数据没有仅管理员可发言
+	if dec.Len() > 0 {
+		var onlyAdminSend uint8
+		if onlyAdminSend, err = dec.Uint8(); err != nil {
+			return err
+		}
+		c.OnlyAdminSend = wkutil.Uint8ToBool(onlyAdminSend)
+	}
+	// 兼容旧数据，旧数据没有历史消息可见策略
+	if dec.Len() > 0 {
+		if c.HistoryVisibility, err = dec.Uint8(); err != nil {
+			return err
+		}
+		if c.HistoryVisibleCount, err = dec.Uint32(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -627,6 +678,41 @@ type SetChannelLastMessageSeqReq struct {
 	Seq         uint64 `json:"seq,omitempty"`
 }
 
+// ChannelMessageCmd 频道的消息命令（撤回、编辑、清理等需要在频道每个副本上执行的命令）
+type ChannelMessageCmd struct {
+	ChannelId   string
+	ChannelType uint8
+	Index       uint64 // 命令的序号（槽日志的下标，频道内递增）
+	Data        []byte // 命令数据
+}
+
+// MessageEdit 消息的某个编辑版本
+type MessageEdit struct {
+	MessageId int64  `json:"message_id,omitempty"`
+	Version   uint32 `json:"version,omitempty"`   // 版本号
+	Payload   []byte `json:"payload,omitempty"`   // 此版本的消息内容
+	EditedAt  int32  `json:"edited_at,omitempty"` // 编辑时间(10位，到秒)
+	LogSeq    uint64 `json:"-"`                   // 产生此版本的编辑命令的序号
+}
+
+// StreamMeta 流元数据
+type StreamMeta struct {
+	StreamNo    string             `json:"stream_no,omitempty"`    // 流编号
+	ChannelId   string             `json:"channel_id,omitempty"`   // 频道ID
+	ChannelType uint8              `json:"channel_type,omitempty"` // 频道类型
+	MessageId   int64              `json:"message_id,omitempty"`   // 流开始消息的ID
+	FromUid     string             `json:"from_uid,omitempty"`     // 发送者
+	StreamFlag  wkproto.StreamFlag `json:"stream_flag,omitempty"`  // 流标记
+	CreatedAt   int64              `json:"created_at,omitempty"`   // 创建时间(10位，到秒)
+}
+
+// StreamItem 流元素
+type StreamItem struct {
+	StreamSeq   uint32 `json:"stream_seq,omitempty"`    // 流序号
+	ClientMsgNo string `json:"client_msg_no,omitempty"` // 客户端消息唯一编号
+	Payload     []byte `json:"payload,omitempty"`       // 内容
+}
+
 type AppendMessagesReq struct {
 	ChannelId   string    `json:"channel_id,omitempty"`
 	ChannelType uint8     `json:"channel_type,omitempty"`
@@ -656,3 +742,193 @@ type Channel struct {
 	ChannelId   string `json:"channel_id,omitempty"`
 	ChannelType uint8  `json:"channel_type,omitempty"`
 }
+
+// ScheduledMessage 定时消息
+type ScheduledMessage struct {
+	Id          uint64 `json:"id,omitempty"`
+	SendAt      int64  `json:"send_at,omitempty"` // 计划发送时间(10位，到秒)
+	FromUid     string `json:"from_uid,omitempty"`
+	ChannelId   string `json:"channel_id,omitempty"`
+	ChannelType uint8  `json:"channel_type,omitempty"`
+	ClientMsgNo string `json:"client_msg_no,omitempty"`
+	RedDot      bool   `json:"red_dot,omitempty"`
+	SyncOnce    bool   `json:"sync_once,omitempty"`
+	NoPersist   bool   `json:"no_persist,omitempty"`
+	Expire      uint32 `json:"expire,omitempty"`
+	Payload     []byte `json:"payload,omitempty"`
+	CreatedAt   int64  `json:"created_at,omitempty"`
+}
+
+// Reaction 消息回应
+type Reaction struct {
+	ChannelId   string `json:"channel_id,omitempty"`
+	ChannelType uint8  `json:"channel_type,omitempty"`
+	MessageId   int64  `json:"message_id,omitempty"`
+	MessageSeq  uint64 `json:"message_seq,omitempty"`
+	Uid         string `json:"uid,omitempty"`
+	Emoji       string `json:"emoji,omitempty"`
+	IsDeleted   bool   `json:"is_deleted,omitempty"`
+	Version     uint64 `json:"version,omitempty"` // 频道内回应的版本号
+	CreatedAt   int64  `json:"created_at,omitempty"`
+}
+
+// MessageReceipt 用户对频道内消息的已读回执
+type MessageReceipt struct {
+	ChannelId   string   `json:"channel_id,omitempty"`
+	ChannelType uint8    `json:"channel_type,omitempty"`
+	Uid         string   `json:"uid,omitempty"`          // 已读用户
+	MessageSeqs []uint64 `json:"message_seqs,omitempty"` // 已读的消息序号
+	ReadedAt    int64    `json:"readed_at,omitempty"`    // 已读时间
+}
+
+// MessageReader 消息的已读用户
+type MessageReader struct {
+	Uid      string `json:"uid,omitempty"`
+	ReadedAt int64  `json:"readed_at,omitempty"`
+}
+
+// MessageDedup 消息去重索引指向的原消息
+type MessageDedup struct {
+	MessageId  int64  // 消息ID
+	MessageSeq uint64 // 消息序号
+	Timestamp  int64  // 消息时间（秒）
+}
+
+// SensitiveWord 敏感词规则
+type SensitiveWord struct {
+	Word      string `json:"word"`       // 敏感词或正则表达式
+	Regex     bool   `json:"regex"`      // 是否为正则表达式
+	Action    uint8  `json:"action"`     // 命中后的处理方式，由上层定义
+	CreatedAt int64  `json:"created_at"` // 创建时间（秒）
+}
+
+// RateLimit 用户或频道的发送频率限制规则（覆盖全局配置）
+type RateLimit struct {
+	TargetType  uint8   `json:"target_type"`  // 规则类型，由上层定义
+	Target      string  `json:"target"`       // 用户uid或频道id
+	ChannelType uint8   `json:"channel_type"` // 频道类型（频道规则）
+	Rate        float64 `json:"rate"`         // 每秒发送数量
+	Burst       int     `json:"burst"`        // 允许的突发数量
+	MemberRate  float64 `json:"member_rate"`  // 频道内每个用户每秒发送数量（频道规则）
+	MemberBurst int     `json:"member_burst"` // 频道内每个用户允许的突发数量（频道规则）
+}
+
+// UserBan 用户封禁
+type UserBan struct {
+	Uid       string `json:"uid"`
+	Reason    string `json:"reason"`     // 封禁原因
+	ExpireAt  int64  `json:"expire_at"`  // 封禁到期时间（秒），0表示永久
+	CreatedAt int64  `json:"created_at"` // 封禁时间（秒）
+}
+
+// Expired 封禁是否已到期
+func (u UserBan) Expired(now int64) bool {
+	return u.ExpireAt > 0 && u.ExpireAt <= now
+}
+
+const (
+	PushTypeAPNs uint8 = 1 // 苹果推送
+	PushTypeFCM  uint8 = 2 // Firebase推送
+)
+
+// DevicePush 设备推送令牌
+type DevicePush struct {
+	Uid        string `json:"uid"`
+	DeviceFlag uint64 `json:"device_flag"` // 设备标识
+	PushType   uint8  `json:"push_type"`   // 推送类型
+	PushToken  string `json:"push_token"`  // 推送令牌
+	UpdatedAt  int64  `json:"updated_at"`  // 更新时间（秒）
+}
+
+const (
+	WebhookQueueOutbox     uint8 = 0 // 发件箱（待推送）
+	WebhookQueueDeadLetter uint8 = 1 // 死信（超过最大重试次数）
+)
+
+// WebhookEvent webhook事件
+type WebhookEvent struct {
+	Id          uint64          `json:"id"`
+	Endpoint    string          `json:"endpoint"`             // 推送的端点名称
+	Event       string          `json:"event"`                // 事件标示
+	Data        json.RawMessage `json:"data"`                 // 事件数据（json）
+	CreatedAt   int64           `json:"created_at"`           // 创建时间（秒）
+	Attempts    int             `json:"attempts"`             // 已推送次数
+	NextRetryAt int64           `json:"next_retry_at"`        // 下次推送时间（毫秒）
+	LastError   string          `json:"last_error,omitempty"` // 最后一次推送失败的原因
+	DeadAt      int64           `json:"dead_at,omitempty"`    // 移入死信的时间（秒）
+}
+
+// WebhookEndpoint webhook端点
+type WebhookEndpoint struct {
+	Name        string   `json:"name"`             // 端点名称（唯一）
+	Addr        string   `json:"addr"`             // 端点地址，http(s)://开头为http，否则为grpc地址（ip:port）
+	Events      []string `json:"events,omitempty"` // 订阅的事件，为空表示订阅所有事件
+	Secret      string   `json:"secret,omitempty"` // 签名密钥，为空则使用全局密钥
+	Concurrency int      `json:"concurrency"`      // 推送并发数
+	CreatedAt   int64    `json:"created_at"`       // 创建时间（秒）
+}
+
+// Subscribed 是否订阅了事件
+func (w WebhookEndpoint) Subscribed(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ConversationMute 会话免打扰
+type ConversationMute struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	ExpireAt    int64  `json:"expire_at"` // 免打扰到期时间（秒），0表示永久
+}
+
+// Expired 免打扰是否已到期
+func (c ConversationMute) Expired(now int64) bool {
+	return c.ExpireAt > 0 && c.ExpireAt <= now
+}
+
+const (
+	SubscriberRoleMember uint8 = 0   // 普通成员
+	SubscriberRoleOwner  uint8 = 1   // 群主
+	SubscriberRoleAdmin  uint8 = 2   // 管理员
+	SubscriberRoleCustom uint8 = 100 // 自定义角色从100开始，权限等同于普通成员
+)
+
+// Subscriber 订阅者
+type Subscriber struct {
+	Uid        string          `json:"uid"`
+	Role       uint8           `json:"role"`                  // 角色
+	JoinedAt   int64           `json:"joined_at,omitempty"`   // 加入时间（秒）
+	JoinSeq    uint64          `json:"join_seq,omitempty"`    // 加入时频道的最新消息序号
+	InviterUid string          `json:"inviter_uid,omitempty"` // 邀请人
+	Nickname   string          `json:"nickname,omitempty"`    // 频道内昵称
+	Extra      json.RawMessage `json:"extra,omitempty"`       // 自定义扩展数据（json）
+}
+
+// IsAdmin 是否是群主或管理员
+func (s Subscriber) IsAdmin() bool {
+	return s.Role == SubscriberRoleOwner || s.Role == SubscriberRoleAdmin
+}
+
+// ChannelMute 频道禁言
+type ChannelMute struct {
+	Uid      string `json:"uid"`       // 被禁言的用户，为空表示全员禁言
+	ExpireAt int64  `json:"expire_at"` // 禁言到期时间（秒），0表示永久
+}
+
+// Expired 禁言是否已到期
+func (c ChannelMute) Expired(now int64) bool {
+	return c.ExpireAt > 0 && c.ExpireAt <= now
+}
+
+// MessageReadCount 消息的已读数量
+type MessageReadCount struct {
+	MessageSeq uint64 `json:"message_seq,omitempty"`
+	Count      uint32 `json:"count,omitempty"`
+}
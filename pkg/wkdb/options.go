@@ -1,5 +1,7 @@
 package wkdb
 
+import "time"
+
 type Options struct {
 	NodeId            uint64
 	DataDir           string
@@ -9,6 +11,10 @@ type Options struct {
 	EnableCost   bool
 	ShardNum     int               // 数据库分区数量，一但设置就不能修改
 	IsCmdChannel func(string) bool // 是否是cmd频道
+	// 是否开启消息全文索引
+	FullTextIndex bool
+	// 消息去重窗口，窗口内发送者重复的客户端消息编号视为重复消息，为0表示不去重
+	MessageDedupWindow time.Duration
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -18,6 +24,9 @@ func NewOptions(opt ...Option) *Options {
 		SlotCount:         128,
 		EnableCost:        true,
 		ShardNum:          16,
+
+		FullTextIndex:      true,
+		MessageDedupWindow: time.Minute * 10,
 	}
 	for _, f := range opt {
 		f(o)
@@ -68,3 +77,15 @@ func WithIsCmdChannel(f func(string) bool) Option {
 		o.IsCmdChannel = f
 	}
 }
+
+func WithFullTextIndex(fullTextIndex bool) Option {
+	return func(o *Options) {
+		o.FullTextIndex = fullTextIndex
+	}
+}
+
+func WithMessageDedupWindow(window time.Duration) Option {
+	return func(o *Options) {
+		o.MessageDedupWindow = window
+	}
+}
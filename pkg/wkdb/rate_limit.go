@@ -0,0 +1,129 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateRateLimits(limits []RateLimit) error {
+	if len(limits) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, l := range limits {
+		targetHash := key.RateLimitHash(l.TargetType, l.Target, l.ChannelType)
+
+		// target
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.Target), []byte(l.Target), wk.noSync); err != nil {
+			return err
+		}
+
+		// targetType
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.TargetType), []byte{l.TargetType}, wk.noSync); err != nil {
+			return err
+		}
+
+		// channelType
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.ChannelType), []byte{l.ChannelType}, wk.noSync); err != nil {
+			return err
+		}
+
+		// rate
+		rateBytes := make([]byte, 8)
+		wk.endian.PutUint64(rateBytes, math.Float64bits(l.Rate))
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.Rate), rateBytes, wk.noSync); err != nil {
+			return err
+		}
+
+		// burst
+		burstBytes := make([]byte, 8)
+		wk.endian.PutUint64(burstBytes, uint64(l.Burst))
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.Burst), burstBytes, wk.noSync); err != nil {
+			return err
+		}
+
+		// memberRate
+		memberRateBytes := make([]byte, 8)
+		wk.endian.PutUint64(memberRateBytes, math.Float64bits(l.MemberRate))
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.MemberRate), memberRateBytes, wk.noSync); err != nil {
+			return err
+		}
+
+		// memberBurst
+		memberBurstBytes := make([]byte, 8)
+		wk.endian.PutUint64(memberBurstBytes, uint64(l.MemberBurst))
+		if err := batch.Set(key.NewRateLimitColumnKeyWithHash(targetHash, key.TableRateLimit.Column.MemberBurst), memberBurstBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveRateLimits(limits []RateLimit) error {
+	if len(limits) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, l := range limits {
+		targetHash := key.RateLimitHash(l.TargetType, l.Target, l.ChannelType)
+		if err := batch.DeleteRange(key.NewRateLimitColumnKeyWithHash(targetHash, key.MinColumnKey), key.NewRateLimitColumnKeyWithHash(targetHash, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetRateLimits() ([]RateLimit, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewRateLimitColumnKeyWithHash(0, key.MinColumnKey),
+		UpperBound: key.NewRateLimitColumnKeyWithHash(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		limits      = make([]RateLimit, 0)
+		preHash     uint64
+		preLimit    RateLimit
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		targetHash, columnName, err := key.ParseRateLimitColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if targetHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				limits = append(limits, preLimit)
+			}
+			preHash = targetHash
+			preLimit = RateLimit{}
+		}
+		switch columnName {
+		case key.TableRateLimit.Column.Target:
+			preLimit.Target = string(iter.Value())
+		case key.TableRateLimit.Column.TargetType:
+			preLimit.TargetType = iter.Value()[0]
+		case key.TableRateLimit.Column.ChannelType:
+			preLimit.ChannelType = iter.Value()[0]
+		case key.TableRateLimit.Column.Rate:
+			preLimit.Rate = math.Float64frombits(wk.endian.Uint64(iter.Value()))
+		case key.TableRateLimit.Column.Burst:
+			preLimit.Burst = int(wk.endian.Uint64(iter.Value()))
+		case key.TableRateLimit.Column.MemberRate:
+			preLimit.MemberRate = math.Float64frombits(wk.endian.Uint64(iter.Value()))
+		case key.TableRateLimit.Column.MemberBurst:
+			preLimit.MemberBurst = int(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		limits = append(limits, preLimit)
+	}
+	return limits, nil
+}
@@ -0,0 +1,41 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimits(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	userLimit := wkdb.RateLimit{TargetType: 1, Target: "u1", Rate: 0.5, Burst: 2, MemberRate: -1, MemberBurst: -1}
+	channelLimit := wkdb.RateLimit{TargetType: 2, Target: "g1", ChannelType: 2, Rate: 10, Burst: 20, MemberRate: 1, MemberBurst: 3}
+	err = d.AddOrUpdateRateLimits([]wkdb.RateLimit{userLimit, channelLimit})
+	assert.NoError(t, err)
+
+	limits, err := d.GetRateLimits()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.RateLimit{userLimit, channelLimit}, limits)
+
+	// 更新
+	channelLimit.Rate = 5
+	err = d.AddOrUpdateRateLimits([]wkdb.RateLimit{channelLimit})
+	assert.NoError(t, err)
+
+	// 移除
+	err = d.RemoveRateLimits([]wkdb.RateLimit{{TargetType: 1, Target: "u1"}})
+	assert.NoError(t, err)
+
+	limits, err = d.GetRateLimits()
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.RateLimit{channelLimit}, limits)
+}
@@ -0,0 +1,282 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateReaction(reaction Reaction) error {
+	wk.dblock.reactionLock.lockByChannel(reaction.ChannelId, reaction.ChannelType)
+	defer wk.dblock.reactionLock.unlockByChannel(reaction.ChannelId, reaction.ChannelType)
+
+	db := wk.channelDb(reaction.ChannelId, reaction.ChannelType)
+	primaryKey := key.NewReactionPrimaryKey(reaction.MessageSeq, reaction.Uid, reaction.Emoji)
+
+	// 版本号由调用方分配（集群中为槽日志的下标，保证各副本一致），不大于频道当前版本号的表示已应用过
+	channelVersion, err := wk.getReactionVersion(db, reaction.ChannelId, reaction.ChannelType)
+	if err != nil {
+		return err
+	}
+	if reaction.Version <= channelVersion {
+		return nil
+	}
+	version := reaction.Version
+
+	// 获取旧的回应
+	oldVersion, oldIsDeleted, exist, err := wk.getReactionState(db, reaction.ChannelId, reaction.ChannelType, primaryKey)
+	if err != nil {
+		return err
+	}
+	if !exist && reaction.IsDeleted { // 不存在的回应无需删除
+		return nil
+	}
+	if exist && oldIsDeleted == reaction.IsDeleted { // 没有变化
+		return nil
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 删除旧的版本索引
+	if exist {
+		if err = batch.Delete(key.NewReactionSecondIndexVersionKey(reaction.ChannelId, reaction.ChannelType, oldVersion, primaryKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	if err = wk.writeReaction(reaction, primaryKey, batch); err != nil {
+		return err
+	}
+
+	// 版本索引
+	if err = batch.Set(key.NewReactionSecondIndexVersionKey(reaction.ChannelId, reaction.ChannelType, version, primaryKey), nil, wk.noSync); err != nil {
+		return err
+	}
+
+	// 频道回应版本号
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, version)
+	if err = batch.Set(key.NewChannelCommonColumnKey(reaction.ChannelId, reaction.ChannelType, key.TableChannelCommon.Column.ReactionVersion), versionBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetReactions(channelId string, channelType uint8, messageSeq uint64) ([]Reaction, error) {
+	minPrimaryKey, maxPrimaryKey := key.NewReactionMessagePrimaryKeyRange(messageSeq)
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionColumnKey(channelId, channelType, minPrimaryKey, key.MinColumnKey),
+		UpperBound: key.NewReactionColumnKey(channelId, channelType, maxPrimaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	reactions, err := wk.parseReactions(iter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Reaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		if reaction.IsDeleted {
+			continue
+		}
+		results = append(results, reaction)
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) GetReactionsAfterVersion(channelId string, channelType uint8, version uint64, limit int) ([]Reaction, error) {
+	var maxPrimaryKey [24]byte
+	for i := range maxPrimaryKey {
+		maxPrimaryKey[i] = 0xff
+	}
+	db := wk.channelDb(channelId, channelType)
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionSecondIndexVersionKey(channelId, channelType, version+1, [24]byte{}),
+		UpperBound: key.NewReactionSecondIndexVersionKey(channelId, channelType, math.MaxUint64, maxPrimaryKey),
+	})
+	defer iter.Close()
+
+	results := make([]Reaction, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, primaryKey, err := key.ParseReactionSecondIndexVersionKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		reaction, err := wk.getReaction(db, channelId, channelType, primaryKey)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		results = append(results, reaction)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) getReaction(db *pebble.DB, channelId string, channelType uint8, primaryKey [24]byte) (Reaction, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewReactionColumnKey(channelId, channelType, primaryKey, key.MinColumnKey),
+		UpperBound: key.NewReactionColumnKey(channelId, channelType, primaryKey, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	reactions, err := wk.parseReactions(iter)
+	if err != nil {
+		return Reaction{}, err
+	}
+	if len(reactions) == 0 {
+		return Reaction{}, ErrNotFound
+	}
+	return reactions[0], nil
+}
+
+// getReactionState 获取回应的版本号和删除状态
+func (wk *wukongDB) getReactionState(db *pebble.DB, channelId string, channelType uint8, primaryKey [24]byte) (version uint64, isDeleted bool, exist bool, err error) {
+	versionBytes, closer, err := db.Get(key.NewReactionColumnKey(channelId, channelType, primaryKey, key.TableReaction.Column.Version))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, false, false, nil
+		}
+		return 0, false, false, err
+	}
+	version = wk.endian.Uint64(versionBytes)
+	closer.Close()
+
+	isDeletedBytes, closer, err := db.Get(key.NewReactionColumnKey(channelId, channelType, primaryKey, key.TableReaction.Column.IsDeleted))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return version, false, true, nil
+		}
+		return 0, false, false, err
+	}
+	isDeleted = wkutil.Uint8ToBool(isDeletedBytes[0])
+	closer.Close()
+	return version, isDeleted, true, nil
+}
+
+// getReactionVersion 获取频道当前的回应版本号
+func (wk *wukongDB) getReactionVersion(db *pebble.DB, channelId string, channelType uint8) (uint64, error) {
+	data, closer, err := db.Get(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.ReactionVersion))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return wk.endian.Uint64(data), nil
+}
+
+func (wk *wukongDB) writeReaction(reaction Reaction, primaryKey [24]byte, w pebble.Writer) error {
+	var err error
+
+	// channelId
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.ChannelId), []byte(reaction.ChannelId), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelType
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.ChannelType), []byte{reaction.ChannelType}, wk.noSync); err != nil {
+		return err
+	}
+
+	// messageId
+	messageIdBytes := make([]byte, 8)
+	wk.endian.PutUint64(messageIdBytes, uint64(reaction.MessageId))
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.MessageId), messageIdBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// messageSeq
+	messageSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(messageSeqBytes, reaction.MessageSeq)
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.MessageSeq), messageSeqBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// uid
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.Uid), []byte(reaction.Uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// emoji
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.Emoji), []byte(reaction.Emoji), wk.noSync); err != nil {
+		return err
+	}
+
+	// isDeleted
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.IsDeleted), []byte{wkutil.BoolToUint8(reaction.IsDeleted)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// version
+	versionBytes := make([]byte, 8)
+	wk.endian.PutUint64(versionBytes, reaction.Version)
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.Version), versionBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	createdAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(createdAtBytes, uint64(reaction.CreatedAt))
+	if err = w.Set(key.NewReactionColumnKey(reaction.ChannelId, reaction.ChannelType, primaryKey, key.TableReaction.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (wk *wukongDB) parseReactions(iter *pebble.Iterator) ([]Reaction, error) {
+	var (
+		reactions     = make([]Reaction, 0)
+		prePrimaryKey [24]byte
+		preReaction   Reaction
+		lastNeedAdd   bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		primaryKey, columnName, err := key.ParseReactionColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if primaryKey != prePrimaryKey || !lastNeedAdd {
+			if lastNeedAdd {
+				reactions = append(reactions, preReaction)
+			}
+			prePrimaryKey = primaryKey
+			preReaction = Reaction{}
+		}
+		switch columnName {
+		case key.TableReaction.Column.ChannelId:
+			preReaction.ChannelId = string(iter.Value())
+		case key.TableReaction.Column.ChannelType:
+			preReaction.ChannelType = iter.Value()[0]
+		case key.TableReaction.Column.MessageId:
+			preReaction.MessageId = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableReaction.Column.MessageSeq:
+			preReaction.MessageSeq = wk.endian.Uint64(iter.Value())
+		case key.TableReaction.Column.Uid:
+			preReaction.Uid = string(iter.Value())
+		case key.TableReaction.Column.Emoji:
+			preReaction.Emoji = string(iter.Value())
+		case key.TableReaction.Column.IsDeleted:
+			preReaction.IsDeleted = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableReaction.Column.Version:
+			preReaction.Version = wk.endian.Uint64(iter.Value())
+		case key.TableReaction.Column.CreatedAt:
+			preReaction.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		reactions = append(reactions, preReaction)
+	}
+	return reactions, nil
+}
@@ -0,0 +1,89 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOrUpdateReaction(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	reaction := wkdb.Reaction{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   100,
+		MessageSeq:  1,
+		Uid:         "u1",
+		Emoji:       "👍",
+		CreatedAt:   1000,
+		Version:     1,
+	}
+	err = d.AddOrUpdateReaction(reaction)
+	assert.NoError(t, err)
+
+	// 重复应用同一版本忽略
+	err = d.AddOrUpdateReaction(reaction)
+	assert.NoError(t, err)
+
+	// 没有变化的回应不产生新的版本
+	reaction.Version = 2
+	err = d.AddOrUpdateReaction(reaction)
+	assert.NoError(t, err)
+
+	reaction2 := reaction
+	reaction2.Uid = "u2"
+	reaction2.Version = 3
+	err = d.AddOrUpdateReaction(reaction2)
+	assert.NoError(t, err)
+
+	reactions, err := d.GetReactions(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+
+	reactions, err = d.GetReactionsAfterVersion(channelId, channelType, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+	assert.Equal(t, uint64(1), reactions[0].Version)
+	assert.Equal(t, "u1", reactions[0].Uid)
+	assert.Equal(t, uint64(3), reactions[1].Version)
+
+	// 删除回应
+	reaction.IsDeleted = true
+	reaction.Version = 4
+	err = d.AddOrUpdateReaction(reaction)
+	assert.NoError(t, err)
+
+	reactions, err = d.GetReactions(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+	assert.Equal(t, "u2", reactions[0].Uid)
+
+	reactions, err = d.GetReactionsAfterVersion(channelId, channelType, 3, 0)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+	assert.Equal(t, "u1", reactions[0].Uid)
+	assert.True(t, reactions[0].IsDeleted)
+	assert.Equal(t, uint64(4), reactions[0].Version)
+
+	// 旧版本索引已删除
+	reactions, err = d.GetReactionsAfterVersion(channelId, channelType, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+
+	// 其他消息没有回应
+	reactions, err = d.GetReactions(channelId, channelType, 2)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 0)
+}
@@ -0,0 +1,211 @@
+package wkdb
+
+import (
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddMessageReceipt(receipt MessageReceipt, onlyCount bool) error {
+	wk.dblock.receiptLock.lockByChannel(receipt.ChannelId, receipt.ChannelType)
+	defer wk.dblock.receiptLock.unlockByChannel(receipt.ChannelId, receipt.ChannelType)
+
+	messageSeqs := uniqueMessageSeqs(receipt.MessageSeqs)
+	if len(messageSeqs) == 0 {
+		return nil
+	}
+
+	db := wk.channelDb(receipt.ChannelId, receipt.ChannelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	var err error
+	if onlyCount {
+		err = wk.addMessageReadCountsByCursor(db, receipt, messageSeqs, batch)
+	} else {
+		err = wk.addMessageReaders(db, receipt, messageSeqs, batch)
+	}
+	if err != nil {
+		return err
+	}
+	if batch.Empty() {
+		return nil
+	}
+	return batch.Commit(wk.sync)
+}
+
+// 保存已读用户并累加已读数量
+func (wk *wukongDB) addMessageReaders(db *pebble.DB, receipt MessageReceipt, messageSeqs []uint64, w pebble.Writer) error {
+	readedAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(readedAtBytes, uint64(receipt.ReadedAt))
+
+	for _, messageSeq := range messageSeqs {
+		uidKey := key.NewMessageReceiptColumnKey(receipt.ChannelId, receipt.ChannelType, messageSeq, receipt.Uid, key.TableMessageReceipt.Column.Uid)
+		exist, err := wk.hasKey(db, uidKey)
+		if err != nil {
+			return err
+		}
+		if exist { // 已回执过
+			continue
+		}
+		if err = w.Set(uidKey, []byte(receipt.Uid), wk.noSync); err != nil {
+			return err
+		}
+		if err = w.Set(key.NewMessageReceiptColumnKey(receipt.ChannelId, receipt.ChannelType, messageSeq, receipt.Uid, key.TableMessageReceipt.Column.ReadedAt), readedAtBytes, wk.noSync); err != nil {
+			return err
+		}
+		if err = wk.incMessageReadCount(db, receipt.ChannelId, receipt.ChannelType, messageSeq, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 只累加已读数量，通过用户的回执游标去重
+func (wk *wukongDB) addMessageReadCountsByCursor(db *pebble.DB, receipt MessageReceipt, messageSeqs []uint64, w pebble.Writer) error {
+	cursorKey := key.NewMessageReceiptCursorColumnKey(receipt.ChannelId, receipt.ChannelType, receipt.Uid, key.TableMessageReceiptCursor.Column.ReadedToMsgSeq)
+	cursor, err := wk.getUint64Value(db, cursorKey)
+	if err != nil {
+		return err
+	}
+	maxSeq := cursor
+	for _, messageSeq := range messageSeqs {
+		if messageSeq <= cursor {
+			continue
+		}
+		if err = wk.incMessageReadCount(db, receipt.ChannelId, receipt.ChannelType, messageSeq, w); err != nil {
+			return err
+		}
+		maxSeq = messageSeq
+	}
+	if maxSeq == cursor {
+		return nil
+	}
+	cursorBytes := make([]byte, 8)
+	wk.endian.PutUint64(cursorBytes, maxSeq)
+	return w.Set(cursorKey, cursorBytes, wk.noSync)
+}
+
+func (wk *wukongDB) incMessageReadCount(db *pebble.DB, channelId string, channelType uint8, messageSeq uint64, w pebble.Writer) error {
+	countKey := key.NewMessageReadCountColumnKey(channelId, channelType, messageSeq, key.TableMessageReadCount.Column.Count)
+	count, err := wk.getMessageReadCount(db, countKey)
+	if err != nil {
+		return err
+	}
+	countBytes := make([]byte, 4)
+	wk.endian.PutUint32(countBytes, count+1)
+	return w.Set(countKey, countBytes, wk.noSync)
+}
+
+func (wk *wukongDB) GetMessageReaders(channelId string, channelType uint8, messageSeq uint64) ([]MessageReader, error) {
+	lowKey, highKey := key.NewMessageReceiptMessageKeyRange(channelId, channelType, messageSeq)
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: lowKey,
+		UpperBound: highKey,
+	})
+	defer iter.Close()
+
+	var (
+		readers     = make([]MessageReader, 0)
+		preUidHash  uint64
+		preReader   MessageReader
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		uidHash, columnName, err := key.ParseMessageReceiptColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if uidHash != preUidHash || !lastNeedAdd {
+			if lastNeedAdd {
+				readers = append(readers, preReader)
+			}
+			preUidHash = uidHash
+			preReader = MessageReader{}
+		}
+		switch columnName {
+		case key.TableMessageReceipt.Column.Uid:
+			preReader.Uid = string(iter.Value())
+		case key.TableMessageReceipt.Column.ReadedAt:
+			preReader.ReadedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		readers = append(readers, preReader)
+	}
+	return readers, nil
+}
+
+func (wk *wukongDB) GetMessageReadCounts(channelId string, channelType uint8, messageSeqs []uint64) ([]MessageReadCount, error) {
+	db := wk.channelDb(channelId, channelType)
+	results := make([]MessageReadCount, 0, len(messageSeqs))
+	for _, messageSeq := range messageSeqs {
+		count, err := wk.getMessageReadCount(db, key.NewMessageReadCountColumnKey(channelId, channelType, messageSeq, key.TableMessageReadCount.Column.Count))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, MessageReadCount{
+			MessageSeq: messageSeq,
+			Count:      count,
+		})
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) getMessageReadCount(db *pebble.DB, countKey []byte) (uint32, error) {
+	data, closer, err := db.Get(countKey)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return wk.endian.Uint32(data), nil
+}
+
+func (wk *wukongDB) getUint64Value(db *pebble.DB, k []byte) (uint64, error) {
+	data, closer, err := db.Get(k)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	return wk.endian.Uint64(data), nil
+}
+
+func (wk *wukongDB) hasKey(db *pebble.DB, k []byte) (bool, error) {
+	_, closer, err := db.Get(k)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+// 去重并升序排列
+func uniqueMessageSeqs(messageSeqs []uint64) []uint64 {
+	results := make([]uint64, 0, len(messageSeqs))
+	exists := make(map[uint64]struct{}, len(messageSeqs))
+	for _, messageSeq := range messageSeqs {
+		if messageSeq == 0 {
+			continue
+		}
+		if _, ok := exists[messageSeq]; ok {
+			continue
+		}
+		exists[messageSeq] = struct{}{}
+		results = append(results, messageSeq)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i] < results[j]
+	})
+	return results
+}
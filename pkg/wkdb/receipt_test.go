@@ -0,0 +1,108 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddMessageReceipt(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	err = d.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         "u1",
+		MessageSeqs: []uint64{1, 2, 2},
+		ReadedAt:    1000,
+	}, false)
+	assert.NoError(t, err)
+
+	// 重复回执不累加
+	err = d.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         "u1",
+		MessageSeqs: []uint64{1},
+		ReadedAt:    1001,
+	}, false)
+	assert.NoError(t, err)
+
+	err = d.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         "u2",
+		MessageSeqs: []uint64{1},
+		ReadedAt:    1002,
+	}, false)
+	assert.NoError(t, err)
+
+	counts, err := d.GetMessageReadCounts(channelId, channelType, []uint64{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.MessageReadCount{{MessageSeq: 1, Count: 2}, {MessageSeq: 2, Count: 1}, {MessageSeq: 3, Count: 0}}, counts)
+
+	readers, err := d.GetMessageReaders(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, readers, 2)
+	for _, reader := range readers {
+		if reader.Uid == "u1" {
+			assert.Equal(t, int64(1000), reader.ReadedAt)
+		} else {
+			assert.Equal(t, "u2", reader.Uid)
+			assert.Equal(t, int64(1002), reader.ReadedAt)
+		}
+	}
+}
+
+func TestAddMessageReceiptOnlyCount(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	err = d.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         "u1",
+		MessageSeqs: []uint64{1, 2},
+	}, true)
+	assert.NoError(t, err)
+
+	// 小于等于游标的消息不再累加
+	err = d.AddMessageReceipt(wkdb.MessageReceipt{
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		Uid:         "u1",
+		MessageSeqs: []uint64{2, 3},
+	}, true)
+	assert.NoError(t, err)
+
+	counts, err := d.GetMessageReadCounts(channelId, channelType, []uint64{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), counts[0].Count)
+	assert.Equal(t, uint32(1), counts[1].Count)
+	assert.Equal(t, uint32(1), counts[2].Count)
+
+	// 不保存已读用户
+	readers, err := d.GetMessageReaders(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Len(t, readers, 0)
+}
@@ -0,0 +1,267 @@
+package wkdb
+
+import (
+	"math"
+	"sort"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddScheduledMessage(m ScheduledMessage) error {
+	db := wk.channelDb(m.ChannelId, m.ChannelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	channelHash := key.ChannelIdToNum(m.ChannelId, m.ChannelType)
+
+	// sendAt
+	sendAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(sendAtBytes, uint64(m.SendAt))
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.SendAt), sendAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// fromUid
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.FromUid), []byte(m.FromUid), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelId
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.ChannelId), []byte(m.ChannelId), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelType
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.ChannelType), []byte{m.ChannelType}, wk.noSync); err != nil {
+		return err
+	}
+
+	// clientMsgNo
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.ClientMsgNo), []byte(m.ClientMsgNo), wk.noSync); err != nil {
+		return err
+	}
+
+	// header
+	header := []byte{wkutil.BoolToUint8(m.RedDot), wkutil.BoolToUint8(m.SyncOnce), wkutil.BoolToUint8(m.NoPersist)}
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.Header), header, wk.noSync); err != nil {
+		return err
+	}
+
+	// expire
+	expireBytes := make([]byte, 4)
+	wk.endian.PutUint32(expireBytes, m.Expire)
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.Expire), expireBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// payload
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.Payload), m.Payload, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	createdAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(createdAtBytes, uint64(m.CreatedAt))
+	if err := batch.Set(key.NewScheduledMessageColumnKeyWithHash(channelHash, m.Id, key.TableScheduledMessage.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// sendAt index
+	if err := batch.Set(key.NewScheduledMessageSecondIndexSendAtKey(uint64(m.SendAt), channelHash, m.Id), nil, wk.noSync); err != nil {
+		return err
+	}
+
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) DeleteScheduledMessage(channelId string, channelType uint8, id uint64) error {
+	m, err := wk.GetScheduledMessage(channelId, channelType, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	err = batch.DeleteRange(key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MinColumnKey), key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MaxColumnKey), wk.noSync)
+	if err != nil {
+		return err
+	}
+	if err = batch.Delete(key.NewScheduledMessageSecondIndexSendAtKey(uint64(m.SendAt), channelHash, id), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetScheduledMessage(channelId string, channelType uint8, id uint64) (ScheduledMessage, error) {
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MinColumnKey),
+		UpperBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	messages, err := wk.parseScheduledMessages(iter)
+	if err != nil {
+		return ScheduledMessage{}, err
+	}
+	if len(messages) == 0 {
+		return ScheduledMessage{}, ErrNotFound
+	}
+	return messages[0], nil
+}
+
+func (wk *wukongDB) GetScheduledMessages(channelId string, channelType uint8) ([]ScheduledMessage, error) {
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, 0, key.MinColumnKey),
+		UpperBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	messages, err := wk.parseScheduledMessages(iter)
+	if err != nil {
+		return nil, err
+	}
+	// 频道hash可能冲突，过滤掉其他频道的定时消息
+	results := make([]ScheduledMessage, 0, len(messages))
+	for _, m := range messages {
+		if m.ChannelId == channelId && m.ChannelType == channelType {
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) GetDueScheduledMessages(sendAt int64, limit int) ([]ScheduledMessage, error) {
+	results := make([]ScheduledMessage, 0)
+	for _, db := range wk.dbs {
+		messages, err := wk.getDueScheduledMessages(db, sendAt, limit)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, messages...)
+	}
+	// 合并各分区结果后按发送时间排序
+	sortScheduledMessages(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) getDueScheduledMessages(db *pebble.DB, sendAt int64, limit int) ([]ScheduledMessage, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageSecondIndexSendAtKey(0, 0, 0),
+		UpperBound: key.NewScheduledMessageSecondIndexSendAtKey(uint64(sendAt), math.MaxUint64, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	results := make([]ScheduledMessage, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, channelHash, id, err := key.ParseScheduledMessageSecondIndexSendAtKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		m, err := wk.getScheduledMessageByHash(db, channelHash, id)
+		if err != nil {
+			if err == ErrNotFound {
+				continue
+			}
+			return nil, err
+		}
+		results = append(results, m)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func (wk *wukongDB) getScheduledMessageByHash(db *pebble.DB, channelHash uint64, id uint64) (ScheduledMessage, error) {
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MinColumnKey),
+		UpperBound: key.NewScheduledMessageColumnKeyWithHash(channelHash, id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	messages, err := wk.parseScheduledMessages(iter)
+	if err != nil {
+		return ScheduledMessage{}, err
+	}
+	if len(messages) == 0 {
+		return ScheduledMessage{}, ErrNotFound
+	}
+	return messages[0], nil
+}
+
+func (wk *wukongDB) parseScheduledMessages(iter *pebble.Iterator) ([]ScheduledMessage, error) {
+	var (
+		messages    = make([]ScheduledMessage, 0)
+		preId       uint64
+		preMessage  ScheduledMessage
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		id, columnName, err := key.ParseScheduledMessageColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if id != preId || !lastNeedAdd {
+			if lastNeedAdd {
+				messages = append(messages, preMessage)
+			}
+			preId = id
+			preMessage = ScheduledMessage{
+				Id: id,
+			}
+		}
+		switch columnName {
+		case key.TableScheduledMessage.Column.SendAt:
+			preMessage.SendAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableScheduledMessage.Column.FromUid:
+			preMessage.FromUid = string(iter.Value())
+		case key.TableScheduledMessage.Column.ChannelId:
+			preMessage.ChannelId = string(iter.Value())
+		case key.TableScheduledMessage.Column.ChannelType:
+			preMessage.ChannelType = iter.Value()[0]
+		case key.TableScheduledMessage.Column.ClientMsgNo:
+			preMessage.ClientMsgNo = string(iter.Value())
+		case key.TableScheduledMessage.Column.Header:
+			header := iter.Value()
+			preMessage.RedDot = wkutil.Uint8ToBool(header[0])
+			preMessage.SyncOnce = wkutil.Uint8ToBool(header[1])
+			preMessage.NoPersist = wkutil.Uint8ToBool(header[2])
+		case key.TableScheduledMessage.Column.Expire:
+			preMessage.Expire = wk.endian.Uint32(iter.Value())
+		case key.TableScheduledMessage.Column.Payload:
+			// 这里必须复制一份，否则会被pebble覆盖
+			var payload = make([]byte, len(iter.Value()))
+			copy(payload, iter.Value())
+			preMessage.Payload = payload
+		case key.TableScheduledMessage.Column.CreatedAt:
+			preMessage.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		messages = append(messages, preMessage)
+	}
+	return messages, nil
+}
+
+func sortScheduledMessages(messages []ScheduledMessage) {
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].SendAt == messages[j].SendAt {
+			return messages[i].Id < messages[j].Id
+		}
+		return messages[i].SendAt < messages[j].SendAt
+	})
+}
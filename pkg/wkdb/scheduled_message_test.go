@@ -0,0 +1,120 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddScheduledMessage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	m := wkdb.ScheduledMessage{
+		Id:          1,
+		SendAt:      1000,
+		FromUid:     "u1",
+		ChannelId:   "channel1",
+		ChannelType: 2,
+		ClientMsgNo: "no1",
+		RedDot:      true,
+		Expire:      60,
+		Payload:     []byte("hello"),
+		CreatedAt:   900,
+	}
+	err = d.AddScheduledMessage(m)
+	assert.NoError(t, err)
+
+	m2, err := d.GetScheduledMessage(m.ChannelId, m.ChannelType, m.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, m, m2)
+
+	_, err = d.GetScheduledMessage(m.ChannelId, m.ChannelType, 2)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
+
+func TestGetScheduledMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for i := 1; i <= 3; i++ {
+		err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+			Id:          uint64(i),
+			SendAt:      int64(1000 + i),
+			ChannelId:   "channel1",
+			ChannelType: 2,
+			Payload:     []byte("hello"),
+		})
+		assert.NoError(t, err)
+	}
+	err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+		Id:          4,
+		SendAt:      1000,
+		ChannelId:   "channel2",
+		ChannelType: 2,
+		Payload:     []byte("hello"),
+	})
+	assert.NoError(t, err)
+
+	messages, err := d.GetScheduledMessages("channel1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, uint64(1), messages[0].Id)
+	assert.Equal(t, uint64(3), messages[2].Id)
+}
+
+func TestGetDueScheduledMessages(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		err = d.AddScheduledMessage(wkdb.ScheduledMessage{
+			Id:          uint64(i),
+			SendAt:      int64(1000 + i),
+			ChannelId:   "channel1",
+			ChannelType: 2,
+			Payload:     []byte("hello"),
+		})
+		assert.NoError(t, err)
+	}
+
+	messages, err := d.GetDueScheduledMessages(1003, 0)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, int64(1001), messages[0].SendAt)
+	assert.Equal(t, int64(1003), messages[2].SendAt)
+
+	messages, err = d.GetDueScheduledMessages(1005, 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	err = d.DeleteScheduledMessage("channel1", 2, 1)
+	assert.NoError(t, err)
+
+	messages, err = d.GetDueScheduledMessages(1003, 0)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint64(2), messages[0].Id)
+
+	_, err = d.GetScheduledMessage("channel1", 2, 1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
@@ -0,0 +1,103 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateSensitiveWords(words []SensitiveWord) error {
+	if len(words) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, w := range words {
+		wordHash := key.HashWithString(w.Word)
+
+		// word
+		if err := batch.Set(key.NewSensitiveWordColumnKeyWithHash(wordHash, key.TableSensitiveWord.Column.Word), []byte(w.Word), wk.noSync); err != nil {
+			return err
+		}
+
+		// regex
+		if err := batch.Set(key.NewSensitiveWordColumnKeyWithHash(wordHash, key.TableSensitiveWord.Column.Regex), []byte{wkutil.BoolToUint8(w.Regex)}, wk.noSync); err != nil {
+			return err
+		}
+
+		// action
+		if err := batch.Set(key.NewSensitiveWordColumnKeyWithHash(wordHash, key.TableSensitiveWord.Column.Action), []byte{w.Action}, wk.noSync); err != nil {
+			return err
+		}
+
+		// createdAt
+		createdAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, uint64(w.CreatedAt))
+		if err := batch.Set(key.NewSensitiveWordColumnKeyWithHash(wordHash, key.TableSensitiveWord.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveSensitiveWords(words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, word := range words {
+		wordHash := key.HashWithString(word)
+		if err := batch.DeleteRange(key.NewSensitiveWordColumnKeyWithHash(wordHash, key.MinColumnKey), key.NewSensitiveWordColumnKeyWithHash(wordHash, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetSensitiveWords() ([]SensitiveWord, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSensitiveWordColumnKeyWithHash(0, key.MinColumnKey),
+		UpperBound: key.NewSensitiveWordColumnKeyWithHash(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		words       = make([]SensitiveWord, 0)
+		preHash     uint64
+		preWord     SensitiveWord
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		wordHash, columnName, err := key.ParseSensitiveWordColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if wordHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				words = append(words, preWord)
+			}
+			preHash = wordHash
+			preWord = SensitiveWord{}
+		}
+		switch columnName {
+		case key.TableSensitiveWord.Column.Word:
+			preWord.Word = string(iter.Value())
+		case key.TableSensitiveWord.Column.Regex:
+			preWord.Regex = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableSensitiveWord.Column.Action:
+			preWord.Action = iter.Value()[0]
+		case key.TableSensitiveWord.Column.CreatedAt:
+			preWord.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		words = append(words, preWord)
+	}
+	return words, nil
+}
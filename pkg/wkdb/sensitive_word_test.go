@@ -0,0 +1,49 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensitiveWords(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateSensitiveWords([]wkdb.SensitiveWord{
+		{Word: "word1", Action: 1, CreatedAt: 100},
+		{Word: "word2", Action: 2, CreatedAt: 101},
+		{Word: "\\d{11}", Regex: true, Action: 3, CreatedAt: 102},
+	})
+	assert.NoError(t, err)
+
+	words, err := d.GetSensitiveWords()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.SensitiveWord{
+		{Word: "word1", Action: 1, CreatedAt: 100},
+		{Word: "word2", Action: 2, CreatedAt: 101},
+		{Word: "\\d{11}", Regex: true, Action: 3, CreatedAt: 102},
+	}, words)
+
+	// 更新
+	err = d.AddOrUpdateSensitiveWords([]wkdb.SensitiveWord{{Word: "word1", Action: 3, CreatedAt: 103}})
+	assert.NoError(t, err)
+
+	// 移除
+	err = d.RemoveSensitiveWords([]string{"word2", "notexist"})
+	assert.NoError(t, err)
+
+	words, err = d.GetSensitiveWords()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.SensitiveWord{
+		{Word: "word1", Action: 3, CreatedAt: 103},
+		{Word: "\\d{11}", Regex: true, Action: 3, CreatedAt: 102},
+	}, words)
+}
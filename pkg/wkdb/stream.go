@@ -0,0 +1,193 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) SaveStreamMeta(meta StreamMeta) error {
+	db := wk.channelDb(meta.ChannelId, meta.ChannelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	var err error
+
+	// streamNo
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.StreamNo), []byte(meta.StreamNo), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelId
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.ChannelId), []byte(meta.ChannelId), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelType
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.ChannelType), []byte{meta.ChannelType}, wk.noSync); err != nil {
+		return err
+	}
+
+	// messageId
+	messageIdBytes := make([]byte, 8)
+	wk.endian.PutUint64(messageIdBytes, uint64(meta.MessageId))
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.MessageId), messageIdBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// fromUid
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.FromUid), []byte(meta.FromUid), wk.noSync); err != nil {
+		return err
+	}
+
+	// streamFlag
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.StreamFlag), []byte{uint8(meta.StreamFlag)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	createdAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(createdAtBytes, uint64(meta.CreatedAt))
+	if err = batch.Set(key.NewStreamMetaColumnKey(meta.ChannelId, meta.ChannelType, meta.StreamNo, key.TableStreamMeta.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetStreamMeta(channelId string, channelType uint8, streamNo string) (StreamMeta, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewStreamMetaColumnKey(channelId, channelType, streamNo, key.MinColumnKey),
+		UpperBound: key.NewStreamMetaColumnKey(channelId, channelType, streamNo, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		meta    StreamMeta
+		hasData bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		columnName, err := key.ParseStreamMetaColumnKey(iter.Key())
+		if err != nil {
+			return StreamMeta{}, err
+		}
+		switch columnName {
+		case key.TableStreamMeta.Column.StreamNo:
+			meta.StreamNo = string(iter.Value())
+		case key.TableStreamMeta.Column.ChannelId:
+			meta.ChannelId = string(iter.Value())
+		case key.TableStreamMeta.Column.ChannelType:
+			meta.ChannelType = iter.Value()[0]
+		case key.TableStreamMeta.Column.MessageId:
+			meta.MessageId = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableStreamMeta.Column.FromUid:
+			meta.FromUid = string(iter.Value())
+		case key.TableStreamMeta.Column.StreamFlag:
+			meta.StreamFlag = wkproto.StreamFlag(iter.Value()[0])
+		case key.TableStreamMeta.Column.CreatedAt:
+			meta.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		hasData = true
+	}
+	if !hasData {
+		return StreamMeta{}, ErrNotFound
+	}
+	return meta, nil
+}
+
+func (wk *wukongDB) StreamEnd(channelId string, channelType uint8, streamNo string) error {
+	db := wk.channelDb(channelId, channelType)
+
+	// 本节点没有此流则忽略
+	_, closer, err := db.Get(key.NewStreamMetaColumnKey(channelId, channelType, streamNo, key.TableStreamMeta.Column.StreamNo))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	closer.Close()
+
+	return db.Set(key.NewStreamMetaColumnKey(channelId, channelType, streamNo, key.TableStreamMeta.Column.StreamFlag), []byte{uint8(wkproto.StreamFlagEnd)}, wk.sync)
+}
+
+func (wk *wukongDB) AppendStreamItem(channelId string, channelType uint8, streamNo string, item StreamItem) error {
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// clientMsgNo
+	if err := batch.Set(key.NewStreamItemColumnKey(channelId, channelType, streamNo, item.StreamSeq, key.TableStreamItem.Column.ClientMsgNo), []byte(item.ClientMsgNo), wk.noSync); err != nil {
+		return err
+	}
+
+	// payload
+	if err := batch.Set(key.NewStreamItemColumnKey(channelId, channelType, streamNo, item.StreamSeq, key.TableStreamItem.Column.Payload), item.Payload, wk.noSync); err != nil {
+		return err
+	}
+
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetStreamItems(channelId string, channelType uint8, streamNo string) ([]StreamItem, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewStreamItemColumnKey(channelId, channelType, streamNo, 0, key.MinColumnKey),
+		UpperBound: key.NewStreamItemColumnKey(channelId, channelType, streamNo, math.MaxUint32, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		items       = make([]StreamItem, 0)
+		preSeq      uint32
+		preItem     StreamItem
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		streamSeq, columnName, err := key.ParseStreamItemColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if streamSeq != preSeq || !lastNeedAdd {
+			if lastNeedAdd {
+				items = append(items, preItem)
+			}
+			preSeq = streamSeq
+			preItem = StreamItem{
+				StreamSeq: streamSeq,
+			}
+		}
+		switch columnName {
+		case key.TableStreamItem.Column.ClientMsgNo:
+			preItem.ClientMsgNo = string(iter.Value())
+		case key.TableStreamItem.Column.Payload:
+			// 这里必须复制一份，否则会被pebble覆盖
+			var payload = make([]byte, len(iter.Value()))
+			copy(payload, iter.Value())
+			preItem.Payload = payload
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		items = append(items, preItem)
+	}
+	return items, nil
+}
+
+func (wk *wukongDB) GetStreamLastSeq(channelId string, channelType uint8, streamNo string) (uint32, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewStreamItemColumnKey(channelId, channelType, streamNo, 0, key.MinColumnKey),
+		UpperBound: key.NewStreamItemColumnKey(channelId, channelType, streamNo, math.MaxUint32, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	if !iter.Last() {
+		return 0, nil
+	}
+	streamSeq, _, err := key.ParseStreamItemColumnKey(iter.Key())
+	if err != nil {
+		return 0, err
+	}
+	return streamSeq, nil
+}
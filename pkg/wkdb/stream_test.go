@@ -0,0 +1,96 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMeta(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+
+	_, err = d.GetStreamMeta(channelId, channelType, "stream1")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+
+	meta := wkdb.StreamMeta{
+		StreamNo:    "stream1",
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		MessageId:   100,
+		FromUid:     "u1",
+		StreamFlag:  wkproto.StreamFlagStart,
+		CreatedAt:   1000,
+	}
+	err = d.SaveStreamMeta(meta)
+	assert.NoError(t, err)
+
+	meta2, err := d.GetStreamMeta(channelId, channelType, "stream1")
+	assert.NoError(t, err)
+	assert.Equal(t, meta, meta2)
+
+	err = d.StreamEnd(channelId, channelType, "stream1")
+	assert.NoError(t, err)
+
+	meta2, err = d.GetStreamMeta(channelId, channelType, "stream1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkproto.StreamFlagEnd, meta2.StreamFlag)
+}
+
+func TestStreamItems(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	streamNo := "stream1"
+
+	lastSeq, err := d.GetStreamLastSeq(channelId, channelType, streamNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), lastSeq)
+
+	for i := 1; i <= 3; i++ {
+		err = d.AppendStreamItem(channelId, channelType, streamNo, wkdb.StreamItem{
+			StreamSeq:   uint32(i),
+			ClientMsgNo: "no",
+			Payload:     []byte{byte(i)},
+		})
+		assert.NoError(t, err)
+	}
+	// 其他流的数据不应被读取
+	err = d.AppendStreamItem(channelId, channelType, "stream2", wkdb.StreamItem{
+		StreamSeq: 10,
+		Payload:   []byte("other"),
+	})
+	assert.NoError(t, err)
+
+	items, err := d.GetStreamItems(channelId, channelType, streamNo)
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+	for i, item := range items {
+		assert.Equal(t, uint32(i+1), item.StreamSeq)
+		assert.Equal(t, "no", item.ClientMsgNo)
+		assert.Equal(t, []byte{byte(i + 1)}, item.Payload)
+	}
+
+	lastSeq, err = d.GetStreamLastSeq(channelId, channelType, streamNo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), lastSeq)
+}
@@ -21,15 +21,27 @@ func (wk *wukongDB) AddSubscribers(channelId string, channelType uint8, subscrib
 		return fmt.Errorf("AddSubscribers: channelId: %s channelType: %d not found", channelId, channelType)
 	}
 
+	// 已存在的订阅者不重复计数
+	existMap, err := wk.getSubscriberIdsByUids(channelId, channelType, subscribers)
+	if err != nil {
+		return err
+	}
+
 	w := db.NewBatch()
 	defer w.Close()
+	addCount := 0
 	for _, uid := range subscribers {
+		if _, ok := existMap[uid]; ok {
+			continue
+		}
 		id := key.HashWithString(uid)
 		if err := wk.writeSubscriber(channelId, channelType, id, uid, w); err != nil {
 			return err
 		}
+		existMap[uid] = id
+		addCount++
 	}
-	err = wk.incChannelInfoSubscriberCount(channelPrimaryId, len(subscribers), db)
+	err = wk.incChannelInfoSubscriberCount(channelPrimaryId, addCount, db)
 	if err != nil {
 		wk.Error("incChannelInfoSubscriberCount failed", zap.Error(err))
 		return err
@@ -47,6 +59,193 @@ func (wk *wukongDB) GetSubscribers(channelId string, channelType uint8) ([]strin
 	return wk.parseSubscriber(iter, 0)
 }
 
+func (wk *wukongDB) GetSubscribersDetail(channelId string, channelType uint8) ([]Subscriber, error) {
+	subscribers := make([]Subscriber, 0)
+	err := wk.IterateSubscribers(channelId, channelType, func(subscriber Subscriber) bool {
+		subscribers = append(subscribers, subscriber)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+func (wk *wukongDB) GetSubscribersPage(channelId string, channelType uint8, cursor string, limit int) ([]Subscriber, error) {
+	var startId uint64
+	if cursor != "" {
+		cursorId := key.HashWithString(cursor)
+		if cursorId == math.MaxUint64 {
+			return nil, nil
+		}
+		startId = cursorId + 1
+	}
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSubscriberPrimaryKey(channelId, channelType, startId),
+		UpperBound: key.NewSubscriberPrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+
+	subscribers := make([]Subscriber, 0, limit)
+	err := wk.iterateSubscriber(iter, func(subscriber Subscriber) bool {
+		subscribers = append(subscribers, subscriber)
+		return limit <= 0 || len(subscribers) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+func (wk *wukongDB) GetSubscriberCount(channelId string, channelType uint8) (int, error) {
+	channelPrimaryId, err := wk.getChannelPrimaryKey(channelId, channelType)
+	if err != nil {
+		return 0, err
+	}
+	if channelPrimaryId == 0 {
+		return 0, nil
+	}
+	countBytes, closer, err := wk.channelDb(channelId, channelType).Get(key.NewChannelInfoColumnKey(channelPrimaryId, key.TableChannelInfo.Column.SubscriberCount))
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer closer.Close()
+	if len(countBytes) < 4 {
+		return 0, nil
+	}
+	return int(wk.endian.Uint32(countBytes)), nil
+}
+
+func (wk *wukongDB) IterateSubscribers(channelId string, channelType uint8, iterFnc func(subscriber Subscriber) bool) error {
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSubscriberPrimaryKey(channelId, channelType, 0),
+		UpperBound: key.NewSubscriberPrimaryKey(channelId, channelType, math.MaxUint64),
+	})
+	defer iter.Close()
+	return wk.iterateSubscriber(iter, iterFnc)
+}
+
+func (wk *wukongDB) GetSubscriber(channelId string, channelType uint8, uid string) (Subscriber, error) {
+	idMap, err := wk.getSubscriberIdsByUids(channelId, channelType, []string{uid})
+	if err != nil {
+		return Subscriber{}, err
+	}
+	id, ok := idMap[uid]
+	if !ok {
+		return Subscriber{}, ErrNotFound
+	}
+	subscriber := Subscriber{
+		Uid: uid,
+	}
+	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+		LowerBound: key.NewSubscriberColumnKey(channelId, channelType, id, [2]byte{0x00, 0x00}),
+		UpperBound: key.NewSubscriberColumnKey(channelId, channelType, id, [2]byte{0xff, 0xff}),
+	})
+	defer iter.Close()
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, columnName, err := key.ParseSubscriberColumnKey(iter.Key())
+		if err != nil {
+			return Subscriber{}, err
+		}
+		wk.parseSubscriberColumn(&subscriber, columnName, iter.Value())
+	}
+	return subscriber, nil
+}
+
+func (wk *wukongDB) AddOrUpdateSubscribers(channelId string, channelType uint8, subscribers []Subscriber) error {
+	db := wk.channelDb(channelId, channelType)
+
+	channelPrimaryId, err := wk.getChannelPrimaryKey(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if channelPrimaryId == 0 {
+		return fmt.Errorf("AddOrUpdateSubscribers: channelId: %s channelType: %d not found", channelId, channelType)
+	}
+
+	uids := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		uids = append(uids, subscriber.Uid)
+	}
+	existMap, err := wk.getSubscriberIdsByUids(channelId, channelType, uids)
+	if err != nil {
+		return err
+	}
+
+	w := db.NewBatch()
+	defer w.Close()
+	addCount := 0
+	for _, subscriber := range subscribers {
+		id, ok := existMap[subscriber.Uid]
+		if !ok {
+			id = key.HashWithString(subscriber.Uid)
+			if err := wk.writeSubscriber(channelId, channelType, id, subscriber.Uid, w); err != nil {
+				return err
+			}
+			existMap[subscriber.Uid] = id
+			addCount++
+		}
+		if err := wk.writeSubscriberAttrs(channelId, channelType, id, subscriber, w); err != nil {
+			return err
+		}
+	}
+	if addCount > 0 {
+		err = wk.incChannelInfoSubscriberCount(channelPrimaryId, addCount, db)
+		if err != nil {
+			wk.Error("incChannelInfoSubscriberCount failed", zap.Error(err))
+			return err
+		}
+	}
+	return w.Commit(wk.sync)
+}
+
+func (wk *wukongDB) UpdateSubscribers(channelId string, channelType uint8, subscribers []Subscriber) error {
+	uids := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		uids = append(uids, subscriber.Uid)
+	}
+	idMap, err := wk.getSubscriberIdsByUids(channelId, channelType, uids)
+	if err != nil {
+		return err
+	}
+	if len(idMap) == 0 {
+		return nil
+	}
+	batch := wk.channelDb(channelId, channelType).NewBatch()
+	defer batch.Close()
+	for _, subscriber := range subscribers {
+		id, ok := idMap[subscriber.Uid]
+		if !ok { // 不是订阅者忽略
+			continue
+		}
+		if err = wk.writeSubscriberAttrs(channelId, channelType, id, subscriber, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) SetSubscriberRole(channelId string, channelType uint8, uids []string, role uint8) error {
+	idMap, err := wk.getSubscriberIdsByUids(channelId, channelType, uids)
+	if err != nil {
+		return err
+	}
+	if len(idMap) == 0 {
+		return nil
+	}
+	batch := wk.channelDb(channelId, channelType).NewBatch()
+	defer batch.Close()
+	for _, id := range idMap {
+		if err = batch.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.Role), []byte{role}, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
 func (wk *wukongDB) RemoveSubscribers(channelId string, channelType uint8, subscribers []string) error {
 
 	channelPrimaryId, err := wk.getChannelPrimaryKey(channelId, channelType)
@@ -140,6 +339,25 @@ func (wk *wukongDB) removeSubscriber(channelId string, channelType uint8, id uin
 		return err
 	}
 
+	// role
+	if err = w.Delete(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.Role), wk.sync); err != nil {
+		return err
+	}
+
+	// attrs
+	attrColumns := [][2]byte{
+		key.TableSubscriber.Column.JoinedAt,
+		key.TableSubscriber.Column.JoinSeq,
+		key.TableSubscriber.Column.InviterUid,
+		key.TableSubscriber.Column.Nickname,
+		key.TableSubscriber.Column.Extra,
+	}
+	for _, columnName := range attrColumns {
+		if err = w.Delete(key.NewSubscriberColumnKey(channelId, channelType, id, columnName), wk.sync); err != nil {
+			return err
+		}
+	}
+
 	// uid index
 	uidIndexKey := key.NewSubscriberIndexUidKey(channelId, channelType, uid)
 	if err = w.Delete(uidIndexKey, wk.sync); err != nil {
@@ -153,7 +371,7 @@ func (wk *wukongDB) getSubscriberIdsByUids(channelId string, channelType uint8,
 	resultMap := make(map[string]uint64)
 	for _, uid := range uids {
 		uidIndexKey := key.NewSubscriberIndexUidKey(channelId, channelType, uid)
-		uidIndexValue, closer, err := wk.shardDB(channelId).Get(uidIndexKey)
+		uidIndexValue, closer, err := wk.channelDb(channelId, channelType).Get(uidIndexKey)
 		if err != nil {
 			if err == pebble.ErrNotFound {
 				continue
@@ -214,3 +432,96 @@ func (wk *wukongDB) parseSubscriber(iter *pebble.Iterator, limit int) ([]string,
 	}
 	return subscribers, nil
 }
+
+// iterateSubscriber 遍历订阅者，iterFnc返回false停止遍历
+func (wk *wukongDB) iterateSubscriber(iter *pebble.Iterator, iterFnc func(subscriber Subscriber) bool) error {
+	var (
+		preId         uint64
+		preSubscriber Subscriber
+		hasData       bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		id, columnName, err := key.ParseSubscriberColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if id != preId || !hasData {
+			if hasData {
+				if !iterFnc(preSubscriber) {
+					return nil
+				}
+			}
+			preId = id
+			preSubscriber = Subscriber{}
+		}
+		wk.parseSubscriberColumn(&preSubscriber, columnName, iter.Value())
+		hasData = true
+	}
+	if hasData {
+		_ = iterFnc(preSubscriber)
+	}
+	return nil
+}
+
+func (wk *wukongDB) parseSubscriberColumn(subscriber *Subscriber, columnName [2]byte, value []byte) {
+	switch columnName {
+	case key.TableUser.Column.Uid:
+		subscriber.Uid = string(value)
+	case key.TableSubscriber.Column.Role:
+		if len(value) > 0 {
+			subscriber.Role = value[0]
+		}
+	case key.TableSubscriber.Column.JoinedAt:
+		if len(value) >= 8 {
+			subscriber.JoinedAt = int64(wk.endian.Uint64(value))
+		}
+	case key.TableSubscriber.Column.JoinSeq:
+		if len(value) >= 8 {
+			subscriber.JoinSeq = wk.endian.Uint64(value)
+		}
+	case key.TableSubscriber.Column.InviterUid:
+		subscriber.InviterUid = string(value)
+	case key.TableSubscriber.Column.Nickname:
+		subscriber.Nickname = string(value)
+	case key.TableSubscriber.Column.Extra:
+		if len(value) > 0 {
+			subscriber.Extra = append([]byte(nil), value...)
+		}
+	}
+}
+
+// writeSubscriberAttrs 写入订阅者的属性（不包含角色）
+func (wk *wukongDB) writeSubscriberAttrs(channelId string, channelType uint8, id uint64, subscriber Subscriber, w pebble.Writer) error {
+	var (
+		err error
+	)
+	// joinedAt
+	joinedAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(joinedAtBytes, uint64(subscriber.JoinedAt))
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.JoinedAt), joinedAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// joinSeq
+	joinSeqBytes := make([]byte, 8)
+	wk.endian.PutUint64(joinSeqBytes, subscriber.JoinSeq)
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.JoinSeq), joinSeqBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// inviterUid
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.InviterUid), []byte(subscriber.InviterUid), wk.noSync); err != nil {
+		return err
+	}
+
+	// nickname
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.Nickname), []byte(subscriber.Nickname), wk.noSync); err != nil {
+		return err
+	}
+
+	// extra
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, id, key.TableSubscriber.Column.Extra), subscriber.Extra, wk.noSync); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package wkdb
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTermRuneLen     = 32   // 单个词的最大长度，超过的不索引
+	maxTermsOfMessage  = 1024 // 每条消息最多索引的词数量
+	maxIndexPayloadLen = 64 * 1024
+)
+
+// tokenize 对文本分词，返回词和词频
+// 拉丁文等按单词切分并转小写，中日韩文字没有分隔符，按单字和相邻双字切分
+func tokenize(text string) map[string]uint16 {
+	terms := make(map[string]uint16)
+	addTerm := func(term string) {
+		if len(terms) >= maxTermsOfMessage {
+			if _, ok := terms[term]; !ok {
+				return
+			}
+		}
+		if terms[term] < 0xffff {
+			terms[term]++
+		}
+	}
+	splitText(text, func(word string) {
+		addTerm(word)
+	}, func(cjk []rune) {
+		for i := range cjk {
+			addTerm(string(cjk[i]))
+			if i+1 < len(cjk) {
+				addTerm(string(cjk[i : i+2]))
+			}
+		}
+	})
+	return terms
+}
+
+// tokenizeQuery 对搜索关键字分词，所有词都需要匹配
+// 中日韩文字连续多个字时按相邻双字匹配，单个字时按单字匹配
+func tokenizeQuery(text string) []string {
+	terms := make([]string, 0)
+	exists := make(map[string]struct{})
+	addTerm := func(term string) {
+		if _, ok := exists[term]; ok {
+			return
+		}
+		exists[term] = struct{}{}
+		terms = append(terms, term)
+	}
+	splitText(text, addTerm, func(cjk []rune) {
+		if len(cjk) == 1 {
+			addTerm(string(cjk))
+			return
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			addTerm(string(cjk[i : i+2]))
+		}
+	})
+	return terms
+}
+
+// splitText 将文本切分为单词和连续的中日韩文字
+func splitText(text string, onWord func(word string), onCJK func(cjk []rune)) {
+	var (
+		word []rune
+		cjk  []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 && len(word) <= maxTermRuneLen {
+			onWord(string(word))
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) > 0 {
+			onCJK(cjk)
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// payloadText 获取消息内容中需要索引的文本
+// json格式的消息内容只索引字符串类型的值，非文本的内容（例如加密内容）不索引
+func payloadText(payload []byte) string {
+	if len(payload) == 0 || len(payload) > maxIndexPayloadLen {
+		return ""
+	}
+	if payload[0] == '{' || payload[0] == '[' {
+		var value interface{}
+		if err := json.Unmarshal(payload, &value); err == nil {
+			var builder strings.Builder
+			collectJSONStrings(value, &builder)
+			return builder.String()
+		}
+	}
+	if !utf8.Valid(payload) {
+		return ""
+	}
+	return string(payload)
+}
+
+func collectJSONStrings(value interface{}, builder *strings.Builder) {
+	switch v := value.(type) {
+	case string:
+		builder.WriteString(v)
+		builder.WriteByte(' ')
+	case []interface{}:
+		for _, item := range v {
+			collectJSONStrings(item, builder)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			collectJSONStrings(item, builder)
+		}
+	}
+}
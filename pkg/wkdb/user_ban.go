@@ -0,0 +1,122 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateUserBans(bans []UserBan) error {
+	if len(bans) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, ban := range bans {
+		// uid
+		if err := batch.Set(key.NewUserBanColumnKey(ban.Uid, key.TableUserBan.Column.Uid), []byte(ban.Uid), wk.noSync); err != nil {
+			return err
+		}
+
+		// reason
+		if err := batch.Set(key.NewUserBanColumnKey(ban.Uid, key.TableUserBan.Column.Reason), []byte(ban.Reason), wk.noSync); err != nil {
+			return err
+		}
+
+		// expireAt
+		expireAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(expireAtBytes, uint64(ban.ExpireAt))
+		if err := batch.Set(key.NewUserBanColumnKey(ban.Uid, key.TableUserBan.Column.ExpireAt), expireAtBytes, wk.noSync); err != nil {
+			return err
+		}
+
+		// createdAt
+		createdAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, uint64(ban.CreatedAt))
+		if err := batch.Set(key.NewUserBanColumnKey(ban.Uid, key.TableUserBan.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveUserBans(uids []string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, uid := range uids {
+		if err := batch.DeleteRange(key.NewUserBanColumnKey(uid, key.MinColumnKey), key.NewUserBanColumnKey(uid, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetUserBans() ([]UserBan, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserBanColumnKeyWithHash(0, key.MinColumnKey),
+		UpperBound: key.NewUserBanColumnKeyWithHash(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	return wk.parseUserBans(iter)
+}
+
+func (wk *wukongDB) GetUserBan(uid string) (UserBan, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewUserBanColumnKey(uid, key.MinColumnKey),
+		UpperBound: key.NewUserBanColumnKey(uid, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	bans, err := wk.parseUserBans(iter)
+	if err != nil {
+		return UserBan{}, err
+	}
+	if len(bans) == 0 {
+		return UserBan{}, ErrNotFound
+	}
+	return bans[0], nil
+}
+
+func (wk *wukongDB) parseUserBans(iter *pebble.Iterator) ([]UserBan, error) {
+	var (
+		bans        = make([]UserBan, 0)
+		preHash     uint64
+		preBan      UserBan
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		uidHash, columnName, err := key.ParseUserBanColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if uidHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				bans = append(bans, preBan)
+			}
+			preHash = uidHash
+			preBan = UserBan{}
+		}
+		switch columnName {
+		case key.TableUserBan.Column.Uid:
+			preBan.Uid = string(iter.Value())
+		case key.TableUserBan.Column.Reason:
+			preBan.Reason = string(iter.Value())
+		case key.TableUserBan.Column.ExpireAt:
+			preBan.ExpireAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableUserBan.Column.CreatedAt:
+			preBan.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		bans = append(bans, preBan)
+	}
+	return bans, nil
+}
@@ -0,0 +1,55 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserBans(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateUserBans([]wkdb.UserBan{
+		{Uid: "u1", Reason: "spam", ExpireAt: 100, CreatedAt: 10},
+		{Uid: "u2", Reason: "abuse", CreatedAt: 20},
+	})
+	assert.NoError(t, err)
+
+	// 覆盖
+	err = d.AddOrUpdateUserBans([]wkdb.UserBan{
+		{Uid: "u1", Reason: "spam again", ExpireAt: 200, CreatedAt: 30},
+	})
+	assert.NoError(t, err)
+
+	bans, err := d.GetUserBans()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.UserBan{
+		{Uid: "u1", Reason: "spam again", ExpireAt: 200, CreatedAt: 30},
+		{Uid: "u2", Reason: "abuse", CreatedAt: 20},
+	}, bans)
+
+	ban, err := d.GetUserBan("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.UserBan{Uid: "u1", Reason: "spam again", ExpireAt: 200, CreatedAt: 30}, ban)
+
+	assert.True(t, wkdb.UserBan{ExpireAt: 200}.Expired(300))
+	assert.False(t, wkdb.UserBan{ExpireAt: 0}.Expired(300)) // 永久封禁
+
+	err = d.RemoveUserBans([]string{"u1", "notExist"})
+	assert.NoError(t, err)
+
+	bans, err = d.GetUserBans()
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.UserBan{{Uid: "u2", Reason: "abuse", CreatedAt: 20}}, bans)
+
+	_, err = d.GetUserBan("u1")
+	assert.Equal(t, wkdb.ErrNotFound, err)
+}
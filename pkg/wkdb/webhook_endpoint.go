@@ -0,0 +1,121 @@
+package wkdb
+
+import (
+	"math"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AddOrUpdateWebhookEndpoints(endpoints []WebhookEndpoint) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, endpoint := range endpoints {
+		nameHash := key.HashWithString(endpoint.Name)
+
+		// name
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.Name), []byte(endpoint.Name), wk.noSync); err != nil {
+			return err
+		}
+
+		// addr
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.Addr), []byte(endpoint.Addr), wk.noSync); err != nil {
+			return err
+		}
+
+		// events
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.Events), []byte(strings.Join(endpoint.Events, ",")), wk.noSync); err != nil {
+			return err
+		}
+
+		// secret
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.Secret), []byte(endpoint.Secret), wk.noSync); err != nil {
+			return err
+		}
+
+		// concurrency
+		concurrencyBytes := make([]byte, 4)
+		wk.endian.PutUint32(concurrencyBytes, uint32(endpoint.Concurrency))
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.Concurrency), concurrencyBytes, wk.noSync); err != nil {
+			return err
+		}
+
+		// createdAt
+		createdAtBytes := make([]byte, 8)
+		wk.endian.PutUint64(createdAtBytes, uint64(endpoint.CreatedAt))
+		if err := batch.Set(key.NewWebhookEndpointColumnKey(nameHash, key.TableWebhookEndpoint.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookEndpoints(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	for _, name := range names {
+		nameHash := key.HashWithString(name)
+		if err := batch.DeleteRange(key.NewWebhookEndpointColumnKey(nameHash, key.MinColumnKey), key.NewWebhookEndpointColumnKey(nameHash, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookEndpoints() ([]WebhookEndpoint, error) {
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookEndpointColumnKey(0, key.MinColumnKey),
+		UpperBound: key.NewWebhookEndpointColumnKey(math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		endpoints   = make([]WebhookEndpoint, 0)
+		preHash     uint64
+		preEndpoint WebhookEndpoint
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		nameHash, columnName, err := key.ParseWebhookEndpointColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if nameHash != preHash || !lastNeedAdd {
+			if lastNeedAdd {
+				endpoints = append(endpoints, preEndpoint)
+			}
+			preHash = nameHash
+			preEndpoint = WebhookEndpoint{}
+		}
+		switch columnName {
+		case key.TableWebhookEndpoint.Column.Name:
+			preEndpoint.Name = string(iter.Value())
+		case key.TableWebhookEndpoint.Column.Addr:
+			preEndpoint.Addr = string(iter.Value())
+		case key.TableWebhookEndpoint.Column.Events:
+			if len(iter.Value()) > 0 {
+				preEndpoint.Events = strings.Split(string(iter.Value()), ",")
+			}
+		case key.TableWebhookEndpoint.Column.Secret:
+			preEndpoint.Secret = string(iter.Value())
+		case key.TableWebhookEndpoint.Column.Concurrency:
+			preEndpoint.Concurrency = int(wk.endian.Uint32(iter.Value()))
+		case key.TableWebhookEndpoint.Column.CreatedAt:
+			preEndpoint.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		endpoints = append(endpoints, preEndpoint)
+	}
+	return endpoints, nil
+}
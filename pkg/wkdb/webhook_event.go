@@ -0,0 +1,244 @@
+package wkdb
+
+import (
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/cockroachdb/pebble"
+)
+
+func (wk *wukongDB) AppendWebhookEvents(queue uint8, events []WebhookEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, event := range events {
+		if err := wk.writeWebhookEvent(queue, event, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetWebhookEvents(queue uint8, endpoint string, startId uint64, limit int) ([]WebhookEvent, error) {
+	if startId == math.MaxUint64 {
+		return nil, nil
+	}
+	endpointHash := key.HashWithString(endpoint)
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookEventColumnKey(queue, endpointHash, startId+1, key.MinColumnKey),
+		UpperBound: key.NewWebhookEventColumnKey(queue, endpointHash, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	events := make([]WebhookEvent, 0)
+	err := wk.iterWebhookEvent(iter, func(event WebhookEvent) bool {
+		events = append(events, event)
+		return limit <= 0 || len(events) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (wk *wukongDB) GetWebhookEvent(queue uint8, endpoint string, id uint64) (WebhookEvent, error) {
+	endpointHash := key.HashWithString(endpoint)
+	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+		LowerBound: key.NewWebhookEventColumnKey(queue, endpointHash, id, key.MinColumnKey),
+		UpperBound: key.NewWebhookEventColumnKey(queue, endpointHash, id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		event WebhookEvent
+		found bool
+	)
+	err := wk.iterWebhookEvent(iter, func(e WebhookEvent) bool {
+		event = e
+		found = true
+		return false
+	})
+	if err != nil {
+		return WebhookEvent{}, err
+	}
+	if !found {
+		return WebhookEvent{}, ErrNotFound
+	}
+	return event, nil
+}
+
+func (wk *wukongDB) UpdateWebhookEventRetry(endpoint string, id uint64, attempts int, nextRetryAt int64, lastError string) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	endpointHash := key.HashWithString(endpoint)
+
+	// attempts
+	attemptsBytes := make([]byte, 4)
+	wk.endian.PutUint32(attemptsBytes, uint32(attempts))
+	if err := batch.Set(key.NewWebhookEventColumnKey(WebhookQueueOutbox, endpointHash, id, key.TableWebhookEvent.Column.Attempts), attemptsBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// nextRetryAt
+	nextRetryAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(nextRetryAtBytes, uint64(nextRetryAt))
+	if err := batch.Set(key.NewWebhookEventColumnKey(WebhookQueueOutbox, endpointHash, id, key.TableWebhookEvent.Column.NextRetryAt), nextRetryAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// lastError
+	if err := batch.Set(key.NewWebhookEventColumnKey(WebhookQueueOutbox, endpointHash, id, key.TableWebhookEvent.Column.LastError), []byte(lastError), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookEvents(queue uint8, endpoint string, ids []uint64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, id := range ids {
+		if err := wk.deleteWebhookEvent(queue, endpoint, id, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) MoveWebhookEvents(fromQueue, toQueue uint8, events []WebhookEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	for _, event := range events {
+		if err := wk.deleteWebhookEvent(fromQueue, event.Endpoint, event.Id, batch); err != nil {
+			return err
+		}
+		if err := wk.writeWebhookEvent(toQueue, event, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) RemoveWebhookEventsOfEndpoint(endpoint string) error {
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	endpointHash := key.HashWithString(endpoint)
+	for _, queue := range []uint8{WebhookQueueOutbox, WebhookQueueDeadLetter} {
+		if err := batch.DeleteRange(key.NewWebhookEventColumnKey(queue, endpointHash, 0, key.MinColumnKey), key.NewWebhookEventColumnKey(queue, endpointHash, math.MaxUint64, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) writeWebhookEvent(queue uint8, event WebhookEvent, w pebble.Writer) error {
+	endpointHash := key.HashWithString(event.Endpoint)
+
+	// endpoint
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.Endpoint), []byte(event.Endpoint), wk.noSync); err != nil {
+		return err
+	}
+
+	// event
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.Event), []byte(event.Event), wk.noSync); err != nil {
+		return err
+	}
+
+	// data
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.Data), event.Data, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	createdAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(createdAtBytes, uint64(event.CreatedAt))
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.CreatedAt), createdAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// attempts
+	attemptsBytes := make([]byte, 4)
+	wk.endian.PutUint32(attemptsBytes, uint32(event.Attempts))
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.Attempts), attemptsBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// nextRetryAt
+	nextRetryAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(nextRetryAtBytes, uint64(event.NextRetryAt))
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.NextRetryAt), nextRetryAtBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// lastError
+	if err := w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.LastError), []byte(event.LastError), wk.noSync); err != nil {
+		return err
+	}
+
+	// deadAt
+	deadAtBytes := make([]byte, 8)
+	wk.endian.PutUint64(deadAtBytes, uint64(event.DeadAt))
+	return w.Set(key.NewWebhookEventColumnKey(queue, endpointHash, event.Id, key.TableWebhookEvent.Column.DeadAt), deadAtBytes, wk.noSync)
+}
+
+func (wk *wukongDB) deleteWebhookEvent(queue uint8, endpoint string, id uint64, w pebble.Writer) error {
+	endpointHash := key.HashWithString(endpoint)
+	return w.DeleteRange(key.NewWebhookEventColumnKey(queue, endpointHash, id, key.MinColumnKey), key.NewWebhookEventColumnKey(queue, endpointHash, id, key.MaxColumnKey), wk.noSync)
+}
+
+func (wk *wukongDB) iterWebhookEvent(iter *pebble.Iterator, iterFnc func(event WebhookEvent) bool) error {
+	var (
+		preId       uint64
+		preEvent    WebhookEvent
+		lastNeedAdd bool
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		id, columnName, err := key.ParseWebhookEventColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if id != preId || !lastNeedAdd {
+			if lastNeedAdd {
+				if !iterFnc(preEvent) {
+					return nil
+				}
+			}
+			preId = id
+			preEvent = WebhookEvent{Id: id}
+		}
+		switch columnName {
+		case key.TableWebhookEvent.Column.Endpoint:
+			preEvent.Endpoint = string(iter.Value())
+		case key.TableWebhookEvent.Column.Event:
+			preEvent.Event = string(iter.Value())
+		case key.TableWebhookEvent.Column.Data:
+			data := make([]byte, len(iter.Value()))
+			copy(data, iter.Value())
+			preEvent.Data = data
+		case key.TableWebhookEvent.Column.CreatedAt:
+			preEvent.CreatedAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableWebhookEvent.Column.Attempts:
+			preEvent.Attempts = int(wk.endian.Uint32(iter.Value()))
+		case key.TableWebhookEvent.Column.NextRetryAt:
+			preEvent.NextRetryAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableWebhookEvent.Column.LastError:
+			preEvent.LastError = string(iter.Value())
+		case key.TableWebhookEvent.Column.DeadAt:
+			preEvent.DeadAt = int64(wk.endian.Uint64(iter.Value()))
+		}
+		lastNeedAdd = true
+	}
+	if lastNeedAdd {
+		iterFnc(preEvent)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package wkdb_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookEvents(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	events := []wkdb.WebhookEvent{
+		{Id: 1, Endpoint: "default", Event: "msg.offline", Data: json.RawMessage(`{"a":1}`), CreatedAt: 10},
+		{Id: 2, Endpoint: "default", Event: "user.onlinestatus", Data: json.RawMessage(`["u1-1-1"]`), CreatedAt: 20},
+		{Id: 3, Endpoint: "default", Event: "msg.notify", Data: json.RawMessage(`[]`), CreatedAt: 30},
+	}
+	err = d.AppendWebhookEvents(wkdb.WebhookQueueOutbox, events)
+	assert.NoError(t, err)
+
+	// 其他端点的队列互不影响
+	otherEvent := wkdb.WebhookEvent{Id: 1, Endpoint: "analytics", Event: "msg.offline", Data: json.RawMessage(`{"a":1}`), CreatedAt: 10}
+	err = d.AppendWebhookEvents(wkdb.WebhookQueueOutbox, []wkdb.WebhookEvent{otherEvent})
+	assert.NoError(t, err)
+
+	// 分页
+	result, err := d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "default", 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, events[:2], result)
+	result, err = d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "default", 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, events[2:], result)
+
+	// 重试信息
+	err = d.UpdateWebhookEventRetry("default", 1, 2, 5000, "timeout")
+	assert.NoError(t, err)
+	event, err := d.GetWebhookEvent(wkdb.WebhookQueueOutbox, "default", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, event.Attempts)
+	assert.Equal(t, int64(5000), event.NextRetryAt)
+	assert.Equal(t, "timeout", event.LastError)
+
+	// 移入死信
+	event.DeadAt = 100
+	err = d.MoveWebhookEvents(wkdb.WebhookQueueOutbox, wkdb.WebhookQueueDeadLetter, []wkdb.WebhookEvent{event})
+	assert.NoError(t, err)
+	_, err = d.GetWebhookEvent(wkdb.WebhookQueueOutbox, "default", 1)
+	assert.Equal(t, wkdb.ErrNotFound, err)
+	deadLetters, err := d.GetWebhookEvents(wkdb.WebhookQueueDeadLetter, "default", 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.WebhookEvent{event}, deadLetters)
+
+	err = d.RemoveWebhookEvents(wkdb.WebhookQueueOutbox, "default", []uint64{2, 100})
+	assert.NoError(t, err)
+	result, err = d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "default", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, events[2:], result)
+
+	result, err = d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "analytics", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []wkdb.WebhookEvent{otherEvent}, result)
+
+	// 移除端点的所有事件
+	err = d.RemoveWebhookEventsOfEndpoint("default")
+	assert.NoError(t, err)
+	result, err = d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "default", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 0)
+	deadLetters, err = d.GetWebhookEvents(wkdb.WebhookQueueDeadLetter, "default", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 0)
+	result, err = d.GetWebhookEvents(wkdb.WebhookQueueOutbox, "analytics", 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	endpoints := []wkdb.WebhookEndpoint{
+		{Name: "analytics", Addr: "http://127.0.0.1:8080/webhook", Events: []string{"msg.notify", "user.onlinestatus"}, Concurrency: 4, CreatedAt: 10},
+		{Name: "push", Addr: "127.0.0.1:9000", Secret: "s", Concurrency: 8, CreatedAt: 20},
+	}
+	err = d.AddOrUpdateWebhookEndpoints(endpoints)
+	assert.NoError(t, err)
+
+	result, err := d.GetWebhookEndpoints()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, endpoints, result)
+
+	endpoints[0].Events = []string{"msg.offline"}
+	err = d.AddOrUpdateWebhookEndpoints(endpoints[:1])
+	assert.NoError(t, err)
+	err = d.RemoveWebhookEndpoints([]string{"push"})
+	assert.NoError(t, err)
+
+	result, err = d.GetWebhookEndpoints()
+	assert.NoError(t, err)
+	assert.Equal(t, endpoints[:1], result)
+}
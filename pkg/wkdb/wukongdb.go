@@ -7,6 +7,7 @@ import (
 	"hash"
 	"hash/fnv"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -14,6 +15,7 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/bwmarrin/snowflake"
 	"github.com/cockroachdb/pebble"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
@@ -32,6 +34,9 @@ type wukongDB struct {
 	cancelCtx    context.Context
 	cancelFunc   context.CancelFunc
 
+	fromUidTimestampIndexed []atomic.Bool  // 每个分区的旧消息是否已补齐发送者+消息时间索引
+	backfillWg              sync.WaitGroup // 等待索引补齐协程退出
+
 	h hash.Hash32
 }
 
@@ -108,11 +113,27 @@ func (wk *wukongDB) Open() error {
 
 	go wk.collectMetricsLoop()
 
+	// 旧版本写入的消息没有发送者+消息时间索引，后台补齐，补齐之前按发送者索引查询
+	wk.fromUidTimestampIndexed = make([]atomic.Bool, len(wk.dbs))
+	for i, db := range wk.dbs {
+		indexed, err := wk.isFromUidTimestampIndexed(db)
+		if err != nil {
+			return err
+		}
+		if indexed {
+			wk.fromUidTimestampIndexed[i].Store(true)
+			continue
+		}
+		wk.backfillWg.Add(1)
+		go wk.backfillFromUidTimestampIndex(i, db)
+	}
+
 	return nil
 }
 
 func (wk *wukongDB) Close() error {
 	wk.cancelFunc()
+	wk.backfillWg.Wait()
 	for _, db := range wk.dbs {
 		if err := db.Close(); err != nil {
 			wk.Error("close db error", zap.Error(err))
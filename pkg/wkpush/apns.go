@@ -0,0 +1,178 @@
+package wkpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APNsEndpointProduction  = "https://api.push.apple.com"
+	APNsEndpointDevelopment = "https://api.sandbox.push.apple.com"
+
+	apnsTokenRefreshInterval = time.Minute * 50 // 苹果要求令牌在20到60分钟之间刷新
+)
+
+// APNsOptions 苹果推送配置（token认证）
+type APNsOptions struct {
+	Endpoint   string       // 推送地址，为空使用生产环境地址
+	KeyId      string       // p8密钥的key id
+	TeamId     string       // 开发者团队id
+	Topic      string       // 应用的bundle id
+	PrivateKey []byte       // p8密钥内容（PEM格式）
+	HTTPClient *http.Client // 为空使用默认的http2客户端
+}
+
+// APNsProvider 苹果推送（HTTP/2 + token认证）
+type APNsProvider struct {
+	opts       APNsOptions
+	privateKey *ecdsa.PrivateKey
+	client     *http.Client
+
+	mu          sync.Mutex
+	bearer      string
+	bearerIssue time.Time
+}
+
+func NewAPNsProvider(opts APNsOptions) (*APNsProvider, error) {
+	if opts.KeyId == "" || opts.TeamId == "" || opts.Topic == "" {
+		return nil, errors.New("apns keyId, teamId and topic are required")
+	}
+	privateKey, err := jwt.ParseECPrivateKeyFromPEM(opts.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse apns private key failed: %w", err)
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = APNsEndpointProduction
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Transport: &http.Transport{
+				ForceAttemptHTTP2: true,
+				TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12},
+				IdleConnTimeout:   time.Minute * 5,
+			},
+		}
+	}
+	return &APNsProvider{
+		opts:       opts,
+		privateKey: privateKey,
+		client:     client,
+	}, nil
+}
+
+func (a *APNsProvider) Name() string {
+	return "apns"
+}
+
+func (a *APNsProvider) Push(ctx context.Context, n *Notification) error {
+	bearer, err := a.bearerToken()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(a.payload(n))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/3/device/%s", a.opts.Endpoint, n.Token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", a.opts.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("content-type", "application/json")
+	if n.CollapseId != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseId)
+	}
+	if n.Expiration > 0 {
+		req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(n.Expiration).Unix(), 10))
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &result)
+
+	switch result.Reason {
+	case "BadDeviceToken", "Unregistered", "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: %s", ErrInvalidToken, result.Reason)
+	case "ExpiredProviderToken", "InvalidProviderToken": // 令牌过期或无效，重新生成后重试
+		a.resetBearer()
+		return &Error{StatusCode: resp.StatusCode, Reason: result.Reason, Retryable: true}
+	}
+	return &Error{StatusCode: resp.StatusCode, Reason: result.Reason, Retryable: retryableStatus(resp.StatusCode)}
+}
+
+func (a *APNsProvider) payload(n *Notification) map[string]interface{} {
+	aps := map[string]interface{}{
+		"alert": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+	}
+	if n.Badge > 0 {
+		aps["badge"] = n.Badge
+	}
+	if n.Sound != "" {
+		aps["sound"] = n.Sound
+	}
+	payload := map[string]interface{}{}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	payload["aps"] = aps
+	return payload
+}
+
+// bearerToken 获取认证令牌，过期前复用
+func (a *APNsProvider) bearerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.bearer != "" && time.Since(a.bearerIssue) < apnsTokenRefreshInterval {
+		return a.bearer, nil
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.opts.TeamId,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.opts.KeyId
+	bearer, err := token.SignedString(a.privateKey)
+	if err != nil {
+		return "", err
+	}
+	a.bearer = bearer
+	a.bearerIssue = now
+	return bearer, nil
+}
+
+func (a *APNsProvider) resetBearer() {
+	a.mu.Lock()
+	a.bearer = ""
+	a.mu.Unlock()
+}
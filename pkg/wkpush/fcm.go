@@ -0,0 +1,238 @@
+package wkpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	FCMEndpoint = "https://fcm.googleapis.com"
+	FCMTokenURL = "https://oauth2.googleapis.com/token"
+
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMOptions Firebase推送配置（HTTP v1，服务账号认证）
+type FCMOptions struct {
+	Endpoint    string       // 推送地址，为空使用默认地址
+	TokenURL    string       // 获取访问令牌的地址，为空使用服务账号中的地址或默认地址
+	ProjectId   string       // 项目id
+	ClientEmail string       // 服务账号邮箱
+	PrivateKey  []byte       // 服务账号私钥（PEM格式）
+	HTTPClient  *http.Client // 为空使用http.DefaultClient
+}
+
+// LoadFCMServiceAccount 从服务账号json文件读取配置
+func LoadFCMServiceAccount(path string) (FCMOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FCMOptions{}, err
+	}
+	var account struct {
+		ProjectId   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err = json.Unmarshal(data, &account); err != nil {
+		return FCMOptions{}, err
+	}
+	return FCMOptions{
+		TokenURL:    account.TokenURI,
+		ProjectId:   account.ProjectId,
+		ClientEmail: account.ClientEmail,
+		PrivateKey:  []byte(account.PrivateKey),
+	}, nil
+}
+
+// FCMProvider Firebase推送（HTTP v1）
+type FCMProvider struct {
+	opts       FCMOptions
+	privateKey *rsa.PrivateKey
+	client     *http.Client
+
+	mu           sync.Mutex
+	accessToken  string
+	accessExpire time.Time
+}
+
+func NewFCMProvider(opts FCMOptions) (*FCMProvider, error) {
+	if opts.ProjectId == "" || opts.ClientEmail == "" {
+		return nil, errors.New("fcm projectId and clientEmail are required")
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(opts.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse fcm private key failed: %w", err)
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = FCMEndpoint
+	}
+	if opts.TokenURL == "" {
+		opts.TokenURL = FCMTokenURL
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &FCMProvider{
+		opts:       opts,
+		privateKey: privateKey,
+		client:     client,
+	}, nil
+}
+
+func (f *FCMProvider) Name() string {
+	return "fcm"
+}
+
+func (f *FCMProvider) Push(ctx context.Context, n *Notification) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": f.message(n)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1/projects/%s/messages:send", f.opts.Endpoint, f.opts.ProjectId), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(respBody, &result)
+
+	reason := result.Error.Status
+	for _, detail := range result.Error.Details {
+		if detail.ErrorCode != "" {
+			reason = detail.ErrorCode
+			break
+		}
+	}
+	switch {
+	case reason == "UNREGISTERED" || resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+	case resp.StatusCode == http.StatusUnauthorized: // 访问令牌失效，重新获取后重试
+		f.resetToken()
+		return &Error{StatusCode: resp.StatusCode, Reason: reason, Retryable: true}
+	}
+	return &Error{StatusCode: resp.StatusCode, Reason: reason, Retryable: retryableStatus(resp.StatusCode)}
+}
+
+func (f *FCMProvider) message(n *Notification) map[string]interface{} {
+	message := map[string]interface{}{
+		"token": n.Token,
+		"notification": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+	}
+	if len(n.Data) > 0 {
+		message["data"] = n.Data
+	}
+	android := map[string]interface{}{
+		"priority": "high",
+	}
+	if n.CollapseId != "" {
+		android["collapse_key"] = n.CollapseId
+	}
+	if n.Expiration > 0 {
+		android["ttl"] = strconv.FormatInt(int64(n.Expiration/time.Second), 10) + "s"
+	}
+	if n.Sound != "" {
+		android["notification"] = map[string]string{"sound": n.Sound}
+	}
+	message["android"] = android
+	return message
+}
+
+// token 获取访问令牌，过期前一分钟重新获取
+func (f *FCMProvider) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Now().Before(f.accessExpire) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.opts.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.opts.TokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.privateKey)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", &Error{StatusCode: resp.StatusCode, Reason: string(respBody), Retryable: retryableStatus(resp.StatusCode)}
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(respBody, &result); err != nil {
+		return "", err
+	}
+	if result.AccessToken == "" {
+		return "", errors.New("fcm access token is empty")
+	}
+	f.accessToken = result.AccessToken
+	f.accessExpire = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
+
+func (f *FCMProvider) resetToken() {
+	f.mu.Lock()
+	f.accessToken = ""
+	f.mu.Unlock()
+}
@@ -0,0 +1,60 @@
+package wkpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidToken 推送令牌无效（已卸载或令牌过期），调用方应删除此令牌
+var ErrInvalidToken = errors.New("invalid push token")
+
+// Notification 推送通知
+type Notification struct {
+	Token      string            // 设备推送令牌
+	Title      string            // 标题
+	Body       string            // 内容
+	Badge      int               // 角标，小于等于0不设置
+	Sound      string            // 声音
+	CollapseId string            // 合并标识，相同标识的通知只显示最新的一条
+	Data       map[string]string // 自定义数据
+	Expiration time.Duration     // 通知有效期，为0表示使用推送服务的默认值
+}
+
+// Provider 推送服务提供者
+type Provider interface {
+	// Name 提供者名称
+	Name() string
+	// Push 推送通知，令牌无效返回ErrInvalidToken，可以重试的错误通过IsRetryable判断
+	Push(ctx context.Context, n *Notification) error
+}
+
+// Error 推送服务返回的错误
+type Error struct {
+	StatusCode int    // http状态码
+	Reason     string // 推送服务返回的原因
+	Retryable  bool   // 是否可以重试
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("push failed, status: %d, reason: %s", e.StatusCode, e.Reason)
+}
+
+// IsRetryable 推送错误是否可以重试，网络错误和推送服务的限流及服务端错误可以重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		return false
+	}
+	var pushErr *Error
+	if errors.As(err, &pushErr) {
+		return pushErr.Retryable
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// retryableStatus 限流和服务端错误可以重试
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
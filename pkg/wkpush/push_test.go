@@ -0,0 +1,145 @@
+package wkpush_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkpush"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPNsPush(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, "com.example.app", r.Header.Get("apns-topic"))
+
+		// 校验token认证
+		bearer := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		token, err := jwt.Parse(bearer, func(token *jwt.Token) (interface{}, error) {
+			return &ecKey.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}))
+		assert.NoError(t, err)
+		assert.Equal(t, "KEY123", token.Header["kid"])
+
+		switch r.URL.Path {
+		case "/3/device/good":
+			var payload map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "g1", payload["channel_id"])
+			assert.Equal(t, "hello", payload["aps"].(map[string]interface{})["alert"].(map[string]interface{})["body"])
+			w.WriteHeader(http.StatusOK)
+		case "/3/device/bad":
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"reason":"ServiceUnavailable"}`))
+		}
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	provider, err := wkpush.NewAPNsProvider(wkpush.APNsOptions{
+		Endpoint:   srv.URL,
+		KeyId:      "KEY123",
+		TeamId:     "TEAM123",
+		Topic:      "com.example.app",
+		PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+		HTTPClient: srv.Client(),
+	})
+	assert.NoError(t, err)
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "good", Title: "title", Body: "hello", Data: map[string]string{"channel_id": "g1"}})
+	assert.NoError(t, err)
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "bad", Body: "hello"})
+	assert.ErrorIs(t, err, wkpush.ErrInvalidToken)
+	assert.False(t, wkpush.IsRetryable(err))
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "busy", Body: "hello"})
+	assert.Error(t, err)
+	assert.True(t, wkpush.IsRetryable(err))
+}
+
+func TestFCMPush(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	var tokenRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests.Add(1)
+			assert.NoError(t, r.ParseForm())
+			assertion, err := jwt.Parse(r.Form.Get("assertion"), func(token *jwt.Token) (interface{}, error) {
+				return &rsaKey.PublicKey, nil
+			}, jwt.WithValidMethods([]string{"RS256"}))
+			assert.NoError(t, err)
+			iss, _ := assertion.Claims.GetIssuer()
+			assert.Equal(t, "push@example.iam.gserviceaccount.com", iss)
+			_, _ = w.Write([]byte(`{"access_token":"access123","expires_in":3600}`))
+		case "/v1/projects/demo/messages:send":
+			assert.Equal(t, "Bearer access123", r.Header.Get("Authorization"))
+			var body struct {
+				Message struct {
+					Token string            `json:"token"`
+					Data  map[string]string `json:"data"`
+				} `json:"message"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			switch body.Message.Token {
+			case "good":
+				assert.Equal(t, "g1", body.Message.Data["channel_id"])
+				_, _ = w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+			case "bad":
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			default:
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"error":{"status":"RESOURCE_EXHAUSTED"}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	provider, err := wkpush.NewFCMProvider(wkpush.FCMOptions{
+		Endpoint:    srv.URL,
+		TokenURL:    srv.URL + "/token",
+		ProjectId:   "demo",
+		ClientEmail: "push@example.iam.gserviceaccount.com",
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+	})
+	assert.NoError(t, err)
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "good", Body: "hello", Data: map[string]string{"channel_id": "g1"}})
+	assert.NoError(t, err)
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "bad", Body: "hello"})
+	assert.ErrorIs(t, err, wkpush.ErrInvalidToken)
+
+	err = provider.Push(context.Background(), &wkpush.Notification{Token: "busy", Body: "hello"})
+	assert.True(t, wkpush.IsRetryable(err))
+
+	// 访问令牌在有效期内复用
+	assert.Equal(t, int32(1), tokenRequests.Load())
+}
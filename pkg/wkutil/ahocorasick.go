@@ -0,0 +1,120 @@
+package wkutil
+
+import (
+	"unicode"
+)
+
+// AhoCorasick 多模式串匹配自动机（按rune匹配，忽略大小写），构建后只读，可并发使用
+type AhoCorasick struct {
+	nodes       []acNode
+	patternLens []int // 模式串的rune数量
+}
+
+type acNode struct {
+	children map[rune]int32
+	fail     int32
+	outputs  []int // 以此节点结尾的模式串下标（包含失败节点的输出）
+}
+
+// AhoCorasickMatch 匹配结果，Start和End为文本中rune的下标，区间为[Start,End)
+type AhoCorasickMatch struct {
+	Pattern int // 模式串下标
+	Start   int
+	End     int
+}
+
+// NewAhoCorasick 根据模式串构建自动机，空的模式串会被忽略
+func NewAhoCorasick(patterns []string) *AhoCorasick {
+	ac := &AhoCorasick{
+		nodes:       []acNode{{children: make(map[rune]int32)}},
+		patternLens: make([]int, len(patterns)),
+	}
+	for i, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		cur := int32(0)
+		for _, r := range pattern {
+			r = unicode.ToLower(r)
+			next, ok := ac.nodes[cur].children[r]
+			if !ok {
+				next = int32(len(ac.nodes))
+				ac.nodes = append(ac.nodes, acNode{children: make(map[rune]int32)})
+				ac.nodes[cur].children[r] = next
+			}
+			cur = next
+			ac.patternLens[i]++
+		}
+		ac.nodes[cur].outputs = append(ac.nodes[cur].outputs, i)
+	}
+	ac.buildFail()
+	return ac
+}
+
+// buildFail 按层级构建失败指针
+func (ac *AhoCorasick) buildFail() {
+	queue := make([]int32, 0, len(ac.nodes))
+	for _, child := range ac.nodes[0].children {
+		ac.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range ac.nodes[cur].children {
+			fail := ac.nodes[cur].fail
+			for {
+				if next, ok := ac.nodes[fail].children[r]; ok && next != child {
+					ac.nodes[child].fail = next
+					break
+				}
+				if fail == 0 {
+					ac.nodes[child].fail = 0
+					break
+				}
+				fail = ac.nodes[fail].fail
+			}
+			// 合并失败节点的输出，匹配时不需要再沿失败指针查找
+			failOutputs := ac.nodes[ac.nodes[child].fail].outputs
+			if len(failOutputs) > 0 {
+				ac.nodes[child].outputs = append(ac.nodes[child].outputs, failOutputs...)
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Empty 是否没有任何模式串
+func (ac *AhoCorasick) Empty() bool {
+	return len(ac.nodes) <= 1
+}
+
+// FindAll 查找文本中所有匹配的模式串
+func (ac *AhoCorasick) FindAll(text []rune) []AhoCorasickMatch {
+	if ac.Empty() {
+		return nil
+	}
+	var matches []AhoCorasickMatch
+	cur := int32(0)
+	for i, r := range text {
+		r = unicode.ToLower(r)
+		for {
+			if next, ok := ac.nodes[cur].children[r]; ok {
+				cur = next
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = ac.nodes[cur].fail
+		}
+		for _, pattern := range ac.nodes[cur].outputs {
+			matches = append(matches, AhoCorasickMatch{
+				Pattern: pattern,
+				Start:   i + 1 - ac.patternLens[pattern],
+				End:     i + 1,
+			})
+		}
+	}
+	return matches
+}
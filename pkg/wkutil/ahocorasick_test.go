@@ -0,0 +1,29 @@
+package wkutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAhoCorasickFindAll(t *testing.T) {
+	ac := NewAhoCorasick([]string{"he", "she", "his", "hers", "", "敏感词"})
+
+	matches := ac.FindAll([]rune("ushers"))
+	assert.ElementsMatch(t, []AhoCorasickMatch{
+		{Pattern: 1, Start: 1, End: 4}, // she
+		{Pattern: 0, Start: 2, End: 4}, // he
+		{Pattern: 3, Start: 2, End: 6}, // hers
+	}, matches)
+
+	// 忽略大小写
+	matches = ac.FindAll([]rune("HIS"))
+	assert.Equal(t, []AhoCorasickMatch{{Pattern: 2, Start: 0, End: 3}}, matches)
+
+	// 多字节字符按rune计算位置
+	matches = ac.FindAll([]rune("这是敏感词！"))
+	assert.Equal(t, []AhoCorasickMatch{{Pattern: 5, Start: 2, End: 5}}, matches)
+
+	assert.Empty(t, ac.FindAll([]rune("nothing")))
+	assert.True(t, NewAhoCorasick(nil).Empty())
+}
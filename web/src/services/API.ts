@@ -114,9 +114,19 @@ export default class API {
         })
     }
 
-    // 获取频道订阅者
-    public subscribers(channelId: string, channelType: number): Promise<any> {
-        return APIClient.shared.get(`/cluster/channels/${channelId}/${channelType}/subscribers`)
+    // 获取频道订阅者（传limit时分页获取，cursor为上一页最后一个订阅者的uid）
+    public subscribers(channelId: string, channelType: number, cursor?: string, limit?: number): Promise<any> {
+        return APIClient.shared.get(`/cluster/channels/${channelId}/${channelType}/subscribers`, {
+            param: {
+                cursor: cursor,
+                limit: limit,
+            }
+        })
+    }
+
+    // 获取频道订阅者数量
+    public subscriberCount(channelId: string, channelType: number): Promise<any> {
+        return APIClient.shared.get(`/cluster/channels/${channelId}/${channelType}/subscribers/count`)
     }
 
     // 获取频道黑名单列表